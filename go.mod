@@ -1,99 +1,124 @@
 module github.com/pathbox/learning-go
 
-go 1.14
+go 1.18
 
 require (
 	github.com/BurntSushi/toml v0.3.1
-	github.com/PuerkitoBio/boom v0.0.0-20140219125548-fecdef1c97ca // indirect
 	github.com/RoaringBitmap/roaring v0.4.21
 	github.com/Shopify/sarama v1.26.4
-	github.com/asim/go-micro/v3 v3.5.0 // indirect
-	github.com/atotto/clipboard v0.1.4 // indirect
+	github.com/alicebob/miniredis v2.5.0+incompatible
 	github.com/benmanns/goworker v0.1.3
-	github.com/bits-and-blooms/bitset v1.2.1 // indirect
 	github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc
-	github.com/bramvdbogaerde/go-scp v0.0.0-20200119201711-987556b8bdd7 // indirect
 	github.com/bwmarrin/snowflake v0.3.0
 	github.com/c3mb0/go-do-work v0.0.0-20160309135746-a33fd02143e1
-	github.com/cihub/seelog v0.0.0-20170130134532-f561c5e57575 // indirect
 	github.com/cirocosta/gupload v0.0.0-20180103143842-e6d8fa4fdf4c
 	github.com/coreos/etcd v3.3.20+incompatible
-	github.com/coreos/go-semver v0.3.0 // indirect
-	github.com/coreos/pkg v0.0.0-20180928190104-399ea9e2e55f // indirect
 	github.com/cornelk/hashmap v1.0.1
 	github.com/dchest/siphash v1.2.2
 	github.com/djimenez/iconv-go v0.0.0-20160305225143-8960e66bd3da
 	github.com/dlclark/regexp2 v1.2.0
 	github.com/dovejb/quicktag v0.0.0-20190829080553-340537080f34
 	github.com/eapache/channels v1.1.0
-	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
-	github.com/facebookgo/grace v0.0.0-20180706040059-75cf19382434 // indirect
-	github.com/facebookgo/httpdown v0.0.0-20180706035922-5979d39b15c2 // indirect
-	github.com/facebookgo/stats v0.0.0-20151006221625-1b76add642e4 // indirect
 	github.com/fvbock/endless v0.0.0-20170109170031-447134032cb6
 	github.com/garyburd/redigo v1.6.0
+	github.com/gin-gonic/gin v1.6.3
+	github.com/go-playground/validator/v10 v10.2.0
 	github.com/go-sql-driver/mysql v1.5.0
 	github.com/gobwas/glob v0.2.3
 	github.com/gobwas/pool v0.2.1
 	github.com/gocraft/web v0.0.0-20190207150652-9707327fb69b
-	github.com/gogo/protobuf v1.3.1 // indirect
 	github.com/golang/mock v1.4.4
 	github.com/golang/protobuf v1.4.2
-	github.com/google/pprof v0.0.0-20201117184057-ae444373da19 // indirect
+	github.com/golang/snappy v0.0.1
 	github.com/gorilla/context v1.1.1
 	github.com/gorilla/mux v1.7.3
-	github.com/grooveshark/golib v0.1.0 // indirect
-	github.com/grpc-ecosystem/go-grpc-middleware v1.2.2 // indirect
-	github.com/hashicorp/golang-lru v0.5.3 // indirect
+	github.com/hashicorp/golang-lru v0.5.3
 	github.com/hashicorp/raft v1.1.2
 	github.com/huandu/skiplist v0.0.0-20191129113331-b90e16040d86
 	github.com/jmoiron/sqlx v1.2.0
-	github.com/json-iterator/go v1.1.9 // indirect
 	github.com/justinas/alice v0.0.0-20171023064455-03f45bd4b7da
 	github.com/justinas/nosurf v0.0.0-20190416172904-05988550ea18
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/kinwyb/go v0.0.0-20201029032031-48239eb7299c
-	github.com/klauspost/reedsolomon v1.9.9 // indirect
 	github.com/lib/pq v1.2.0
 	github.com/mediocregopher/okq-go v0.0.0-20160211201133-048e319dd5ee
-	github.com/mediocregopher/radix.v2 v0.0.0-20181115013041-b67df6e626f9 // indirect
-	github.com/mitchellh/cli v1.1.2 // indirect
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/natefinch/lumberjack v2.0.0+incompatible
+	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d
+	github.com/orcaman/concurrent-map v1.0.0
 	github.com/ory/ladon v1.2.0
 	github.com/pascaldekloe/redis v1.3.0
-	github.com/pborman/uuid v1.2.0 // indirect
-	github.com/pkg/browser v0.0.0-20210621091255-c198bc921a84 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/pkg/profile v1.4.0
 	github.com/pquerna/otp v1.2.0
-	github.com/rakyll/pb v0.0.0-20160123035540-8d46b8b097ef // indirect
 	github.com/rs/zerolog v1.18.0
 	github.com/sirupsen/logrus v1.6.0
-	github.com/sjwhitworth/golearn v0.0.0-20201127221938-294d65fca392 // indirect
 	github.com/spaolacci/murmur3 v1.1.0
 	github.com/stretchr/testify v1.6.1
 	github.com/stvp/tempredis v0.0.0-20181119212430-b82af8480203
-	github.com/templexxx/cpufeat v0.0.0-20180724012125-cef66df7f161 // indirect
 	github.com/templexxx/tsc v0.0.3
-	github.com/templexxx/xor v0.0.0-20191217153810-f85b25db303b // indirect
 	github.com/throttled/throttled v2.2.4+incompatible
 	github.com/tidwall/wal v0.1.1
-	github.com/tikv/minitrace-go v0.0.0-20210119063709-5194f6ab6fd7 // indirect
-	github.com/tjfoc/gmsm v1.3.2 // indirect
 	github.com/willf/bitset v1.1.10
-	github.com/xtaci/kcp-go v5.4.20+incompatible // indirect
 	github.com/yangwenmai/ratelimit v0.0.0-20180104140304-44221c2292e1
-	github.com/youtube/vitess v2.1.1+incompatible // indirect
 	github.com/zenazn/goji v0.9.0
 	go.etcd.io/etcd v3.3.22+incompatible
 	go.uber.org/zap v1.14.1
+	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	golang.org/x/exp v0.0.0-20200331195152-e8c3332aa8e5
 	golang.org/x/net v0.0.0-20201021035429-f5854403a974
-	golang.org/x/tools v0.0.0-20201125231158-b5590deeca9b // indirect
+	golang.org/x/text v0.3.5
 	google.golang.org/grpc v1.29.1
-	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
+	google.golang.org/protobuf v1.23.0
 	gopkg.in/throttled/throttled.v1 v1.0.0
+	gopkg.in/yaml.v2 v2.3.0
+	rsc.io/qr v0.2.0
+)
+
+require (
+	github.com/PuerkitoBio/boom v0.0.0-20140219125548-fecdef1c97ca // indirect
+	github.com/alicebob/gopher-json v0.0.0-20200520072559-a9ecdc9d1d3a // indirect
+	github.com/asim/go-micro/v3 v3.5.0 // indirect
+	github.com/atotto/clipboard v0.1.4 // indirect
+	github.com/bits-and-blooms/bitset v1.2.1 // indirect
+	github.com/bramvdbogaerde/go-scp v0.0.0-20200119201711-987556b8bdd7 // indirect
+	github.com/cihub/seelog v0.0.0-20170130134532-f561c5e57575 // indirect
+	github.com/coreos/go-semver v0.3.0 // indirect
+	github.com/coreos/pkg v0.0.0-20180928190104-399ea9e2e55f // indirect
+	github.com/facebookgo/clock v0.0.0-20150410010913-600d898af40a // indirect
+	github.com/facebookgo/grace v0.0.0-20180706040059-75cf19382434 // indirect
+	github.com/facebookgo/httpdown v0.0.0-20180706035922-5979d39b15c2 // indirect
+	github.com/facebookgo/stats v0.0.0-20151006221625-1b76add642e4 // indirect
+	github.com/gin-contrib/sse v0.1.0 // indirect
+	github.com/go-playground/locales v0.13.0 // indirect
+	github.com/go-playground/universal-translator v0.17.0 // indirect
+	github.com/gogo/protobuf v1.3.1 // indirect
+	github.com/gomodule/redigo v2.0.0+incompatible // indirect
+	github.com/google/pprof v0.0.0-20201117184057-ae444373da19 // indirect
+	github.com/grooveshark/golib v0.1.0 // indirect
+	github.com/grpc-ecosystem/go-grpc-middleware v1.2.2 // indirect
+	github.com/json-iterator/go v1.1.9 // indirect
+	github.com/klauspost/reedsolomon v1.9.9 // indirect
+	github.com/leodido/go-urn v1.2.0 // indirect
+	github.com/mattn/go-isatty v0.0.12 // indirect
+	github.com/mediocregopher/radix.v2 v0.0.0-20181115013041-b67df6e626f9 // indirect
+	github.com/mitchellh/cli v1.1.2 // indirect
+	github.com/pborman/uuid v1.2.0 // indirect
+	github.com/pkg/browser v0.0.0-20210621091255-c198bc921a84 // indirect
+	github.com/rakyll/pb v0.0.0-20160123035540-8d46b8b097ef // indirect
+	github.com/sjwhitworth/golearn v0.0.0-20201127221938-294d65fca392 // indirect
+	github.com/templexxx/cpufeat v0.0.0-20180724012125-cef66df7f161 // indirect
+	github.com/templexxx/xor v0.0.0-20191217153810-f85b25db303b // indirect
+	github.com/tikv/minitrace-go v0.0.0-20210119063709-5194f6ab6fd7 // indirect
+	github.com/tjfoc/gmsm v1.3.2 // indirect
+	github.com/ugorji/go/codec v1.1.7 // indirect
+	github.com/xtaci/kcp-go v5.4.20+incompatible // indirect
+	github.com/youtube/vitess v2.1.1+incompatible // indirect
+	github.com/yuin/gopher-lua v0.0.0-20191220021717-ab39c6098bdb // indirect
+	golang.org/x/sys v0.0.0-20210319071255-635bc2c9138d // indirect
+	golang.org/x/tools v0.0.0-20201125231158-b5590deeca9b // indirect
+	google.golang.org/genproto v0.0.0-20200430143042-b979b6f78d84 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
 	sigs.k8s.io/yaml v1.2.0 // indirect
 )
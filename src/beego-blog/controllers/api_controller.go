@@ -1,16 +1,17 @@
+package controllers
+
 import (
   "fmt"
   "strings"
-  "time"
 
   "github.com/ulricqin/beego-blog/g"
+  "github.com/ulricqin/beego-blog/media"
   "github.com/ulricqin/beego-blog/models/catalog"
-  "github.com/ulricqin/goutils/filetool"
   "github.com/ulricqin/goutils/strtool"
 )
 
 const (
-  EDITOR_IMG_DIR = "static/uploads/editor"
+  EDITOR_IMG_PREFIX = "editor"
 )
 
 type ApiController struct {
@@ -35,38 +36,40 @@ func (this *ApiController) Upload() {
   defer func() {
     this.Data["json"] = &result
     this.ServeJSON()
-    }()
+  }()
 
-    _, header, err := this.GetFile("image")
-    if err != nil {
-      return
-    }
+  if !this.IsAdmin {
+    result["message"] = "login first"
+    return
+  }
 
-    ext := filetool.Ext(header.Filename)
-    imgPath := fmt.Sprintf("%s/%d%s", EDITOR_IMG_DIR, time.Now().Unix(), ext)
+  f, _, err := this.GetFile("image")
+  if err != nil {
+    result["message"] = err.Error()
+    return
+  }
+  defer f.Close()
 
-    filetool.InsureDir(EDITOR_IMG_DIR)
-    err = this.SaveToFile("image", imgPath)
-    if err != nil {
-      return
-    }
+  data, err := media.ReadUpload(f, g.MediaOptions)
+  if err != nil {
+    result["message"] = err.Error()
+    return
+  }
 
-    imgUrl := "/" + imgPath
-    if g.UseQiniu{
-      if addr, er := g.UploadFile(imgPath, imgPath); er != nil {
-        return
-      } else {
-        imgUrl = addr
-        filetool.Unlink(imgPath)
-      }
-    }
+  m, err := media.Save(g.Media, EDITOR_IMG_PREFIX, data, g.MediaOptions)
+  if err != nil {
+    result["message"] = err.Error()
+    return
+  }
 
-    result["link"] = imgUrl
+  result["link"] = m.Url
+  if m.Thumb != nil {
+    result["thumb"] = m.Thumb.Url
+  }
   result["success"] = true
 }
 
-
-func (this *Application) Markdown() {
+func (this *ApiController) Markdown() {
   if this.IsAjax() {
     result := map[string]interface{}{
       "success": false,
@@ -81,4 +84,4 @@ func (this *Application) Markdown() {
     this.Data["json"] = result
     this.ServeJSON()
   }
-}
\ No newline at end of file
+}
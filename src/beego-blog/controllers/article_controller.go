@@ -12,7 +12,7 @@ type ArticleController struct {
 
 func (this *ArticleController) Draft() {
   var blogs []*models.Blog
-  blog.Blogs().Filter("Status": 0).All(&blogs)
+  blog.Blogs().Filter("Status", 0).All(&blogs)
   this.Data["Blogs"] = blogs
   this.Layout = "layout/admin.html"
   this.TplName = "article/draft.html"
@@ -30,6 +30,7 @@ func (this *ArticleController) DoAdd() {
   title := this.GetString("title")
   ident := this.GetString("ident")
   keywords := this.GetString("keywords")
+  tags := this.GetString("tags")
   catalog_id := this.GetIntWithDefault("catalog_id", -1)
   aType := this.GetIntWithDefault("type", -1)
   status := this.GetIntWithDefault("status", -1)
@@ -51,7 +52,7 @@ func (this *ArticleController) DoAdd() {
     return
   }
 
-  b := &models.Blog{Ident: ident, Title: title, Keywords: keywords, CatalogId: int64(catalog_id), Type: int8(aType), Status: int8(status)}
+  b := &models.Blog{Ident: ident, Title: title, Keywords: keywords, Tags: tags, CatalogId: int64(catalog_id), Type: int8(aType), Status: int8(status)}
   _, err := blog.Save(b, content)
 
   if err != nil {
@@ -99,6 +100,7 @@ func (this *ArticleController) DoEdit() {
   title := this.GetString("title")
   ident := this.GetString("ident")
   keywords := this.GetString("keywords")
+  tags := this.GetString("tags")
   catalog_id := this.GetIntWithDefault("catalog_id", -1)
   aType := this.GetIntWithDefault("type", -1)
   status := this.GetIntWithDefault("status", -1)
@@ -123,6 +125,7 @@ func (this *ArticleController) DoEdit() {
   b.Ident = ident
   b.Title = title
   b.Keywords = keywords
+  b.Tags = tags
   b.CatalogId = int64(catalog_id)
   b.Type = int8(aType)
   b.Status = int8(status)
@@ -3,14 +3,13 @@ package controllers
 import (
 	"fmt"
 	"github.com/ulricqin/beego-blog/g"
+	"github.com/ulricqin/beego-blog/media"
 	"github.com/ulricqin/beego-blog/models"
 	"github.com/ulricqin/beego-blog/models/catalog"
-	"github.com/ulricqin/goutils/filetool"
-	"time"
 )
 
 const (
-	CATALOG_IMG_DIR = "static/uploads/catalogs"
+	CATALOG_IMG_PREFIX = "catalogs"
 )
 
 type CatalogController struct {
@@ -79,33 +78,24 @@ func (this *CatalogController) extractCatalog(imgMust bool) (*models.Catalog, er
 		return nil, fmt.Errorf("ident is blank")
 	}
 
-	_, header, err := this.GetFile("img")
+	f, _, err := this.GetFile("img")
 	if err != nil && imgMust {
 		return nil, err
 	}
 
 	if err == nil {
-		ext := filetool.Ext(header.Filename)
-		imgPath := fmt.Sprintf("%s/%s_%d%s", CATALOG_IMG_DIR, o.Ident, time.Now().Unix(), ext)
-
-		filetool.InsureDir(CATALOG_IMG_DIR)
-		err = this.SaveToFile("img", imgPath)
+		defer f.Close()
+		var m *media.Media
+		data, err := media.ReadUpload(f, g.MediaOptions)
+		if err == nil {
+			m, err = media.Save(g.Media, CATALOG_IMG_PREFIX, data, g.MediaOptions)
+		}
 		if err != nil && imgMust {
 			return nil, err
 		}
 
 		if err == nil {
-			o.ImgUrl = "/" + imgPath
-			if g.UseQiniu {
-				if addr, er := g.UploadFile(imgPath, imgPath); er != nil {
-					if imgMust {
-						return nil, er
-					}
-				} else {
-					o.ImgUrl = addr
-					filetool.Unlink(imgPath)
-				}
-			}
+			o.ImgUrl = m.Url
 		}
 	}
 
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/ulricqin/beego-blog/g"
+	"github.com/ulricqin/beego-blog/models/blog"
+	"github.com/ulricqin/beego-blog/models/feed"
+	"github.com/ulricqin/beego-blog/models/search"
+)
+
+const feedSize = 20
+
+type FeedController struct {
+	BaseController
+}
+
+// baseUrl returns the absolute url of the blog
+func (this *FeedController) baseUrl() string {
+	if g.BlogUrl != "" {
+		return g.BlogUrl
+	}
+	return this.Ctx.Input.Scheme() + "://" + this.Ctx.Input.Host()
+}
+
+func (this *FeedController) build(self string) *feed.Feed {
+	base := this.baseUrl()
+	f := &feed.Feed{
+		Title:       g.BlogTitle,
+		Link:        base + "/",
+		Self:        base + self,
+		Description: g.BlogResume,
+		Author:      g.RootName,
+		Email:       g.RootEmail,
+	}
+
+	for _, b := range blog.Latest(feedSize) {
+		item := feed.Item{
+			Title:      b.Title,
+			Link:       base + "/article/" + b.Ident,
+			Categories: b.TagList(),
+			Published:  b.Created,
+			Updated:    time.Unix(b.BlogContentLastUpdate, 0),
+		}
+		if b.Content != nil {
+			item.Summary = search.PlainText(b.Content.Content)
+			if r := []rune(item.Summary); len(r) > 300 {
+				item.Summary = string(r[:300]) + "..."
+			}
+			item.Content = g.RenderMarkdown(b.Content.Content)
+		}
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+		f.Items = append(f.Items, item)
+	}
+	if f.Updated.IsZero() {
+		f.Updated = time.Now()
+	}
+	return f
+}
+
+func (this *FeedController) write(contentType string, body []byte, err error) {
+	if err != nil {
+		this.Ctx.Output.SetStatus(500)
+		this.Ctx.WriteString(err.Error())
+		return
+	}
+	this.Ctx.Output.Header("Content-Type", contentType+"; charset=utf-8")
+	this.Ctx.Output.Body(body)
+}
+
+func (this *FeedController) Atom() {
+	body, err := this.build("/feed.atom").Atom()
+	this.write("application/atom+xml", body, err)
+}
+
+func (this *FeedController) Rss() {
+	body, err := this.build("/feed.rss").RSS()
+	this.write("application/rss+xml", body, err)
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"strings"
+
 	"github.com/ulricqin/beego-blog/g"
 	"github.com/ulricqin/beego-blog/models/blog"
 	"github.com/ulricqin/beego-blog/models/catalog"
@@ -62,3 +64,41 @@ func (this *MainController) ListByCatalog() {
 	this.Layout = "layout/default.html"
 	this.TplName = "article/by_catalog.html"
 }
+
+func (this *MainController) ListByTag() {
+	tag := blog.NormalizeTags(this.Ctx.Input.Param(":tag"))
+	if tag == "" {
+		this.Ctx.WriteString("tag is blank")
+		return
+	}
+
+	limit := this.GetIntWithDefault("limit", 10)
+
+	ids := blog.IdsByTag(tag)
+	pager := this.SetPaginator(limit, int64(len(ids)))
+	blogs := blog.ByTag(tag, pager.Offset(), limit)
+
+	this.Data["Tag"] = tag
+	this.Data["Blogs"] = blogs
+	this.Data["PageTitle"] = "#" + tag
+
+	this.Layout = "layout/default.html"
+	this.TplName = "article/by_tag.html"
+}
+
+func (this *MainController) Search() {
+	query := strings.TrimSpace(this.GetString("q"))
+	limit := this.GetIntWithDefault("limit", 10)
+
+	total, _ := blog.Search(query, 0, 0)
+	pager := this.SetPaginator(limit, int64(total))
+	_, hits := blog.Search(query, pager.Offset(), limit)
+
+	this.Data["Query"] = query
+	this.Data["Total"] = total
+	this.Data["Hits"] = hits
+	this.Data["PageTitle"] = "搜索：" + query
+
+	this.Layout = "layout/default.html"
+	this.TplName = "search.html"
+}
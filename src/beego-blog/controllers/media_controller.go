@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"github.com/ulricqin/beego-blog/g"
+	"github.com/ulricqin/beego-blog/media"
+)
+
+type MediaController struct {
+	AdminController
+}
+
+func (this *MediaController) List() {
+	prefix := this.GetString("prefix")
+	if prefix != EDITOR_IMG_PREFIX && prefix != CATALOG_IMG_PREFIX {
+		prefix = ""
+	}
+
+	library, err := media.Library(g.Media, prefix)
+	if err != nil {
+		this.Ctx.WriteString(err.Error())
+		return
+	}
+
+	this.Data["Media"] = library
+	this.Data["Prefix"] = prefix
+	this.Data["IsMedia"] = true
+	this.Layout = "layout/admin.html"
+	this.TplName = "media/list.html"
+}
+
+func (this *MediaController) Del() {
+	key := this.GetString("key")
+	if err := media.Remove(g.Media, key); err != nil {
+		this.Ctx.WriteString(err.Error())
+		return
+	}
+
+	this.Redirect("/me/media?prefix="+this.GetString("prefix"), 302)
+}
@@ -12,6 +12,7 @@ CREATE TABLE IF NOT EXISTS `bb_blog` (
    `ident` varchar(255) NOT NULL UNIQUE,
    `title` varchar(255) NOT NULL,
    `keywords` varchar(255),
+   `tags` varchar(255),
    `catalog_id` bigint NOT NULL,
    `blog_content_id` bigint NOT NULL UNIQUE,
    `blog_content_last_update` bigint NOT NULL,
@@ -27,3 +28,6 @@ CREATE TABLE IF NOT EXISTS `bb_blog_content` (
    `id` bigint AUTO_INCREMENT NOT NULL PRIMARY KEY,
    `content` longtext NOT NULL
 ) ENGINE=INNODB DEFAULT CHARSET=utf8 COLLATE=utf8_general_ci;
+
+-- upgrade of an existing database:
+-- ALTER TABLE `bb_blog` ADD COLUMN `tags` varchar(255) AFTER `keywords`;
@@ -1,21 +1,18 @@
 package g
 
 import (
-	"github.com/qiniu/api.v6/conf"
+	"strings"
 )
 
 var (
-	RootEmail      string
-	RootName       string
-	RootPass       string
-	RootPortrait   string
-	BlogTitle      string
-	BlogResume     string
-	BlogLogo       string
-	QiniuAccessKey string
-	QiniuSecretKey string
-	QiniuScope     string
-	UseQiniu       bool
+	RootEmail    string
+	RootName     string
+	RootPass     string
+	RootPortrait string
+	BlogTitle    string
+	BlogResume   string
+	BlogLogo     string
+	BlogUrl      string
 )
 
 func initCfg() {
@@ -26,10 +23,6 @@ func initCfg() {
 	BlogTitle = Cfg.String("blog_title")
 	BlogResume = Cfg.String("blog_resume")
 	BlogLogo = Cfg.String("blog_logo")
-	QiniuAccessKey = Cfg.String("qiniu_access_key")
-	QiniuSecretKey = Cfg.String("qiniu_secret_key")
-	QiniuScope = Cfg.String("qiniu_scope")
-	UseQiniu = QiniuAccessKey != "" && QiniuSecretKey != "" && QiniuScope != ""
-	conf.ACCESS_KEY = QiniuAccessKey
-	conf.SECRET_KEY = QiniuSecretKey
+	BlogUrl = strings.TrimRight(Cfg.String("blog_url"), "/")
+	initMedia()
 }
@@ -0,0 +1,40 @@
+package g
+
+import (
+	"os"
+
+	"github.com/ulricqin/beego-blog/media"
+	log "github.com/ulricqin/goutils/logtool"
+)
+
+// Media keeps the uploaded images, configured by media_store in app.conf
+var Media media.MediaStore
+var MediaOptions media.Options
+
+func initMedia() {
+	MediaOptions = media.Options{
+		MaxSize:     Cfg.DefaultInt64("media_max_size", 10<<20),
+		MaxPixels:   Cfg.DefaultInt64("media_max_pixels", 40000000),
+		MaxWidth:    Cfg.DefaultInt("media_max_width", 1600),
+		ThumbWidth:  Cfg.DefaultInt("media_thumb_width", 320),
+		ThumbHeight: Cfg.DefaultInt("media_thumb_height", 240),
+	}
+
+	switch store := Cfg.DefaultString("media_store", "local"); store {
+	case "local":
+		Media = media.NewLocalStore(Cfg.DefaultString("media_dir", "static/uploads"), Cfg.DefaultString("media_url", "/static/uploads"))
+	case "s3":
+		Media = &media.S3Store{
+			Endpoint:    Cfg.String("s3_endpoint"),
+			Region:      Cfg.DefaultString("s3_region", "us-east-1"),
+			Bucket:      Cfg.String("s3_bucket"),
+			AccessKey:   Cfg.String("s3_access_key"),
+			SecretKey:   Cfg.String("s3_secret_key"),
+			VirtualHost: Cfg.DefaultBool("s3_virtual_host", false),
+			PublicUrl:   Cfg.String("s3_public_url"),
+		}
+	default:
+		log.Fetal("unknown media_store: %s", store)
+		os.Exit(1)
+	}
+}
@@ -3,10 +3,16 @@ package main
 import (
 	"github.com/astaxie/beego"
 	"github.com/ulricqin/beego-blog/g"
+	"github.com/ulricqin/beego-blog/models/blog"
 	_ "github.com/ulricqin/beego-blog/routers"
 )
 
 func main() {
 	g.InitEnv()
+	if n, err := blog.BuildIndex(); err != nil {
+		beego.Error("search index build fail:", err)
+	} else {
+		beego.Info("search index built with", n, "articles")
+	}
 	beego.Run()
 }
@@ -0,0 +1,88 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/nfnt/resize"
+)
+
+// Image is an upload ready to be stored
+type Image struct {
+	Data             []byte
+	ContentType      string
+	Ext              string
+	Thumb            []byte // nil without thumbnail box
+	ThumbContentType string
+}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Process sniffs the type of data, whatever the name of the upload, and
+// decodes it unless it has more than MaxPixels pixels. An image wider than MaxWidth is scaled down, a thumbnail fitting
+// the thumbnail box is made. The animated gifs are kept as is, their
+// thumbnail is the first frame.
+func Process(data []byte, opts Options) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	// check the size in the header before allocating the pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if opts.MaxPixels > 0 && int64(cfg.Width)*int64(cfg.Height) > opts.MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	ret := &Image{Data: data, ContentType: contentType, Ext: ext}
+	if contentType != "image/gif" && opts.MaxWidth > 0 && img.Bounds().Dx() > opts.MaxWidth {
+		img = resize.Resize(uint(opts.MaxWidth), 0, img, resize.Lanczos3)
+		if ret.Data, err = encode(img, contentType); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ThumbWidth > 0 && opts.ThumbHeight > 0 {
+		thumb := resize.Thumbnail(uint(opts.ThumbWidth), uint(opts.ThumbHeight), img, resize.Lanczos3)
+		// the first frame of a gif keeps its transparency in png
+		ret.ThumbContentType = contentType
+		if contentType == "image/gif" {
+			ret.ThumbContentType = "image/png"
+		}
+		if ret.Thumb, err = encode(thumb, ret.ThumbContentType); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	return buf.Bytes(), err
+}
@@ -0,0 +1,86 @@
+package media
+
+import (
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps the files in Dir, served at UrlPrefix, like
+// static/uploads served at /static/uploads by beego
+type LocalStore struct {
+	Dir       string
+	UrlPrefix string
+}
+
+func NewLocalStore(dir, urlPrefix string) *LocalStore {
+	return &LocalStore{Dir: dir, UrlPrefix: strings.TrimRight(urlPrefix, "/")}
+}
+
+func (this *LocalStore) path(key string) string {
+	return filepath.Join(this.Dir, filepath.FromSlash(key))
+}
+
+func (this *LocalStore) Put(key string, data []byte, contentType string) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	p := this.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(p, data, 0644); err != nil {
+		return "", err
+	}
+	return this.Url(key), nil
+}
+
+func (this *LocalStore) Delete(key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(this.path(key))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (this *LocalStore) List(prefix string) ([]Object, error) {
+	ret := []Object{}
+	err := filepath.Walk(this.Dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == this.Dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(this.Dir, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		ret = append(ret, Object{
+			Key:         key,
+			Url:         this.Url(key),
+			Size:        info.Size(),
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			Modified:    info.ModTime(),
+		})
+		return nil
+	})
+	return ret, err
+}
+
+func (this *LocalStore) Url(key string) string {
+	return this.UrlPrefix + "/" + key
+}
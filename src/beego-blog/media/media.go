@@ -0,0 +1,185 @@
+package media
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("media not found")
+	ErrInvalidKey  = errors.New("media key is invalid")
+	ErrTooLarge    = errors.New("media is too large")
+	ErrUnsupported = errors.New("media type is not supported, upload a jpeg, png or gif image")
+)
+
+// Object is a file of a MediaStore
+type Object struct {
+	Key         string
+	Url         string
+	Size        int64
+	ContentType string
+	Modified    time.Time
+}
+
+// MediaStore keeps the uploaded files under slash separated keys
+type MediaStore interface {
+	// Put stores data under key and returns its public url
+	Put(key string, data []byte, contentType string) (string, error)
+	// Delete removes the file of key, ErrNotFound if there is none
+	Delete(key string) error
+	// List returns the files whose key starts with prefix
+	List(prefix string) ([]Object, error)
+	// Url returns the public url of key
+	Url(key string) string
+}
+
+// Options of the images stored by Save
+type Options struct {
+	MaxSize     int64 // bytes, no limit when 0
+	MaxPixels   int64 // width * height of the decoded images, no limit when 0
+	MaxWidth    int   // wider images are scaled down, not when 0
+	ThumbWidth  int   // bounding box of the thumbnails
+	ThumbHeight int
+}
+
+// Media is an image stored by Save with its thumbnail
+type Media struct {
+	Object
+	Thumb *Object
+}
+
+const thumbSuffix = "_thumb"
+
+// ThumbKey returns the key of the thumbnail of the image key
+func ThumbKey(key string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + thumbSuffix + ext
+}
+
+func isThumb(key string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(key, path.Ext(key)), thumbSuffix)
+}
+
+// CheckKey returns ErrInvalidKey if key is not a relative slash separated
+// path without dot segments
+func CheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "." || seg == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+func newKey(prefix, ext string) string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	now := time.Now()
+	return fmt.Sprintf("%s/%s/%d_%s%s", prefix, now.Format("2006/01"), now.Unix(), hex.EncodeToString(b), ext)
+}
+
+// ReadUpload reads an uploaded file, ErrTooLarge when it is bigger than
+// MaxSize: no more than MaxSize+1 bytes are read
+func ReadUpload(r io.Reader, opts Options) ([]byte, error) {
+	if opts.MaxSize <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, opts.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > opts.MaxSize {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+// Save checks that data is an image, resizes it and stores it with its
+// thumbnail under a new key of prefix
+func Save(store MediaStore, prefix string, data []byte, opts Options) (*Media, error) {
+	if opts.MaxSize > 0 && int64(len(data)) > opts.MaxSize {
+		return nil, ErrTooLarge
+	}
+	img, err := Process(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	key := newKey(prefix, img.Ext)
+	url, err := store.Put(key, img.Data, img.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	m := &Media{Object: Object{Key: key, Url: url, Size: int64(len(img.Data)), ContentType: img.ContentType, Modified: time.Now()}}
+
+	if img.Thumb != nil {
+		thumbKey := ThumbKey(key)
+		thumbUrl, err := store.Put(thumbKey, img.Thumb, img.ThumbContentType)
+		if err != nil {
+			store.Delete(key)
+			return nil, err
+		}
+		m.Thumb = &Object{Key: thumbKey, Url: thumbUrl, Size: int64(len(img.Thumb)), ContentType: img.ThumbContentType, Modified: m.Modified}
+	}
+	return m, nil
+}
+
+// Remove deletes the image key and its thumbnail
+func Remove(store MediaStore, key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	if err := store.Delete(key); err != nil {
+		return err
+	}
+	if err := store.Delete(ThumbKey(key)); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// Library returns the images of prefix with their thumbnails, the latest
+// first
+func Library(store MediaStore, prefix string) ([]*Media, error) {
+	objects, err := store.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	thumbs := map[string]Object{}
+	for _, o := range objects {
+		if isThumb(o.Key) {
+			thumbs[o.Key] = o
+		}
+	}
+
+	ret := []*Media{}
+	for _, o := range objects {
+		if isThumb(o.Key) {
+			continue
+		}
+		m := &Media{Object: o}
+		if t, ok := thumbs[ThumbKey(o.Key)]; ok {
+			m.Thumb = &t
+		}
+		ret = append(ret, m)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].Modified.Equal(ret[j].Modified) {
+			return ret[i].Modified.After(ret[j].Modified)
+		}
+		return ret[i].Key > ret[j].Key
+	})
+	return ret, nil
+}
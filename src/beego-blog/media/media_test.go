@@ -0,0 +1,150 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func testPNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x*height/width, color.RGBA{255, 0, 0, 255})
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// testStore runs the operations of a MediaStore, its urls starting with
+// urlPrefix
+func testStore(t *testing.T, store MediaStore, urlPrefix string) {
+	url, err := store.Put("editor/a b.png", []byte("data"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(url, urlPrefix) || !strings.HasSuffix(url, "/editor/a%20b.png") && !strings.HasSuffix(url, "/editor/a b.png") {
+		t.Errorf("url = %q", url)
+	}
+	store.Put("catalogs/c.png", []byte("c"), "image/png")
+
+	objects, err := store.List("editor/")
+	if err != nil || len(objects) != 1 || objects[0].Key != "editor/a b.png" || objects[0].Size != 4 ||
+		objects[0].ContentType != "image/png" {
+		t.Fatalf("List = %+v, %v", objects, err)
+	}
+
+	if err := store.Delete("editor/a b.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("editor/a b.png"); err != ErrNotFound {
+		t.Errorf("second Delete = %v", err)
+	}
+	if _, err := store.Put("../escape.png", nil, "image/png"); err != ErrInvalidKey {
+		t.Errorf("Put outside of the store = %v", err)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore(t.TempDir(), "/static/uploads/"), "/static/uploads/")
+
+	objects, err := NewLocalStore(t.TempDir()+"/missing", "/u").List("")
+	if err != nil || len(objects) != 0 {
+		t.Errorf("List of a missing dir = %v, %v", objects, err)
+	}
+}
+
+func TestSave(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "/static/uploads")
+	opts := Options{MaxSize: 1 << 20, MaxWidth: 400, ThumbWidth: 100, ThumbHeight: 100}
+
+	m, err := Save(store, "editor", testPNG(800, 200), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(m.Key, "editor/") || !strings.HasSuffix(m.Key, ".png") || m.Thumb == nil ||
+		m.Thumb.Key != ThumbKey(m.Key) {
+		t.Fatalf("saved %+v", m)
+	}
+
+	objects, _ := store.List("")
+	for _, o := range objects {
+		img, _, err := image.Decode(bytes.NewReader(readFile(t, store, o.Key)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.Pt(400, 100)
+		if o.Key == m.Thumb.Key {
+			want = image.Pt(100, 25)
+		}
+		if img.Bounds().Size() != want {
+			t.Errorf("%s is %v, want %v", o.Key, img.Bounds().Size(), want)
+		}
+	}
+
+	library, err := Library(store, "editor/")
+	if err != nil || len(library) != 1 || library[0].Key != m.Key || library[0].Thumb == nil {
+		t.Fatalf("Library = %+v, %v", library, err)
+	}
+
+	if _, err := Save(store, "editor", []byte("<html>not an image</html>"), opts); err != ErrUnsupported {
+		t.Errorf("Save of html = %v", err)
+	}
+	if _, err := Save(store, "editor", testPNG(800, 200), Options{MaxSize: 10}); err != ErrTooLarge {
+		t.Errorf("Save of a large image = %v", err)
+	}
+	if _, err := Save(store, "editor", testPNG(800, 200), Options{MaxPixels: 800*200 - 1}); err != ErrTooLarge {
+		t.Errorf("Save of an image with too many pixels = %v", err)
+	}
+
+	if err := Remove(store, m.Key); err != nil {
+		t.Fatal(err)
+	}
+	if objects, _ := store.List(""); len(objects) != 0 {
+		t.Errorf("left after Remove: %+v", objects)
+	}
+}
+
+func TestProcessMaxPixels(t *testing.T) {
+	// a png header claiming 100000x100000 pixels, a few bytes only
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Process(data, Options{MaxPixels: 40000000}); err != ErrTooLarge {
+		t.Errorf("Process of a huge header = %v", err)
+	}
+
+	if _, err := Process(testPNG(100, 100), Options{MaxPixels: 100 * 100}); err != nil {
+		t.Errorf("Process at MaxPixels = %v", err)
+	}
+}
+
+func TestReadUpload(t *testing.T) {
+	data, err := ReadUpload(strings.NewReader("0123456789"), Options{MaxSize: 10})
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("ReadUpload at MaxSize = %q, %v", data, err)
+	}
+	if _, err := ReadUpload(strings.NewReader("0123456789a"), Options{MaxSize: 10}); err != ErrTooLarge {
+		t.Errorf("ReadUpload past MaxSize = %v", err)
+	}
+	if data, err := ReadUpload(strings.NewReader("0123456789a"), Options{}); err != nil || len(data) != 11 {
+		t.Errorf("ReadUpload without MaxSize = %q, %v", data, err)
+	}
+}
+
+func readFile(t *testing.T, store *LocalStore, key string) []byte {
+	b, err := ioutil.ReadFile(store.path(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,282 @@
+package media
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Store keeps the files in a bucket of Amazon S3 or of a compatible
+// service: MinIO, Ceph, Qiniu, Aliyun OSS...
+type S3Store struct {
+	Endpoint  string // https://s3.amazonaws.com
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// the bucket is a subdomain of the endpoint rather than a path segment
+	VirtualHost bool
+	// url of the bucket for the readers, a CDN for example, the bucket url
+	// when empty
+	PublicUrl string
+	Client    *http.Client
+}
+
+func (this *S3Store) client() *http.Client {
+	if this.Client != nil {
+		return this.Client
+	}
+	return http.DefaultClient
+}
+
+// bucketUrl returns the url of the bucket on the endpoint
+func (this *S3Store) bucketUrl() *url.URL {
+	u, err := url.Parse(strings.TrimRight(this.Endpoint, "/"))
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: this.Endpoint}
+	}
+	if this.VirtualHost {
+		u.Host = this.Bucket + "." + u.Host
+	} else {
+		u.Path += "/" + this.Bucket
+	}
+	return u
+}
+
+func (this *S3Store) Url(key string) string {
+	if this.PublicUrl != "" {
+		return strings.TrimRight(this.PublicUrl, "/") + "/" + escapeKey(key)
+	}
+	u := this.bucketUrl()
+	return u.String() + "/" + escapeKey(key)
+}
+
+func (this *S3Store) Put(key string, data []byte, contentType string) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	resp, err := this.do("PUT", key, nil, header, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return this.Url(key), nil
+}
+
+func (this *S3Store) Delete(key string) error {
+	if err := CheckKey(key); err != nil {
+		return err
+	}
+	// S3 answers 204 whether the key exists or not
+	if _, err := this.head(key); err != nil {
+		return err
+	}
+	resp, err := this.do("DELETE", key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (this *S3Store) head(key string) (*http.Response, error) {
+	resp, err := this.do("HEAD", key, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+		Size         int64
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (this *S3Store) List(prefix string) ([]Object, error) {
+	ret := []Object{}
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	for {
+		resp, err := this.do("GET", "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			ret = append(ret, Object{
+				Key:         c.Key,
+				Url:         this.Url(c.Key),
+				Size:        c.Size,
+				ContentType: mime.TypeByExtension(path.Ext(c.Key)),
+				Modified:    c.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return ret, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// do sends a signed request for key, the bucket itself when key is empty,
+// and returns the response of a 2xx status
+func (this *S3Store) do(method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := this.bucketUrl()
+	rawPath := u.EscapedPath()
+	if key != "" {
+		rawPath += "/" + escapeKey(key)
+	}
+	if rawPath == "" {
+		rawPath = "/"
+	}
+	u.Path, _ = url.PathUnescape(rawPath)
+	u.RawPath = rawPath
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	signV4(req, body, this.AccessKey, this.SecretKey, this.Region, time.Now())
+
+	resp, err := this.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	msg, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("s3 %s %s: %s %s", method, key, resp.Status, msg)
+}
+
+// escapeKey escapes the segments of key as S3 expects them
+func escapeKey(key string) string {
+	segs := strings.Split(key, "/")
+	for i, s := range segs {
+		segs[i] = uriEncode(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// uriEncode escapes everything but the unreserved characters of RFC 3986
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), query[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k)+"="+uriEncode(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signV4 signs the request with AWS Signature Version 4, the host and every
+// header of the request are signed
+func signV4(req *http.Request, body []byte, accessKey, secretKey, region string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	payloadHash := sha256Hex(body)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
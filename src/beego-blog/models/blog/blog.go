@@ -5,9 +5,12 @@ import (
 	"github.com/astaxie/beego/orm"
 	"github.com/ulricqin/beego-blog/g"
 	. "github.com/ulricqin/beego-blog/models"
+	"strings"
 	"time"
 )
 
+const latestSize = 20
+
 func OneById(id int64) *Blog {
 	if id <= 0 {
 		return nil
@@ -39,15 +42,20 @@ func OneByIdInDB(id int64) *Blog {
 	return &o
 }
 
+// identKey is the cache key of the id of an ident, apart from the id keys
+func identKey(ident string) string {
+	return "ident_" + ident
+}
+
 func IdByIdent(ident string) int64 {
 	if ident == "" {
 		return 0
 	}
 
-	val := g.BlogCacheGet(ident)
+	val := g.BlogCacheGet(identKey(ident))
 	if val == nil {
 		if p := OneByIdentInDB(ident); p != nil {
-			g.BlogCachePut(ident, p.Id)
+			g.BlogCachePut(identKey(ident), p.Id)
 			return p.Id
 		} else {
 			return 0
@@ -81,13 +89,7 @@ func OneByIdentInDB(ident string) *Blog {
 	return &c
 }
 
-func IdsInDB(catalog_id int64) []int64 {
-	if catalog_id <= 0 {
-		return []int64{}
-	}
-
-	var blogs []Blog
-	Blogs().Filter("CatalogId", catalog_id).Filter("Status", 1).OrderBy("-Created").All(&blogs, "Id")
+func ids(blogs []Blog) []int64 {
 	size := len(blogs)
 	if size == 0 {
 		return []int64{}
@@ -101,6 +103,16 @@ func IdsInDB(catalog_id int64) []int64 {
 	return ret
 }
 
+func IdsInDB(catalog_id int64) []int64 {
+	if catalog_id <= 0 {
+		return []int64{}
+	}
+
+	var blogs []Blog
+	Blogs().Filter("CatalogId", catalog_id).Filter("Status", 1).OrderBy("-Created").All(&blogs, "Id")
+	return ids(blogs)
+}
+
 func ReadBlogContent(b *Blog) *BlogContent {
 	if b.Id <= 0 || b.BlogContentId <= 0 {
 		return nil
@@ -147,31 +159,156 @@ func Ids(catalog_id int64) []int64 {
 	return val.([]int64)
 }
 
-func ByCatalog(catalog_id int64, offset, limit int) []*Blog {
-	ids := Ids(catalog_id)
-	size := len(ids)
-	if size == 0 {
-		return []*Blog{}
+// NormalizeTags returns the tags of a comma separated list in lower case,
+// without blanks and duplicates, as they are stored in Blog.Tags
+func NormalizeTags(tags string) string {
+	tags = strings.Replace(tags, "，", ",", -1)
+	seen := map[string]bool{}
+	ret := []string{}
+	for _, t := range strings.Split(tags, ",") {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		ret = append(ret, t)
 	}
+	return strings.Join(ret, ",")
+}
 
-	if size > limit {
-		end := offset + limit
-		if end > size {
-			end = size
+func hasTag(b *Blog, tag string) bool {
+	for _, t := range b.TagList() {
+		if t == tag {
+			return true
 		}
+	}
+	return false
+}
 
-		ids = ids[offset:end]
+func IdsByTagInDB(tag string) []int64 {
+	tag = NormalizeTags(tag)
+	if tag == "" || strings.Contains(tag, ",") {
+		return []int64{}
 	}
 
-	size = len(ids)
-	ret := make([]*Blog, size)
-	for i := 0; i < size; i++ {
-		ret[i] = OneById(ids[i])
-		ret[i].Content = ReadBlogContent(ret[i])
+	var blogs []Blog
+	Blogs().Filter("Tags__icontains", tag).Filter("Status", 1).OrderBy("-Created").All(&blogs, "Id", "Tags")
+
+	// icontains also matches the tags containing tag
+	tagged := blogs[:0]
+	for i := range blogs {
+		if hasTag(&blogs[i], tag) {
+			tagged = append(tagged, blogs[i])
+		}
+	}
+	return ids(tagged)
+}
+
+func IdsByTag(tag string) []int64 {
+	tag = NormalizeTags(tag)
+	if tag == "" {
+		return []int64{}
+	}
+
+	key := "article_ids_of_tag_" + tag
+	val := g.BlogCacheGet(key)
+	if val == nil {
+		if ids := IdsByTagInDB(tag); len(ids) != 0 {
+			g.BlogCachePut(key, ids)
+			return ids
+		} else {
+			return []int64{}
+		}
+	}
+
+	return val.([]int64)
+}
+
+func LatestIdsInDB() []int64 {
+	var blogs []Blog
+	Blogs().Filter("Status", 1).OrderBy("-Created").Limit(latestSize).All(&blogs, "Id")
+	return ids(blogs)
+}
+
+func LatestIds() []int64 {
+	val := g.BlogCacheGet("latest_ids")
+	if val == nil {
+		if ids := LatestIdsInDB(); len(ids) != 0 {
+			g.BlogCachePut("latest_ids", ids)
+			return ids
+		} else {
+			return []int64{}
+		}
+	}
+
+	return val.([]int64)
+}
+
+// byIds returns the articles of a page of ids with their content
+func byIds(ids []int64, offset, limit int) []*Blog {
+	size := len(ids)
+	if offset >= size {
+		return []*Blog{}
+	}
+
+	end := offset + limit
+	if end > size {
+		end = size
+	}
+	ids = ids[offset:end]
+
+	ret := make([]*Blog, 0, len(ids))
+	for _, id := range ids {
+		b := OneById(id)
+		if b == nil {
+			continue
+		}
+		b.Content = ReadBlogContent(b)
+		ret = append(ret, b)
 	}
 	return ret
 }
 
+func ByCatalog(catalog_id int64, offset, limit int) []*Blog {
+	return byIds(Ids(catalog_id), offset, limit)
+}
+
+func ByTag(tag string, offset, limit int) []*Blog {
+	return byIds(IdsByTag(tag), offset, limit)
+}
+
+// Latest returns the last released articles, at most 20
+func Latest(limit int) []*Blog {
+	return byIds(LatestIds(), 0, limit)
+}
+
+// invalidate drops the cached values of the article before and after a
+// change, old is nil on creation and b on deletion. The lists are only
+// dropped when the change moves the article in or out of them, not when
+// its views are counted.
+func invalidate(old, b *Blog) {
+	listed := old == nil || b == nil || old.Ident != b.Ident || old.CatalogId != b.CatalogId ||
+		old.Status != b.Status || old.Tags != b.Tags || !old.Created.Equal(b.Created)
+
+	for _, p := range []*Blog{old, b} {
+		if p == nil {
+			continue
+		}
+		g.BlogCacheDel(fmt.Sprintf("%d", p.Id))
+		if !listed {
+			continue
+		}
+		g.BlogCacheDel(identKey(p.Ident))
+		g.BlogCacheDel(fmt.Sprintf("article_ids_of_%d", p.CatalogId))
+		for _, t := range p.TagList() {
+			g.BlogCacheDel("article_ids_of_tag_" + t)
+		}
+	}
+	if listed {
+		g.BlogCacheDel("latest_ids")
+	}
+}
+
 func Save(this *Blog, blogContent string) (int64, error) {
 	if IdentExists(this.Ident) {
 		return 0, fmt.Errorf("blog english identity exists")
@@ -184,12 +321,15 @@ func Save(this *Blog, blogContent string) (int64, error) {
 		return 0, e
 	}
 
+	this.Tags = NormalizeTags(this.Tags)
 	this.BlogContentId = blogContentId
 	this.BlogContentLastUpdate = time.Now().Unix()
 
 	id, err := or.Insert(this)
 	if err == nil {
-		g.BlogCacheDel(fmt.Sprintf("article_ids_of_%d", this.CatalogId))
+		this.Id = id
+		invalidate(nil, this)
+		index(this, blogContent)
 	}
 
 	return id, err
@@ -202,7 +342,8 @@ func Del(b *Blog) error {
 	}
 
 	if num > 0 {
-		g.BlogCacheDel(fmt.Sprintf("article_ids_of_%d", b.CatalogId))
+		invalidate(b, nil)
+		SearchIndex.Remove(b.Id)
 		BlogContents().Filter("Id", b.BlogContentId).Delete()
 	}
 
@@ -214,8 +355,20 @@ func Update(b *Blog, content string) error {
 		return fmt.Errorf("primary key:id not set")
 	}
 
+	old := OneByIdInDB(b.Id)
+	if old == nil {
+		return fmt.Errorf("no such article")
+	}
+	if b.Ident != old.Ident && IdentExists(b.Ident) {
+		return fmt.Errorf("blog english identity exists")
+	}
+
 	bc := ReadBlogContent(b)
-	if content != "" && bc.Content != content {
+	if bc == nil {
+		return fmt.Errorf("content of article %d not found", b.Id)
+	}
+	contentChanged := content != "" && bc.Content != content
+	if contentChanged {
 		bc.Content = content
 		_, e := orm.NewOrm().Update(bc)
 		if e != nil {
@@ -224,11 +377,17 @@ func Update(b *Blog, content string) error {
 		b.BlogContentLastUpdate = time.Now().Unix()
 	}
 
+	b.Tags = NormalizeTags(b.Tags)
 	_, err := orm.NewOrm().Update(b)
-	if err == nil {
-		g.BlogCacheDel(fmt.Sprintf("%d", b.Id))
+	if err != nil {
+		return err
 	}
-	return err
+
+	invalidate(old, b)
+	if contentChanged || b.Title != old.Title || b.Tags != old.Tags || b.Status != old.Status {
+		index(b, bc.Content)
+	}
+	return nil
 }
 
 func Blogs() orm.QuerySeter {
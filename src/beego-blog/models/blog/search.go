@@ -0,0 +1,69 @@
+package blog
+
+import (
+	"html/template"
+	"strings"
+
+	. "github.com/ulricqin/beego-blog/models"
+	"github.com/ulricqin/beego-blog/models/search"
+)
+
+// SearchIndex is the full-text index of the released articles, kept up to
+// date by Save, Update and Del
+var SearchIndex = search.NewIndex()
+
+// Hit is an article found by Search
+type Hit struct {
+	Blog    *Blog
+	Snippet template.HTML
+}
+
+// index adds a released article to SearchIndex, its tags weigh as its title,
+// and removes a draft from it
+func index(b *Blog, content string) {
+	if b.Status != 1 {
+		SearchIndex.Remove(b.Id)
+		return
+	}
+	SearchIndex.Add(b.Id, b.Title+" "+strings.Replace(b.Tags, ",", " ", -1), content)
+}
+
+// BuildIndex indexes the released articles of the database, it returns the
+// number of articles indexed
+func BuildIndex() (int, error) {
+	var blogs []Blog
+	_, err := Blogs().Filter("Status", 1).All(&blogs)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range blogs {
+		if bc := readBlogContentInDB(&blogs[i]); bc != nil {
+			index(&blogs[i], bc.Content)
+		}
+	}
+	return SearchIndex.Len(), nil
+}
+
+// Search returns the number of articles matching the query and the page of
+// them at offset, best match first
+func Search(query string, offset, limit int) (int, []*Hit) {
+	results := SearchIndex.Search(query, 0)
+	total := len(results)
+	if offset >= total {
+		return total, []*Hit{}
+	}
+	if end := offset + limit; end < total {
+		results = results[offset:end]
+	} else {
+		results = results[offset:]
+	}
+
+	hits := make([]*Hit, 0, len(results))
+	for _, r := range results {
+		if b := OneById(r.Id); b != nil {
+			hits = append(hits, &Hit{Blog: b, Snippet: r.Snippet})
+		}
+	}
+	return total, hits
+}
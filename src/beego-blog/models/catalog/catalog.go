@@ -41,15 +41,20 @@ func OneByIdInDB(id int64) *Catalog {
 	return &c
 }
 
+// identKey is the cache key of the id of an ident, apart from the id keys
+func identKey(ident string) string {
+	return "ident_" + ident
+}
+
 func IdByIdent(ident string) int64 {
 	if ident == "" {
 		return 0
 	}
 
-	val := g.CatalogCacheGet(ident)
+	val := g.CatalogCacheGet(identKey(ident))
 	if val == nil {
 		if cp := OneByIdentInDB(ident); cp != nil {
-			g.CatalogCachePut(ident, cp.Id)
+			g.CatalogCachePut(identKey(ident), cp.Id)
 			return cp.Id
 		} else {
 			return 0
@@ -93,7 +98,7 @@ func AllIdsInDB() []int64 {
 
 	ret := make([]int64, size)
 	for i := 0; i < size; i++ {
-		ret[i] = catalogs[i].id
+		ret[i] = catalogs[i].Id
 	}
 
 	return ret
@@ -145,6 +150,8 @@ func Del(c *Catalog) error {
 	}
 	if num > 0 {
 		g.CatalogCacheDel("ids")
+		g.CatalogCacheDel(fmt.Sprintf("%d", c.Id))
+		g.CatalogCacheDel(identKey(c.Ident))
 	}
 	return nil
 }
@@ -154,9 +161,16 @@ func Update(this *Catalog) error {
 		return fmt.Errorf("primary key id not set")
 	}
 
-	_, err := orm.NewOrm().Update(thi)
+	old := OneByIdInDB(this.Id)
+	_, err := orm.NewOrm().Update(this)
 	if err == nil {
 		g.CatalogCacheDel(fmt.Sprintf("%d", this.Id))
+		if old != nil && old.Ident != this.Ident {
+			g.CatalogCacheDel(identKey(old.Ident))
+		}
+		if old != nil && old.DisplayOrder != this.DisplayOrder {
+			g.CatalogCacheDel("ids")
+		}
 	}
 	return err
 }
@@ -0,0 +1,188 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Feed is the list of the latest articles, encoded as Atom or RSS 2.0
+type Feed struct {
+	Title       string
+	Link        string // home page of the blog, absolute
+	Self        string // URL of the feed itself
+	Description string
+	Author      string
+	Email       string
+	Updated     time.Time
+	Items       []Item
+}
+
+type Item struct {
+	Title      string
+	Link       string
+	Id         string // permanent identifier, the link when empty
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+	Summary    string
+	Content    string // HTML
+}
+
+func (this *Item) id() string {
+	if this.Id != "" {
+		return this.Id
+	}
+	return this.Link
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr,omitempty"`
+	Body string `xml:",chardata"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	Link       atomLink       `xml:"link"`
+	Id         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Id       string      `xml:"id"`
+	Links    []atomLink  `xml:"link"`
+	Updated  string      `xml:"updated"`
+	Author   *atomPerson `xml:"author,omitempty"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// Atom returns the feed as an Atom document
+func (this *Feed) Atom() ([]byte, error) {
+	f := atomFeed{
+		Title:    this.Title,
+		Subtitle: this.Description,
+		Id:       this.Link,
+		Links:    []atomLink{{Href: this.Link}},
+		Updated:  this.Updated.Format(time.RFC3339),
+	}
+	if this.Self != "" {
+		f.Links = append(f.Links, atomLink{Href: this.Self, Rel: "self", Type: "application/atom+xml"})
+	}
+	if this.Author != "" {
+		f.Author = &atomPerson{Name: this.Author, Email: this.Email}
+	}
+	for _, item := range this.Items {
+		updated := item.Updated
+		if updated.IsZero() {
+			updated = item.Published
+		}
+		e := atomEntry{
+			Title:     item.Title,
+			Link:      atomLink{Href: item.Link},
+			Id:        item.id(),
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   updated.Format(time.RFC3339),
+		}
+		for _, c := range item.Categories {
+			e.Categories = append(e.Categories, atomCategory{c})
+		}
+		if item.Summary != "" {
+			e.Summary = &atomText{Body: item.Summary}
+		}
+		if item.Content != "" {
+			e.Content = &atomText{Type: "html", Body: item.Content}
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return encode(f)
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        rssGuid  `xml:"guid"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          *atomLink `xml:"http://www.w3.org/2005/Atom link,omitempty"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// RSS returns the feed as an RSS 2.0 document, the items described by their
+// content or else their summary
+func (this *Feed) RSS() ([]byte, error) {
+	f := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         this.Title,
+			Link:          this.Link,
+			Description:   this.Description,
+			LastBuildDate: this.Updated.Format(time.RFC1123Z),
+		},
+	}
+	if this.Self != "" {
+		f.Channel.Self = &atomLink{Href: this.Self, Rel: "self", Type: "application/rss+xml"}
+	}
+	for _, item := range this.Items {
+		description := item.Content
+		if description == "" {
+			description = item.Summary
+		}
+		f.Channel.Items = append(f.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Guid:        rssGuid{IsPermaLink: item.Id == "", Value: item.id()},
+			Categories:  item.Categories,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Description: description,
+		})
+	}
+	return encode(f)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
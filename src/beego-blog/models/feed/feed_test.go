@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() *Feed {
+	published := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	return &Feed{
+		Title:       "Blog",
+		Link:        "http://blog.example.com/",
+		Self:        "http://blog.example.com/feed.atom",
+		Description: "Notes",
+		Author:      "Ann",
+		Updated:     published,
+		Items: []Item{{
+			Title:      "Go & channels",
+			Link:       "http://blog.example.com/article/channels",
+			Categories: []string{"go"},
+			Published:  published,
+			Summary:    "About channels",
+			Content:    "<p>About <b>channels</b></p>",
+		}},
+	}
+}
+
+func TestAtom(t *testing.T) {
+	b, err := testFeed().Atom()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, want := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<link href="http://blog.example.com/feed.atom" rel="self" type="application/atom+xml"></link>`,
+		`<title>Go &amp; channels</title>`,
+		`<id>http://blog.example.com/article/channels</id>`,
+		`<updated>2016-05-01T10:00:00Z</updated>`,
+		`<category term="go"></category>`,
+		`<content type="html">&lt;p&gt;About &lt;b&gt;channels&lt;/b&gt;&lt;/p&gt;</content>`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("missing %s in\n%s", want, s)
+		}
+	}
+}
+
+func TestRSS(t *testing.T) {
+	b, err := testFeed().RSS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Guid        string `xml:"guid"`
+				PubDate     string `xml:"pubDate"`
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != "2.0" || got.Channel.Title != "Blog" || len(got.Channel.Items) != 1 {
+		t.Fatalf("decoded %+v", got)
+	}
+	item := got.Channel.Items[0]
+	if item.Guid != "http://blog.example.com/article/channels" || item.PubDate != "Sun, 01 May 2016 10:00:00 +0000" ||
+		item.Description != "<p>About <b>channels</b></p>" {
+		t.Errorf("item %+v", item)
+	}
+}
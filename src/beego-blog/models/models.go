@@ -5,6 +5,7 @@ package models
 import (
 	"github.com/astaxie/beego/orm"
 	_ "github.com/go-sql-driver/mysql"
+	"strings"
 	"time"
 )
 
@@ -22,6 +23,7 @@ type Blog struct {
 	Ident                 string `orm:"unique"`
 	Title                 string
 	Keywords              string       `orm:"null"`
+	Tags                  string       `orm:"null"` /*lower case, comma separated*/
 	CatalogId             int64        `orm:"index"`
 	Content               *BlogContent `orm:"-"`
 	BlogContentId         int64        `orm:"unique"`
@@ -32,6 +34,14 @@ type Blog struct {
 	Created               time.Time `orm:"auto_now_add;type(datetime)"`
 }
 
+// TagList returns the tags of the article
+func (this *Blog) TagList() []string {
+	if this.Tags == "" {
+		return []string{}
+	}
+	return strings.Split(this.Tags, ",")
+}
+
 type BlogContent struct {
 	Id      int64
 	Content string `orm:"type(text)"`
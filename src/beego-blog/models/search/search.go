@@ -0,0 +1,312 @@
+package search
+
+import (
+	"html"
+	"html/template"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 parameters
+const (
+	k1 = 1.2
+	b  = 0.75
+
+	// the terms of the title count as much as titleBoost terms of the text
+	titleBoost = 3
+
+	// snippetRunes is the length of the snippets around the first match
+	snippetRunes = 160
+)
+
+type doc struct {
+	text   []rune // plain text of the markdown, for the snippets
+	length int    // number of terms, title included
+}
+
+// Index is an inverted index of the articles, safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	docs     map[int64]*doc
+	postings map[string]map[int64]int // term -> article id -> frequency
+	total    int                      // sum of the document lengths
+}
+
+// Result is an article matching a query
+type Result struct {
+	Id      int64
+	Score   float64
+	Snippet template.HTML // escaped text with the matches in <mark>
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[int64]*doc{},
+		postings: map[string]map[int64]int{},
+	}
+}
+
+// Add indexes the article, replacing its previous version
+func (this *Index) Add(id int64, title, markdown string) {
+	text := PlainText(markdown)
+
+	freq := map[string]int{}
+	n := 0
+	for _, t := range Terms(title) {
+		freq[t] += titleBoost
+		n += titleBoost
+	}
+	for _, t := range Terms(text) {
+		freq[t]++
+		n++
+	}
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.remove(id)
+	this.docs[id] = &doc{text: []rune(text), length: n}
+	this.total += n
+	for t, f := range freq {
+		p := this.postings[t]
+		if p == nil {
+			p = map[int64]int{}
+			this.postings[t] = p
+		}
+		p[id] = f
+	}
+}
+
+// Remove drops the article from the index
+func (this *Index) Remove(id int64) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.remove(id)
+}
+
+func (this *Index) remove(id int64) {
+	d, ok := this.docs[id]
+	if !ok {
+		return
+	}
+	// the terms of the document are not kept, walk the postings
+	for t, p := range this.postings {
+		if _, ok := p[id]; ok {
+			delete(p, id)
+			if len(p) == 0 {
+				delete(this.postings, t)
+			}
+		}
+	}
+	this.total -= d.length
+	delete(this.docs, id)
+}
+
+// Len returns the number of indexed articles
+func (this *Index) Len() int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return len(this.docs)
+}
+
+// Search returns the articles containing all the terms of the query, best
+// BM25 score first, at most limit of them when limit > 0
+func (this *Index) Search(query string, limit int) []Result {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+
+	n := float64(len(this.docs))
+	avg := float64(this.total) / math.Max(n, 1)
+	scores := map[int64]float64{}
+	for i, t := range unique(terms) {
+		p := this.postings[t]
+		idf := math.Log(1 + (n-float64(len(p))+0.5)/(float64(len(p))+0.5))
+		for id, f := range p {
+			if _, ok := scores[id]; !ok && i > 0 {
+				// missed a previous term
+				continue
+			}
+			tf := float64(f)
+			dl := float64(this.docs[id].length)
+			scores[id] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*dl/avg))
+		}
+		// keep the documents having every term so far
+		for id := range scores {
+			if _, ok := p[id]; !ok {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{Id: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Id > results[j].Id
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	words := queryWords(query)
+	for i := range results {
+		results[i].Snippet = snippet(this.docs[results[i].Id].text, words)
+	}
+	return results
+}
+
+func unique(terms []string) []string {
+	seen := map[string]bool{}
+	ret := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func isHan(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// words splits the text on the runes which are not letters or digits, a run
+// of Han characters is a word of its own
+func words(s string) []string {
+	var ret []string
+	var cur []rune
+	han := false
+	flush := func() {
+		if len(cur) > 0 {
+			ret = append(ret, string(cur))
+			cur = cur[:0]
+		}
+	}
+	for _, r := range strings.ToLower(s) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(cur) > 0 && isHan(r) != han {
+			flush()
+		}
+		han = isHan(r)
+		cur = append(cur, r)
+	}
+	flush()
+	return ret
+}
+
+// Terms returns the lower case terms of s: its words, the Han runs being
+// cut in overlapping bigrams as Chinese has no spaces
+func Terms(s string) []string {
+	var terms []string
+	for _, w := range words(s) {
+		rs := []rune(w)
+		if !isHan(rs[0]) || len(rs) == 1 {
+			terms = append(terms, w)
+			continue
+		}
+		for i := 0; i+1 < len(rs); i++ {
+			terms = append(terms, string(rs[i:i+2]))
+		}
+	}
+	return terms
+}
+
+// queryWords returns the words of the query to highlight, the longest first
+func queryWords(query string) [][]rune {
+	var ret [][]rune
+	for _, w := range unique(words(query)) {
+		ret = append(ret, []rune(w))
+	}
+	sort.Slice(ret, func(i, j int) bool { return len(ret[i]) > len(ret[j]) })
+	return ret
+}
+
+// snippet returns the escaped text around the first match of the words,
+// with every match in the window wrapped in <mark>
+func snippet(text []rune, words [][]rune) template.HTML {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+	matchAt := func(i int) int {
+		for _, w := range words {
+			if i+len(w) <= len(lower) && string(lower[i:i+len(w)]) == string(w) {
+				return len(w)
+			}
+		}
+		return 0
+	}
+
+	first := -1
+	for i := range lower {
+		if matchAt(i) > 0 {
+			first = i
+			break
+		}
+	}
+	start := 0
+	if first > snippetRunes/4 {
+		start = first - snippetRunes/4
+	}
+	end := start + snippetRunes
+	if end > len(text) {
+		end = len(text)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := matchAt(i); n > 0 {
+			if i+n > end {
+				n = end - i
+			}
+			sb.WriteString("<mark>" + html.EscapeString(string(text[i:i+n])) + "</mark>")
+			i += n
+			continue
+		}
+		sb.WriteString(html.EscapeString(string(text[i])))
+		i++
+	}
+	if end < len(text) {
+		sb.WriteString("…")
+	}
+	return template.HTML(sb.String())
+}
+
+var (
+	fences     = regexp.MustCompile("(?m)^\\s*(```|~~~).*$")
+	images     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	links      = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	tags       = regexp.MustCompile(`<[^>]+>`)
+	marks      = regexp.MustCompile("(?m)^\\s*(#{1,6}|>+|[-*+]|\\d+\\.)\\s+|[*_`~]+")
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// PlainText returns the text of the markdown without its syntax
+func PlainText(markdown string) string {
+	s := fences.ReplaceAllString(markdown, "")
+	s = images.ReplaceAllString(s, "$1")
+	s = links.ReplaceAllString(s, "$1")
+	s = tags.ReplaceAllString(s, " ")
+	s = marks.ReplaceAllString(s, "")
+	s = whitespace.ReplaceAllString(s, " ")
+	return strings.TrimSpace(html.UnescapeString(s))
+}
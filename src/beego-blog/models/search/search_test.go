@@ -0,0 +1,64 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTerms(t *testing.T) {
+	got := Terms("Go语言并发 in *Go*, v1.2!")
+	want := []string{"go", "语言", "言并", "并发", "in", "go", "v1", "2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms = %q, want %q", got, want)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	md := "# Title\n\nSome **bold** and [a link](http://x.y/z) ![img](a.png)\n\n```go\nfmt.Println(1)\n```\n> quote &amp; more"
+	want := "Title Some bold and a link img fmt.Println(1) quote & more"
+	if got := PlainText(md); got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "Channels in Go", "Go channels pass values between goroutines. A channel can be buffered.")
+	idx.Add(2, "Maps", "Maps are hash tables. Go maps are not safe for concurrent use, use a mutex or channels.")
+	idx.Add(3, "并发编程", "使用 goroutine 和 channel 实现并发。")
+
+	results := idx.Search("channels", 0)
+	if len(results) != 2 || results[0].Id != 1 || results[1].Id != 2 {
+		t.Fatalf("Search(channels) = %+v", results)
+	}
+	if !strings.Contains(string(results[0].Snippet), "<mark>channels</mark>") {
+		t.Errorf("snippet = %s", results[0].Snippet)
+	}
+
+	if results := idx.Search("go MUTEX", 0); len(results) != 1 || results[0].Id != 2 {
+		t.Errorf("Search(go mutex) = %+v", results)
+	}
+	if results := idx.Search("并发", 10); len(results) != 1 || results[0].Id != 3 ||
+		!strings.Contains(string(results[0].Snippet), "<mark>并发</mark>") {
+		t.Errorf("Search(并发) = %+v", results)
+	}
+	if results := idx.Search("channels", 1); len(results) != 1 {
+		t.Errorf("limit ignored: %+v", results)
+	}
+	if results := idx.Search("?!", 0); results != nil {
+		t.Errorf("Search without terms = %+v", results)
+	}
+
+	idx.Add(1, "Slices", "Slices grow when len < cap.")
+	if results := idx.Search("channels", 0); len(results) != 1 || results[0].Id != 2 {
+		t.Errorf("after replace = %+v", results)
+	}
+	if results := idx.Search("grow", 0); len(results) != 1 || string(results[0].Snippet) != "Slices <mark>grow</mark> when len &lt; cap." {
+		t.Errorf("escaped snippet = %+v", results)
+	}
+	idx.Remove(2)
+	if idx.Len() != 2 || len(idx.Search("maps", 0)) != 0 {
+		t.Errorf("after remove: %d docs", idx.Len())
+	}
+}
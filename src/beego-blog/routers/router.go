@@ -11,6 +11,10 @@ func init() {
 	beego.Router("/", &controllers.MainController{})
 	beego.Router("/article/:ident", &controllers.MainController{}, "get:Read")
 	beego.Router("/catalog/:ident", &controllers.MainController{}, "get:ListByCatalog")
+	beego.Router("/tag/:tag", &controllers.MainController{}, "get:ListByTag")
+	beego.Router("/search", &controllers.MainController{}, "get:Search")
+	beego.Router("/feed.atom", &controllers.FeedController{}, "get:Atom")
+	beego.Router("/feed.rss", &controllers.FeedController{}, "get:Rss")
 
 	beego.Router("/login", &controllers.LoginController{}, "get:Login;post:DoLogin")
 	beego.Router("/logout", &controllers.LoginController{}, "get:Logout")
@@ -23,4 +27,6 @@ func init() {
 	beego.Router("/me/article/edit", &controllers.ArticleController{}, "get:Edit;post:DoEdit")
 	beego.Router("/me/article/del", &controllers.ArticleController{}, "get:Del")
 	beego.Router("/me/article/draft", &controllers.ArticleController{}, "get:Draft")
+	beego.Router("/me/media", &controllers.MediaController{}, "get:List")
+	beego.Router("/me/media/del", &controllers.MediaController{}, "get:Del")
 }
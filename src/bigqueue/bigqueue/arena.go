@@ -0,0 +1,60 @@
+package bigqueue
+
+import (
+	"os"
+	"syscall"
+)
+
+// arena is a file of the queue mapped in memory
+type arena struct {
+	f     *os.File
+	data  []byte
+	dirty bool   // written since the last sync
+	used  uint64 // last access, to unmap the least recently used arena
+}
+
+// mapFile opens the file at path, grows it to size bytes if needed and
+// maps it in memory
+func mapFile(path string, size int) (*arena, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() < int64(size) {
+		if err := f.Truncate(int64(size)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &arena{f: f, data: data}, nil
+}
+
+// sync writes the mapped pages to disk, fsync also covers the pages
+// modified through the mapping
+func (a *arena) sync() error {
+	if !a.dirty {
+		return nil
+	}
+	a.dirty = false
+	return a.f.Sync()
+}
+
+func (a *arena) close() error {
+	err := syscall.Munmap(a.data)
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
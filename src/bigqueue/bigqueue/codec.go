@@ -0,0 +1,52 @@
+package bigqueue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+)
+
+// Codec converts the elements of a QueueG to and from bytes
+type Codec[T any] struct {
+	Marshal   func(T) ([]byte, error)
+	Unmarshal func([]byte) (T, error)
+}
+
+// BytesCodec stores byte slices as they are
+var BytesCodec = Codec[[]byte]{
+	Marshal:   func(b []byte) ([]byte, error) { return b, nil },
+	Unmarshal: func(b []byte) ([]byte, error) { return b, nil },
+}
+
+// StringCodec stores strings as they are
+var StringCodec = Codec[string]{
+	Marshal:   func(s string) ([]byte, error) { return []byte(s), nil },
+	Unmarshal: func(b []byte) (string, error) { return string(b), nil },
+}
+
+// Int64Codec stores int64 values as varints
+var Int64Codec = Codec[int64]{
+	Marshal: func(n int64) ([]byte, error) {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutVarint(buf, n)], nil
+	},
+	Unmarshal: func(b []byte) (int64, error) {
+		n, l := binary.Varint(b)
+		if l != len(b) {
+			return 0, errors.New("bigqueue: invalid varint")
+		}
+		return n, nil
+	},
+}
+
+// JSONCodec stores values of any type as JSON
+func JSONCodec[T any]() Codec[T] {
+	return Codec[T]{
+		Marshal: func(v T) ([]byte, error) { return json.Marshal(v) },
+		Unmarshal: func(b []byte) (T, error) {
+			var v T
+			err := json.Unmarshal(b, &v)
+			return v, err
+		},
+	}
+}
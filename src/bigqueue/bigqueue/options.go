@@ -0,0 +1,80 @@
+package bigqueue
+
+import (
+	"errors"
+	"time"
+)
+
+// SyncPolicy tells when the queue forces its files to disk
+type SyncPolicy int
+
+const (
+	// SyncNever leaves the writes to the OS, they survive a crash of the
+	// process but not of the machine
+	SyncNever SyncPolicy = iota
+	// SyncAlways syncs after every Enqueue and Dequeue
+	SyncAlways
+	// SyncPeriodic syncs when a write happens at least SyncInterval after the last sync
+	SyncPeriodic
+)
+
+const (
+	defaultArenaSize      = 128 * 1024 * 1024
+	defaultMaxInMemArenas = 10
+	defaultSyncInterval   = time.Second
+)
+
+// ErrInvalidArenaSize is returned when the arena size is not positive
+var ErrInvalidArenaSize = errors.New("bigqueue: arena size must be positive")
+
+// ErrTooFewArenas is returned when less than 2 arenas may be kept in memory
+var ErrTooFewArenas = errors.New("bigqueue: at least 2 arenas must be kept in memory")
+
+type options struct {
+	arenaSize      int
+	maxInMemArenas int
+	syncPolicy     SyncPolicy
+	syncInterval   time.Duration
+}
+
+// Option is an option of NewQueueG and NewMmapQueue
+type Option func(*options) error
+
+// SetArenaSize sets the size of the files holding the elements, 128MB by default.
+// It cannot change once the queue was created.
+func SetArenaSize(size int) Option {
+	return func(o *options) error {
+		if size <= 0 {
+			return ErrInvalidArenaSize
+		}
+		o.arenaSize = size
+		return nil
+	}
+}
+
+// SetMaxInMemArenas sets how many arenas are mapped in memory at once, 10 by default
+func SetMaxInMemArenas(n int) Option {
+	return func(o *options) error {
+		if n < 2 {
+			return ErrTooFewArenas
+		}
+		o.maxInMemArenas = n
+		return nil
+	}
+}
+
+// SetSyncPolicy sets when the queue forces its files to disk, SyncNever by default
+func SetSyncPolicy(p SyncPolicy) Option {
+	return func(o *options) error {
+		o.syncPolicy = p
+		return nil
+	}
+}
+
+// SetSyncInterval sets the interval of SyncPeriodic, 1s by default
+func SetSyncInterval(d time.Duration) Option {
+	return func(o *options) error {
+		o.syncInterval = d
+		return nil
+	}
+}
@@ -0,0 +1,602 @@
+package bigqueue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConsumer is the consumer used by the Peek, Dequeue and IsEmpty methods of a queue
+const DefaultConsumer = "default"
+
+const (
+	metaFile       = "meta.dat"
+	metaSize       = 24 // head, tail, arena size
+	consumerPrefix = "consumer_"
+	fileSuffix     = ".dat"
+	headerSize     = 8 // length of an element
+)
+
+var (
+	// ErrEmptyQueue is returned when a consumer read every element
+	ErrEmptyQueue = errors.New("bigqueue: queue is empty")
+
+	// ErrQueueClosed is returned when the queue was closed
+	ErrQueueClosed = errors.New("bigqueue: queue is closed")
+
+	// ErrInvalidConsumer is returned for a consumer name that is not made
+	// of letters, digits, '_', '-' and '.'
+	ErrInvalidConsumer = errors.New("bigqueue: invalid consumer name")
+
+	// ErrArenaSizeMismatch is returned when a queue is opened with another
+	// arena size than it was created with
+	ErrArenaSizeMismatch = errors.New("bigqueue: arena size differs from the one of the queue")
+
+	// ErrCorruptQueue is returned when the length of an element goes past the tail of the queue
+	ErrCorruptQueue = errors.New("bigqueue: corrupt element length")
+)
+
+var consumerName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// QueueG is a FIFO queue stored in a directory. The elements are appended
+// to fixed size files, the arenas, which are mapped in memory when used.
+// Every named consumer reads the queue at its own pace, its offset is kept
+// on disk; the arenas are deleted once every consumer read past them.
+// All the methods are safe for concurrent use.
+type QueueG[T any] struct {
+	mu    sync.Mutex
+	dir   string
+	opts  options
+	codec Codec[T]
+
+	meta      *arena // head, tail and arena size
+	arenas    map[int64]*arena
+	consumers map[string]*ConsumerG[T]
+	uses      uint64
+	lastSync  time.Time
+	closed    bool
+}
+
+// MmapQueue is a queue of byte slices
+type MmapQueue = QueueG[[]byte]
+
+// NewMmapQueue opens the queue of byte slices in dir, creating it if needed
+func NewMmapQueue(dir string, opts ...Option) (*MmapQueue, error) {
+	return NewQueueG(dir, BytesCodec, opts...)
+}
+
+// NewQueueG opens the queue in dir, creating it if needed.
+// The elements are converted to bytes with codec.
+func NewQueueG[T any](dir string, codec Codec[T], opts ...Option) (*QueueG[T], error) {
+	q := &QueueG[T]{
+		dir:   dir,
+		codec: codec,
+		opts: options{
+			arenaSize:      defaultArenaSize,
+			maxInMemArenas: defaultMaxInMemArenas,
+			syncInterval:   defaultSyncInterval,
+		},
+		arenas:    make(map[int64]*arena),
+		consumers: make(map[string]*ConsumerG[T]),
+		lastSync:  time.Now(),
+	}
+	for _, opt := range opts {
+		if err := opt(&q.opts); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if q.meta, err = mapFile(filepath.Join(dir, metaFile), metaSize); err != nil {
+		return nil, err
+	}
+	switch size := q.getMeta(16); size {
+	case 0:
+		q.setMeta(16, int64(q.opts.arenaSize))
+	case int64(q.opts.arenaSize):
+	default:
+		q.meta.close()
+		return nil, ErrArenaSizeMismatch
+	}
+
+	// every consumer holds the arenas it did not read yet
+	names, err := filepath.Glob(filepath.Join(dir, consumerPrefix+"*"+fileSuffix))
+	if err != nil {
+		q.Close()
+		return nil, err
+	}
+	for _, name := range names {
+		name = strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), consumerPrefix), fileSuffix)
+		if _, err := q.openConsumer(name); err != nil {
+			q.Close()
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+// Dir returns the directory of the queue
+func (q *QueueG[T]) Dir() string {
+	return q.dir
+}
+
+func (q *QueueG[T]) getMeta(offset int) int64 {
+	return int64(binary.LittleEndian.Uint64(q.meta.data[offset:]))
+}
+
+func (q *QueueG[T]) setMeta(offset int, v int64) {
+	binary.LittleEndian.PutUint64(q.meta.data[offset:], uint64(v))
+	q.meta.dirty = true
+}
+
+// head is the offset of the oldest element kept, tail the end of the last one
+func (q *QueueG[T]) head() int64 { return q.getMeta(0) }
+func (q *QueueG[T]) tail() int64 { return q.getMeta(8) }
+
+func (q *QueueG[T]) arenaPath(index int64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("arena_%d%s", index, fileSuffix))
+}
+
+// arena returns the arena at index, mapping it and unmapping the least
+// recently used one if needed
+func (q *QueueG[T]) arena(index int64) (*arena, error) {
+	q.uses++
+	if a, ok := q.arenas[index]; ok {
+		a.used = q.uses
+		return a, nil
+	}
+
+	if len(q.arenas) >= q.opts.maxInMemArenas {
+		lru := int64(-1)
+		for i, a := range q.arenas {
+			if lru < 0 || a.used < q.arenas[lru].used {
+				lru = i
+			}
+		}
+		if err := q.unmapArena(lru); err != nil {
+			return nil, err
+		}
+	}
+
+	a, err := mapFile(q.arenaPath(index), q.opts.arenaSize)
+	if err != nil {
+		return nil, err
+	}
+	a.used = q.uses
+	q.arenas[index] = a
+	return a, nil
+}
+
+func (q *QueueG[T]) unmapArena(index int64) error {
+	a := q.arenas[index]
+	delete(q.arenas, index)
+	if q.opts.syncPolicy != SyncNever {
+		if err := a.sync(); err != nil {
+			a.close()
+			return err
+		}
+	}
+	return a.close()
+}
+
+// copyAt copies between b and the queue at offset, across arenas
+func (q *QueueG[T]) copyAt(offset int64, b []byte, write bool) error {
+	size := int64(q.opts.arenaSize)
+	for len(b) > 0 {
+		a, err := q.arena(offset / size)
+		if err != nil {
+			return err
+		}
+		data := a.data[offset%size:]
+		var n int
+		if write {
+			n = copy(data, b)
+			a.dirty = true
+		} else {
+			n = copy(b, data)
+		}
+		b = b[n:]
+		offset += int64(n)
+	}
+	return nil
+}
+
+// Enqueue appends v to the queue
+func (q *QueueG[T]) Enqueue(v T) error {
+	b, err := q.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	tail := q.tail()
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint64(header[:], uint64(len(b)))
+	if err := q.copyAt(tail, header[:], true); err != nil {
+		return err
+	}
+	if err := q.copyAt(tail+headerSize, b, true); err != nil {
+		return err
+	}
+	// the element is visible once it is written completely
+	q.setMeta(8, tail+headerSize+int64(len(b)))
+
+	return q.maybeSync()
+}
+
+// Consumer returns the consumer called name, creating it if needed.
+// A new consumer starts at the oldest element kept in the queue.
+func (q *QueueG[T]) Consumer(name string) (*ConsumerG[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	return q.openConsumer(name)
+}
+
+func (q *QueueG[T]) openConsumer(name string) (*ConsumerG[T], error) {
+	if c, ok := q.consumers[name]; ok {
+		return c, nil
+	}
+	if !consumerName.MatchString(name) {
+		return nil, ErrInvalidConsumer
+	}
+
+	path := filepath.Join(q.dir, consumerPrefix+name+fileSuffix)
+	_, err := os.Stat(path)
+	created := os.IsNotExist(err)
+
+	off, err := mapFile(path, 8)
+	if err != nil {
+		return nil, err
+	}
+	c := &ConsumerG[T]{q: q, name: name, off: off}
+	if created {
+		c.setOffset(q.head())
+	}
+	q.consumers[name] = c
+	return c, nil
+}
+
+// Consumers returns the names of the consumers of the queue
+func (q *QueueG[T]) Consumers() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names := make([]string, 0, len(q.consumers))
+	for name := range q.consumers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// DeleteConsumer removes the consumer called name and its offset,
+// so that it does not hold the arenas anymore
+func (q *QueueG[T]) DeleteConsumer(name string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	c, ok := q.consumers[name]
+	if !ok {
+		return nil
+	}
+	delete(q.consumers, name)
+	c.off.close()
+	if err := os.Remove(c.off.f.Name()); err != nil {
+		return err
+	}
+	return q.gc()
+}
+
+// gc deletes the arenas every consumer read past
+func (q *QueueG[T]) gc() error {
+	if len(q.consumers) == 0 {
+		return nil
+	}
+
+	min := q.tail()
+	for _, c := range q.consumers {
+		if off := c.offset(); off < min {
+			min = off
+		}
+	}
+
+	size := int64(q.opts.arenaSize)
+	for index := q.head() / size; index < min/size; index++ {
+		if _, ok := q.arenas[index]; ok {
+			a := q.arenas[index]
+			delete(q.arenas, index)
+			a.close()
+		}
+		if err := os.Remove(q.arenaPath(index)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	q.setMeta(0, min)
+	return nil
+}
+
+func (q *QueueG[T]) maybeSync() error {
+	switch q.opts.syncPolicy {
+	case SyncAlways:
+		return q.sync()
+	case SyncPeriodic:
+		if time.Since(q.lastSync) >= q.opts.syncInterval {
+			return q.sync()
+		}
+	}
+	return nil
+}
+
+// sync writes the arenas and then the offsets to disk
+func (q *QueueG[T]) sync() error {
+	q.lastSync = time.Now()
+	for _, a := range q.arenas {
+		if err := a.sync(); err != nil {
+			return err
+		}
+	}
+	if err := q.meta.sync(); err != nil {
+		return err
+	}
+	for _, c := range q.consumers {
+		if err := c.off.sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the queue to disk, whatever the sync policy
+func (q *QueueG[T]) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	return q.sync()
+}
+
+// Close unmaps and closes the files of the queue, syncing them first
+// unless the sync policy is SyncNever
+func (q *QueueG[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+
+	var err error
+	if q.opts.syncPolicy != SyncNever {
+		err = q.sync()
+	}
+	for index, a := range q.arenas {
+		if cerr := a.close(); err == nil {
+			err = cerr
+		}
+		delete(q.arenas, index)
+	}
+	for _, c := range q.consumers {
+		if cerr := c.off.close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := q.meta.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// defaultConsumer returns DefaultConsumer, nil if it does not exist and create is not set
+func (q *QueueG[T]) defaultConsumer(create bool) (*ConsumerG[T], error) {
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	if c, ok := q.consumers[DefaultConsumer]; ok || !create {
+		return c, nil
+	}
+	return q.openConsumer(DefaultConsumer)
+}
+
+// IsEmpty reports whether DefaultConsumer read every element.
+// DefaultConsumer is not created until Dequeue is called.
+func (q *QueueG[T]) IsEmpty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c, err := q.defaultConsumer(false)
+	if err != nil {
+		return true
+	}
+	if c == nil {
+		return q.head() == q.tail()
+	}
+	return c.offset() == q.tail()
+}
+
+// Peek returns the next element of DefaultConsumer
+func (q *QueueG[T]) Peek() (T, error) {
+	q.mu.Lock()
+	var b []byte
+	c, err := q.defaultConsumer(false)
+	switch {
+	case err != nil:
+	case c == nil:
+		b, _, err = q.element(q.head(), true)
+	default:
+		b, _, err = c.next(true)
+	}
+	q.mu.Unlock()
+
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return q.codec.Unmarshal(b)
+}
+
+// Dequeue moves DefaultConsumer past its next element, creating it if needed
+func (q *QueueG[T]) Dequeue() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c, err := q.defaultConsumer(true)
+	if err != nil {
+		return err
+	}
+	return c.dequeue()
+}
+
+// element returns the element at off as bytes, if read is set, and the offset after it
+func (q *QueueG[T]) element(off int64, read bool) ([]byte, int64, error) {
+	tail := q.tail()
+	if off == tail {
+		return nil, 0, ErrEmptyQueue
+	}
+
+	if tail-off < headerSize {
+		return nil, 0, ErrCorruptQueue
+	}
+	var header [headerSize]byte
+	if err := q.copyAt(off, header[:], false); err != nil {
+		return nil, 0, err
+	}
+	// a length past the tail would make us read garbage, or allocate it
+	size := binary.LittleEndian.Uint64(header[:])
+	if size > uint64(tail-off-headerSize) {
+		return nil, 0, ErrCorruptQueue
+	}
+	next := off + headerSize + int64(size)
+	if !read {
+		return nil, next, nil
+	}
+	b := make([]byte, size)
+	if err := q.copyAt(off+headerSize, b, false); err != nil {
+		return nil, 0, err
+	}
+	return b, next, nil
+}
+
+// ConsumerG reads a queue, its offset is kept on disk
+type ConsumerG[T any] struct {
+	q    *QueueG[T]
+	name string
+	off  *arena
+}
+
+// Consumer reads a queue of byte slices
+type Consumer = ConsumerG[[]byte]
+
+// Name returns the name of the consumer
+func (c *ConsumerG[T]) Name() string {
+	return c.name
+}
+
+func (c *ConsumerG[T]) offset() int64 {
+	return int64(binary.LittleEndian.Uint64(c.off.data))
+}
+
+func (c *ConsumerG[T]) setOffset(v int64) {
+	binary.LittleEndian.PutUint64(c.off.data, uint64(v))
+	c.off.dirty = true
+}
+
+// deleted reports whether the consumer was deleted or its queue closed
+func (c *ConsumerG[T]) deleted() bool {
+	return c.q.closed || c.q.consumers[c.name] != c
+}
+
+// IsEmpty reports whether the consumer read every element
+func (c *ConsumerG[T]) IsEmpty() bool {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+
+	return c.deleted() || c.offset() == c.q.tail()
+}
+
+// next returns the next element as bytes, if read is set, and the offset after it
+func (c *ConsumerG[T]) next(read bool) ([]byte, int64, error) {
+	if c.deleted() {
+		return nil, 0, ErrQueueClosed
+	}
+	return c.q.element(c.offset(), read)
+}
+
+// Peek returns the next element without moving past it
+func (c *ConsumerG[T]) Peek() (T, error) {
+	c.q.mu.Lock()
+	b, _, err := c.next(true)
+	c.q.mu.Unlock()
+
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.q.codec.Unmarshal(b)
+}
+
+// Dequeue moves past the next element
+func (c *ConsumerG[T]) Dequeue() error {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+
+	return c.dequeue()
+}
+
+func (c *ConsumerG[T]) dequeue() error {
+	_, next, err := c.next(false)
+	if err != nil {
+		return err
+	}
+	c.setOffset(next)
+	if err := c.q.gc(); err != nil {
+		return err
+	}
+	return c.q.maybeSync()
+}
+
+// Pop returns the next element and moves past it
+func (c *ConsumerG[T]) Pop() (T, error) {
+	c.q.mu.Lock()
+	defer c.q.mu.Unlock()
+
+	var zero T
+	b, next, err := c.next(true)
+	if err != nil {
+		return zero, err
+	}
+	v, err := c.q.codec.Unmarshal(b)
+	if err != nil {
+		return zero, err
+	}
+	c.setOffset(next)
+	if err := c.q.gc(); err != nil {
+		return zero, err
+	}
+	return v, c.q.maybeSync()
+}
@@ -0,0 +1,229 @@
+package bigqueue
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func arenaFiles(t *testing.T, dir string) int {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "arena_*.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(names)
+}
+
+func TestEnqueueDequeue(t *testing.T) {
+	dir := t.TempDir()
+	bq, err := NewMmapQueue(dir, SetArenaSize(64), SetMaxInMemArenas(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bq.Close()
+
+	if !bq.IsEmpty() {
+		t.Fatal("new queue is not empty")
+	}
+	if _, err := bq.Peek(); err != ErrEmptyQueue {
+		t.Fatalf("Peek() = %v, want ErrEmptyQueue", err)
+	}
+
+	// elements larger than an arena span several ones
+	elems := []string{"", "elem", strings.Repeat("x", 150), "elem2"}
+	for _, e := range elems {
+		if err := bq.Enqueue([]byte(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range elems {
+		got, err := bq.Peek()
+		if err != nil || string(got) != e {
+			t.Fatalf("Peek() = %q, %v, want %q", got, err, e)
+		}
+		if err := bq.Dequeue(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bq.IsEmpty() {
+		t.Fatal("queue should be empty")
+	}
+	if err := bq.Dequeue(); err != ErrEmptyQueue {
+		t.Fatalf("Dequeue() = %v, want ErrEmptyQueue", err)
+	}
+}
+
+func TestDefaultConsumerOnDequeue(t *testing.T) {
+	q, err := NewQueueG(t.TempDir(), StringCodec, SetArenaSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	// a consumer holds the arenas, reading the head must not create one
+	q.Enqueue("a")
+	q.Enqueue("b")
+	if v, err := q.Peek(); err != nil || v != "a" || q.IsEmpty() {
+		t.Fatalf("Peek() = %q, %v", v, err)
+	}
+	if names := q.Consumers(); len(names) != 0 {
+		t.Fatalf("Consumers() = %v before Dequeue", names)
+	}
+
+	if err := q.Dequeue(); err != nil {
+		t.Fatal(err)
+	}
+	if names := q.Consumers(); len(names) != 1 || names[0] != DefaultConsumer {
+		t.Fatalf("Consumers() = %v after Dequeue", names)
+	}
+	if v, err := q.Peek(); err != nil || v != "b" {
+		t.Fatalf("Peek() = %q, %v", v, err)
+	}
+}
+
+func TestCorruptLength(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewMmapQueue(dir, SetArenaSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.Enqueue([]byte("elem"))
+	q.Close()
+
+	// the length of the first element claims more bytes than the queue has
+	f, err := os.OpenFile(filepath.Join(dir, "arena_0.dat"), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, 0)
+	f.Close()
+
+	q, err = NewMmapQueue(dir, SetArenaSize(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	if _, err := q.Peek(); err != ErrCorruptQueue {
+		t.Fatalf("Peek() = %v, want ErrCorruptQueue", err)
+	}
+	if err := q.Dequeue(); err != ErrCorruptQueue {
+		t.Fatalf("Dequeue() = %v, want ErrCorruptQueue", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewQueueG(dir, Int64Codec, SetArenaSize(32), SetSyncPolicy(SyncAlways))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := q.Consumer("reader")
+	for i := int64(0); i < 100; i++ {
+		q.Enqueue(i)
+	}
+	for i := 0; i < 40; i++ {
+		c.Dequeue()
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewQueueG(dir, Int64Codec, SetArenaSize(64)); err != ErrArenaSizeMismatch {
+		t.Fatalf("got %v, want ErrArenaSizeMismatch", err)
+	}
+
+	q, err = NewQueueG(dir, Int64Codec, SetArenaSize(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	c, _ = q.Consumer("reader")
+	for i := int64(40); i < 100; i++ {
+		v, err := c.Pop()
+		if err != nil || v != i {
+			t.Fatalf("Pop() = %d, %v, want %d", v, err, i)
+		}
+	}
+	if !c.IsEmpty() {
+		t.Fatal("consumer should be empty")
+	}
+}
+
+func TestConsumersAndGC(t *testing.T) {
+	dir := t.TempDir()
+	q, err := NewQueueG(dir, StringCodec, SetArenaSize(16), SetMaxInMemArenas(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	fast, _ := q.Consumer("fast")
+	slow, _ := q.Consumer("slow")
+	if _, err := q.Consumer("../escape"); err != ErrInvalidConsumer {
+		t.Fatalf("got %v, want ErrInvalidConsumer", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		q.Enqueue("item" + strconv.Itoa(i)) // 8+5 or 8+6 bytes
+	}
+	total := arenaFiles(t, dir)
+
+	for i := 0; i < 20; i++ {
+		v, err := fast.Pop()
+		if err != nil || v != "item"+strconv.Itoa(i) {
+			t.Fatalf("fast Pop() = %q, %v", v, err)
+		}
+	}
+	if n := arenaFiles(t, dir); n != total {
+		t.Fatalf("arenas deleted while slow did not read them: %d of %d", n, total)
+	}
+
+	for i := 0; i < 10; i++ {
+		slow.Dequeue()
+	}
+	if n := arenaFiles(t, dir); n >= total || n == 0 {
+		t.Fatalf("%d arenas of %d left after half of the reads", n, total)
+	}
+
+	// a new consumer starts at the oldest element kept
+	late, _ := q.Consumer("late")
+	if v, _ := late.Peek(); v != "item10" {
+		t.Fatalf("late consumer starts at %q", v)
+	}
+
+	if err := q.DeleteConsumer("slow"); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.DeleteConsumer("late"); err != nil {
+		t.Fatal(err)
+	}
+	if n := arenaFiles(t, dir); n > 1 {
+		t.Fatalf("%d arenas left once every consumer read the queue", n)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "consumer_slow.dat")); !os.IsNotExist(err) {
+		t.Fatalf("offset of deleted consumer still exists: %v", err)
+	}
+	if err := slow.Dequeue(); err != ErrQueueClosed {
+		t.Fatalf("deleted consumer Dequeue() = %v", err)
+	}
+}
+
+func TestJSONCodec(t *testing.T) {
+	type point struct{ X, Y int }
+	q, err := NewQueueG(t.TempDir(), JSONCodec[point](), SetArenaSize(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	q.Enqueue(point{1, 2})
+	if p, err := q.Peek(); err != nil || p != (point{1, 2}) {
+		t.Fatalf("Peek() = %v, %v", p, err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
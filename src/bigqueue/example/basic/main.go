@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 
-	"github.com/grandecola/bigqueue"
+	"github.com/pathbox/learning-go/src/bigqueue/bigqueue"
 )
 
 func main() {
@@ -17,7 +17,7 @@ func main() {
 		panic(err)
 	}
 
-	if err := bq.EnqueueString("elem2"); err != nil {
+	if err := bq.Enqueue([]byte("elem2")); err != nil {
 		panic(err)
 	}
 
@@ -35,10 +35,10 @@ func main() {
 		panic(err)
 	}
 
-	if elem2, err := bq.PeekString(); err != nil {
+	if elem2, err := bq.Peek(); err != nil {
 		panic(err)
 	} else {
-		fmt.Println("expected: elem2, peeked:", elem2)
+		fmt.Println("expected: elem2, peeked:", string(elem2))
 	}
 
 	if err := bq.Dequeue(); err != nil {
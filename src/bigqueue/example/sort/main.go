@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+	"log"
+)
+
+func main() {
+	input := flag.String("input", "input.txt", "file of integers, one per line")
+	temp := flag.String("temp", "tmp", "empty directory for the intermediate queues")
+	output := flag.String("output", "output.txt", "sorted file")
+	maxMem := flag.Int("max", 1000000, "number of integers held in memory")
+	flag.Parse()
+
+	if err := ExternalSort(*input, *temp, *output, *maxMem); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pathbox/learning-go/src/bigqueue/bigqueue"
+)
+
+var (
+	bqFileIndexCount = 0
+)
+
+// the sorted runs are small queues read once, keep few arenas in memory
+var runOptions = []bigqueue.Option{
+	bigqueue.SetArenaSize(4 * 1024 * 1024),
+	bigqueue.SetMaxInMemArenas(2),
+}
+
+type run = bigqueue.QueueG[int64]
+
+// maxFanIn bounds the number of runs merged at once, every run being
+// merged keeps its files open and an arena mapped
+const maxFanIn = 64
+
+// ExternalSort perform external sort https://en.wikipedia.org/wiki/External_sorting
+// The inputPath should be a path to a file containing integers in each line
+// The outputPath is similarly formatted file with sorted integers
+// The tempPath is used to write intermediate files
+// maxMemSortSize is number of elements that can be held in memory: the
+// input is divided in sorted runs of this size, then merged up to
+// maxMemSortSize or maxFanIn runs at a time, keeping only the head of each
+// run in memory. The runs are stored closed and only opened by their merge.
+func ExternalSort(inputPath, tempPath, outputPath string, maxMemSortSize int) error {
+	if maxMemSortSize < 2 {
+		return fmt.Errorf("maxMemSortSize must be at least 2")
+	}
+
+	files, err := ioutil.ReadDir(tempPath)
+	if err != nil {
+		return fmt.Errorf("unable to read temp directory :: %v", err)
+	}
+	if len(files) != 0 {
+		return fmt.Errorf("non-empty temp directory")
+	}
+
+	log.Println("starting divide step")
+	runs, err := divide(inputPath, tempPath, maxMemSortSize)
+	if err != nil {
+		return fmt.Errorf("error in divide step :: %v", err)
+	}
+
+	fanIn := maxMemSortSize
+	if fanIn > maxFanIn {
+		fanIn = maxFanIn
+	}
+
+	log.Println("starting merge step")
+	for iteration := 0; len(runs) > fanIn; iteration++ {
+		log.Printf("iteration %d, # queues %d\n", iteration, len(runs))
+		if runs, err = mergePass(tempPath, fanIn, runs); err != nil {
+			return fmt.Errorf("error in merge step :: %v", err)
+		}
+	}
+
+	if err := writeToFile(runs, outputPath); err != nil {
+		return fmt.Errorf("error in writing output to file :: %v", err)
+	}
+
+	return nil
+}
+
+// divide step divides all the input data into sorted group of elements.
+// Each group is persisted to disk using bigqueue interface, the directories
+// of the closed queues are returned.
+func divide(inputPath, tempPath string, maxMemSortSize int) ([]string, error) {
+	log.Println("reading input file")
+	queues := make([]string, 0)
+
+	// open input file
+	fd, err := os.Open(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("error in opening input file :: %v", err)
+	}
+	defer fd.Close()
+	reader := bufio.NewReader(fd)
+
+	// read all the data from input file and divide it in multiple queues
+	// such that each queue has data sorted and has maximum size of maxMemSortSize
+	data := make([]int64, 0, maxMemSortSize)
+	for {
+		// each line contains 1 element in the file
+		str, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error in reading input file :: %v", err)
+		}
+		eof := err == io.EOF
+
+		// convert the element into integer
+		if str = strings.TrimSpace(str); str != "" {
+			num, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("error in converting {%s} :: %v", str, err)
+			}
+			data = append(data, num)
+		}
+
+		// check whether we have enough element to perform in memory sort
+		if len(data) < maxMemSortSize && !eof {
+			continue
+		}
+
+		// if yes, add the sorted elements into the queue
+		if len(data) != 0 {
+			sort.Slice(data, func(i, j int) bool { return data[i] < data[j] })
+			dir, err := buildBigQueue(tempPath, data)
+			if err != nil {
+				return nil, fmt.Errorf("error in building bigqueue :: %v", err)
+			}
+
+			// add the queue in the list and truncate the slice that holds data in memory
+			queues = append(queues, dir)
+			data = data[:0]
+		}
+		if eof {
+			break
+		}
+	}
+
+	return queues, nil
+}
+
+// mergePass merges the runs k at a time into longer runs
+func mergePass(tempPath string, k int, runs []string) ([]string, error) {
+	next := make([]string, 0, (len(runs)+k-1)/k)
+	for i := 0; i < len(runs); i += k {
+		last := i + k
+		if last > len(runs) {
+			last = len(runs)
+		}
+
+		mq, err := bigqueue.NewQueueG(getTempDir(tempPath), bigqueue.Int64Codec, runOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create bigqueue :: %v", err)
+		}
+		if err := mergeQueues(runs[i:last], mq.Enqueue); err != nil {
+			mq.Close()
+			return nil, fmt.Errorf("error in merging queues :: %v", err)
+		}
+		if err := mq.Close(); err != nil {
+			return nil, fmt.Errorf("unable to close bigqueue :: %v", err)
+		}
+		next = append(next, mq.Dir())
+	}
+	return next, nil
+}
+
+// head is the next element of a run
+type head struct {
+	value int64
+	index int
+}
+
+// heads is a min heap of the next elements of the runs
+type heads []head
+
+func (h heads) Len() int            { return len(h) }
+func (h heads) Less(i, j int) bool  { return h[i].value < h[j].value }
+func (h heads) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *heads) Push(x interface{}) { *h = append(*h, x.(head)) }
+func (h *heads) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// mergeQueues is a k-way merge of the runs in dirs, emitting the elements in order.
+// The runs are opened for the merge, then closed and removed once read.
+func mergeQueues(dirs []string, emit func(int64) error) error {
+	queueList := make([]*run, 0, len(dirs))
+	defer func() {
+		for _, q := range queueList {
+			q.Close()
+		}
+	}()
+	for _, dir := range dirs {
+		q, err := bigqueue.NewQueueG(dir, bigqueue.Int64Codec, runOptions...)
+		if err != nil {
+			return fmt.Errorf("unable to open bigqueue :: %v", err)
+		}
+		queueList = append(queueList, q)
+	}
+
+	h := make(heads, 0, len(queueList))
+	for i, q := range queueList {
+		if v, err := q.Peek(); err == nil {
+			h = append(h, head{v, i})
+		} else if err != bigqueue.ErrEmptyQueue {
+			return fmt.Errorf("unable to peek :: %v", err)
+		}
+	}
+	heap.Init(&h)
+
+	for len(h) > 0 {
+		top := h[0]
+		if err := emit(top.value); err != nil {
+			return err
+		}
+
+		q := queueList[top.index]
+		if err := q.Dequeue(); err != nil {
+			return fmt.Errorf("unable to dequeue :: %v", err)
+		}
+		v, err := q.Peek()
+		switch err {
+		case nil:
+			h[0].value = v
+			heap.Fix(&h, 0)
+		case bigqueue.ErrEmptyQueue:
+			heap.Pop(&h)
+		default:
+			return fmt.Errorf("unable to peek :: %v", err)
+		}
+	}
+
+	for _, q := range queueList {
+		if err := closeQueue(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildBigQueue writes data to a new queue and closes it, it returns the queue directory
+func buildBigQueue(tempPath string, data []int64) (string, error) {
+	bq, err := bigqueue.NewQueueG(getTempDir(tempPath), bigqueue.Int64Codec, runOptions...)
+	if err != nil {
+		return "", fmt.Errorf("unable to init bigqueue :: %v", err)
+	}
+
+	// write all the data to bigqueue
+	for _, e := range data {
+		if err := bq.Enqueue(e); err != nil {
+			bq.Close()
+			return "", fmt.Errorf("unable to write to bigqueue :: %v", err)
+		}
+	}
+
+	return bq.Dir(), bq.Close()
+}
+
+func getTempDir(tempPath string) string {
+	queueDir := "q" + strconv.Itoa(bqFileIndexCount)
+	bqFileIndexCount++
+
+	queuePath := path.Join(tempPath, queueDir)
+	if err := os.MkdirAll(queuePath, 0700); err != nil {
+		panic(err)
+	}
+
+	return queuePath
+}
+
+// closeQueue closes a run and deletes its directory
+func closeQueue(q *run) error {
+	dir := q.Dir()
+	if err := q.Close(); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func writeToFile(runs []string, outputPath string) error {
+	// write the final output to file
+	od, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("error in opening input file :: %v", err)
+	}
+	defer od.Close()
+
+	w := bufio.NewWriter(od)
+	err = mergeQueues(runs, func(v int64) error {
+		_, err := w.WriteString(strconv.FormatInt(v, 10) + "\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExternalSort(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	temp := filepath.Join(dir, "tmp")
+	os.Mkdir(temp, 0700)
+
+	nums := make([]int, 1000)
+	var b strings.Builder
+	for i := range nums {
+		nums[i] = rand.Intn(20000) - 10000
+		b.WriteString(strconv.Itoa(nums[i]) + "\n")
+	}
+	if err := ioutil.WriteFile(input, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1000 elements in runs of 7, merged 7 at a time: several passes
+	if err := ExternalSort(input, temp, output, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(nums)
+	lines := strings.Split(strings.TrimSpace(string(got)), "\n")
+	if len(lines) != len(nums) {
+		t.Fatalf("got %d lines, want %d", len(lines), len(nums))
+	}
+	for i, line := range lines {
+		if line != strconv.Itoa(nums[i]) {
+			t.Fatalf("line %d = %s, want %d", i, line, nums[i])
+		}
+	}
+
+	if left, _ := ioutil.ReadDir(temp); len(left) != 0 {
+		t.Fatalf("%d queues left in the temp directory", len(left))
+	}
+}
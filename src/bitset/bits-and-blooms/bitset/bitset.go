@@ -209,7 +209,7 @@ func (b *BitSet) FlipRange(start, end uint) *BitSet {
 // remain in memory until the GC frees it.
 func (b *BitSet) Shrink(lastbitindex uint) *BitSet {
 	length := lastbitindex + 1
-	dx := wordsNeeded(length)
+	idx := wordsNeeded(length)
 	if idx > len(b.set) {
 		return b
 	}
@@ -10,12 +10,29 @@ const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
 	INT     = "INT"
+	FLOAT   = "FLOAT"
+	STRING  = "STRING"
+	IDENT   = "IDENT"
+	TRUE    = "TRUE"
+	FALSE   = "FALSE"
 
 	PLUS     = "+"
 	MINUS    = "-"
 	ASTERISK = "*"
 	SLASH    = "/"
-
+	PERCENT  = "%"
+	BANG     = "!"
+
+	EQ     = "=="
+	NOT_EQ = "!="
+	LT     = "<"
+	LE     = "<="
+	GT     = ">"
+	GE     = ">="
+	AND    = "&&"
+	OR     = "||"
+
+	COMMA  = ","
 	LPAREN = "("
 	RPAREN = ")"
 )
@@ -23,17 +40,30 @@ const (
 const ( // 优先级排序
 	_ int = iota
 	LOWEST
-	SUM     // +, -
-	PRODUCT // *, /
-	PREFIX  // -X
-	CALL    // (X)
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	EQUALS      // ==, !=
+	LESSGREATER // <, <=, >, >=
+	SUM         // +, -
+	PRODUCT     // *, /, %
+	PREFIX      // -X, !X
+	CALL        // f(X)
 )
 
 var precedences = map[string]int{
+	OR:       LOGICAL_OR,
+	AND:      LOGICAL_AND,
+	EQ:       EQUALS,
+	NOT_EQ:   EQUALS,
+	LT:       LESSGREATER,
+	LE:       LESSGREATER,
+	GT:       LESSGREATER,
+	GE:       LESSGREATER,
 	PLUS:     SUM,
 	MINUS:    SUM,
 	SLASH:    PRODUCT,
 	ASTERISK: PRODUCT,
+	PERCENT:  PRODUCT,
 	LPAREN:   CALL,
 }
 
@@ -91,6 +121,7 @@ func evalInfixExpression(left int64, operator string, right int64) int64 {
 type Token struct {
 	Type    string
 	Literal string
+	Pos     int // byte offset in input
 }
 
 func newToken(tokenType string, c byte) Token {
@@ -100,6 +131,11 @@ func newToken(tokenType string, c byte) Token {
 	}
 }
 
+var keywords = map[string]string{
+	"true":  TRUE,
+	"false": FALSE,
+}
+
 type Lexer struct {
 	input        string
 	position     int
@@ -117,12 +153,15 @@ func (l *Lexer) NextToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
+	pos := l.position
 
 	switch l.ch {
 	case '(':
 		tok = newToken(LPAREN, l.ch)
 	case ')':
 		tok = newToken(RPAREN, l.ch)
+	case ',':
+		tok = newToken(COMMA, l.ch)
 	case '+':
 		tok = newToken(PLUS, l.ch)
 	case '-':
@@ -131,13 +170,40 @@ func (l *Lexer) NextToken() Token {
 		tok = newToken(SLASH, l.ch)
 	case '*':
 		tok = newToken(ASTERISK, l.ch)
+	case '%':
+		tok = newToken(PERCENT, l.ch)
+	case '=':
+		tok = l.twoCharToken('=', EQ, ILLEGAL)
+	case '!':
+		tok = l.twoCharToken('=', NOT_EQ, BANG)
+	case '<':
+		tok = l.twoCharToken('=', LE, LT)
+	case '>':
+		tok = l.twoCharToken('=', GE, GT)
+	case '&':
+		tok = l.twoCharToken('&', AND, ILLEGAL)
+	case '|':
+		tok = l.twoCharToken('|', OR, ILLEGAL)
+	case '"':
+		tok.Type = STRING
+		tok.Literal = l.readString()
+		tok.Pos = pos
+		return tok
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
 	default:
 		if isDigit(l.ch) {
-			tok.Type = INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
+			tok.Pos = pos
+			return tok
+		} else if isLetter(l.ch) {
+			tok.Literal = l.readIdentifier()
+			tok.Type = IDENT
+			if typ, ok := keywords[tok.Literal]; ok {
+				tok.Type = typ
+			}
+			tok.Pos = pos
 			return tok
 		} else {
 			tok = newToken(ILLEGAL, l.ch)
@@ -145,13 +211,28 @@ func (l *Lexer) NextToken() Token {
 	}
 
 	l.readChar()
+	tok.Pos = pos
 	return tok
 }
 
+// twoCharToken returns token of type two if next char is second, otherwise token of type one
+func (l *Lexer) twoCharToken(second byte, two, one string) Token {
+	if l.peekChar() == second {
+		first := l.ch
+		l.readChar()
+		return Token{Type: two, Literal: string([]byte{first, l.ch})}
+	}
+	return newToken(one, l.ch)
+}
+
 func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isLetter(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+}
+
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
@@ -164,11 +245,68 @@ func (l *Lexer) readChar() {
 
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) peekChar() byte {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition]
+}
+
+// readNumber reads integer or float with optional fraction and exponent
+func (l *Lexer) readNumber() (string, string) {
 	position := l.position
+	typ := INT
 	for isDigit(l.ch) {
 		l.readChar()
 	}
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		typ = FLOAT
+		l.readChar()
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || (next == '+' || next == '-') && l.readPosition+1 < len(l.input) && isDigit(l.input[l.readPosition+1]) {
+			typ = FLOAT
+			l.readChar()
+			l.readChar()
+			for isDigit(l.ch) {
+				l.readChar()
+			}
+		}
+	}
+	return typ, l.input[position:l.position]
+}
+
+func (l *Lexer) readIdentifier() string {
+	position := l.position
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+	return l.input[position:l.position]
+}
+
+// readString reads double quoted string including quotes, escapes are validated by parser
+func (l *Lexer) readString() string {
+	position := l.position
+	for {
+		l.readChar()
+		if l.ch == '\\' {
+			l.readChar()
+			if l.ch == 0 {
+				break
+			}
+			continue
+		}
+		if l.ch == '"' || l.ch == 0 {
+			break
+		}
+	}
+	if l.ch == '"' {
+		l.readChar()
+	}
 	return l.input[position:l.position]
 }
 
@@ -189,6 +327,58 @@ type IntegerLiteralExpression struct {
 
 func (il *IntegerLiteralExpression) String() string { return il.Token.Literal }
 
+type FloatLiteralExpression struct {
+	Token Token
+	Value float64
+}
+
+func (fl *FloatLiteralExpression) String() string { return fl.Token.Literal }
+
+type StringLiteralExpression struct {
+	Token Token
+	Value string
+}
+
+func (sl *StringLiteralExpression) String() string { return sl.Token.Literal }
+
+type BooleanLiteralExpression struct {
+	Token Token
+	Value bool
+}
+
+func (bl *BooleanLiteralExpression) String() string { return bl.Token.Literal }
+
+// IdentifierExpression is a variable resolved from environment
+type IdentifierExpression struct {
+	Token Token
+	Value string
+}
+
+func (ie *IdentifierExpression) String() string { return ie.Value }
+
+// CallExpression calls function resolved from environment
+type CallExpression struct {
+	Token     Token // the function name token
+	Function  string
+	Arguments []Expression
+}
+
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ce.Function)
+	out.WriteString("(")
+	for i, arg := range ce.Arguments {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(arg.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type PrefixExpression struct {
 	Token    Token
 	Operator string
@@ -243,7 +433,7 @@ type Parser struct {
 	prefixParseFns map[string]prefixParseFn
 	infixParseFns  map[string]infixParseFn
 
-	errors []string
+	errors []*EvalError
 }
 
 func (p *Parser) registerPrefix(tokenType string, fn prefixParseFn) {
@@ -257,19 +447,25 @@ func (p *Parser) registerInfix(tokenType string, fn infixParseFn) {
 func NewParser(l *Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*EvalError{},
 	}
 
 	p.prefixParseFns = make(map[string]prefixParseFn)
 	p.registerPrefix(INT, p.parseIntegerLiteral)
+	p.registerPrefix(FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(STRING, p.parseStringLiteral)
+	p.registerPrefix(TRUE, p.parseBooleanLiteral)
+	p.registerPrefix(FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(IDENT, p.parseIdentifier)
 	p.registerPrefix(MINUS, p.parsePrefixExpression)
+	p.registerPrefix(BANG, p.parsePrefixExpression)
 	p.registerPrefix(LPAREN, p.parseGroupedExpression)
 
 	p.infixParseFns = make(map[string]infixParseFn)
-	p.registerInfix(PLUS, p.parseInfixExpression)
-	p.registerInfix(MINUS, p.parseInfixExpression)
-	p.registerInfix(SLASH, p.parseInfixExpression)
-	p.registerInfix(ASTERISK, p.parseInfixExpression)
+	for _, op := range []string{PLUS, MINUS, SLASH, ASTERISK, PERCENT, EQ, NOT_EQ, LT, LE, GT, GE, AND, OR} {
+		p.registerInfix(op, p.parseInfixExpression)
+	}
+	p.registerInfix(LPAREN, p.parseCallExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -279,7 +475,14 @@ func NewParser(l *Lexer) *Parser {
 
 func (p *Parser) ParseExpression(precedence int) Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.unexpected(p.curToken)
+		return nil
+	}
 	returnExp := prefix() // 执行prefix函数 返回了 expression
+	if returnExp == nil {
+		return nil
+	}
 
 	for precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
@@ -289,6 +492,9 @@ func (p *Parser) ParseExpression(precedence int) Expression {
 
 		p.nextToken()
 		returnExp = infix(returnExp)
+		if returnExp == nil {
+			return nil
+		}
 	}
 
 	return returnExp
@@ -308,10 +514,30 @@ func (p *Parser) curPrecedence() int {
 	return LOWEST
 }
 
+func (p *Parser) errorf(pos int, format string, args ...interface{}) {
+	p.errors = append(p.errors, NewErrorAt(ErrParser, pos, format, args...))
+}
+
+// unexpected records error for token which can not start expression, illegal tokens are lexer errors
+func (p *Parser) unexpected(tok Token) {
+	if tok.Type == ILLEGAL {
+		p.errors = append(p.errors, NewErrorAt(ErrLexer, tok.Pos, "illegal character %s", describe(tok)))
+		return
+	}
+	p.errorf(tok.Pos, "unexpected %s", describe(tok))
+}
+
 func (p *Parser) peekError(t string) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instend",
+	p.errorf(p.peekToken.Pos, "expected next token to be %s, got %s instend",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+}
+
+// describe returns token for error messages
+func describe(tok Token) string {
+	if tok.Type == EOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", tok.Literal)
 }
 
 func (p *Parser) expectPeek(t string) bool {
@@ -339,8 +565,7 @@ func (p *Parser) parseIntegerLiteral() Expression {
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken.Pos, "could not parse %q as integer", p.curToken.Literal)
 		return nil
 	}
 
@@ -348,6 +573,32 @@ func (p *Parser) parseIntegerLiteral() Expression {
 	return lit
 }
 
+func (p *Parser) parseFloatLiteral() Expression {
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorf(p.curToken.Pos, "could not parse %q as float", p.curToken.Literal)
+		return nil
+	}
+	return &FloatLiteralExpression{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseStringLiteral() Expression {
+	value, err := strconv.Unquote(p.curToken.Literal)
+	if err != nil {
+		p.errorf(p.curToken.Pos, "invalid string literal %s", p.curToken.Literal)
+		return nil
+	}
+	return &StringLiteralExpression{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseBooleanLiteral() Expression {
+	return &BooleanLiteralExpression{Token: p.curToken, Value: p.curToken.Type == TRUE}
+}
+
+func (p *Parser) parseIdentifier() Expression {
+	return &IdentifierExpression{Token: p.curToken, Value: p.curToken.Literal}
+}
+
 func (p *Parser) parsePrefixExpression() Expression {
 
 	expression := &PrefixExpression{
@@ -356,6 +607,9 @@ func (p *Parser) parsePrefixExpression() Expression {
 	}
 	p.nextToken()
 	expression.Right = p.ParseExpression(PREFIX)
+	if expression.Right == nil {
+		return nil
+	}
 	return expression
 }
 
@@ -363,7 +617,7 @@ func (p *Parser) parseGroupedExpression() Expression {
 	p.nextToken()
 	exp := p.ParseExpression(LOWEST)
 
-	if !p.expectPeek(RPAREN) {
+	if exp == nil || !p.expectPeek(RPAREN) {
 		return nil
 	}
 	return exp
@@ -387,10 +641,52 @@ func (p *Parser) parseInfixExpression(left Expression) Expression {
 	//	expression.Right = p.parseExpression(precedence)
 	//}
 	expression.Right = p.ParseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
 
 	return expression
 }
 
+// parseCallExpression parses argument list, only identifiers can be called
+func (p *Parser) parseCallExpression(function Expression) Expression {
+	ident, ok := function.(*IdentifierExpression)
+	if !ok {
+		p.errorf(p.curToken.Pos, "cannot call %s", function.String())
+		return nil
+	}
+
+	call := &CallExpression{Token: ident.Token, Function: ident.Value}
+	if p.peekTokenIs(RPAREN) {
+		p.nextToken()
+		return call
+	}
+
+	for {
+		p.nextToken()
+		arg := p.ParseExpression(LOWEST)
+		if arg == nil {
+			return nil
+		}
+		call.Arguments = append(call.Arguments, arg)
+
+		if !p.peekTokenIs(COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(RPAREN) {
+		return nil
+	}
+	return call
+}
+
+// Errors returns parse errors prefixed with their position
 func (p *Parser) Errors() []string {
-	return p.errors
+	msgs := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		msgs[i] = fmt.Sprintf("at %d: %s", err.Pos(), err.Message())
+	}
+	return msgs
 }
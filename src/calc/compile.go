@@ -0,0 +1,102 @@
+package calc
+
+// Program is type checked expression which can be evaluated many times
+type Program struct {
+	exp   Expression
+	decls *Declarations
+	typ   Type
+}
+
+// Compile parses and type checks input against declarations, nil declarations means no variables and functions
+func Compile(input string, decls *Declarations) (*Program, error) {
+	if decls == nil {
+		decls = NewDeclarations()
+	}
+
+	exp, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	decls = decls.Copy()
+	typ, typeErr := check(exp, decls)
+	if typeErr != nil {
+		return nil, typeErr
+	}
+	return &Program{exp: exp, decls: decls, typ: typ}, nil
+}
+
+// Type returns static type of program result
+func (p *Program) Type() Type {
+	return p.typ
+}
+
+// Eval evaluates program with variables from env, functions come from the declarations
+func (p *Program) Eval(env map[string]interface{}) (interface{}, error) {
+	return (&evaluator{env: env, decls: p.decls}).run(p.exp)
+}
+
+// check does static type checking of expression, returns its type
+func check(exp Expression, decls *Declarations) (Type, *EvalError) {
+	switch node := exp.(type) {
+	case *IntegerLiteralExpression:
+		return TypeInt, nil
+	case *FloatLiteralExpression:
+		return TypeFloat, nil
+	case *StringLiteralExpression:
+		return TypeString, nil
+	case *BooleanLiteralExpression:
+		return TypeBool, nil
+	case *IdentifierExpression:
+		typ, ok := decls.vars[node.Value]
+		if !ok {
+			return 0, NewErrorAt(ErrType, node.Token.Pos, "undeclared variable %s", node.Value)
+		}
+		return typ, nil
+	case *CallExpression:
+		return checkCall(node, decls)
+	case *PrefixExpression:
+		right, err := check(node.Right, decls)
+		if err != nil {
+			return 0, err
+		}
+		return CheckPrefix(node.Token.Pos, node.Operator, right)
+	case *InfixExpression:
+		return checkInfix(node, decls)
+	}
+	return 0, NewErrorAt(ErrType, 0, "unknown expression %T", exp)
+}
+
+func checkCall(node *CallExpression, decls *Declarations) (Type, *EvalError) {
+	pos := node.Token.Pos
+	fn, ok := decls.funcs[node.Function]
+	if !ok {
+		return 0, NewErrorAt(ErrType, pos, "undeclared function %s", node.Function)
+	}
+	if len(node.Arguments) != len(fn.Params) {
+		return 0, NewErrorAt(ErrType, pos, "function %s expects %d arguments, got %d", node.Function, len(fn.Params), len(node.Arguments))
+	}
+	for i, arg := range node.Arguments {
+		typ, err := check(arg, decls)
+		if err != nil {
+			return 0, err
+		}
+		if !Assignable(typ, fn.Params[i]) {
+			return 0, NewErrorAt(ErrType, pos, "argument %d of %s must be %s, got %s", i+1, node.Function, fn.Params[i], typ)
+		}
+	}
+	return fn.Result, nil
+}
+
+func checkInfix(node *InfixExpression, decls *Declarations) (Type, *EvalError) {
+	left, err := check(node.Left, decls)
+	if err != nil {
+		return 0, err
+	}
+	right, err := check(node.Right, decls)
+	if err != nil {
+		return 0, err
+	}
+
+	return CheckBinary(node.Token.Pos, node.Operator, left, right)
+}
@@ -0,0 +1,83 @@
+package calc
+
+import "fmt"
+
+// EvalErrorType error code type
+type EvalErrorType int
+
+const (
+	// ErrParser error code raised by parser
+	ErrParser EvalErrorType = iota
+
+	// ErrLexer error code raised by lexer
+	ErrLexer
+
+	// ErrInterpreter error code raised by interpreter
+	ErrInterpreter
+
+	// ErrType error code raised by type checker
+	ErrType
+)
+
+func (err EvalErrorType) String() string {
+	switch err {
+	case ErrParser:
+		return "Parser error"
+	case ErrLexer:
+		return "Lexer error"
+	case ErrInterpreter:
+		return "Interpreter error"
+	case ErrType:
+		return "Type error"
+	default:
+		return "UNDEFINED"
+	}
+}
+
+// EvalError represents error during lexing, parsing, type checking or interpreting
+type EvalError struct {
+	// message
+	s string
+
+	// type of error
+	code EvalErrorType
+
+	// byte offset in the expression where the error occured, -1 if unknown
+	pos int
+}
+
+func (err EvalError) String() string {
+	if err.pos < 0 {
+		return fmt.Sprintf("%s: %s", err.code, err.s)
+	}
+	return fmt.Sprintf("%s at %d: %s", err.code, err.pos, err.s)
+}
+
+// Error implements error interface
+func (err EvalError) Error() string {
+	return err.String()
+}
+
+// Code returns type of error
+func (err EvalError) Code() EvalErrorType {
+	return err.code
+}
+
+// Pos returns byte offset in the expression where the error occured, -1 if unknown
+func (err EvalError) Pos() int {
+	return err.pos
+}
+
+// Message returns error message without code and position
+func (err EvalError) Message() string {
+	return err.s
+}
+
+// NewErrorAt intantiates new error of type code at position pos
+func NewErrorAt(code EvalErrorType, pos int, msg string, args ...interface{}) *EvalError {
+	return &EvalError{
+		s:    fmt.Sprintf(msg, args...),
+		code: code,
+		pos:  pos,
+	}
+}
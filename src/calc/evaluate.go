@@ -0,0 +1,165 @@
+package calc
+
+// Parse parses whole input into expression which can be evaluated many times by EvalValue
+func Parse(input string) (Expression, error) {
+	parser := NewParser(NewLex(input))
+
+	exp := parser.ParseExpression(LOWEST)
+	if len(parser.errors) == 0 && !parser.peekTokenIs(EOF) {
+		parser.unexpected(parser.peekToken)
+	}
+	if len(parser.errors) > 0 {
+		return nil, parser.errors[0]
+	}
+	return exp, nil
+}
+
+// Evaluate parses and evaluates input with variables and functions from env,
+// see EvalValue
+func Evaluate(input string, env map[string]interface{}) (interface{}, error) {
+	exp, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return EvalValue(exp, env)
+}
+
+// EvalValue evaluates expression to int64, float64, string or bool.
+// Identifiers are resolved from env, Go numbers are converted to int64 or float64
+// and Func values can be called.
+func EvalValue(exp Expression, env map[string]interface{}) (interface{}, error) {
+	return (&evaluator{env: env}).run(exp)
+}
+
+// evaluator resolves variables from env, and functions from decls
+// when the expression was compiled, from env otherwise
+type evaluator struct {
+	env   map[string]interface{}
+	decls *Declarations
+}
+
+// run evaluates expression, it keeps nil *EvalError from becoming non nil error
+func (e *evaluator) run(exp Expression) (interface{}, error) {
+	v, err := e.eval(exp)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (e *evaluator) eval(exp Expression) (interface{}, *EvalError) {
+	switch node := exp.(type) {
+	case *IntegerLiteralExpression:
+		return node.Value, nil
+	case *FloatLiteralExpression:
+		return node.Value, nil
+	case *StringLiteralExpression:
+		return node.Value, nil
+	case *BooleanLiteralExpression:
+		return node.Value, nil
+	case *IdentifierExpression:
+		return e.variable(node)
+	case *CallExpression:
+		return e.call(node)
+	case *PrefixExpression:
+		right, err := e.eval(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		return Prefix(node.Token.Pos, node.Operator, right)
+	case *InfixExpression:
+		return e.infix(node)
+	}
+	return nil, NewErrorAt(ErrInterpreter, -1, "unknown expression %T", exp)
+}
+
+func (e *evaluator) variable(node *IdentifierExpression) (interface{}, *EvalError) {
+	pos := node.Token.Pos
+	v, ok := e.env[node.Value]
+	if !ok {
+		return nil, NewErrorAt(ErrInterpreter, pos, "undefined variable %s", node.Value)
+	}
+	value, ok := Normalize(v)
+	if !ok {
+		return nil, NewErrorAt(ErrInterpreter, pos, "unsupported type %T of variable %s", v, node.Value)
+	}
+	if e.decls != nil {
+		typ := e.decls.vars[node.Value]
+		if value, ok = Convert(value, typ); !ok {
+			return nil, NewErrorAt(ErrInterpreter, pos, "variable %s must be %s, got %T", node.Value, typ, v)
+		}
+	}
+	return value, nil
+}
+
+func (e *evaluator) call(node *CallExpression) (interface{}, *EvalError) {
+	pos := node.Token.Pos
+	var fn Func
+	var decl FuncDecl
+	if e.decls != nil {
+		decl = e.decls.funcs[node.Function]
+		fn = decl.Fn
+	} else {
+		switch f := e.env[node.Function].(type) {
+		case Func:
+			fn = f
+		case func(args ...interface{}) (interface{}, error):
+			fn = f
+		}
+	}
+	if fn == nil {
+		return nil, NewErrorAt(ErrInterpreter, pos, "undefined function %s", node.Function)
+	}
+
+	args := make([]interface{}, len(node.Arguments))
+	for i, argExp := range node.Arguments {
+		arg, err := e.eval(argExp)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+		if e.decls != nil {
+			var ok bool
+			if args[i], ok = Convert(arg, decl.Params[i]); !ok {
+				return nil, NewErrorAt(ErrInterpreter, pos, "argument %d of %s must be %s, got %s", i+1, node.Function, decl.Params[i], typeName(arg))
+			}
+		}
+	}
+
+	v, err := fn(args...)
+	if err != nil {
+		return nil, NewErrorAt(ErrInterpreter, pos, "%s: %v", node.Function, err)
+	}
+	result, ok := Normalize(v)
+	if ok && e.decls != nil {
+		result, ok = Convert(result, decl.Result)
+	}
+	if !ok {
+		return nil, NewErrorAt(ErrInterpreter, pos, "%s returned unsupported type %T", node.Function, v)
+	}
+	return result, nil
+}
+
+func (e *evaluator) infix(node *InfixExpression) (interface{}, *EvalError) {
+	left, err := e.eval(node.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	// logical operators short circuit
+	if node.Operator == AND || node.Operator == OR {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, NewErrorAt(ErrInterpreter, node.Token.Pos, "operator %s not defined on %s", node.Operator, typeName(left))
+		}
+		if node.Operator == AND && !l || node.Operator == OR && l {
+			return l, nil
+		}
+	}
+
+	right, err := e.eval(node.Right)
+	if err != nil {
+		return nil, err
+	}
+	return Binary(node.Token.Pos, node.Operator, left, right)
+}
@@ -0,0 +1,221 @@
+package calc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	env := map[string]interface{}{
+		"price":   9.5,
+		"qty":     12,
+		"small":   uint8(3),
+		"country": "DE",
+		"vip":     false,
+		"max": Func(func(args ...interface{}) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, errors.New("expects 2 arguments")
+			}
+			if args[0].(float64) > args[1].(float64) {
+				return args[0], nil
+			}
+			return args[1], nil
+		}),
+		"upper": func(args ...interface{}) (interface{}, error) {
+			return strings.ToUpper(args[0].(string)), nil
+		},
+		"now": func(args ...interface{}) (interface{}, error) {
+			return 42, nil
+		},
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2 * 3", int64(7)},
+		{"7 % 4 + 7 / 2", int64(6)},
+		{"7 / 2.0", 3.5},
+		{"1.5e1 - 5", 10.0},
+		{"price * qty", 114.0},
+		{"small * -qty", int64(-36)},
+		{`"a" + "b" == "ab"`, true},
+		{`country < "FR"`, true},
+		{"1 < 2 && 2 <= 2 && 3 > 2 && !(3 >= 4)", true},
+		{"1 == 1.0 && true != false", true},
+		{"vip || price * qty > 100 && country != \"XX\"", true},
+		{"false && missing", false},
+		{"true || missing", true},
+		{"max(price, 10.5)", 10.5},
+		{`upper(country) + "!"`, "DE!"},
+		{"now() + 1", int64(43)},
+	}
+
+	for _, tt := range tests {
+		res, err := Evaluate(tt.input, env)
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if res != tt.expected {
+			t.Errorf("%q: got=%#v, want=%#v", tt.input, res, tt.expected)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	env := map[string]interface{}{
+		"fail": Func(func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("boom")
+		}),
+		"ch": make(chan int),
+	}
+
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"1 +", 3, "unexpected end of input"},
+		{"1 2", 2, "unexpected \"2\""},
+		{"(1 + 2", 6, "expected next token to be )"},
+		{"1 = 2", 2, "illegal character \"=\""},
+		{`"abc`, 0, "invalid string literal"},
+		{"1(2)", 1, "cannot call 1"},
+		{"1 + x", 4, "undefined variable x"},
+		{"ch", 0, "unsupported type"},
+		{"nope(1)", 0, "undefined function nope"},
+		{"  fail()", 2, "fail: boom"},
+		{`1 + "a"`, 2, "operator + not defined on int and string"},
+		{"1.5 % 2", 4, "operator % not defined on float and float"},
+		{"-true", 0, "operator - not defined on bool"},
+		{"1 && true", 2, "operator && not defined on int"},
+		{"10 / (5 - 5)", 3, "division by zero"},
+	}
+
+	for _, tt := range tests {
+		_, err := Evaluate(tt.input, env)
+		e, ok := err.(*EvalError)
+		if !ok {
+			t.Errorf("%q: got error %v, want *EvalError", tt.input, err)
+			continue
+		}
+		if e.Pos() != tt.pos || !strings.Contains(e.Message(), tt.msg) {
+			t.Errorf("%q: got %v, want %q at %d", tt.input, e, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestParseOnce(t *testing.T) {
+	exp, err := Parse("n * 2 >= limit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, want := range map[int]bool{1: false, 5: true, 10: true} {
+		res, err := EvalValue(exp, map[string]interface{}{"n": n, "limit": 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != want {
+			t.Errorf("n=%d: got=%v, want=%v", n, res, want)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	decls := NewDeclarations().
+		Var("price", TypeFloat).
+		Var("qty", TypeInt).
+		Var("country", TypeString).
+		Var("extra", TypeAny).
+		Func("max", []Type{TypeFloat, TypeFloat}, TypeFloat, func(args ...interface{}) (interface{}, error) {
+			if args[0].(float64) > args[1].(float64) {
+				return args[0], nil
+			}
+			return args[1], nil
+		})
+
+	program, err := Compile(`max(price * qty, 10) > 100 && country != "XX"`, decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.Type() != TypeBool {
+		t.Fatalf("type = %s", program.Type())
+	}
+	for qty, want := range map[int]bool{1: false, 20: true} {
+		res, err := program.Eval(map[string]interface{}{"price": 9.5, "qty": qty, "country": "DE"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res != want {
+			t.Errorf("qty=%d: got=%v, want=%v", qty, res, want)
+		}
+	}
+
+	// int variables are converted to their declared float type
+	program, err = Compile("price / 2", decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := program.Eval(map[string]interface{}{"price": 3}); err != nil || res != 1.5 {
+		t.Fatalf("got=%v, %v", res, err)
+	}
+
+	// any typed variables are checked at runtime
+	program, err = Compile("extra + 1", decls)
+	if err != nil || program.Type() != TypeAny {
+		t.Fatalf("extra: %v", err)
+	}
+	if _, err := program.Eval(map[string]interface{}{"extra": "a"}); err == nil {
+		t.Fatal("expected runtime type error")
+	}
+
+	// functions are only resolved from declarations
+	program, _ = Compile("qty * 2", decls)
+	if _, err := program.Eval(map[string]interface{}{"qty": "12"}); err == nil {
+		t.Fatal("expected error for string qty")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	decls := NewDeclarations().
+		Var("qty", TypeInt).
+		Func("len", []Type{TypeString}, TypeInt, func(args ...interface{}) (interface{}, error) {
+			return len(args[0].(string)), nil
+		})
+
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"1 +", 3, "unexpected end of input"},
+		{"1 + x", 4, "undeclared variable x"},
+		{"nope(1)", 0, "undeclared function nope"},
+		{"max(qty)", 0, "undeclared function max"},
+		{"len(qty)", 0, "argument 1 of len must be string, got int"},
+		{"len()", 0, "function len expects 1 arguments, got 0"},
+		{`qty + "a"`, 4, "operator + not defined on int and string"},
+		{"1.5 % qty", 4, "operator % not defined on float and int"},
+		{"-true", 0, "operator - not defined on bool"},
+		{"qty && true", 4, "operator && not defined on int and bool"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(tt.input, decls)
+		e, ok := err.(*EvalError)
+		if !ok {
+			t.Errorf("%q: got error %v, want *EvalError", tt.input, err)
+			continue
+		}
+		if e.Pos() != tt.pos || !strings.Contains(e.Message(), tt.msg) {
+			t.Errorf("%q: got %v, want %q at %d", tt.input, e, tt.msg, tt.pos)
+		}
+	}
+
+	_, err := Compile("1 + x", decls)
+	if e, ok := err.(*EvalError); !ok || e.Code() != ErrType {
+		t.Errorf("got %v, want type error", err)
+	}
+}
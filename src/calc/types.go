@@ -0,0 +1,389 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+)
+
+// Type is static type of expression values
+type Type int
+
+const (
+	// TypeAny is checked at runtime only
+	TypeAny Type = iota
+	TypeInt
+	TypeFloat
+	TypeString
+	TypeBool
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeString:
+		return "string"
+	case TypeBool:
+		return "bool"
+	default:
+		return "any"
+	}
+}
+
+// Func is function callable from expressions.
+// Arguments are passed as int64, float64, string or bool
+type Func func(args ...interface{}) (interface{}, error)
+
+// FuncDecl is declared function signature, arguments are converted to Params before Fn is called
+type FuncDecl struct {
+	Params []Type
+	Result Type
+	Fn     Func
+}
+
+// Declarations holds variables and functions available to compiled expressions
+type Declarations struct {
+	vars  map[string]Type
+	funcs map[string]FuncDecl
+}
+
+// NewDeclarations creates empty declarations
+func NewDeclarations() *Declarations {
+	return &Declarations{
+		vars:  make(map[string]Type),
+		funcs: make(map[string]FuncDecl),
+	}
+}
+
+// Var declares variable name of type typ
+func (d *Declarations) Var(name string, typ Type) *Declarations {
+	d.vars[name] = typ
+	return d
+}
+
+// Func registers function name, it replaces function of the same name
+func (d *Declarations) Func(name string, params []Type, result Type, fn Func) *Declarations {
+	d.funcs[name] = FuncDecl{Params: params, Result: result, Fn: fn}
+	return d
+}
+
+// LookupVar returns type of declared variable name
+func (d *Declarations) LookupVar(name string) (Type, bool) {
+	typ, ok := d.vars[name]
+	return typ, ok
+}
+
+// LookupFunc returns declared function name
+func (d *Declarations) LookupFunc(name string) (FuncDecl, bool) {
+	fn, ok := d.funcs[name]
+	return fn, ok
+}
+
+// Copy returns snapshot of declarations so that compiled program is not affected by later changes
+func (d *Declarations) Copy() *Declarations {
+	c := &Declarations{
+		vars:  make(map[string]Type, len(d.vars)),
+		funcs: make(map[string]FuncDecl, len(d.funcs)),
+	}
+	for k, v := range d.vars {
+		c.vars[k] = v
+	}
+	for k, v := range d.funcs {
+		c.funcs[k] = v
+	}
+	return c
+}
+
+// IsNumeric reports whether type is int or float
+func IsNumeric(t Type) bool {
+	return t == TypeInt || t == TypeFloat
+}
+
+// Assignable reports whether value of type from can be used where type to is expected
+func Assignable(from, to Type) bool {
+	return from == to || from == TypeAny || to == TypeAny || from == TypeInt && to == TypeFloat
+}
+
+// TypeOf returns type of runtime value, TypeAny for values which are not normalized
+func TypeOf(v interface{}) Type {
+	switch v.(type) {
+	case int64:
+		return TypeInt
+	case float64:
+		return TypeFloat
+	case string:
+		return TypeString
+	case bool:
+		return TypeBool
+	default:
+		return TypeAny
+	}
+}
+
+// Normalize converts Go value to one of int64, float64, string or bool
+func Normalize(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case float32:
+		return float64(v), true
+	case float64, string, bool:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// Convert converts normalized value to expected type, only int to float conversion is done
+func Convert(v interface{}, to Type) (interface{}, bool) {
+	from := TypeOf(v)
+	switch {
+	case from == TypeAny:
+		return nil, false
+	case from == to || to == TypeAny:
+		return v, true
+	case from == TypeInt && to == TypeFloat:
+		return float64(v.(int64)), true
+	default:
+		return nil, false
+	}
+}
+
+// typeName returns type name of runtime value for error messages
+func typeName(v interface{}) string {
+	if typ := TypeOf(v); typ != TypeAny {
+		return typ.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// CheckPrefix returns static type of prefix operator - or ! applied to operand of type right,
+// pos is reported in the type error
+func CheckPrefix(pos int, operator string, right Type) (Type, *EvalError) {
+	switch {
+	case operator == MINUS && (right == TypeAny || IsNumeric(right)):
+		return right, nil
+	case operator == BANG && Assignable(right, TypeBool):
+		return TypeBool, nil
+	}
+	return 0, NewErrorAt(ErrType, pos, "operator %s not defined on %s", operator, right)
+}
+
+// CheckBinary returns static type of binary operator applied to operands of types left and right,
+// pos is reported in the type error
+func CheckBinary(pos int, operator string, left, right Type) (Type, *EvalError) {
+	mismatch := func() (Type, *EvalError) {
+		return 0, NewErrorAt(ErrType, pos, "operator %s not defined on %s and %s", operator, left, right)
+	}
+
+	if left == TypeAny || right == TypeAny {
+		switch operator {
+		case EQ, NOT_EQ, LT, LE, GT, GE:
+			return TypeBool, nil
+		case AND, OR:
+			if !Assignable(left, TypeBool) || !Assignable(right, TypeBool) {
+				return mismatch()
+			}
+			return TypeBool, nil
+		default:
+			return TypeAny, nil
+		}
+	}
+
+	switch operator {
+	case PLUS:
+		if left == TypeString && right == TypeString {
+			return TypeString, nil
+		}
+		fallthrough
+	case MINUS, ASTERISK, SLASH:
+		if !IsNumeric(left) || !IsNumeric(right) {
+			return mismatch()
+		}
+		if left == TypeFloat || right == TypeFloat {
+			return TypeFloat, nil
+		}
+		return TypeInt, nil
+	case PERCENT:
+		if left != TypeInt || right != TypeInt {
+			return mismatch()
+		}
+		return TypeInt, nil
+	case EQ, NOT_EQ:
+		if left != right && !(IsNumeric(left) && IsNumeric(right)) {
+			return mismatch()
+		}
+		return TypeBool, nil
+	case LT, LE, GT, GE:
+		if !(IsNumeric(left) && IsNumeric(right)) && !(left == TypeString && right == TypeString) {
+			return mismatch()
+		}
+		return TypeBool, nil
+	case AND, OR:
+		if left != TypeBool || right != TypeBool {
+			return mismatch()
+		}
+		return TypeBool, nil
+	}
+	return mismatch()
+}
+
+// Prefix evaluates prefix operator - or ! on normalized value, pos is reported in the error
+func Prefix(pos int, operator string, right interface{}) (interface{}, *EvalError) {
+	switch operator {
+	case MINUS:
+		switch v := right.(type) {
+		case int64:
+			return -v, nil
+		case float64:
+			return -v, nil
+		}
+	case BANG:
+		if v, ok := right.(bool); ok {
+			return !v, nil
+		}
+	}
+	return nil, NewErrorAt(ErrInterpreter, pos, "operator %s not defined on %s", operator, typeName(right))
+}
+
+// Binary evaluates binary operator on normalized values, && and || are evaluated
+// without short circuit, pos is reported in the error
+func Binary(pos int, operator string, left, right interface{}) (interface{}, *EvalError) {
+	// promote int to float when operands differ
+	switch l := left.(type) {
+	case int64:
+		if r, ok := right.(float64); ok {
+			left = float64(l)
+			right = r
+		}
+	case float64:
+		if r, ok := right.(int64); ok {
+			right = float64(r)
+		}
+	}
+
+	mismatch := func() (interface{}, *EvalError) {
+		return nil, NewErrorAt(ErrInterpreter, pos, "operator %s not defined on %s and %s", operator, typeName(left), typeName(right))
+	}
+
+	switch l := left.(type) {
+	case int64:
+		r, ok := right.(int64)
+		if !ok {
+			return mismatch()
+		}
+		switch operator {
+		case PLUS:
+			return l + r, nil
+		case MINUS:
+			return l - r, nil
+		case ASTERISK:
+			return l * r, nil
+		case SLASH, PERCENT:
+			if r == 0 {
+				return nil, NewErrorAt(ErrInterpreter, pos, "division by zero")
+			}
+			if operator == SLASH {
+				return l / r, nil
+			}
+			return l % r, nil
+		}
+		if result, ok := compare(operator, l < r, l == r); ok {
+			return result, nil
+		}
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return mismatch()
+		}
+		switch operator {
+		case PLUS:
+			return l + r, nil
+		case MINUS:
+			return l - r, nil
+		case ASTERISK:
+			return l * r, nil
+		case SLASH:
+			return l / r, nil
+		case PERCENT:
+			return mismatch()
+		}
+		if math.IsNaN(l) || math.IsNaN(r) {
+			switch operator {
+			case EQ, NOT_EQ, LT, LE, GT, GE:
+				return operator == NOT_EQ, nil
+			}
+			return mismatch()
+		}
+		if result, ok := compare(operator, l < r, l == r); ok {
+			return result, nil
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return mismatch()
+		}
+		if operator == PLUS {
+			return l + r, nil
+		}
+		if result, ok := compare(operator, l < r, l == r); ok {
+			return result, nil
+		}
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return mismatch()
+		}
+		switch operator {
+		case EQ:
+			return l == r, nil
+		case NOT_EQ:
+			return l != r, nil
+		case AND:
+			return l && r, nil
+		case OR:
+			return l || r, nil
+		}
+	}
+	return mismatch()
+}
+
+// compare evaluates comparison operator given result of less and equal, ok is false for other operators
+func compare(operator string, less, equal bool) (result bool, ok bool) {
+	switch operator {
+	case EQ:
+		return equal, true
+	case NOT_EQ:
+		return !equal, true
+	case LT:
+		return less, true
+	case LE:
+		return less || equal, true
+	case GT:
+		return !less && !equal, true
+	case GE:
+		return !less, true
+	default:
+		return false, false
+	}
+}
@@ -37,7 +37,7 @@ func (tr *Tree) insert(n *nodeT, cell uint64, data interface{}, bits uint) {
 		if bits == 0 || len(n.items) < maxItems {
 			i := tr.find(n, cell)
 			n.items = append(n.items, cellT{})
-			copy(n.items[i+1:], b.items[i:len(items)-1])
+			copy(n.items[i+1:], n.items[i:len(n.items)-1])
 			n.items[i] = cellT{id: cell, data: data}
 			return
 		}
@@ -45,7 +45,7 @@ func (tr *Tree) insert(n *nodeT, cell uint64, data interface{}, bits uint) {
 		tr.insert(n, cell, data, bits)
 		return
 	}
-	i := int(cell >> bits & (nNode - 1))
+	i := int(cell >> bits & (nNodes - 1))
 	for i >= len(n.nodes) {
 		n.nodes = append(n.nodes, nil)
 	}
@@ -148,35 +148,35 @@ func (tr *Tree) Scan(iter func(cell uint64, data interface{}) bool) {
 }
 
 func (tr *Tree) scan(n *nodeT, iter func(cell uint64, data interface{}) bool) bool {
-	uf !n.branch{
-		for i := 0; i< len(n.items); i++{
+	if !n.branch {
+		for i := 0; i < len(n.items); i++ {
 			if !iter(n.items[i].id, n.items[i].data) {
 				return false
 			}
-		} else {
-			for i := 0; i < len(n.nodes); i++{
-				if n.nodes[i] != nil {
-					if !tr.scan(n.nodes[i], iter) {
-						return false
-					}
+		}
+	} else {
+		for i := 0; i < len(n.nodes); i++ {
+			if n.nodes[i] != nil {
+				if !tr.scan(n.nodes[i], iter) {
+					return false
 				}
 			}
 		}
-		return true
 	}
+	return true
 }
 
-// Range iterates over the three start with the cell param.
-func (tr *Tree) Range(cell uint64, iter func(cell uint64, key interface{}) bool) {
+// Range iterates over the tree in cell order, starting with the first
+// item whose cell is greater than or equal to the cell param.
+func (tr *Tree) Range(cell uint64, iter func(cell uint64, data interface{}) bool) {
 	if tr.root == nil {
 		return
 	}
 	tr._range(tr.root, cell, 64-nBits, iter)
 }
 
-func (tr *Tree) _range(n *nodeT, cell uint64, bits uint, iter func(cell uint64, data interface{}) bool) (hit, ok bool) {
+func (tr *Tree) _range(n *nodeT, cell uint64, bits uint, iter func(cell uint64, data interface{}) bool) bool {
 	if !n.branch {
-		hit = true
 		i := tr.find(n, cell) - 1
 		for ; i >= 0; i-- {
 			if n.items[i].id < cell {
@@ -186,28 +186,24 @@ func (tr *Tree) _range(n *nodeT, cell uint64, bits uint, iter func(cell uint64,
 		i++
 		for ; i < len(n.items); i++ {
 			if !iter(n.items[i].id, n.items[i].data) {
-				return hit, false
+				return false
 			}
 		}
-		return hit, true
-	}
-	i := int(cell >> bits & (nNodes - 1))
-	if i >= len(n.nodes) || n.nodes[i] == nil {
-		return hit, true
+		return true
 	}
-	for ; i < len(n.nodes); i++ {
-		if n.nodes[i] != nil {
-			if hit {
-				if !tr.scan(n.nodes[i], iter) {
-					return hit, false
-				}
-			} else {
-				hit, ok = tr._range(n.nodes[i], cell, bits-nBits, iter)
-				if !ok {
-					return hit, false
-				}
+	start := int(cell >> bits & (nNodes - 1))
+	for i := start; i < len(n.nodes); i++ {
+		if n.nodes[i] == nil {
+			continue
+		}
+		// the nodes after the start node only hold greater cells
+		if i > start {
+			if !tr.scan(n.nodes[i], iter) {
+				return false
 			}
+		} else if !tr._range(n.nodes[i], cell, bits-nBits, iter) {
+			return false
 		}
 	}
-	return hit, true
-}
\ No newline at end of file
+	return true
+}
@@ -0,0 +1,63 @@
+package celltree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var tr Tree
+	var cells []uint64
+	for i := 0; i < 5000; i++ {
+		// few distinct high bytes, so the start node of a range is often missing
+		cell := uint64(rnd.Intn(4))<<62 | uint64(rnd.Int63n(1<<20))
+		cells = append(cells, cell)
+		tr.Insert(cell, i)
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i] < cells[j] })
+
+	for i := 0; i < 100; i++ {
+		start := rnd.Uint64()
+		var got []uint64
+		tr.Range(start, func(cell uint64, data interface{}) bool {
+			got = append(got, cell)
+			return true
+		})
+		want := cells[sort.Search(len(cells), func(i int) bool { return cells[i] >= start }):]
+		if len(got) != len(want) {
+			t.Fatalf("Range(%x) = %d cells, want %d", start, len(got), len(want))
+		}
+		for j := range got {
+			if got[j] != want[j] {
+				t.Fatalf("Range(%x)[%d] = %x, want %x", start, j, got[j], want[j])
+			}
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	var tr Tree
+	for i := 0; i < 1000; i++ {
+		tr.Insert(uint64(i)<<40, i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		tr.Remove(uint64(i)<<40, i)
+	}
+	tr.Remove(1<<40, "missing")
+	if tr.Len() != 500 {
+		t.Fatalf("Len() = %d, want 500", tr.Len())
+	}
+	n := 0
+	tr.Scan(func(cell uint64, data interface{}) bool {
+		if data.(int)%2 == 0 {
+			t.Fatalf("removed item %v scanned", data)
+		}
+		n++
+		return true
+	})
+	if n != 500 {
+		t.Fatalf("scanned %d items", n)
+	}
+}
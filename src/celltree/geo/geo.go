@@ -0,0 +1,178 @@
+// Package geo indexes points by latitude and longitude in a celltree.Tree.
+//
+// Points are encoded into cells along a Hilbert curve, so points close to
+// each other usually get close cells. A query is split into a few cell
+// ranges covering its area, the ranges are read from the tree and the
+// points found are filtered by their exact position.
+package geo
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pathbox/learning-go/src/celltree"
+)
+
+// EarthRadius is the mean radius of the Earth in meters
+const EarthRadius = 6371008.8
+
+// maxRanges bounds the number of cell ranges a query is split into
+const maxRanges = 64
+
+// Item is a point stored in an Index
+type Item struct {
+	Lat, Lon float64
+	Data     interface{}
+}
+
+// Result is an item found by Nearby with its distance in meters
+type Result struct {
+	Item
+	Distance float64
+}
+
+// Rect is a bounding box in degrees.
+// A box with MinLon greater than MaxLon crosses the antimeridian.
+type Rect struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// Contains tells if the point is inside the box, edges included
+func (r Rect) Contains(lat, lon float64) bool {
+	if lat < r.MinLat || lat > r.MaxLat {
+		return false
+	}
+	if r.MinLon <= r.MaxLon {
+		return lon >= r.MinLon && lon <= r.MaxLon
+	}
+	return lon >= r.MinLon || lon <= r.MaxLon
+}
+
+// Index is a geospatial index of points
+type Index struct {
+	tree celltree.Tree
+}
+
+// Len returns the number of points in the index
+func (ix *Index) Len() int {
+	return ix.tree.Len()
+}
+
+// Insert adds a point with its user data
+func (ix *Index) Insert(lat, lon float64, data interface{}) {
+	ix.tree.Insert(Cell(lat, lon), Item{Lat: lat, Lon: lon, Data: data})
+}
+
+// Remove removes one point inserted at lat, lon with data.
+// Data is compared with ==, so it must be comparable, use RemoveWhen for
+// data such as slices or maps.
+func (ix *Index) Remove(lat, lon float64, data interface{}) {
+	ix.RemoveWhen(lat, lon, func(d interface{}) bool {
+		return d == data
+	})
+}
+
+// RemoveWhen removes one point inserted at lat, lon whose data matches cond
+func (ix *Index) RemoveWhen(lat, lon float64, cond func(data interface{}) bool) {
+	ix.tree.RemoveWhen(Cell(lat, lon), func(v interface{}) bool {
+		item := v.(Item)
+		return item.Lat == lat && item.Lon == lon && cond(item.Data)
+	})
+}
+
+// Within returns the points inside the box
+func (ix *Index) Within(rect Rect) []Item {
+	var items []Item
+	for _, r := range splitAntimeridian(rect) {
+		ix.search(r, func(item Item) {
+			items = append(items, item)
+		})
+	}
+	return items
+}
+
+// Nearby returns the points at most radius meters away from lat, lon, closest first
+func (ix *Index) Nearby(lat, lon, radius float64) []Result {
+	var results []Result
+	for _, r := range boundingRects(lat, lon, radius) {
+		ix.search(r, func(item Item) {
+			if d := Distance(lat, lon, item.Lat, item.Lon); d <= radius {
+				results = append(results, Result{Item: item, Distance: d})
+			}
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Distance < results[j].Distance })
+	return results
+}
+
+// search calls fn for the items inside r, which must not cross the antimeridian
+func (ix *Index) search(r Rect, fn func(Item)) {
+	for _, cr := range cover(r) {
+		ix.tree.Range(cr.lo, func(cell uint64, data interface{}) bool {
+			if cell > cr.hi {
+				return false
+			}
+			if item := data.(Item); r.Contains(item.Lat, item.Lon) {
+				fn(item)
+			}
+			return true
+		})
+	}
+}
+
+// Distance returns the great-circle distance in meters between two points
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	φ1, φ2 := radians(lat1), radians(lat2)
+	dφ, dλ := radians(lat2-lat1), radians(lon2-lon1)
+	a := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// boundingRects returns the boxes containing the circle of radius meters
+// around lat, lon, split at the antimeridian
+func boundingRects(lat, lon, radius float64) []Rect {
+	if radius < 0 {
+		return nil
+	}
+	// a small margin keeps points on the circle despite rounding errors
+	dLat := degrees(radius/EarthRadius) + 1e-9
+	r := Rect{MinLat: lat - dLat, MaxLat: lat + dLat, MinLon: -180, MaxLon: 180}
+	if r.MinLat <= -90 || r.MaxLat >= 90 {
+		// the circle covers a pole, hence every longitude
+		r.MinLat, r.MaxLat = math.Max(r.MinLat, -90), math.Min(r.MaxLat, 90)
+		return []Rect{r}
+	}
+
+	sin := math.Sin(radius/EarthRadius) / math.Cos(radians(lat))
+	if sin >= 1 {
+		return []Rect{r}
+	}
+	dLon := degrees(math.Asin(sin)) + 1e-9
+	r.MinLon, r.MaxLon = normalizeLon(lon-dLon), normalizeLon(lon+dLon)
+	return splitAntimeridian(r)
+}
+
+func normalizeLon(lon float64) float64 {
+	for lon < -180 {
+		lon += 360
+	}
+	for lon > 180 {
+		lon -= 360
+	}
+	return lon
+}
+
+func splitAntimeridian(r Rect) []Rect {
+	if r.MinLon <= r.MaxLon {
+		return []Rect{r}
+	}
+	east, west := r, r
+	east.MaxLon = 180
+	west.MinLon = -180
+	return []Rect{east, west}
+}
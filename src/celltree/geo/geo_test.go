@@ -0,0 +1,183 @@
+package geo
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func randomIndex(rnd *rand.Rand, n int) (*Index, []Item) {
+	ix := &Index{}
+	items := make([]Item, n)
+	for i := range items {
+		items[i] = Item{Lat: rnd.Float64()*180 - 90, Lon: rnd.Float64()*360 - 180, Data: i}
+		ix.Insert(items[i].Lat, items[i].Lon, i)
+	}
+	return ix, items
+}
+
+func ids(items []Item) []int {
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.Data.(int))
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWithinBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ix, items := randomIndex(rnd, 20000)
+
+	for i := 0; i < 200; i++ {
+		lat0, lat1 := rnd.Float64()*180-90, rnd.Float64()*180-90
+		if lat0 > lat1 {
+			lat0, lat1 = lat1, lat0
+		}
+		// boxes crossing the antimeridian have MinLon > MaxLon
+		rect := Rect{MinLat: lat0, MaxLat: lat1, MinLon: rnd.Float64()*360 - 180, MaxLon: rnd.Float64()*360 - 180}
+		if i%2 == 0 {
+			rect.MaxLon = rect.MinLon + rnd.Float64()*(180-rect.MinLon)
+		}
+
+		var want []Item
+		for _, item := range items {
+			if rect.Contains(item.Lat, item.Lon) {
+				want = append(want, item)
+			}
+		}
+		if got := ix.Within(rect); !equal(ids(got), ids(want)) {
+			t.Fatalf("Within(%+v) found %d points, want %d", rect, len(got), len(want))
+		}
+	}
+}
+
+func TestNearbyBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	ix, items := randomIndex(rnd, 20000)
+
+	centers := [][2]float64{{89.5, 10}, {-89.9, -170}, {10, 179.9}, {-20, -179.5}}
+	for i := 0; i < 200; i++ {
+		centers = append(centers, [2]float64{rnd.Float64()*180 - 90, rnd.Float64()*360 - 180})
+	}
+	for i, c := range centers {
+		radius := rnd.Float64() * 2000000
+		if i%10 == 0 {
+			radius = rnd.Float64() * 10000000
+		}
+
+		var want []Item
+		for _, item := range items {
+			if Distance(c[0], c[1], item.Lat, item.Lon) <= radius {
+				want = append(want, item)
+			}
+		}
+		got := ix.Nearby(c[0], c[1], radius)
+		var found []Item
+		for j, r := range got {
+			if j > 0 && r.Distance < got[j-1].Distance {
+				t.Fatalf("Nearby results not sorted by distance")
+			}
+			found = append(found, r.Item)
+		}
+		if !equal(ids(found), ids(want)) {
+			t.Fatalf("Nearby(%v, %v, %v) found %d points, want %d", c[0], c[1], radius, len(found), len(want))
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	ix := &Index{}
+	ix.Insert(48.85, 2.35, "paris")
+	ix.Insert(48.85, 2.35, "paris again")
+	ix.Remove(48.85, 2.35, "paris")
+	ix.Remove(48.85, 2.35, "missing")
+	if ix.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", ix.Len())
+	}
+	got := ix.Nearby(48.86, 2.34, 5000)
+	if len(got) != 1 || got[0].Data != "paris again" {
+		t.Fatalf("Nearby = %v", got)
+	}
+}
+
+func TestRemoveWhen(t *testing.T) {
+	type place struct {
+		id   int
+		tags []string // not comparable
+	}
+	ix := &Index{}
+	ix.Insert(48.85, 2.35, place{1, []string{"city"}})
+	ix.Insert(48.85, 2.35, place{2, []string{"city"}})
+	ix.RemoveWhen(48.85, 2.35, func(data interface{}) bool {
+		return data.(place).id == 1
+	})
+	got := ix.Nearby(48.85, 2.35, 10)
+	if len(got) != 1 || got[0].Data.(place).id != 2 {
+		t.Fatalf("Nearby = %v", got)
+	}
+}
+
+func TestCellLocality(t *testing.T) {
+	// the four quadrants of the grid are consecutive quarters of the curve
+	quarter := uint64(1) << 62
+	seen := map[uint64]bool{}
+	for _, c := range []struct{ lat, lon float64 }{{-45, -90}, {45, -90}, {45, 90}, {-45, 90}} {
+		seen[Cell(c.lat, c.lon)/quarter] = true
+	}
+	if len(seen) != 4 {
+		t.Fatalf("quadrants share a quarter of the curve: %v", seen)
+	}
+}
+
+const collection = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "id": "eiffel", "properties": {"name": "Eiffel Tower"},
+     "geometry": {"type": "Point", "coordinates": [2.2945, 48.8584]}},
+    {"type": "Feature", "properties": {"name": "stations"},
+     "geometry": {"type": "MultiPoint", "coordinates": [[2.3551, 48.8809], [2.3730, 48.8443]]}},
+    {"type": "Feature", "properties": {"name": "road"},
+     "geometry": {"type": "LineString", "coordinates": [[2.0, 48.0], [3.0, 49.0]]}}
+  ]
+}`
+
+func TestLoadGeoJSON(t *testing.T) {
+	ix := &Index{}
+	n, err := ix.LoadGeoJSON(strings.NewReader(collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 || ix.Len() != 3 {
+		t.Fatalf("loaded %d points, Len() = %d", n, ix.Len())
+	}
+
+	got := ix.Nearby(48.8584, 2.2945, 100)
+	if len(got) != 1 {
+		t.Fatalf("Nearby(eiffel) = %v", got)
+	}
+	f := got[0].Data.(*Feature)
+	if f.ID != "eiffel" || f.Properties["name"] != "Eiffel Tower" {
+		t.Fatalf("feature = %+v", f)
+	}
+
+	if _, err := ix.LoadGeoJSON(strings.NewReader(`{"type": "Point", "coordinates": [200, 0]}`)); err == nil {
+		t.Fatal("out of range position accepted")
+	}
+	if _, err := ix.LoadGeoJSON(strings.NewReader(`{"type": "Circle"}`)); err == nil {
+		t.Fatal("unknown type accepted")
+	}
+}
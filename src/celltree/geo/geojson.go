@@ -0,0 +1,108 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Feature is the user data of the points loaded from GeoJSON
+type Feature struct {
+	ID         interface{}
+	Properties map[string]interface{}
+}
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	ID          interface{}     `json:"id"`
+	Properties  json.RawMessage `json:"properties"`
+	Geometry    *geoJSON        `json:"geometry"`
+	Geometries  []geoJSON       `json:"geometries"`
+	Features    []geoJSON       `json:"features"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// LoadGeoJSON inserts the points of a GeoJSON object: a FeatureCollection,
+// a Feature or a geometry. The points of Point and MultiPoint geometries are
+// inserted with a *Feature as data, other geometries are skipped.
+// It returns the number of points inserted.
+func (ix *Index) LoadGeoJSON(r io.Reader) (int, error) {
+	var obj geoJSON
+	if err := json.NewDecoder(r).Decode(&obj); err != nil {
+		return 0, err
+	}
+	return ix.load(&obj, &Feature{})
+}
+
+func (ix *Index) load(obj *geoJSON, feature *Feature) (int, error) {
+	switch obj.Type {
+	case "FeatureCollection":
+		n := 0
+		for i := range obj.Features {
+			loaded, err := ix.load(&obj.Features[i], nil)
+			n += loaded
+			if err != nil {
+				return n, err
+			}
+		}
+		return n, nil
+	case "Feature":
+		feature := &Feature{ID: obj.ID}
+		if len(obj.Properties) > 0 {
+			if err := json.Unmarshal(obj.Properties, &feature.Properties); err != nil {
+				return 0, err
+			}
+		}
+		if obj.Geometry == nil {
+			return 0, nil
+		}
+		return ix.load(obj.Geometry, feature)
+	case "GeometryCollection":
+		n := 0
+		for i := range obj.Geometries {
+			loaded, err := ix.load(&obj.Geometries[i], feature)
+			n += loaded
+			if err != nil {
+				return n, err
+			}
+		}
+		return n, nil
+	case "Point":
+		var p []float64
+		if err := json.Unmarshal(obj.Coordinates, &p); err != nil {
+			return 0, err
+		}
+		if err := ix.loadPoint(p, feature); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	case "MultiPoint":
+		var ps [][]float64
+		if err := json.Unmarshal(obj.Coordinates, &ps); err != nil {
+			return 0, err
+		}
+		for i, p := range ps {
+			if err := ix.loadPoint(p, feature); err != nil {
+				return i, err
+			}
+		}
+		return len(ps), nil
+	case "LineString", "MultiLineString", "Polygon", "MultiPolygon":
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("geo: unknown GeoJSON type %q", obj.Type)
+	}
+}
+
+// loadPoint inserts a GeoJSON position, which is longitude first
+func (ix *Index) loadPoint(p []float64, feature *Feature) error {
+	if len(p) < 2 {
+		return fmt.Errorf("geo: GeoJSON position %v needs longitude and latitude", p)
+	}
+	lon, lat := p[0], p[1]
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return fmt.Errorf("geo: GeoJSON position %v out of range", p)
+	}
+	ix.Insert(lat, lon, feature)
+	return nil
+}
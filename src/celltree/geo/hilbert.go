@@ -0,0 +1,145 @@
+package geo
+
+import "sort"
+
+// bits per axis, two axes fill a uint64 cell
+const order = 32
+
+// Cell returns the cell of a point: its position along a Hilbert curve
+// filling a 2^32 x 2^32 grid over longitudes and latitudes
+func Cell(lat, lon float64) uint64 {
+	return hilbert(lonToX(lon), latToY(lat))
+}
+
+func lonToX(lon float64) uint32 {
+	return scale((lon + 180) / 360)
+}
+
+func latToY(lat float64) uint32 {
+	return scale((lat + 90) / 180)
+}
+
+// scale maps [0, 1] to the grid coordinates
+func scale(f float64) uint32 {
+	if !(f > 0) {
+		return 0
+	}
+	if f >= 1 {
+		return 1<<order - 1
+	}
+	return uint32(f * (1 << order))
+}
+
+// hilbert returns the distance of x, y along the Hilbert curve
+func hilbert(x, y uint32) uint64 {
+	var d uint64
+	for s := uint32(1 << (order - 1)); s > 0; s >>= 1 {
+		var rx, ry uint32
+		if x&s != 0 {
+			rx = 1
+		}
+		if y&s != 0 {
+			ry = 1
+		}
+		d += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+		// rotate the quadrant so the curve keeps its orientation
+		if ry == 0 {
+			if rx == 1 {
+				x, y = ^x, ^y
+			}
+			x, y = y, x
+		}
+	}
+	return d
+}
+
+// cellRange is an inclusive range of cells
+type cellRange struct {
+	lo, hi uint64
+}
+
+// quad is an aligned square of the grid at a level of the quadtree,
+// its side is 2^(order-level) and all its cells form one range
+type quad struct {
+	x, y  uint32
+	level uint
+}
+
+func (q quad) side() uint64 {
+	return uint64(1) << (order - q.level)
+}
+
+func (q quad) cells() cellRange {
+	if q.level == 0 {
+		return cellRange{0, 1<<64 - 1}
+	}
+	mask := uint64(1)<<(2*(order-q.level)) - 1
+	d := hilbert(q.x, q.y)
+	return cellRange{d &^ mask, d | mask}
+}
+
+// cover returns the sorted cell ranges covering r.
+// The grid is divided like a quadtree: squares inside r are kept whole and
+// the squares crossing its edges are divided until there would be more
+// than maxRanges of them.
+func cover(r Rect) []cellRange {
+	if r.MinLat > r.MaxLat || r.MinLon > r.MaxLon {
+		return nil
+	}
+	x0, x1 := lonToX(r.MinLon), lonToX(r.MaxLon)
+	y0, y1 := latToY(r.MinLat), latToY(r.MaxLat)
+
+	inside := func(lo, hi, min, max uint32) bool { return lo >= min && hi <= max }
+	outside := func(lo, hi, min, max uint32) bool { return hi < min || lo > max }
+
+	var ranges []cellRange
+	edges := []quad{{}}
+	for len(edges) > 0 && edges[0].level < order {
+		var full, next []quad
+		for _, q := range edges {
+			half := uint32(q.side() / 2)
+			for _, c := range [4]quad{
+				{q.x, q.y, q.level + 1},
+				{q.x + half, q.y, q.level + 1},
+				{q.x, q.y + half, q.level + 1},
+				{q.x + half, q.y + half, q.level + 1},
+			} {
+				cx1, cy1 := c.x+half-1, c.y+half-1
+				switch {
+				case outside(c.x, cx1, x0, x1) || outside(c.y, cy1, y0, y1):
+				case inside(c.x, cx1, x0, x1) && inside(c.y, cy1, y0, y1):
+					full = append(full, c)
+				default:
+					next = append(next, c)
+				}
+			}
+		}
+		if len(ranges)+len(full)+len(next) > maxRanges {
+			break
+		}
+		for _, q := range full {
+			ranges = append(ranges, q.cells())
+		}
+		edges = next
+	}
+	for _, q := range edges {
+		ranges = append(ranges, q.cells())
+	}
+	return merge(ranges)
+}
+
+// merge sorts the ranges and joins the adjacent ones
+func merge(ranges []cellRange) []cellRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
+	var merged []cellRange
+	for _, cr := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].hi != 1<<64-1 && merged[n-1].hi+1 >= cr.lo {
+			if cr.hi > merged[n-1].hi {
+				merged[n-1].hi = cr.hi
+			}
+			continue
+		}
+		merged = append(merged, cr)
+	}
+	return merged
+}
@@ -0,0 +1,108 @@
+package trie
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Scorer is implemented by meta information carrying a ranking score
+type Scorer interface {
+	Score() float64
+}
+
+// Score returns the ranking score of meta information: the result of Score()
+// for a Scorer, the value of a number, and 0 otherwise.
+func Score(meta interface{}) float64 {
+	switch m := meta.(type) {
+	case Scorer:
+		return m.Score()
+	case float64:
+		return m
+	case float32:
+		return float64(m)
+	case int:
+		return float64(m)
+	case int64:
+		return float64(m)
+	case int32:
+		return float64(m)
+	case uint:
+		return float64(m)
+	case uint64:
+		return float64(m)
+	case uint32:
+		return float64(m)
+	default:
+		return 0
+	}
+}
+
+// Match is a key found by Complete or LevenshteinSearch
+type Match struct {
+	Key      string
+	Meta     interface{}
+	Score    float64
+	Distance int // edit distance to the query, 0 for Complete
+}
+
+// Complete returns at most n keys starting with prefix, highest score first
+// and shorter keys first among equal scores.
+// Every node knows the best score below it, so only the branches which can
+// still make it into the top n are visited.
+func (t *Trie) Complete(prefix string, n int) []Match {
+	node := findNode(t.Root(), t.normalize(prefix))
+	if node == nil || n <= 0 {
+		return nil
+	}
+
+	var matches []Match
+	queue := &nodeQueue{node}
+	for queue.Len() > 0 && len(matches) < n {
+		x := heap.Pop(queue).(*Node)
+		if x.term {
+			matches = append(matches, Match{Key: x.key, Meta: x.meta, Score: x.best})
+			continue
+		}
+		for _, c := range x.children {
+			if !math.IsInf(c.best, -1) {
+				heap.Push(queue, c)
+			}
+		}
+	}
+	return matches
+}
+
+// nodeQueue pops the node with the best score first.
+// Ties go to terminating nodes, then to shorter and lexically smaller paths,
+// so results with equal scores come in a stable order.
+type nodeQueue []*Node
+
+func (q nodeQueue) Len() int { return len(q) }
+
+func (q nodeQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if a.best != b.best {
+		return a.best > b.best
+	}
+	if a.term != b.term {
+		return a.term
+	}
+	if a.term {
+		return a.key < b.key
+	}
+	if a.depth != b.depth {
+		return a.depth < b.depth
+	}
+	return a.val < b.val
+}
+
+func (q nodeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(*Node)) }
+
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
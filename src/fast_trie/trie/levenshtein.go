@@ -0,0 +1,86 @@
+package trie
+
+import "sort"
+
+// LevenshteinSearch returns the keys within maxDistance edits (insertions,
+// deletions or substitutions of one rune) of query, closest first, then
+// highest score first.
+//
+// The trie is walked like a Levenshtein automaton: every node extends the
+// edit distance row of its parent by one rune, and a branch is abandoned as
+// soon as no cell of its row is within maxDistance.
+func (t *Trie) LevenshteinSearch(query string, maxDistance int) []Match {
+	if maxDistance < 0 {
+		return nil
+	}
+
+	q := t.normalize(query)
+	row := make([]int, len(q)+1)
+	for i := range row {
+		row[i] = i
+	}
+
+	s := &levenshteinSearch{query: q, max: maxDistance}
+	for _, c := range t.root.children {
+		s.walk(c, row)
+	}
+
+	sort.Slice(s.matches, func(i, j int) bool {
+		a, b := s.matches[i], s.matches[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Key < b.Key
+	})
+	return s.matches
+}
+
+type levenshteinSearch struct {
+	query   []rune
+	max     int
+	matches []Match
+}
+
+// walk visits n given the edit distance row of its parent
+func (s *levenshteinSearch) walk(n *Node, prev []int) {
+	if n.term {
+		if d := prev[len(s.query)]; d <= s.max {
+			s.matches = append(s.matches, Match{Key: n.key, Meta: n.meta, Score: n.best, Distance: d})
+		}
+		return
+	}
+
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	best := row[0]
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if s.query[i-1] == n.val {
+			cost = 0
+		}
+		row[i] = min3(row[i-1]+1, prev[i]+1, prev[i-1]+cost)
+		if row[i] < best {
+			best = row[i]
+		}
+	}
+	if best > s.max {
+		return
+	}
+
+	for _, c := range n.children {
+		s.walk(c, row)
+	}
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,223 @@
+package trie
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrInvalidData is returned by UnmarshalBinary for data not produced by MarshalBinary
+var ErrInvalidData = errors.New("trie: invalid binary data")
+
+var magic = []byte("TRIE\x01")
+
+// tags of the meta information types
+const (
+	metaNil = iota
+	metaInt
+	metaInt64
+	metaFloat64
+	metaString
+	metaBool
+	metaBytes
+	metaGob // any other type, it must be registered with gob.Register
+)
+
+// MarshalBinary encodes the options and the keys with their meta information.
+// Keys are written in order, each sharing its prefix with the previous one.
+func (t *Trie) MarshalBinary() ([]byte, error) {
+	var terms []*Node
+	nodes := []*Node{t.root}
+	for len(nodes) > 0 {
+		n := nodes[len(nodes)-1]
+		nodes = nodes[:len(nodes)-1]
+		if n.term {
+			terms = append(terms, n)
+		}
+		for _, c := range n.children {
+			nodes = append(nodes, c)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i].key < terms[j].key })
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+	var flags byte
+	if t.opts.IgnoreCase {
+		flags |= 1
+	}
+	buf.WriteByte(flags)
+	buf.WriteByte(byte(t.opts.Normalization))
+	putUvarint(&buf, uint64(len(terms)))
+
+	prev := ""
+	for _, n := range terms {
+		shared := 0
+		for shared < len(prev) && shared < len(n.key) && prev[shared] == n.key[shared] {
+			shared++
+		}
+		putUvarint(&buf, uint64(shared))
+		putString(&buf, n.key[shared:])
+		if err := putMeta(&buf, n.meta); err != nil {
+			return nil, err
+		}
+		prev = n.key
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces the content and the options of the trie with data produced by MarshalBinary
+func (t *Trie) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	head := make([]byte, len(magic)+2)
+	if _, err := r.Read(head); err != nil || !bytes.Equal(head[:len(magic)], magic) {
+		return ErrInvalidData
+	}
+	loaded := NewWithOptions(Options{
+		IgnoreCase:    head[len(magic)]&1 != 0,
+		Normalization: Normalization(head[len(magic)+1]),
+	})
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ErrInvalidData
+	}
+
+	prev := ""
+	for i := uint64(0); i < count; i++ {
+		shared, err := binary.ReadUvarint(r)
+		if err != nil || shared > uint64(len(prev)) {
+			return ErrInvalidData
+		}
+		suffix, err := readString(r)
+		if err != nil {
+			return err
+		}
+		meta, err := readMeta(r)
+		if err != nil {
+			return err
+		}
+
+		key := prev[:shared] + suffix
+		loaded.Add(key, meta)
+		prev = key
+	}
+	if r.Len() != 0 {
+		return ErrInvalidData
+	}
+
+	*t = *loaded
+	return nil
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func putString(buf *bytes.Buffer, s string) {
+	putUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func putMeta(buf *bytes.Buffer, meta interface{}) error {
+	var b [binary.MaxVarintLen64]byte
+	switch m := meta.(type) {
+	case nil:
+		buf.WriteByte(metaNil)
+	case int:
+		buf.WriteByte(metaInt)
+		buf.Write(b[:binary.PutVarint(b[:], int64(m))])
+	case int64:
+		buf.WriteByte(metaInt64)
+		buf.Write(b[:binary.PutVarint(b[:], m)])
+	case float64:
+		buf.WriteByte(metaFloat64)
+		binary.LittleEndian.PutUint64(b[:8], math.Float64bits(m))
+		buf.Write(b[:8])
+	case string:
+		buf.WriteByte(metaString)
+		putString(buf, m)
+	case bool:
+		buf.WriteByte(metaBool)
+		if m {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case []byte:
+		buf.WriteByte(metaBytes)
+		putString(buf, string(m))
+	default:
+		var encoded bytes.Buffer
+		if err := gob.NewEncoder(&encoded).Encode(&meta); err != nil {
+			return err
+		}
+		buf.WriteByte(metaGob)
+		putString(buf, encoded.String())
+	}
+	return nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return "", ErrInvalidData
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b), nil
+}
+
+func readMeta(r *bytes.Reader) (interface{}, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, ErrInvalidData
+	}
+
+	switch tag {
+	case metaNil:
+		return nil, nil
+	case metaInt, metaInt64:
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, ErrInvalidData
+		}
+		if tag == metaInt {
+			return int(v), nil
+		}
+		return v, nil
+	case metaFloat64:
+		var b [8]byte
+		if n, _ := r.Read(b[:]); n != 8 {
+			return nil, ErrInvalidData
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+	case metaString:
+		return readString(r)
+	case metaBool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, ErrInvalidData
+		}
+		return b != 0, nil
+	case metaBytes:
+		s, err := readString(r)
+		return []byte(s), err
+	case metaGob:
+		s, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		var meta interface{}
+		if err := gob.NewDecoder(bytes.NewReader([]byte(s))).Decode(&meta); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	default:
+		return nil, ErrInvalidData
+	}
+}
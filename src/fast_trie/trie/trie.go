@@ -1,20 +1,50 @@
 package trie
 
-import "sort"
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
 
 type Node struct {
 	val      rune
 	term     bool
 	depth    int
 	meta     interface{}
+	key      string  // key as added, on terminating nodes
+	best     float64 // highest score of the terminating nodes below
 	mask     uint64
 	parent   *Node
 	children map[rune]*Node
 }
 
+// Normalization is the Unicode normalization applied to keys and queries
+type Normalization int
+
+const (
+	NoNormalization Normalization = iota
+	NFC
+	NFKC
+	// FoldAccents applies NFKC and drops combining marks, so "café" matches "cafe"
+	FoldAccents
+)
+
+// Options control how keys and queries are compared
+type Options struct {
+	// IgnoreCase matches keys case-insensitively
+	IgnoreCase    bool
+	Normalization Normalization
+}
+
 type Trie struct {
 	root *Node
 	size int
+	opts Options
 }
 
 type ByKeys []string
@@ -26,9 +56,16 @@ func (a ByKeys) Less(i, j int) bool { return len(a[i]) < len(a[j]) }
 const nul = 0x0
 
 func New() *Trie {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions creates a trie normalizing keys and queries according to opts.
+// Keys are returned by searches as they were added.
+func NewWithOptions(opts Options) *Trie {
 	return &Trie{
-		root: &Node{children: make(map[rune]*Node), depth: 0},
+		root: &Node{children: make(map[rune]*Node), depth: 0, best: math.Inf(-1)},
 		size: 0,
+		opts: opts,
 	}
 }
 
@@ -36,9 +73,34 @@ func (t *Trie) Root() *Node {
 	return t.root
 }
 
+// Len returns the number of keys in the trie
+func (t *Trie) Len() int {
+	return t.size
+}
+
+// normalize returns the runes of key as they are stored in the trie
+func (t *Trie) normalize(key string) []rune {
+	switch t.opts.Normalization {
+	case NFC:
+		key = norm.NFC.String(key)
+	case NFKC:
+		key = norm.NFKC.String(key)
+	case FoldAccents:
+		fold := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+		if folded, _, err := transform.String(fold, key); err == nil {
+			key = folded
+		}
+	}
+	if t.opts.IgnoreCase {
+		key = strings.ToLower(key)
+	}
+	return []rune(key)
+}
+
+// Add adds key or replaces its meta information.
+// The meta information may carry a score used to rank Complete results, see Score.
 func (t *Trie) Add(key string, meta interface{}) *Node {
-	t.size++
-	runes := []rune(key)
+	runes := t.normalize(key)
 	bitmask := maskruneslice(runes)
 	node := t.root
 	node.mask |= bitmask
@@ -52,43 +114,54 @@ func (t *Trie) Add(key string, meta interface{}) *Node {
 			node = node.NewChild(r, bitmask, nil, false)
 		}
 	}
+
+	if n, ok := node.children[nul]; ok {
+		n.meta = meta
+		n.key = key
+		n.updateBest()
+		return n
+	}
+
+	t.size++
 	node = node.NewChild(nul, 0, meta, true)
+	node.key = key
+	node.updateBest()
 	return node
 }
 
 func (t *Trie) Find(key string) (*Node, bool) {
-	node := findNode(t.Root(), []rune(key))
+	node := findNode(t.Root(), t.normalize(key))
 	if node == nil {
 		return nil, false
 	}
 
 	node, ok := node.Children()[nul]
-	if !ok || !node.item {
+	if !ok || !node.term {
 		return nil, false
 	}
 	return node, true
 }
 
 func (t *Trie) HasKeysWithPrefix(key string) bool {
-	node := findNode(t.Root(), []rune(key))
+	node := findNode(t.Root(), t.normalize(key))
 	return node != nil
 }
 
+// Remove removes key, it does nothing if the key is not in the trie
 func (t *Trie) Remove(key string) {
-	var (
-		i    int
-		rs   = []rune(key)
-		node = findNode(t.Root(), []rune(key))
-	)
+	node, ok := t.Find(key)
+	if !ok {
+		return
+	}
 
 	t.size--
-	for n := node.Parent(); n != nil; n = n.Parent() {
-		i++
-		if len(n.Children()) > 1 {
-			r := rs[len(rs)-i]
-			n.RemoveChild(r)
-			break
-		}
+	// drop the nodes left without children, then fix the masks and scores above
+	for n := node; n.parent != nil && len(n.children) == 0; n = n.parent {
+		delete(n.parent.children, n.val)
+		node = n.parent
+	}
+	for n := node; n != nil; n = n.parent {
+		n.recompute()
 	}
 }
 
@@ -99,14 +172,18 @@ func (t *Trie) Keys() []string {
 
 // Performs a fuzzy search against the keys in the trie.
 func (t Trie) FuzzySearch(pre string) []string {
-	keys := fuzzycollect(t.Root(), []rune(pre))
+	partial := t.normalize(pre)
+	if len(partial) == 0 {
+		return t.Keys()
+	}
+	keys := fuzzycollect(t.Root(), partial)
 	sort.Sort(ByKeys(keys))
 	return keys
 }
 
 // Performs a prefix search against the keys in the trie.
 func (t Trie) PrefixSearch(pre string) []string {
-	node := findNode(t.Root(), []rune(pre))
+	node := findNode(t.Root(), t.normalize(pre))
 	if node == nil {
 		return nil
 	}
@@ -121,6 +198,7 @@ func (n *Node) NewChild(val rune, bitmask uint64, meta interface{}, term bool) *
 		mask:     bitmask,
 		term:     term,
 		meta:     meta,
+		best:     math.Inf(-1),
 		parent:   n,
 		children: make(map[rune]*Node),
 		depth:    n.depth + 1,
@@ -132,15 +210,46 @@ func (n *Node) NewChild(val rune, bitmask uint64, meta interface{}, term bool) *
 
 func (n *Node) RemoveChild(r rune) {
 	delete(n.children, r)
-	for nd := n.parent; nd != nil; nd = nd.parent {
-		nd.mask ^= nd.mask
-		nd.mask |= uint64(1) << uint64(nd.val-'a')
-		for _, c := range nd.children {
-			nd.mask |= c.mask
+	for nd := n; nd != nil; nd = nd.parent {
+		nd.recompute()
+	}
+}
+
+// recompute rebuilds the mask and the best score of n from its children
+func (n *Node) recompute() {
+	n.mask = 0
+	if n.parent != nil && n.val != nul {
+		n.mask = maskruneslice([]rune{n.val})
+	}
+	n.best = math.Inf(-1)
+	if n.term {
+		n.best = Score(n.meta)
+	}
+	for _, c := range n.children {
+		n.mask |= c.mask
+		if c.best > n.best {
+			n.best = c.best
 		}
 	}
 }
 
+// updateBest propagates the score of a terminating node to its ancestors
+func (n *Node) updateBest() {
+	n.best = Score(n.meta)
+	for p := n.parent; p != nil; p = p.parent {
+		best := math.Inf(-1)
+		for _, c := range p.children {
+			if c.best > best {
+				best = c.best
+			}
+		}
+		if best == p.best {
+			return
+		}
+		p.best = best
+	}
+}
+
 // Returns the parent of this node.
 func (n Node) Parent() *Node {
 	return n.parent
@@ -151,6 +260,11 @@ func (n Node) Meta() interface{} {
 	return n.meta
 }
 
+// Returns the key as it was added, for terminating nodes.
+func (n Node) Key() string {
+	return n.key
+}
+
 // Returns the children of this node.
 func (n Node) Children() map[rune]*Node {
 	return n.children
@@ -219,11 +333,7 @@ func collect(node *Node) []string {
 			nodes = append(nodes, c)
 		}
 		if n.term {
-			word := ""
-			for p := n.parent; p.depth != 0; p = p.parent {
-				word = string(p.val) + word
-			}
-			keys = append(keys, word)
+			keys = append(keys, n.key)
 		}
 	}
 	return keys
@@ -246,6 +356,7 @@ func fuzzycollect(node *Node, partial []rune) []string {
 	for l := len(potential); l > 0; l = len(potential) {
 		i = l - 1
 		p = potential[i]
+		potential = potential[:i]
 		m = maskruneslice(partial[p.idx:])
 		if (p.node.mask & m) != m {
 			continue
@@ -0,0 +1,185 @@
+package trie
+
+import (
+	"encoding/gob"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func sorted(keys []string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+func matchKeys(matches []Match) []string {
+	var keys []string
+	for _, m := range matches {
+		keys = append(keys, m.Key)
+	}
+	return keys
+}
+
+func TestAddFindRemove(t *testing.T) {
+	tr := New()
+	tr.Add("foo", 1)
+	tr.Add("foobar", 2)
+	tr.Add("foo", 3)
+	if tr.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", tr.Len())
+	}
+	if n, ok := tr.Find("foo"); !ok || n.Meta() != 3 {
+		t.Fatalf("Find(foo) = %v, %v", n, ok)
+	}
+
+	tr.Remove("missing")
+	tr.Remove("foobar")
+	if _, ok := tr.Find("foobar"); ok {
+		t.Fatal("foobar still found after Remove")
+	}
+	if tr.HasKeysWithPrefix("foob") {
+		t.Fatal("empty branch left after Remove")
+	}
+	if got := tr.Keys(); !reflect.DeepEqual(got, []string{"foo"}) {
+		t.Fatalf("Keys() = %v", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tr := NewWithOptions(Options{IgnoreCase: true, Normalization: FoldAccents})
+	tr.Add("Café", nil)
+	for _, q := range []string{"café", "CAFE", "café"} {
+		n, ok := tr.Find(q)
+		if !ok || n.Key() != "Café" {
+			t.Fatalf("Find(%q) = %v, %v", q, n, ok)
+		}
+	}
+	if got := tr.PrefixSearch("ca"); !reflect.DeepEqual(got, []string{"Café"}) {
+		t.Fatalf("PrefixSearch(ca) = %v", got)
+	}
+
+	plain := New()
+	plain.Add("Café", nil)
+	if _, ok := plain.Find("cafe"); ok {
+		t.Fatal("found cafe without normalization")
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	tr := New()
+	for _, key := range []string{"foobar", "fizzbuzz", "bar"} {
+		tr.Add(key, nil)
+	}
+	if got := sorted(tr.FuzzySearch("fb")); !reflect.DeepEqual(got, []string{"fizzbuzz", "foobar"}) {
+		t.Fatalf("FuzzySearch(fb) = %v", got)
+	}
+	if got := tr.FuzzySearch(""); len(got) != 3 {
+		t.Fatalf("FuzzySearch() = %v", got)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tr := New()
+	tr.Add("apple", 5)
+	tr.Add("application", 9)
+	tr.Add("apply", 9)
+	tr.Add("ape", 1)
+	tr.Add("banana", 100)
+
+	got := tr.Complete("ap", 3)
+	want := []string{"apply", "application", "apple"}
+	if !reflect.DeepEqual(matchKeys(got), want) {
+		t.Fatalf("Complete(ap, 3) = %v, want %v", matchKeys(got), want)
+	}
+	if got[0].Score != 9 || got[2].Score != 5 {
+		t.Fatalf("scores = %v", got)
+	}
+
+	tr.Remove("application")
+	tr.Add("apple", 50)
+	if got := matchKeys(tr.Complete("a", 2)); !reflect.DeepEqual(got, []string{"apple", "apply"}) {
+		t.Fatalf("Complete(a, 2) after update = %v", got)
+	}
+	if got := tr.Complete("x", 2); got != nil {
+		t.Fatalf("Complete(x) = %v", got)
+	}
+}
+
+func TestLevenshteinSearch(t *testing.T) {
+	tr := New()
+	for _, key := range []string{"kitten", "sitting", "mitten", "bitten", "kit", "smitten"} {
+		tr.Add(key, nil)
+	}
+
+	got := tr.LevenshteinSearch("kitten", 1)
+	want := []string{"kitten", "bitten", "mitten"}
+	if !reflect.DeepEqual(matchKeys(got), want) {
+		t.Fatalf("LevenshteinSearch(kitten, 1) = %v, want %v", matchKeys(got), want)
+	}
+	if got[0].Distance != 0 || got[1].Distance != 1 {
+		t.Fatalf("distances = %v", got)
+	}
+
+	got = tr.LevenshteinSearch("kitten", 3)
+	distances := map[string]int{}
+	for _, m := range got {
+		distances[m.Key] = m.Distance
+	}
+	if distances["sitting"] != 3 || distances["kit"] != 3 || distances["smitten"] != 2 {
+		t.Fatalf("LevenshteinSearch(kitten, 3) = %v", got)
+	}
+	if got := tr.LevenshteinSearch("kitten", -1); got != nil {
+		t.Fatalf("negative distance = %v", got)
+	}
+}
+
+type point struct{ X, Y int }
+
+func init() {
+	gob.Register(point{})
+}
+
+func TestMarshalBinary(t *testing.T) {
+	tr := NewWithOptions(Options{IgnoreCase: true, Normalization: NFC})
+	metas := map[string]interface{}{
+		"Alpha":    1,
+		"alphabet": int64(-7),
+		"beta":     2.5,
+		"gamma":    "g",
+		"delta":    true,
+		"epsilon":  []byte{1, 2},
+		"zeta":     nil,
+		"eta":      point{1, 2},
+	}
+	for key, meta := range metas {
+		tr.Add(key, meta)
+	}
+
+	data, err := tr.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Trie
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != len(metas) || loaded.opts != tr.opts {
+		t.Fatalf("loaded %d keys with %+v", loaded.Len(), loaded.opts)
+	}
+	for key, meta := range metas {
+		n, ok := loaded.Find(key)
+		if !ok || n.Key() != key || !reflect.DeepEqual(n.Meta(), meta) {
+			t.Fatalf("Find(%q) = %v, %v, want %v", key, n, ok, meta)
+		}
+	}
+	if _, ok := loaded.Find("ALPHA"); !ok {
+		t.Fatal("options not restored")
+	}
+
+	if err := loaded.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("truncated data accepted")
+	}
+	if err := loaded.UnmarshalBinary([]byte("nope")); err != ErrInvalidData {
+		t.Fatalf("bad magic: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package fasthttprouter
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Middleware wraps a handler, e.g. to check the authentication before calling
+// it or to log the request after.
+type Middleware func(fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// chain wraps handle by mw, the first one being the outermost
+func chain(handle fasthttp.RequestHandler, mw []Middleware) fasthttp.RequestHandler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		handle = mw[i](handle)
+	}
+	return handle
+}
+
+// Group registers routes under a path prefix, wrapped by its middleware.
+// The middleware are applied when the route is registered so that a request
+// costs the calls of the wrappers only.
+//
+//	api := router.Group("/api", Auth)
+//	api.GET("/users/:id", User)         // GET /api/users/:id
+//	admin := api.Group("/admin", Admin) // Auth then Admin
+//	admin.DELETE("/users/:id", DelUser) // DELETE /api/admin/users/:id
+type Group struct {
+	router     *Router
+	parent     *Group
+	prefix     string
+	middleware []Middleware
+
+	// NotFound and MethodNotAllowed replace the ones of the router for the
+	// paths under the prefix, the group of the longest prefix wins. They are
+	// wrapped by the middleware of the group.
+	NotFound fasthttp.RequestHandler
+
+	MethodNotAllowed fasthttp.RequestHandler
+}
+
+func (r *Router) newGroup(parent *Group, prefix string, mw []Middleware) *Group {
+	if prefix != "" && prefix[0] != '/' {
+		panic("prefix must begin with '/' in prefix '" + prefix + "'")
+	}
+	if parent != nil {
+		prefix = parent.prefix + prefix
+	}
+	g := &Group{
+		router:     r,
+		parent:     parent,
+		prefix:     strings.TrimRight(prefix, "/"),
+		middleware: mw,
+	}
+	r.groups = append(r.groups, g)
+	return g
+}
+
+// Group returns a subgroup of routes under g's prefix plus prefix, wrapped
+// by the middleware of g then by mw.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return g.router.newGroup(g, prefix, mw)
+}
+
+// Use appends middleware of the group. It wraps the routes registered after
+// it.
+func (g *Group) Use(mw ...Middleware) {
+	g.middleware = append(g.middleware, mw...)
+}
+
+// Prefix returns the path prefix of the group
+func (g *Group) Prefix() string {
+	return g.prefix
+}
+
+// GET is a shortcut for group.Handle("GET", path, handle)
+func (g *Group) GET(path string, handle fasthttp.RequestHandler) {
+	g.Handle("GET", path, handle)
+}
+
+// HEAD is a shortcut for group.Handle("HEAD", path, handle)
+func (g *Group) HEAD(path string, handle fasthttp.RequestHandler) {
+	g.Handle("HEAD", path, handle)
+}
+
+// OPTIONS is a shortcut for group.Handle("OPTIONS", path, handle)
+func (g *Group) OPTIONS(path string, handle fasthttp.RequestHandler) {
+	g.Handle("OPTIONS", path, handle)
+}
+
+// POST is a shortcut for group.Handle("POST", path, handle)
+func (g *Group) POST(path string, handle fasthttp.RequestHandler) {
+	g.Handle("POST", path, handle)
+}
+
+// PUT is a shortcut for group.Handle("PUT", path, handle)
+func (g *Group) PUT(path string, handle fasthttp.RequestHandler) {
+	g.Handle("PUT", path, handle)
+}
+
+// PATCH is a shortcut for group.Handle("PATCH", path, handle)
+func (g *Group) PATCH(path string, handle fasthttp.RequestHandler) {
+	g.Handle("PATCH", path, handle)
+}
+
+// DELETE is a shortcut for group.Handle("DELETE", path, handle)
+func (g *Group) DELETE(path string, handle fasthttp.RequestHandler) {
+	g.Handle("DELETE", path, handle)
+}
+
+// Handle registers handle for method and the prefix of the group plus path,
+// wrapped by the global middleware then by the ones of the group.
+func (g *Group) Handle(method, path string, handle fasthttp.RequestHandler) {
+	if len(path) == 0 || path[0] != '/' {
+		panic("path must begin with '/' in path '" + path + "'")
+	}
+	g.router.handle(method, g.prefix+path, handle, g)
+}
+
+// Name names the route of the prefix of the group plus path for Router.URL
+func (g *Group) Name(name, path string) {
+	g.router.Name(name, g.prefix+path)
+}
+
+// chain wraps handle by the middleware of g and its parents then by the
+// global ones
+func (g *Group) chain(handle fasthttp.RequestHandler) fasthttp.RequestHandler {
+	for ; g != nil; g = g.parent {
+		handle = chain(handle, g.middleware)
+	}
+	return handle
+}
+
+// contains reports whether path is under the prefix of g
+func (g *Group) contains(path string) bool {
+	return strings.HasPrefix(path, g.prefix) &&
+		(len(path) == len(g.prefix) || path[len(g.prefix)] == '/')
+}
+
+// errorHandler returns the handler picked by field among the groups
+// containing path, the longest prefix first, else the router's one, wrapped
+// by their middleware
+func (r *Router) errorHandler(path string, field func(*Group) fasthttp.RequestHandler, def fasthttp.RequestHandler) fasthttp.RequestHandler {
+	var group *Group
+	for _, g := range r.groups {
+		if field(g) != nil && g.contains(path) && (group == nil || len(g.prefix) > len(group.prefix)) {
+			group = g
+		}
+	}
+	if group != nil {
+		return chain(group.chain(field(group)), r.middleware)
+	}
+	if def != nil {
+		return chain(def, r.middleware)
+	}
+	return nil
+}
+
+func (r *Router) notFound(path string) fasthttp.RequestHandler {
+	return r.errorHandler(path, func(g *Group) fasthttp.RequestHandler { return g.NotFound }, r.NotFound)
+}
+
+func (r *Router) methodNotAllowed(path string) fasthttp.RequestHandler {
+	return r.errorHandler(path, func(g *Group) fasthttp.RequestHandler { return g.MethodNotAllowed }, r.MethodNotAllowed)
+}
@@ -66,6 +66,13 @@
 //  // use the name of the parameter
 //  user := ps.UserValue("user")
 //
+// Routes can share a path prefix and middleware in a Group, be named and
+// turned back into paths with URL:
+//  api := router.Group("/api", Auth)
+//  api.GET("/users/:id", User)
+//  api.Name("user", "/users/:id")
+//  router.URL("user", "id", "42") // "/api/users/42"
+//
 
 package fasthttprouter
 
@@ -95,6 +102,10 @@ type Router struct {
 	MethodNotAllowed fasthttp.RequestHandler
 
 	PanicHandler func(*fasthttp.RequestCtx, interface{})
+
+	middleware []Middleware
+	groups     []*Group
+	names      map[string]string
 }
 
 func New() *Router {
@@ -151,7 +162,13 @@ func (r *Router) DELETE(path string, handle fasthttp.RequestHandler) {
 // communication with a proxy).
 
 func (r *Router) Handle(method, path string, handle fasthttp.RequestHandler) {
-	if path[0] != '/' {
+	r.handle(method, path, handle, nil)
+}
+
+// handle registers handle wrapped by the global middleware then by the
+// middleware of its group
+func (r *Router) handle(method, path string, handle fasthttp.RequestHandler, group *Group) {
+	if len(path) == 0 || path[0] != '/' {
 		panic("path must begin with '/' in path '" + path + "'")
 	}
 
@@ -165,7 +182,20 @@ func (r *Router) Handle(method, path string, handle fasthttp.RequestHandler) {
 		r.tress[method] = root
 	}
 
-	root.addRoute(path, handle)
+	root.addRoute(path, chain(group.chain(handle), r.middleware))
+}
+
+// Use appends global middleware, the first one being the outermost. It wraps
+// the routes registered after it and the NotFound and MethodNotAllowed
+// handlers.
+func (r *Router) Use(mw ...Middleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Group returns a group of routes under prefix, wrapped by mw after the
+// global middleware.
+func (r *Router) Group(prefix string, mw ...Middleware) *Group {
+	return r.newGroup(nil, prefix, mw)
 }
 
 func (r *Router) ServeFiles(path string, rootPath string) {
@@ -295,8 +325,8 @@ func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
 		if r.HandleMethodNotAllowed {
 			if allow := r.allowed(path, method); len(allow) > 0 {
 				ctx.Response.Header.Set("Allow", allow)
-				if r.MethodNotAllowed != nil {
-					r.MethodNotAllowed(ctx)
+				if h := r.methodNotAllowed(path); h != nil {
+					h(ctx)
 				} else {
 					ctx.SetStatusCode(fasthttp.StatusMethodNotAllowed)
 					ctx.SetContentTypeBytes(defaultContentType)
@@ -308,8 +338,8 @@ func (r *Router) Handler(ctx *fasthttp.RequestCtx) {
 	}
 
 	// Handle 404
-	if r.NotFound != nil {
-		r.NotFound(ctx)
+	if h := r.notFound(path); h != nil {
+		h(ctx)
 	} else {
 		ctx.Error(fasthttp.StatusMessage(fasthttp.StatusNotFound),
 			fasthttp.StatusNotFound)
@@ -0,0 +1,195 @@
+package fasthttprouter
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func serve(r *Router, method, path string) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(method)
+	ctx.Request.SetRequestURI(path)
+	r.Handler(ctx)
+	return ctx
+}
+
+func writer(s string) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString(s)
+	}
+}
+
+// tag writes s before and after the handler it wraps
+func tag(s string) Middleware {
+	return func(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.WriteString(s + "(")
+			h(ctx)
+			ctx.WriteString(")")
+		}
+	}
+}
+
+func TestGroupMiddleware(t *testing.T) {
+	r := New()
+	r.GET("/", writer("index"))
+	r.Use(tag("global"))
+	api := r.Group("/api", tag("api"))
+	api.GET("/users/:id", func(ctx *fasthttp.RequestCtx) {
+		ctx.WriteString("user " + ctx.UserValue("id").(string))
+	})
+	admin := api.Group("/admin/", tag("admin"))
+	admin.Use(tag("audit"))
+	admin.DELETE("/users/:id", writer("deleted"))
+	r.GET("/about", writer("about"))
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/", "index"},
+		{"GET", "/about", "global(about)"},
+		{"GET", "/api/users/42", "global(api(user 42))"},
+		{"DELETE", "/api/admin/users/42", "global(api(admin(audit(deleted))))"},
+	}
+	for _, test := range tests {
+		ctx := serve(r, test.method, test.path)
+		if body := string(ctx.Response.Body()); body != test.body || ctx.Response.StatusCode() != 200 {
+			t.Errorf("%s %s = %d %q, want %q", test.method, test.path, ctx.Response.StatusCode(), body, test.body)
+		}
+	}
+}
+
+func TestGroupErrorHandlers(t *testing.T) {
+	r := New()
+	r.Use(tag("global"))
+	r.NotFound = writer("not found")
+	r.GET("/", writer("index"))
+	api := r.Group("/api", tag("api"))
+	api.NotFound = writer("api not found")
+	api.MethodNotAllowed = writer("api not allowed")
+	api.GET("/users", writer("users"))
+	v2 := api.Group("/v2")
+	v2.NotFound = writer("v2 not found")
+	r.Group("/apiary").GET("/bees", writer("bees"))
+
+	tests := []struct {
+		method, path, body string
+	}{
+		{"GET", "/nothing", "global(not found)"},
+		{"GET", "/api/nothing", "global(api(api not found))"},
+		{"GET", "/api", "global(api(api not found))"},
+		{"GET", "/api/v2/nothing", "global(api(v2 not found))"},
+		{"GET", "/apiary/nothing", "global(not found)"},
+		{"POST", "/api/users", "global(api(api not allowed))"},
+		{"POST", "/apiary/bees", "Method Not Allowed"},
+	}
+	for _, test := range tests {
+		ctx := serve(r, test.method, test.path)
+		if body := string(ctx.Response.Body()); body != test.body {
+			t.Errorf("%s %s = %q, want %q", test.method, test.path, body, test.body)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	r.Name("index", "/")
+	r.Name("file", "/users/:id/files/*filepath")
+	api := r.Group("/api")
+	api.GET("/posts/:slug", writer("post"))
+	api.Name("post", "/posts/:slug")
+
+	tests := []struct {
+		name   string
+		params []string
+		url    string
+	}{
+		{"index", nil, "/"},
+		{"file", []string{"id", "42", "filepath", "/a b/c.txt"}, "/users/42/files/a%20b/c.txt"},
+		{"file", []string{"filepath", "c.txt", "id", "a/b"}, "/users/a%2Fb/files/c.txt"},
+		{"post", []string{"slug", "hello"}, "/api/posts/hello"},
+	}
+	for _, test := range tests {
+		if url, err := r.URL(test.name, test.params...); err != nil || url != test.url {
+			t.Errorf("URL(%q, %q) = %q, %v, want %q", test.name, test.params, url, err, test.url)
+		}
+	}
+
+	for _, params := range [][]string{{"id"}, {"id", "42"}, {"id", "42", "filepath", "/", "x", "1"}} {
+		if _, err := r.URL("file", params...); err == nil {
+			t.Errorf("URL(file, %q) succeeded", params)
+		}
+	}
+	if _, err := r.URL("nothing"); err == nil {
+		t.Errorf("URL of an unknown name succeeded")
+	}
+}
+
+func TestLookupAllocs(t *testing.T) {
+	r := New()
+	r.Use(tag("global"))
+	r.Group("/api", tag("api")).GET("/users", writer("users"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if h, _ := r.Lookup("GET", "/api/users", nil); h == nil {
+			t.Fatal("no handler")
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Lookup allocates %v times", allocs)
+	}
+}
+
+func benchmarkRouter(b *testing.B, r *Router, path string) {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.SetRequestURI(path)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctx.ResetUserValues()
+		r.Handler(ctx)
+	}
+}
+
+func nop(ctx *fasthttp.RequestCtx) {}
+
+func pass(h fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) { h(ctx) }
+}
+
+func BenchmarkStatic(b *testing.B) {
+	r := New()
+	r.GET("/api/users", nop)
+	benchmarkRouter(b, r, "/api/users")
+}
+
+func BenchmarkGroupStatic(b *testing.B) {
+	r := New()
+	r.Use(pass)
+	r.Group("/api", pass).GET("/users", nop)
+	benchmarkRouter(b, r, "/api/users")
+}
+
+func BenchmarkParam(b *testing.B) {
+	r := New()
+	r.GET("/api/users/:id", nop)
+	benchmarkRouter(b, r, "/api/users/42")
+}
+
+func BenchmarkGroupParam(b *testing.B) {
+	r := New()
+	r.Use(pass)
+	r.Group("/api", pass).GET("/users/:id", nop)
+	benchmarkRouter(b, r, "/api/users/42")
+}
+
+func BenchmarkURL(b *testing.B) {
+	r := New()
+	r.Name("file", "/users/:id/files/*filepath")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.URL("file", "id", "42", "filepath", "/a/b.txt")
+	}
+}
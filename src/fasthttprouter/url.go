@@ -0,0 +1,85 @@
+package fasthttprouter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Name names the route of path for URL. It panics if the name is taken.
+func (r *Router) Name(name, path string) {
+	if len(path) == 0 || path[0] != '/' {
+		panic("path must begin with '/' in path '" + path + "'")
+	}
+	if r.names == nil {
+		r.names = make(map[string]string)
+	}
+	if _, ok := r.names[name]; ok {
+		panic("a route is already named '" + name + "'")
+	}
+	r.names[name] = path
+}
+
+// URL returns the path of the route named name with its parameters replaced
+// by params, given as name, value pairs:
+//
+//	router.Name("file", "/users/:id/files/*filepath")
+//	router.URL("file", "id", "42", "filepath", "/a/b.txt") // /users/42/files/a/b.txt
+//
+// The values are escaped, the slashes of catch-all values excepted.
+func (r *Router) URL(name string, params ...string) (string, error) {
+	path, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("fasthttprouter: no route named %q", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("fasthttprouter: odd number of params for route %q", name)
+	}
+
+	used := 0
+	buf := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c != ':' && c != '*' {
+			buf = append(buf, c)
+			continue
+		}
+
+		end := i + 1
+		for end < len(path) && path[end] != '/' {
+			end++
+		}
+		key := path[i+1 : end]
+		i = end - 1
+
+		value, found := "", false
+		for j := 0; j < len(params); j += 2 {
+			if params[j] == key {
+				value, found = params[j+1], true
+				used++
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("fasthttprouter: missing param %q of route %q", key, name)
+		}
+
+		if c == ':' {
+			buf = append(buf, url.PathEscape(value)...)
+			continue
+		}
+		// the catch-all value starts with the '/' already in the path
+		segs := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for k, seg := range segs {
+			if k > 0 {
+				buf = append(buf, '/')
+			}
+			buf = append(buf, url.PathEscape(seg)...)
+		}
+	}
+
+	if used != len(params)/2 {
+		return "", fmt.Errorf("fasthttprouter: unknown params %q for route %q", params, name)
+	}
+	return string(buf), nil
+}
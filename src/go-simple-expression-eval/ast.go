@@ -10,6 +10,28 @@ const (
 	ASTNODE_SUB
 	ASTNODE_MUL
 	ASTNODE_DIV
+	ASTNODE_MOD
+
+	// string, bool and variable leaves
+	ASTNODE_STRING
+	ASTNODE_BOOL
+	ASTNODE_VAR
+
+	// function call, arguments are in Args
+	ASTNODE_CALL
+
+	// unary operators, operand is in Left
+	ASTNODE_NEG
+	ASTNODE_NOT
+
+	ASTNODE_EQ
+	ASTNODE_NEQ
+	ASTNODE_LT
+	ASTNODE_LE
+	ASTNODE_GT
+	ASTNODE_GE
+	ASTNODE_AND
+	ASTNODE_OR
 )
 
 func (t AstNodeType) String() string {
@@ -24,17 +46,94 @@ func (t AstNodeType) String() string {
 		return "ASTNODE_MUL"
 	case ASTNODE_DIV:
 		return "ASTNODE_DIV"
+	case ASTNODE_MOD:
+		return "ASTNODE_MOD"
+	case ASTNODE_STRING:
+		return "ASTNODE_STRING"
+	case ASTNODE_BOOL:
+		return "ASTNODE_BOOL"
+	case ASTNODE_VAR:
+		return "ASTNODE_VAR"
+	case ASTNODE_CALL:
+		return "ASTNODE_CALL"
+	case ASTNODE_NEG:
+		return "ASTNODE_NEG"
+	case ASTNODE_NOT:
+		return "ASTNODE_NOT"
+	case ASTNODE_EQ:
+		return "ASTNODE_EQ"
+	case ASTNODE_NEQ:
+		return "ASTNODE_NEQ"
+	case ASTNODE_LT:
+		return "ASTNODE_LT"
+	case ASTNODE_LE:
+		return "ASTNODE_LE"
+	case ASTNODE_GT:
+		return "ASTNODE_GT"
+	case ASTNODE_GE:
+		return "ASTNODE_GE"
+	case ASTNODE_AND:
+		return "ASTNODE_AND"
+	case ASTNODE_OR:
+		return "ASTNODE_OR"
 	default:
 		return "Unknown"
 	}
 }
 
+// Symbol returns operator symbol of the node type, used in error messages
+func (t AstNodeType) Symbol() string {
+	switch t {
+	case ASTNODE_ADD:
+		return "+"
+	case ASTNODE_SUB, ASTNODE_NEG:
+		return "-"
+	case ASTNODE_MUL:
+		return "*"
+	case ASTNODE_DIV:
+		return "/"
+	case ASTNODE_MOD:
+		return "%"
+	case ASTNODE_NOT:
+		return "!"
+	case ASTNODE_EQ:
+		return "=="
+	case ASTNODE_NEQ:
+		return "!="
+	case ASTNODE_LT:
+		return "<"
+	case ASTNODE_LE:
+		return "<="
+	case ASTNODE_GT:
+		return ">"
+	case ASTNODE_GE:
+		return ">="
+	case ASTNODE_AND:
+		return "&&"
+	case ASTNODE_OR:
+		return "||"
+	default:
+		return t.String()
+	}
+}
+
 type AstNode struct {
-	Typ   AstNodeType
+	Typ AstNodeType
+
+	// literal value, variable or function name
 	Value *string
 
+	// byte offset of the node in the expression
+	Pos int
+
 	Left  *AstNode
 	Right *AstNode
+
+	// function call arguments
+	Args []*AstNode
+
+	// number literal parsed by the type checker, int64 or float64
+	number interface{}
 }
 
 func (node AstNode) String() string {
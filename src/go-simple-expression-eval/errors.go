@@ -1,67 +1,58 @@
 package main
 
-import "fmt"
+import "github.com/pathbox/learning-go/src/calc"
 
-// EvalErrorType error code type
-type EvalErrorType int
+// EvalErrorType error code type, shared with calc package
+type EvalErrorType = calc.EvalErrorType
 
 const (
 	// ErrParser error code raised by parser
-	ErrParser EvalErrorType = iota
+	ErrParser = calc.ErrParser
 
 	// ErrLexer error code raised by lexer
-	ErrLexer
+	ErrLexer = calc.ErrLexer
 
 	// ErrInterpreter error code raised by interpreter
-	ErrInterpreter
-)
-
-func (err EvalErrorType) String() string {
-	switch err {
-	case ErrParser:
-		return "Parser error"
-	case ErrLexer:
-		return "Lexer error"
-	case ErrInterpreter:
-		return "Interpreter error"
-	default:
-		return "UNDEFINED"
-	}
-}
-
-// EvalError represents error during lexing, parsing or interpreting
-type EvalError struct {
-	// message
-	s string
+	ErrInterpreter = calc.ErrInterpreter
 
-	// type of error
-	code EvalErrorType
-}
+	// ErrType error code raised by type checker
+	ErrType = calc.ErrType
+)
 
-func (err EvalError) String() string {
-	return fmt.Sprintf("%s: %s", err.code, err.s)
-}
+// EvalError represents error during lexing, parsing, type checking or interpreting
+type EvalError = calc.EvalError
 
 // NewLexerError intantiates new Lexer error
 func NewLexerError(msg string, args ...interface{}) *EvalError {
-	return &EvalError{
-		s:    fmt.Sprintf(msg, args...),
-		code: ErrLexer,
-	}
+	return NewLexerErrorAt(-1, msg, args...)
+}
+
+// NewLexerErrorAt intantiates new Lexer error at position pos
+func NewLexerErrorAt(pos int, msg string, args ...interface{}) *EvalError {
+	return calc.NewErrorAt(ErrLexer, pos, msg, args...)
 }
 
 // NewParserError intantiates new Parser error
 func NewParserError(msg string, args ...interface{}) *EvalError {
-	return &EvalError{
-		s:    fmt.Sprintf(msg, args...),
-		code: ErrParser,
-	}
+	return NewParserErrorAt(-1, msg, args...)
+}
+
+// NewParserErrorAt intantiates new Parser error at position pos
+func NewParserErrorAt(pos int, msg string, args ...interface{}) *EvalError {
+	return calc.NewErrorAt(ErrParser, pos, msg, args...)
 }
 
 // NewInterpreterError intantiates new Interpreter error
 func NewInterpreterError(msg string, args ...interface{}) *EvalError {
-	return &EvalError{
-		s:    fmt.Sprintf(msg, args...),
-		code: ErrInterpreter,
-	}
+	return NewInterpreterErrorAt(-1, msg, args...)
+}
+
+// NewInterpreterErrorAt intantiates new Interpreter error at position pos
+func NewInterpreterErrorAt(pos int, msg string, args ...interface{}) *EvalError {
+	return calc.NewErrorAt(ErrInterpreter, pos, msg, args...)
+}
+
+// NewTypeErrorAt intantiates new Type error at position pos
+func NewTypeErrorAt(pos int, msg string, args ...interface{}) *EvalError {
+	return calc.NewErrorAt(ErrType, pos, msg, args...)
 }
@@ -2,82 +2,293 @@ package main
 
 import (
 	"strconv"
+	"strings"
+
+	"github.com/pathbox/learning-go/src/calc"
+)
+
+// Type is static type of expression values, shared with calc package
+type Type = calc.Type
+
+const (
+	// TypeAny is checked at runtime only
+	TypeAny    = calc.TypeAny
+	TypeInt    = calc.TypeInt
+	TypeFloat  = calc.TypeFloat
+	TypeString = calc.TypeString
+	TypeBool   = calc.TypeBool
 )
 
-// Implementation of ADD operation
-func add(a int, b int) int {
-	return a + b
+// Function is a user registered function callable from expressions
+// Arguments are passed as int64, float64, string or bool, an int argument is converted for float parameter
+type Function = calc.Func
+
+// Declarations holds variables and functions available to compiled expressions
+type Declarations = calc.Declarations
+
+// NewDeclarations creates declarations with builtin functions len, lower, upper and contains,
+// a function declared later with the same name replaces the builtin
+func NewDeclarations() *Declarations {
+	d := calc.NewDeclarations()
+
+	d.Func("len", []Type{TypeString}, TypeInt, func(args ...interface{}) (interface{}, error) {
+		return int64(len(args[0].(string))), nil
+	})
+	d.Func("lower", []Type{TypeString}, TypeString, func(args ...interface{}) (interface{}, error) {
+		return strings.ToLower(args[0].(string)), nil
+	})
+	d.Func("upper", []Type{TypeString}, TypeString, func(args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(args[0].(string)), nil
+	})
+	d.Func("contains", []Type{TypeString, TypeString}, TypeBool, func(args ...interface{}) (interface{}, error) {
+		return strings.Contains(args[0].(string), args[1].(string)), nil
+	})
+	return d
 }
 
-// Implementation of SUBTRACT operation
-func sub(a int, b int) int {
-	return a - b
+// Program is type checked expression which can be evaluated many times
+type Program struct {
+	ast   *AstNode
+	decls *Declarations
+	typ   Type
 }
 
-// Implementation of MULTIPLY operation
-func mul(a int, b int) int {
-	return a * b
+// Compile parses and type checks expression against declarations, nil declarations means builtins only
+func Compile(expr string, decls *Declarations) (*Program, *EvalError) {
+	if decls == nil {
+		decls = NewDeclarations()
+	}
+
+	ast, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	decls = decls.Copy()
+	typ, err := check(ast, decls)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{ast: ast, decls: decls, typ: typ}, nil
+}
+
+// Type returns static type of program result
+func (p *Program) Type() Type {
+	return p.typ
 }
 
-// Implementation of DIVIDE operation
-func div(a int, b int) int {
-	return a / b
+// Eval evaluates program with variables from env
+// Numeric values of any Go int, uint or float kind are accepted
+func (p *Program) Eval(env map[string]interface{}) (interface{}, *EvalError) {
+	return eval(p.ast, env, p.decls)
 }
 
-// Type of arithmetic function taking two ints and returning ints
-type arithmeticFunc func(int, int) int
+// Static type checking of AST, returns type of node
+func check(node *AstNode, decls *Declarations) (Type, *EvalError) {
+	switch node.Typ {
+	case ASTNODE_LEAF:
+		// parse once, so that an invalid literal is reported by Compile and not by every Eval
+		number, err := parseNumber(node)
+		if err != nil {
+			return 0, err
+		}
+		node.number = number
+		return calc.TypeOf(number), nil
+
+	case ASTNODE_STRING:
+		return TypeString, nil
+
+	case ASTNODE_BOOL:
+		return TypeBool, nil
 
-// type for mapping AST node types to corresponding arithmetic operation
-type interpretMap map[AstNodeType]arithmeticFunc
+	case ASTNODE_VAR:
+		typ, ok := decls.LookupVar(*node.Value)
+		if !ok {
+			return 0, NewTypeErrorAt(node.Pos, "Undeclared variable %q", *node.Value)
+		}
+		return typ, nil
+
+	case ASTNODE_CALL:
+		fn, ok := decls.LookupFunc(*node.Value)
+		if !ok {
+			return 0, NewTypeErrorAt(node.Pos, "Undeclared function %q", *node.Value)
+		}
+		if len(node.Args) != len(fn.Params) {
+			return 0, NewTypeErrorAt(node.Pos, "Function %s expects %d arguments, got %d", *node.Value, len(fn.Params), len(node.Args))
+		}
+		for i, arg := range node.Args {
+			typ, err := check(arg, decls)
+			if err != nil {
+				return 0, err
+			}
+			if !calc.Assignable(typ, fn.Params[i]) {
+				return 0, NewTypeErrorAt(arg.Pos, "Argument %d of %s must be %s, got %s", i+1, *node.Value, fn.Params[i], typ)
+			}
+		}
+		return fn.Result, nil
+	}
+
+	left, err := check(node.Left, decls)
+	if err != nil {
+		return 0, err
+	}
+
+	switch node.Typ {
+	case ASTNODE_NEG, ASTNODE_NOT:
+		return calc.CheckPrefix(node.Pos, node.Typ.Symbol(), left)
+	}
+
+	right, err := check(node.Right, decls)
+	if err != nil {
+		return 0, err
+	}
+
+	return calc.CheckBinary(node.Pos, node.Typ.Symbol(), left, right)
+}
+
+// Parses number literal of leaf node to int64 or float64
+func parseNumber(node *AstNode) (interface{}, *EvalError) {
+	if node.Value == nil {
+		return nil, NewInterpreterErrorAt(node.Pos, "Expected value, got nil")
+	}
+
+	if strings.ContainsAny(*node.Value, ".eE") {
+		number, err := strconv.ParseFloat(*node.Value, 64)
+		if err != nil {
+			return nil, NewParserErrorAt(node.Pos, "Unable to parse number %s", err)
+		}
+		return number, nil
+	}
+	number, err := strconv.ParseInt(*node.Value, 10, 64)
+	if err != nil {
+		return nil, NewParserErrorAt(node.Pos, "Unable to parse number %s", err)
+	}
+	return number, nil
+}
 
 // Recursive post order traversal that evaluates AST
 // 1. Visit left
 // 2. Visit right
 // 3. Visit self
-func postOrderTraversal(node *AstNode, functions interpretMap) (int, *EvalError) {
+func eval(node *AstNode, env map[string]interface{}, decls *Declarations) (interface{}, *EvalError) {
 	if node == nil {
-		return 0, NewInterpreterError("Expected evaluatable node, got nil")
+		return nil, NewInterpreterError("Expected evaluatable node, got nil")
 	}
 
-	// If we are on number node
-	if node.Typ == ASTNODE_LEAF {
-		if node.Value == nil {
-			return 0, NewInterpreterError("Expected value, got nil")
+	switch node.Typ {
+	case ASTNODE_LEAF:
+		if node.number != nil {
+			return node.number, nil
 		}
+		return parseNumber(node)
 
-		// Parse string val to integer
-		number, err := strconv.Atoi(*node.Value)
-		if err != nil {
-			return 0, NewInterpreterError("Unable to parse number %s", err)
+	case ASTNODE_STRING:
+		return *node.Value, nil
+
+	case ASTNODE_BOOL:
+		return *node.Value == "true", nil
+
+	case ASTNODE_VAR:
+		raw, ok := env[*node.Value]
+		if !ok {
+			return nil, NewInterpreterErrorAt(node.Pos, "Undefined variable %q", *node.Value)
+		}
+		value, ok := calc.Normalize(raw)
+		if !ok {
+			return nil, NewInterpreterErrorAt(node.Pos, "Unsupported value type %T of variable %q", raw, *node.Value)
+		}
+		if typ, declared := decls.LookupVar(*node.Value); declared {
+			if value, ok = calc.Convert(value, typ); !ok {
+				return nil, NewInterpreterErrorAt(node.Pos, "Variable %q must be %s, got %T", *node.Value, typ, raw)
+			}
 		}
+		return value, nil
 
-		// return it to higher stack frame (numbers should occur only in leaf nodes)
-		return number, nil
+	case ASTNODE_CALL:
+		return evalCall(node, env, decls)
+
+	case ASTNODE_AND, ASTNODE_OR:
+		// logical operators short circuit
+		left, err := evalBool(node.Left, env, decls)
+		if err != nil {
+			return nil, err
+		}
+		if node.Typ == ASTNODE_AND && !left || node.Typ == ASTNODE_OR && left {
+			return left, nil
+		}
+		return evalBool(node.Right, env, decls)
 	}
 
-	aritFunc := functions[node.Typ]
-	left, err := postOrderTraversal(node.Left, functions)
+	left, err := eval(node.Left, env, decls)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	switch node.Typ {
+	case ASTNODE_NEG, ASTNODE_NOT:
+		return calc.Prefix(node.Pos, node.Typ.Symbol(), left)
 	}
 
-	right, err := postOrderTraversal(node.Right, functions)
+	right, err := eval(node.Right, env, decls)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	// use its value to do computation
-	return aritFunc(left, right), nil
+	return calc.Binary(node.Pos, node.Typ.Symbol(), left, right)
 }
 
-// Interpret is function that evaluates AST and returns corresponding result
-func Interpret(ast *AstNode) (int, *EvalError) {
-	var astInterpretMap = interpretMap{
-		ASTNODE_ADD: add,
-		ASTNODE_SUB: sub,
-		ASTNODE_MUL: mul,
-		ASTNODE_DIV: div,
+// Evaluates node which must produce bool
+func evalBool(node *AstNode, env map[string]interface{}, decls *Declarations) (bool, *EvalError) {
+	value, err := eval(node, env, decls)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, NewInterpreterErrorAt(node.Pos, "Expected bool, got %s", calc.TypeOf(value))
+	}
+	return b, nil
+}
+
+// Evaluates arguments and calls declared function
+func evalCall(node *AstNode, env map[string]interface{}, decls *Declarations) (interface{}, *EvalError) {
+	fn, ok := decls.LookupFunc(*node.Value)
+	if !ok {
+		return nil, NewInterpreterErrorAt(node.Pos, "Undefined function %q", *node.Value)
+	}
+	if len(node.Args) != len(fn.Params) {
+		return nil, NewInterpreterErrorAt(node.Pos, "Function %s expects %d arguments, got %d", *node.Value, len(fn.Params), len(node.Args))
+	}
+
+	args := make([]interface{}, len(node.Args))
+	for i, argNode := range node.Args {
+		arg, err := eval(argNode, env, decls)
+		if err != nil {
+			return nil, err
+		}
+		if args[i], ok = calc.Convert(arg, fn.Params[i]); !ok {
+			return nil, NewInterpreterErrorAt(argNode.Pos, "Argument %d of %s must be %s, got %s", i+1, *node.Value, fn.Params[i], calc.TypeOf(arg))
+		}
+	}
+
+	raw, callErr := fn.Fn(args...)
+	if callErr != nil {
+		return nil, NewInterpreterErrorAt(node.Pos, "%s: %s", *node.Value, callErr)
+	}
+	result, ok := calc.Normalize(raw)
+	if ok {
+		result, ok = calc.Convert(result, fn.Result)
+	}
+	if !ok {
+		return nil, NewInterpreterErrorAt(node.Pos, "Function %s must return %s, got %T", *node.Value, fn.Result, raw)
 	}
+	return result, nil
+}
 
-	return postOrderTraversal(ast, astInterpretMap)
+// Interpret is function that evaluates AST and returns corresponding result
+// Only builtin functions are available and variables are not defined
+func Interpret(ast *AstNode) (interface{}, *EvalError) {
+	return eval(ast, nil, NewDeclarations())
 }
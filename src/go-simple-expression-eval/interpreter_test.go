@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInterpret(t *testing.T) {
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"1+2*(6-8)", int64(-3)},
+		{"7 / 2", int64(3)},
+		{"7 % 4", int64(3)},
+		{"7 / 2.0", 3.5},
+		{"1.5e1 - 5", 10.0},
+		{"-2 * -3", int64(6)},
+		{"--4", int64(4)},
+		{"-(1 + 2) * 3", int64(-9)},
+		{`"ab" + "c"`, "abc"},
+		{`"a\tb" == "a\tb"`, true},
+		{`"abc" < "abd"`, true},
+		{"1 < 2 && 2 <= 2 && 3 > 2 && 3 >= 4 == false", true},
+		{"1 == 1.0", true},
+		{"true != false", true},
+		{"!true || !!true", true},
+		{"1 + 2 == 3 && 2 * 3 == 6", true},
+		{"false && 1 / 0 == 1", false},
+		{"true || 1 / 0 == 1", true},
+		{`len("hello") + 1`, int64(6)},
+		{`upper(lower("MiXeD"))`, "MIXED"},
+		{`contains(lower("HAYSTACK"), "st") && len("") == 0`, true},
+	}
+
+	for _, tt := range tests {
+		ast, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", tt.expr, err)
+			continue
+		}
+		got, err := Interpret(ast)
+		if err != nil {
+			t.Errorf("Interpret(%q): %s", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Interpret(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		code EvalErrorType
+		pos  int
+	}{
+		{"1 + $", ErrLexer, 4},
+		{`"abc`, ErrLexer, 0},
+		{"1 = 2", ErrLexer, 2},
+		{"1 2", ErrParser, 2},
+		{"1 +", ErrParser, 3},
+		{"(1 + 2", ErrParser, 0},
+		{"1 + 2)", ErrParser, 5},
+		{"* 2", ErrParser, 0},
+		{"1, 2", ErrParser, 1},
+		{"len(,)", ErrParser, 4},
+		{"1 + 99999999999999999999", ErrParser, 4},
+		{"1 !2", ErrParser, 2},
+		{`1 + "a"`, ErrType, 2},
+		{"1.5 % 2", ErrType, 4},
+		{"!1", ErrType, 0},
+		{"1 && true", ErrType, 2},
+		{"x + 1", ErrType, 0},
+		{"nope(1)", ErrType, 0},
+		{"len(1)", ErrType, 4},
+		{`contains("a")`, ErrType, 0},
+		{"10 / (5 - 5)", ErrInterpreter, 3},
+	}
+
+	for _, tt := range tests {
+		program, err := Compile(tt.expr, nil)
+		if err == nil {
+			_, err = program.Eval(nil)
+		}
+		if err == nil {
+			t.Errorf("%q: expected error", tt.expr)
+			continue
+		}
+		if err.Code() != tt.code || err.Pos() != tt.pos {
+			t.Errorf("%q: got %s, want %s at %d", tt.expr, err, tt.code, tt.pos)
+		}
+	}
+}
+
+func TestCompileEval(t *testing.T) {
+	decls := NewDeclarations().
+		Var("price", TypeFloat).
+		Var("qty", TypeInt).
+		Var("country", TypeString).
+		Var("vip", TypeBool).
+		Var("extra", TypeAny).
+		Func("max", []Type{TypeFloat, TypeFloat}, TypeFloat, func(args ...interface{}) (interface{}, error) {
+			if args[0].(float64) > args[1].(float64) {
+				return args[0], nil
+			}
+			return args[1], nil
+		}).
+		Func("fail", nil, TypeInt, func(args ...interface{}) (interface{}, error) {
+			return nil, errors.New("boom")
+		})
+
+	program, err := Compile(`vip || max(price * qty, 10) > 100 && country != "XX"`, decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program.Type() != TypeBool {
+		t.Fatalf("Type() = %s", program.Type())
+	}
+
+	envs := []struct {
+		env  map[string]interface{}
+		want bool
+	}{
+		{map[string]interface{}{"price": 9.5, "qty": 12, "country": "DE", "vip": false}, true},
+		{map[string]interface{}{"price": 9, "qty": uint8(2), "country": "DE", "vip": false}, false},
+		{map[string]interface{}{"price": float32(50), "qty": int64(3), "country": "XX", "vip": true}, true},
+		{map[string]interface{}{"price": 50.0, "qty": 3, "country": "XX", "vip": false}, false},
+	}
+	for i, tt := range envs {
+		got, err := program.Eval(tt.env)
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if got != tt.want {
+			t.Errorf("%d: got %v, want %v", i, got, tt.want)
+		}
+	}
+
+	// missing or mistyped variables are runtime errors
+	if _, err := program.Eval(map[string]interface{}{"vip": false}); err == nil || err.Code() != ErrInterpreter {
+		t.Errorf("missing variable: %v", err)
+	}
+	if _, err := program.Eval(map[string]interface{}{"vip": "yes"}); err == nil || !strings.Contains(err.Error(), "must be bool") {
+		t.Errorf("mistyped variable: %v", err)
+	}
+
+	// any typed variables are checked at runtime
+	program, err = Compile("extra + 1", decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := program.Eval(map[string]interface{}{"extra": 1.5}); err != nil || got != 2.5 {
+		t.Errorf("extra + 1 = %v, %v", got, err)
+	}
+	if _, err := program.Eval(map[string]interface{}{"extra": "a"}); err == nil || err.Code() != ErrInterpreter {
+		t.Errorf("extra + 1 with string: %v", err)
+	}
+
+	program, err = Compile("fail() + 1", decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := program.Eval(nil); err == nil || err.Pos() != 0 || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("fail(): %v", err)
+	}
+
+	// declarations changed after compile do not affect program
+	program, err = Compile("qty * 2", decls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls.Var("qty", TypeString)
+	if got, err := program.Eval(map[string]interface{}{"qty": 4}); err != nil || got != int64(8) {
+		t.Errorf("qty * 2 = %v, %v", got, err)
+	}
+}
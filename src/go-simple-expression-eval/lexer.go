@@ -29,6 +29,40 @@ func (typ ItemType) String() string {
 		strType = "IMUL"
 	case IDIV:
 		strType = "IDIV"
+	case IMOD:
+		strType = "IMOD"
+	case ISTRING:
+		strType = "ISTRING"
+	case IIDENT:
+		strType = "IIDENT"
+	case IFUNC:
+		strType = "IFUNC"
+	case ITRUE:
+		strType = "ITRUE"
+	case IFALSE:
+		strType = "IFALSE"
+	case ICOMMA:
+		strType = "ICOMMA"
+	case IEQ:
+		strType = "IEQ"
+	case INEQ:
+		strType = "INEQ"
+	case ILT:
+		strType = "ILT"
+	case ILE:
+		strType = "ILE"
+	case IGT:
+		strType = "IGT"
+	case IGE:
+		strType = "IGE"
+	case IAND:
+		strType = "IAND"
+	case IOR:
+		strType = "IOR"
+	case INOT:
+		strType = "INOT"
+	case INEG:
+		strType = "INEG"
 	case EOF:
 		strType = "EOF"
 	}
@@ -43,7 +77,7 @@ const (
 	// lexing error occured
 	IERR ItemType = iota
 
-	// positive integer
+	// positive integer or float
 	INUMBER
 
 	// left parenthesis
@@ -63,21 +97,62 @@ const (
 
 	// divide / symbol
 	IDIV
+
+	// modulo % symbol
+	IMOD
+
+	// double quoted string literal
+	ISTRING
+
+	// variable name
+	IIDENT
+
+	// function name, an identifier directly followed by (
+	IFUNC
+
+	// true keyword
+	ITRUE
+
+	// false keyword
+	IFALSE
+
+	// argument separator ,
+	ICOMMA
+
+	// comparison == != < <= > >= symbols
+	IEQ
+	INEQ
+	ILT
+	ILE
+	IGT
+	IGE
+
+	// logical && || ! symbols
+	IAND
+	IOR
+	INOT
+
+	// unary minus, never emitted by lexer, parser turns ISUB into it
+	INEG
 )
 
 // Tokens understood by lexer
 const (
-	numbers   = "0123456789"
-	operators = "+-*/"
-	white     = " \n\r\t"
-	lpar      = "("
-	rpar      = ")"
+	numbers    = "0123456789"
+	operators  = "+-*/%=!<>&|"
+	white      = " \n\r\t"
+	lpar       = "("
+	rpar       = ")"
+	comma      = ","
+	quote      = "\""
+	identStart = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_"
+	identChars = identStart + numbers
 )
 
 // Type for lexing state machine, function returns another function which represents state transition
 type stateFn func(*Lexer) stateFn
 
-// LexItem are items emitted by lexer
+// LexItem are items emitted by lexer, Pos is the byte offset of the item in the input
 type LexItem struct {
 	Typ ItemType
 	Pos int
@@ -164,7 +239,7 @@ func (l *Lexer) ignore() {
 func (l *Lexer) emit(typ ItemType) {
 	l.items <- LexItem{
 		Typ: typ,
-		Pos: l.Pos,
+		Pos: l.start,
 		Val: l.text[l.start:l.Pos],
 	}
 	l.start = l.Pos
@@ -173,7 +248,7 @@ func (l *Lexer) emit(typ ItemType) {
 // Helper func that emits error item IERR with message
 func (l *Lexer) errorf(format string, args ...interface{}) stateFn {
 	l.items <- LexItem{
-		Pos: l.Pos,
+		Pos: l.start,
 		Typ: IERR,
 		Val: fmt.Sprintf(format, args...),
 	}
@@ -198,6 +273,14 @@ func lexFn(l *Lexer) stateFn {
 		return lexLpar
 	case strings.ContainsRune(rpar, r):
 		return lexRpar
+	case strings.ContainsRune(comma, r):
+		l.next()
+		l.emit(ICOMMA)
+		return lexFn
+	case strings.ContainsRune(quote, r):
+		return lexString
+	case strings.ContainsRune(identStart, r):
+		return lexIdent
 	default:
 		return l.errorf("Invalid symbol: %q", r)
 	}
@@ -215,6 +298,41 @@ func lexOperator(l *Lexer) stateFn {
 		l.emit(IMUL)
 	case '/':
 		l.emit(IDIV)
+	case '%':
+		l.emit(IMOD)
+	case '=':
+		if !l.consume("=") {
+			return l.errorf("lexOperator: inValid operator: %q, did you mean ==", op)
+		}
+		l.emit(IEQ)
+	case '!':
+		if l.consume("=") {
+			l.emit(INEQ)
+		} else {
+			l.emit(INOT)
+		}
+	case '<':
+		if l.consume("=") {
+			l.emit(ILE)
+		} else {
+			l.emit(ILT)
+		}
+	case '>':
+		if l.consume("=") {
+			l.emit(IGE)
+		} else {
+			l.emit(IGT)
+		}
+	case '&':
+		if !l.consume("&") {
+			return l.errorf("lexOperator: inValid operator: %q, did you mean &&", op)
+		}
+		l.emit(IAND)
+	case '|':
+		if !l.consume("|") {
+			return l.errorf("lexOperator: inValid operator: %q, did you mean ||", op)
+		}
+		l.emit(IOR)
 	default:
 		return l.errorf("lexOperator: inValid operator: %q", op)
 	}
@@ -236,13 +354,59 @@ func lexRpar(l *Lexer) stateFn {
 	return lexFn
 }
 
-// lexes numbers
+// lexes numbers, a fraction or an exponent makes it a float
 func lexNumber(l *Lexer) stateFn {
 	l.consumeAll(numbers)
+	if l.consume(".") {
+		l.consumeAll(numbers)
+	}
+	if l.consume("eE") {
+		l.consume("+-")
+		if !strings.ContainsRune(numbers, l.peek()) {
+			return l.errorf("Invalid exponent in number %q", l.text[l.start:l.Pos])
+		}
+		l.consumeAll(numbers)
+	}
 	l.emit(INUMBER)
 	return lexFn
 }
 
+// lexes double quoted strings, backslash escapes are those of Go
+func lexString(l *Lexer) stateFn {
+	l.next()
+	for {
+		switch l.next() {
+		case '\\':
+			if l.next() == -1 {
+				return l.errorf("Unterminated string")
+			}
+		case '"':
+			l.emit(ISTRING)
+			return lexFn
+		case -1:
+			return l.errorf("Unterminated string")
+		}
+	}
+}
+
+// lexes identifiers and the true/false keywords
+func lexIdent(l *Lexer) stateFn {
+	l.consumeAll(identChars)
+	switch l.text[l.start:l.Pos] {
+	case "true":
+		l.emit(ITRUE)
+	case "false":
+		l.emit(IFALSE)
+	default:
+		if l.peek() == '(' {
+			l.emit(IFUNC)
+		} else {
+			l.emit(IIDENT)
+		}
+	}
+	return lexFn
+}
+
 // Lexes whitespaces and thrashes them (no emitting)
 func lexWhite(l *Lexer) stateFn {
 	l.consumeAll(white)
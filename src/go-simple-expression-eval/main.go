@@ -3,27 +3,57 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 )
 
+// parses command line variable value, numbers and booleans are recognized, anything else is string
+func parseValue(s string) (interface{}, Type) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, TypeInt
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, TypeFloat
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return b, TypeBool
+	}
+	return s, TypeString
+}
+
 func main() {
 
 	args := os.Args
 
 	if len(args) < 2 {
-		fmt.Println("Specify expressions to evaluate...\ne.g.: 1+2*(6-8)")
+		fmt.Println("Specify expressions to evaluate and optional variables...\ne.g.: 1+2*(6-8)\n      'price * qty > 100 && upper(name) == \"GO\"' price=9.5 qty=12 name=go")
 		return
 	}
 
-	// parse given expression into AST
-	ast, err := Parse(args[1])
+	// declare variables given as name=value
+	decls := NewDeclarations()
+	env := make(map[string]interface{})
+	for _, arg := range args[2:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			fmt.Printf("Invalid variable %q, expected name=value\n", arg)
+			return
+		}
+		value, typ := parseValue(kv[1])
+		decls.Var(kv[0], typ)
+		env[kv[0]] = value
+	}
+
+	// parse and type check given expression
+	program, err := Compile(args[1], decls)
 
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	// Interpret AST
-	result, err := Interpret(ast)
+	// Evaluate it
+	result, err := program.Eval(env)
 
 	if err != nil {
 		fmt.Println(err)
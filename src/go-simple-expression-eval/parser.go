@@ -3,94 +3,217 @@ package main
 import (
 	"container/list"
 	"fmt"
+	"strconv"
 )
 
 // Determines precedence of operator given
 func precedence(typ ItemType) int {
 	switch typ {
+	case IOR:
+		return 1
+
+	case IAND:
+		return 2
+
+	case IEQ, INEQ:
+		return 3
+
+	case ILT, ILE, IGT, IGE:
+		return 4
+
 	case IADD:
 		fallthrough
 	case ISUB:
-		return 1
+		return 5
 
-	case IMUL:
-		fallthrough
-	case IDIV:
-		return 2
+	case IMUL, IDIV, IMOD:
+		return 6
+
+	case INEG, INOT:
+		return 7
 
 	default:
 		return -1
 	}
 }
 
+// Reports whether operator given is unary, unary operators are right associative
+func isUnary(typ ItemType) bool {
+	return typ == INEG || typ == INOT
+}
+
+// Reports whether item type is a literal or a variable
+func isOperand(typ ItemType) bool {
+	switch typ {
+	case INUMBER, ISTRING, ITRUE, IFALSE, IIDENT:
+		return true
+	default:
+		return false
+	}
+}
+
+// callItem is postfix form of function call, Argc arguments precede it in the postfix list
+type callItem struct {
+	LexItem
+	Argc int
+}
+
 // Converts infix output form of Lexer to postfix form
 func toPostfix(lx *Lexer) (*list.List, *EvalError) {
+	// drain lexer on early return so that its goroutine can finish
+	defer func() {
+		for range lx.Items() {
+		}
+	}()
 
 	opStack := NewStack()
 	postFix := list.New()
 
+	// number of arguments of function calls in progress, top is the innermost call
+	argCounts := NewStack()
+
+	// operand is expected at the start, after an operator, '(' and ','
+	expectOperand := true
+	prevTyp := EOF
+
 	for item := range lx.Items() {
 		// end of tok stream
 		if item.Typ == EOF {
-			continue
+			break
 		}
 
 		// lexing error
 		if item.Typ == IERR {
-			return nil, NewLexerError("at %d: %s", item.Pos, item.Val)
+			return nil, NewLexerErrorAt(item.Pos, "%s", item.Val)
 		}
 
-		// if its number put to output
-		if item.Typ == INUMBER {
+		switch {
+		// if its literal or variable put to output
+		case isOperand(item.Typ):
+			if !expectOperand {
+				return nil, NewParserErrorAt(item.Pos, "Missing operator before %q", item.Val)
+			}
 			postFix.PushBack(item)
-			continue
-		}
+			expectOperand = false
 
-		// if left parenth put to output
-		if item.Typ == ILPAR {
+		// function name goes to stack, its arguments are counted until matching ')'
+		case item.Typ == IFUNC:
+			if !expectOperand {
+				return nil, NewParserErrorAt(item.Pos, "Missing operator before %q", item.Val)
+			}
+			opStack.Push(item)
+			argCounts.Push(0)
+
+		// if left parenth put to stack
+		case item.Typ == ILPAR:
+			if !expectOperand {
+				return nil, NewParserErrorAt(item.Pos, "Missing operator before '('")
+			}
 			opStack.Push(item)
-			continue
-		}
 
 		// if right parenth
-		if item.Typ == IRPAR {
+		case item.Typ == IRPAR:
+			emptyCall := prevTyp == ILPAR && isCallParen(opStack)
+			if expectOperand && !emptyCall {
+				return nil, NewParserErrorAt(item.Pos, "Missing operand before ')'")
+			}
+
 			// pop stack to output until we find left parenth in stack
 			for opStack.Len() > 0 && opStack.Top().(LexItem).Typ != ILPAR {
 				postFix.PushBack(opStack.Pop())
 			}
 
-			// if there is none then there is error in parity
-			if opStack.Len() > 0 && opStack.Top().(LexItem).Typ != ILPAR {
-				return nil, NewParserError("at %d: Unmatched paretheses", opStack.Top().(LexItem).Pos)
-			}
-
 			// we are in rparenth so if there is no lparenh its parity error
 			if opStack.Len() == 0 {
-				return nil, NewParserError("at %d: Missing '('", item.Pos)
+				return nil, NewParserErrorAt(item.Pos, "Missing '('")
 			}
 			// otherwise just trash it
 			opStack.Pop()
 
-		} else {
+			// parenthesis closes function call
+			if opStack.Len() > 0 && opStack.Top().(LexItem).Typ == IFUNC {
+				argc := argCounts.Pop().(int)
+				if !emptyCall {
+					argc++
+				}
+				postFix.PushBack(callItem{LexItem: opStack.Pop().(LexItem), Argc: argc})
+			}
+			expectOperand = false
+
+		// argument separator
+		case item.Typ == ICOMMA:
+			if expectOperand {
+				return nil, NewParserErrorAt(item.Pos, "Missing argument before ','")
+			}
+			for opStack.Len() > 0 && opStack.Top().(LexItem).Typ != ILPAR {
+				postFix.PushBack(opStack.Pop())
+			}
+			if !isCallParen(opStack) {
+				return nil, NewParserErrorAt(item.Pos, "Unexpected ',' outside of function call")
+			}
+			argCounts.Push(argCounts.Pop().(int) + 1)
+			expectOperand = true
+
+		default:
 			// is any other operator
-			// check precedence
-			for opStack.Len() > 0 && precedence(item.Typ) <= precedence(opStack.Top().(LexItem).Typ) {
+			if expectOperand {
+				// minus in operand position is negation
+				if item.Typ == ISUB {
+					item.Typ = INEG
+				} else if item.Typ != INOT {
+					return nil, NewParserErrorAt(item.Pos, "Missing operand before %q", item.Val)
+				}
+			} else if item.Typ == INOT {
+				return nil, NewParserErrorAt(item.Pos, "Missing operator before '!'")
+			}
+
+			// check precedence, unary operators do not pop operators of the same precedence
+			for opStack.Len() > 0 {
+				top := opStack.Top().(LexItem)
+				if top.Typ == ILPAR || top.Typ == IFUNC {
+					break
+				}
+				if precedence(item.Typ) > precedence(top.Typ) ||
+					isUnary(item.Typ) && precedence(item.Typ) == precedence(top.Typ) {
+					break
+				}
 				// just put it to output
 				postFix.PushBack(opStack.Pop())
 			}
 			// put it to stack
 			opStack.Push(item)
+			expectOperand = true
 		}
+		prevTyp = item.Typ
+	}
+
+	if expectOperand && prevTyp != EOF {
+		return nil, NewParserErrorAt(len(lx.text), "Missing operand at end of expression")
 	}
 
 	// empty stack to output
 	for opStack.Len() > 0 {
-		postFix.PushBack(opStack.Pop())
+		item := opStack.Pop().(LexItem)
+		if item.Typ == ILPAR || item.Typ == IFUNC {
+			return nil, NewParserErrorAt(item.Pos, "Missing ')'")
+		}
+		postFix.PushBack(item)
 	}
 
 	return postFix, nil
 }
 
+// Reports whether left parenthesis on top of the stack opens function call
+func isCallParen(opStack *Stack) bool {
+	if opStack.Len() < 2 || opStack.Top().(LexItem).Typ != ILPAR {
+		return false
+	}
+	lpar := opStack.Pop()
+	isCall := opStack.Top().(LexItem).Typ == IFUNC
+	opStack.Push(lpar)
+	return isCall
+}
+
 // helper method that translates Lexer item types to AST node types
 func translateLexToAstType(typ ItemType) (AstNodeType, *EvalError) {
 	switch typ {
@@ -102,11 +225,54 @@ func translateLexToAstType(typ ItemType) (AstNodeType, *EvalError) {
 		return ASTNODE_MUL, nil
 	case IDIV:
 		return ASTNODE_DIV, nil
+	case IMOD:
+		return ASTNODE_MOD, nil
+	case IEQ:
+		return ASTNODE_EQ, nil
+	case INEQ:
+		return ASTNODE_NEQ, nil
+	case ILT:
+		return ASTNODE_LT, nil
+	case ILE:
+		return ASTNODE_LE, nil
+	case IGT:
+		return ASTNODE_GT, nil
+	case IGE:
+		return ASTNODE_GE, nil
+	case IAND:
+		return ASTNODE_AND, nil
+	case IOR:
+		return ASTNODE_OR, nil
+	case INEG:
+		return ASTNODE_NEG, nil
+	case INOT:
+		return ASTNODE_NOT, nil
 	default:
 		return 0, NewParserError("Unexpected item type occured during parsing %q", typ)
 	}
 }
 
+// Creates leaf node from literal or variable item
+func newLeaf(item LexItem) (*AstNode, *EvalError) {
+	var node *AstNode
+	switch item.Typ {
+	case INUMBER:
+		node = NewAstNode(ASTNODE_LEAF, &item.Val)
+	case ISTRING:
+		str, err := strconv.Unquote(item.Val)
+		if err != nil {
+			return nil, NewParserErrorAt(item.Pos, "Invalid string literal %s", item.Val)
+		}
+		node = NewAstNode(ASTNODE_STRING, &str)
+	case ITRUE, IFALSE:
+		node = NewAstNode(ASTNODE_BOOL, &item.Val)
+	default:
+		node = NewAstNode(ASTNODE_VAR, &item.Val)
+	}
+	node.Pos = item.Pos
+	return node, nil
+}
+
 // Takes list of postfix formed lexer items and builds binary expression tree
 func constructAst(postfixList *list.List) (*AstNode, *EvalError) {
 	// stack for storing nodes for later computation
@@ -114,33 +280,63 @@ func constructAst(postfixList *list.List) (*AstNode, *EvalError) {
 
 	// go trough all items
 	for item := postfixList.Front(); item != nil; item = item.Next() {
+		// function call takes its arguments from stack
+		if call, ok := item.Value.(callItem); ok {
+			if stack.Len() < call.Argc {
+				return nil, NewParserErrorAt(call.Pos, "Missing argument")
+			}
+			node := NewAstNode(ASTNODE_CALL, &call.Val)
+			node.Pos = call.Pos
+			node.Args = make([]*AstNode, call.Argc)
+			for i := call.Argc - 1; i >= 0; i-- {
+				node.Args[i] = stack.Pop().(*AstNode)
+			}
+			stack.Push(node)
+			continue
+		}
+
 		lexItem := item.Value.(LexItem)
-		// if its number, create node and push it to stack
-		if lexItem.Typ == INUMBER {
-			stack.Push(NewAstNode(ASTNODE_LEAF, &lexItem.Val))
-		} else {
-			// otherwise convert type
-			nodeType, err := translateLexToAstType(lexItem.Typ)
+		// if its operand, create node and push it to stack
+		if isOperand(lexItem.Typ) {
+			node, err := newLeaf(lexItem)
 			if err != nil {
-				return nil, NewParserError("at %d: Missing ')'", lexItem.Pos)
+				return nil, err
 			}
-			// create new note
-			node := NewAstNode(nodeType, nil)
+			stack.Push(node)
+			continue
+		}
 
-			// validate we have at least two items in stack
-			if stack.Len() < 2 {
-				return nil, NewParserError("at %d: Missing operand", lexItem.Pos)
-			}
+		// otherwise convert type
+		nodeType, err := translateLexToAstType(lexItem.Typ)
+		if err != nil {
+			return nil, NewParserErrorAt(lexItem.Pos, "Missing ')'")
+		}
+		// create new note
+		node := NewAstNode(nodeType, nil)
+		node.Pos = lexItem.Pos
 
-			// order important, otherwise we switch operands
-			// Pop first time to Right operand
-			node.Right = stack.Pop().(*AstNode)
-			// Pop second time to Left operand
+		if isUnary(lexItem.Typ) {
+			if stack.Len() < 1 {
+				return nil, NewParserErrorAt(lexItem.Pos, "Missing operand")
+			}
 			node.Left = stack.Pop().(*AstNode)
-
-			// push new node to stack
 			stack.Push(node)
+			continue
 		}
+
+		// validate we have at least two items in stack
+		if stack.Len() < 2 {
+			return nil, NewParserErrorAt(lexItem.Pos, "Missing operand")
+		}
+
+		// order important, otherwise we switch operands
+		// Pop first time to Right operand
+		node.Right = stack.Pop().(*AstNode)
+		// Pop second time to Left operand
+		node.Left = stack.Pop().(*AstNode)
+
+		// push new node to stack
+		stack.Push(node)
 	}
 
 	// might occur when user inputs "()" expression, no root node
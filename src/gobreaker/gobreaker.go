@@ -22,6 +22,8 @@ var (
 	ErrTooManyRequests = errors.New("too many requests")
 	// ErrOpenState is returned when the CB state is open
 	ErrOpenState = errors.New("circuit breaker is open")
+	// ErrBulkheadFull is returned when the number of in-flight requests reached the cb maxConcurrentRequests
+	ErrBulkheadFull = errors.New("bulkhead is full")
 )
 
 // String implements stringer interface.
@@ -42,14 +44,36 @@ func (s State) String() string {
 // CircuitBreaker clears the internal Counts either
 // on the change of the state or at the closed-state intervals.
 // Counts ignores the results of the requests sent before clearing.
+//
+// When a rolling window is configured, Requests, TotalSuccesses, TotalFailures
+// and TotalSlowCalls passed to ReadyToTrip cover only the window.
 type Counts struct {
 	Requests             uint32
 	TotalSuccesses       uint32
 	TotalFailures        uint32
+	TotalSlowCalls       uint32
 	ConsecutiveSuccesses uint32
 	ConsecutiveFailures  uint32
 }
 
+// FailureRate returns the ratio of failed requests to finished requests.
+func (c Counts) FailureRate() float64 {
+	finished := c.TotalSuccesses + c.TotalFailures
+	if finished == 0 {
+		return 0
+	}
+	return float64(c.TotalFailures) / float64(finished)
+}
+
+// SlowCallRate returns the ratio of slow requests to finished requests.
+func (c Counts) SlowCallRate() float64 {
+	finished := c.TotalSuccesses + c.TotalFailures
+	if finished == 0 {
+		return 0
+	}
+	return float64(c.TotalSlowCalls) / float64(finished)
+}
+
 func (c *Counts) onRequest() {
 	c.Requests++
 	// fmt.Println(c.Requests)
@@ -67,10 +91,15 @@ func (c *Counts) onFailure() {
 	c.ConsecutiveSuccesses = 0
 }
 
+func (c *Counts) onSlowCall() {
+	c.TotalSlowCalls++
+}
+
 func (c *Counts) clear() {
 	c.Requests = 0
 	c.TotalSuccesses = 0
 	c.TotalFailures = 0
+	c.TotalSlowCalls = 0
 	c.ConsecutiveSuccesses = 0
 	c.ConsecutiveFailures = 0
 }
@@ -97,13 +126,30 @@ func (c *Counts) clear() {
 // Default ReadyToTrip returns true when the number of consecutive failures is more than 5.
 //
 // OnStateChange is called whenever the state of the CircuitBreaker changes.
+//
+// WindowType selects how Counts are aggregated for ReadyToTrip in the closed state.
+// WindowGeneration (the default) counts everything since the last generation.
+// WindowTime counts the last WindowSize seconds and WindowCount counts the last WindowSize calls.
+// If WindowSize is 0 for a rolling window, 60 is used.
+//
+// SlowCallDuration is the duration above which a finished request is counted as slow.
+// If SlowCallDuration is less than or equal to 0, no request is counted as slow.
+// With a rolling window, ReadyToTrip is also consulted after slow successful requests.
+//
+// MaxConcurrentRequests is the maximum number of requests allowed to be in flight at once.
+// Requests over the limit are rejected with ErrBulkheadFull and are not counted.
+// If MaxConcurrentRequests is 0, the number of in-flight requests is not limited.
 type Settings struct {
-	Name          string
-	MaxRequests   uint32
-	Interval      time.Duration
-	Timeout       time.Duration
-	ReadyToTrip   func(counts Counts) bool
-	OnStateChange func(name string, from State, to State)
+	Name                  string
+	MaxRequests           uint32
+	Interval              time.Duration
+	Timeout               time.Duration
+	ReadyToTrip           func(counts Counts) bool
+	OnStateChange         func(name string, from State, to State)
+	WindowType            WindowType
+	WindowSize            uint32
+	SlowCallDuration      time.Duration
+	MaxConcurrentRequests uint32
 }
 
 // CircuitBreaker is a state machine to prevent sending requests that are likely to fail.
@@ -114,11 +160,15 @@ type CircuitBreaker struct {
 	timeout       time.Duration
 	readyToTrip   func(counts Counts) bool
 	onStateChange func(name string, from State, to State)
+	slowCall      time.Duration
+	maxConcurrent uint32
 
 	mutex      sync.Mutex
 	state      State
 	generation uint64
 	counts     Counts
+	window     window
+	inFlight   uint32
 	expiry     time.Time
 }
 
@@ -160,6 +210,10 @@ func NewCircuitBreaker(st Settings) *CircuitBreaker {
 		cb.readyToTrip = st.ReadyToTrip
 	}
 
+	cb.slowCall = st.SlowCallDuration
+	cb.maxConcurrent = st.MaxConcurrentRequests
+	cb.window = newWindow(st.WindowType, st.WindowSize)
+
 	cb.toNewGeneration(time.Now())
 
 	return cb
@@ -194,6 +248,24 @@ func (cb *CircuitBreaker) State() State {
 	return state
 }
 
+// Counts returns a copy of the Counts ReadyToTrip would currently see.
+func (cb *CircuitBreaker) Counts() Counts {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := time.Now()
+	cb.currentState(now)
+	return cb.tripCounts(now)
+}
+
+// InFlight returns the number of requests currently being executed.
+func (cb *CircuitBreaker) InFlight() uint32 {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	return cb.inFlight
+}
+
 // Execute runs the given request if the CircuitBreaker accepts it.
 // Execute returns an error instantly if the CircuitBreaker rejects the request.
 // Otherwise, Execute returns the result of the request.
@@ -205,16 +277,17 @@ func (cb *CircuitBreaker) Execute(req func() (interface{}, error)) (interface{},
 		return nil, err
 	}
 
+	start := time.Now()
 	defer func() {
 		e := recover()
 		if e != nil {
-			cb.afterRequest(generation, false)
+			cb.afterRequest(generation, false, time.Since(start))
 			panic(e)
 		}
 	}()
 
 	result, err := req()
-	cb.afterRequest(generation, err == nil)
+	cb.afterRequest(generation, err == nil, time.Since(start))
 	return result, err
 }
 
@@ -237,8 +310,9 @@ func (tscb *TwoStepCircuitBreaker) Allow() (done func(success bool), err error)
 		return nil, err
 	}
 
+	start := time.Now()
 	return func(success bool) {
-		tscb.cb.afterRequest(generation, success)
+		tscb.cb.afterRequest(generation, success, time.Since(start))
 	}, nil
 }
 
@@ -253,33 +327,61 @@ func (cb *CircuitBreaker) beforeRequest() (uint64, error) {
 		return generation, ErrOpenState
 	} else if state == StateHalfOpen && cb.counts.Requests >= cb.maxRequests {
 		return generation, ErrTooManyRequests
+	} else if cb.maxConcurrent > 0 && cb.inFlight >= cb.maxConcurrent {
+		return generation, ErrBulkheadFull
 	}
 
+	cb.inFlight++
 	cb.counts.onRequest()
 	return generation, nil
 }
 
-func (cb *CircuitBreaker) afterRequest(before uint64, success bool) {
+func (cb *CircuitBreaker) afterRequest(before uint64, success bool, elapsed time.Duration) {
 	cb.mutex.Lock()
 	defer cb.mutex.Unlock()
 
+	cb.inFlight--
+
 	now := time.Now()
 	state, generation := cb.currentState(now)
 	if generation != before {
 		return
 	}
 
+	slow := cb.slowCall > 0 && elapsed > cb.slowCall
+	if slow {
+		cb.counts.onSlowCall()
+	}
+
 	if success {
-		cb.onSuccess(state, now)
+		cb.onSuccess(state, now, slow)
 	} else {
-		cb.onFailure(state, now)
+		cb.onFailure(state, now, slow)
+	}
+}
+
+// tripCounts returns the Counts passed to readyToTrip.
+func (cb *CircuitBreaker) tripCounts(now time.Time) Counts {
+	if cb.window == nil || cb.state != StateClosed {
+		return cb.counts
 	}
+
+	counts := cb.window.counts(now)
+	counts.ConsecutiveSuccesses = cb.counts.ConsecutiveSuccesses
+	counts.ConsecutiveFailures = cb.counts.ConsecutiveFailures
+	return counts
 }
 
-func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
+func (cb *CircuitBreaker) onSuccess(state State, now time.Time, slow bool) {
 	switch state {
 	case StateClosed:
 		cb.counts.onSuccess()
+		if cb.window != nil {
+			cb.window.onResult(now, true, slow)
+			if slow && cb.readyToTrip(cb.tripCounts(now)) {
+				cb.setState(StateOpen, now)
+			}
+		}
 	case StateHalfOpen:
 		cb.counts.onSuccess()
 		if cb.counts.ConsecutiveSuccesses >= cb.maxRequests {
@@ -288,11 +390,14 @@ func (cb *CircuitBreaker) onSuccess(state State, now time.Time) {
 	}
 }
 
-func (cb *CircuitBreaker) onFailure(state State, now time.Time) {
+func (cb *CircuitBreaker) onFailure(state State, now time.Time, slow bool) {
 	switch state {
 	case StateClosed:
 		cb.counts.onFailure()
-		if cb.readyToTrip(cb.counts) {
+		if cb.window != nil {
+			cb.window.onResult(now, false, slow)
+		}
+		if cb.readyToTrip(cb.tripCounts(now)) {
 			cb.setState(StateOpen, now)
 		}
 	case StateHalfOpen:
@@ -332,6 +437,9 @@ func (cb *CircuitBreaker) setState(state State, now time.Time) {
 func (cb *CircuitBreaker) toNewGeneration(now time.Time) {
 	cb.generation++
 	cb.counts.clear()
+	if cb.window != nil && cb.state != StateClosed {
+		cb.window.clear()
+	}
 
 	var zero time.Time
 	switch cb.state {
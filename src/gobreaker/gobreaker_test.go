@@ -1 +1,275 @@
 package gobreaker
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errTest = errors.New("test error")
+
+func succeed(cb *CircuitBreaker) error {
+	_, err := cb.Execute(func() (interface{}, error) { return nil, nil })
+	return err
+}
+
+func fail(cb *CircuitBreaker) error {
+	_, err := cb.Execute(func() (interface{}, error) { return nil, errTest })
+	if err == errTest {
+		return nil
+	}
+	return err
+}
+
+func TestDefaultReadyToTrip(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{Name: "default"})
+	for i := 0; i < 5; i++ {
+		if err := fail(cb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("state = %v, want closed", cb.State())
+	}
+	if err := fail(cb); err != nil {
+		t.Fatal(err)
+	}
+	if cb.State() != StateOpen {
+		t.Fatalf("state = %v, want open", cb.State())
+	}
+	if err := succeed(cb); err != ErrOpenState {
+		t.Fatalf("err = %v, want %v", err, ErrOpenState)
+	}
+}
+
+func TestCountWindow(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		WindowType:  WindowCount,
+		WindowSize:  4,
+		ReadyToTrip: RateReadyToTrip(4, 0.75, 0),
+	})
+
+	// Two old failures fall out of the window as successes arrive.
+	fail(cb)
+	fail(cb)
+	for i := 0; i < 4; i++ {
+		succeed(cb)
+	}
+	c := cb.Counts()
+	if c.Requests != 4 || c.TotalFailures != 0 || c.TotalSuccesses != 4 {
+		t.Fatalf("counts = %+v, want 4 successes in window", c)
+	}
+
+	fail(cb)
+	fail(cb)
+	if cb.State() != StateClosed {
+		t.Fatalf("state = %v, want closed at 50%% failures", cb.State())
+	}
+	fail(cb)
+	if cb.State() != StateOpen {
+		t.Fatalf("state = %v, want open at 75%% failures", cb.State())
+	}
+}
+
+func TestTimeWindow(t *testing.T) {
+	w := newTimeWindow(2)
+	now := time.Unix(1000, 0)
+	w.onResult(now, false, false)
+	w.onResult(now.Add(time.Second), true, true)
+
+	c := w.counts(now.Add(time.Second))
+	if c.Requests != 2 || c.TotalFailures != 1 || c.TotalSlowCalls != 1 {
+		t.Fatalf("counts = %+v", c)
+	}
+	if c.FailureRate() != 0.5 || c.SlowCallRate() != 0.5 {
+		t.Fatalf("rates = %v, %v", c.FailureRate(), c.SlowCallRate())
+	}
+
+	c = w.counts(now.Add(2 * time.Second))
+	if c.Requests != 1 || c.TotalFailures != 0 {
+		t.Fatalf("counts after expiry = %+v", c)
+	}
+}
+
+func TestSlowCallTrip(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{
+		WindowType:       WindowTime,
+		WindowSize:       10,
+		SlowCallDuration: time.Millisecond,
+		ReadyToTrip:      RateReadyToTrip(2, 0, 1),
+	})
+
+	slow := func() (interface{}, error) {
+		time.Sleep(5 * time.Millisecond)
+		return nil, nil
+	}
+	cb.Execute(slow)
+	if cb.State() != StateClosed {
+		t.Fatalf("state = %v, want closed below minimum requests", cb.State())
+	}
+	cb.Execute(slow)
+	if cb.State() != StateOpen {
+		t.Fatalf("state = %v, want open after slow calls", cb.State())
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	cb := NewCircuitBreaker(Settings{MaxConcurrentRequests: 2})
+
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cb.Execute(func() (interface{}, error) {
+				<-release
+				return nil, nil
+			})
+		}()
+	}
+	for cb.InFlight() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := succeed(cb); err != ErrBulkheadFull {
+		t.Fatalf("err = %v, want %v", err, ErrBulkheadFull)
+	}
+	close(release)
+	wg.Wait()
+
+	if err := succeed(cb); err != nil {
+		t.Fatal(err)
+	}
+	if c := cb.Counts(); c.Requests != 3 {
+		t.Fatalf("requests = %d, want rejected call not counted", c.Requests)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	metrics := NewMetrics()
+	transport := NewTransport(nil, Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		Timeout:     time.Hour,
+	}, metrics)
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d", resp.StatusCode)
+		}
+	}
+
+	_, err := client.Get(ts.URL)
+	if !errors.Is(err, ErrOpenState) {
+		t.Fatalf("err = %v, want %v", err, ErrOpenState)
+	}
+
+	body := &closeRecorder{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest("POST", ts.URL, body)
+	if _, err := transport.RoundTrip(req); !errors.Is(err, ErrOpenState) {
+		t.Fatalf("err = %v, want %v", err, ErrOpenState)
+	}
+	if !body.closed {
+		t.Fatal("body of a rejected request is not closed")
+	}
+
+	u, _ := url.Parse(ts.URL)
+	if transport.Breakers.Get(u.Host).State() != StateOpen {
+		t.Fatal("breaker for host is not open")
+	}
+	if n := metrics.Transitions(u.Host, StateClosed, StateOpen); n != 1 {
+		t.Fatalf("transitions = %d, want 1", n)
+	}
+
+	var _ expvar.Var = metrics
+	if metrics.String() == "{}" {
+		t.Fatal("metrics are empty")
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	breakers := NewRegistry(Settings{
+		ReadyToTrip: func(counts Counts) bool { return counts.ConsecutiveFailures >= 2 },
+		Timeout:     time.Hour,
+	}, nil)
+	interceptor := UnaryClientInterceptor(breakers)
+
+	var code codes.Code
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(code, "test")
+	}
+	call := func() error {
+		return interceptor(context.Background(), "/svc/Method", nil, nil, nil, invoker)
+	}
+
+	code = codes.InvalidArgument
+	for i := 0; i < 3; i++ {
+		call()
+	}
+	if breakers.Get("/svc/Method").State() != StateClosed {
+		t.Fatal("client errors tripped the breaker")
+	}
+
+	code = codes.Unavailable
+	call()
+	call()
+	err := call()
+	if status.Code(err) != codes.Unavailable || status.Convert(err).Message() != ErrOpenState.Error() {
+		t.Fatalf("err = %v, want rejection", err)
+	}
+}
+
+func TestRegistryMetricsConcurrent(t *testing.T) {
+	metrics := NewMetrics()
+	registries := []*Registry{NewRegistry(Settings{}, metrics), NewRegistry(Settings{}, metrics)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				registries[i%2].Get(string(rune('a' + (i*100+j)%26)))
+				_ = metrics.String()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(registries[0].Names()) + len(registries[1].Names()); n != 52 {
+		t.Fatalf("breakers = %d, want 52", n)
+	}
+}
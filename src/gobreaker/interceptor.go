@@ -0,0 +1,65 @@
+package gobreaker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a grpc.UnaryClientInterceptor that runs every call through
+// the CircuitBreaker of the call's full method name.
+// Calls rejected by the CircuitBreaker fail with codes.Unavailable.
+func UnaryClientInterceptor(breakers *Registry) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var callErr error
+		_, err := breakers.Get(method).Execute(func() (interface{}, error) {
+			callErr = invoker(ctx, method, req, reply, cc, opts...)
+			if isGRPCFailure(callErr) {
+				return nil, callErr
+			}
+			return nil, nil
+		})
+		if isRejected(err) {
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		return callErr
+	}
+}
+
+// StreamClientInterceptor returns a grpc.StreamClientInterceptor that runs the creation of
+// every stream through the CircuitBreaker of the stream's full method name.
+func StreamClientInterceptor(breakers *Registry) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var stream grpc.ClientStream
+		var callErr error
+		_, err := breakers.Get(method).Execute(func() (interface{}, error) {
+			stream, callErr = streamer(ctx, desc, cc, method, opts...)
+			if isGRPCFailure(callErr) {
+				return nil, callErr
+			}
+			return nil, nil
+		})
+		if isRejected(err) {
+			return nil, status.Error(codes.Unavailable, err.Error())
+		}
+		return stream, callErr
+	}
+}
+
+// isGRPCFailure reports whether err means the server is unhealthy.
+// Errors caused by the request itself, such as codes.InvalidArgument, are not failures.
+func isGRPCFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRejected(err error) bool {
+	return err == ErrOpenState || err == ErrTooManyRequests || err == ErrBulkheadFull
+}
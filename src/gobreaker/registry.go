@@ -0,0 +1,142 @@
+package gobreaker
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Registry lazily creates one CircuitBreaker per name, all configured from the same Settings.
+// If metrics is set, every breaker reports its state changes to it
+// before calling the OnStateChange of the Settings.
+type Registry struct {
+	settings Settings
+	metrics  *Metrics
+
+	mutex    sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewRegistry returns a new Registry creating breakers from st. metrics may be nil.
+func NewRegistry(st Settings, metrics *Metrics) *Registry {
+	return &Registry{
+		settings: st,
+		metrics:  metrics,
+		breakers: make(map[string]*CircuitBreaker),
+	}
+}
+
+// Get returns the CircuitBreaker with the given name, creating it on first use.
+func (r *Registry) Get(name string) *CircuitBreaker {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cb, ok := r.breakers[name]
+	if ok {
+		return cb
+	}
+
+	st := r.settings
+	st.Name = name
+	if r.metrics != nil {
+		onStateChange := st.OnStateChange
+		st.OnStateChange = func(name string, from State, to State) {
+			r.metrics.OnStateChange(name, from, to)
+			if onStateChange != nil {
+				onStateChange(name, from, to)
+			}
+		}
+		r.metrics.Register(name)
+	}
+
+	cb = NewCircuitBreaker(st)
+	r.breakers[name] = cb
+	return cb
+}
+
+// Names returns the names of the breakers created so far.
+func (r *Registry) Names() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Metrics records the current state and the state transitions of circuit breakers.
+// It implements expvar.Var, so it can be exposed with expvar.Publish.
+type Metrics struct {
+	mutex    sync.Mutex
+	breakers map[string]*breakerMetrics
+}
+
+type breakerMetrics struct {
+	State       string            `json:"state"`
+	Transitions map[string]uint64 `json:"transitions"`
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{breakers: make(map[string]*breakerMetrics)}
+}
+
+// Register adds the named breaker in the closed state, if it is not known yet.
+func (m *Metrics) Register(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.register(name)
+}
+
+// register must be called with m.mutex held.
+func (m *Metrics) register(name string) *breakerMetrics {
+	bm, ok := m.breakers[name]
+	if !ok {
+		bm = &breakerMetrics{
+			State:       StateClosed.String(),
+			Transitions: make(map[string]uint64),
+		}
+		m.breakers[name] = bm
+	}
+	return bm
+}
+
+// OnStateChange records a state change. It has the signature of Settings.OnStateChange.
+func (m *Metrics) OnStateChange(name string, from State, to State) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	bm := m.register(name)
+	bm.State = to.String()
+	bm.Transitions[transitionKey(from, to)]++
+}
+
+// Transitions returns how many times the named breaker went from one state to another.
+func (m *Metrics) Transitions(name string, from State, to State) uint64 {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	bm, ok := m.breakers[name]
+	if !ok {
+		return 0
+	}
+	return bm.Transitions[transitionKey(from, to)]
+}
+
+// String implements expvar.Var by returning the metrics as a JSON object keyed by breaker name.
+func (m *Metrics) String() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, err := json.Marshal(m.breakers)
+	if err != nil {
+		return "{}"
+	}
+	return string(b)
+}
+
+func transitionKey(from State, to State) string {
+	return from.String() + "->" + to.String()
+}
@@ -0,0 +1,70 @@
+package gobreaker
+
+import (
+	"errors"
+	"net/http"
+)
+
+var errFailureResponse = errors.New("failure response")
+
+// Transport is an http.RoundTripper that runs every request through
+// the CircuitBreaker of the request's host.
+type Transport struct {
+	// Base is the underlying RoundTripper. If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Breakers provides the CircuitBreaker for each host.
+	Breakers *Registry
+	// IsFailure decides whether a response counts as a failure.
+	// Transport errors are always failures. If nil, 5xx responses are failures.
+	IsFailure func(resp *http.Response) bool
+}
+
+// NewTransport returns a Transport over base using breakers created from st.
+func NewTransport(base http.RoundTripper, st Settings, metrics *Metrics) *Transport {
+	return &Transport{
+		Base:     base,
+		Breakers: NewRegistry(st, metrics),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+// A failure response is still returned to the caller, but counted as a failure.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	isFailure := t.IsFailure
+	if isFailure == nil {
+		isFailure = defaultIsFailure
+	}
+
+	cb := t.Breakers.Get(req.URL.Host)
+	sent := false
+	result, err := cb.Execute(func() (interface{}, error) {
+		sent = true
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		if isFailure(resp) {
+			return resp, errFailureResponse
+		}
+		return resp, nil
+	})
+	if err == errFailureResponse {
+		err = nil
+	}
+	if err != nil {
+		// RoundTrip must close the body, even when the breaker rejects the request
+		if !sent && req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return result.(*http.Response), nil
+}
+
+func defaultIsFailure(resp *http.Response) bool {
+	return resp.StatusCode >= http.StatusInternalServerError
+}
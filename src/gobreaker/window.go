@@ -0,0 +1,195 @@
+package gobreaker
+
+import "time"
+
+// WindowType is a type that represents how CircuitBreaker aggregates Counts for ReadyToTrip.
+type WindowType int
+
+// These constants are window types of CircuitBreaker.
+const (
+	WindowGeneration WindowType = iota
+	WindowTime
+	WindowCount
+)
+
+const defaultWindowSize = 60
+
+// String implements stringer interface.
+func (w WindowType) String() string {
+	switch w {
+	case WindowGeneration:
+		return "generation"
+	case WindowTime:
+		return "time"
+	case WindowCount:
+		return "count"
+	default:
+		return "unknown window type"
+	}
+}
+
+// window aggregates the results of finished requests.
+// The Requests of the returned Counts is the number of results in the window.
+type window interface {
+	onResult(now time.Time, success bool, slow bool)
+	counts(now time.Time) Counts
+	clear()
+}
+
+func newWindow(typ WindowType, size uint32) window {
+	if size == 0 {
+		size = defaultWindowSize
+	}
+
+	switch typ {
+	case WindowTime:
+		return newTimeWindow(size)
+	case WindowCount:
+		return newCountWindow(size)
+	default:
+		return nil
+	}
+}
+
+// bucket holds the results of the requests finished within one second.
+type bucket struct {
+	second    int64
+	successes uint32
+	failures  uint32
+	slowCalls uint32
+}
+
+// timeWindow keeps one bucket per second for the last len(buckets) seconds.
+type timeWindow struct {
+	buckets []bucket
+}
+
+func newTimeWindow(seconds uint32) *timeWindow {
+	return &timeWindow{buckets: make([]bucket, seconds)}
+}
+
+func (w *timeWindow) onResult(now time.Time, success bool, slow bool) {
+	sec := now.Unix()
+	b := &w.buckets[sec%int64(len(w.buckets))]
+	if b.second != sec {
+		*b = bucket{second: sec}
+	}
+
+	if success {
+		b.successes++
+	} else {
+		b.failures++
+	}
+	if slow {
+		b.slowCalls++
+	}
+}
+
+func (w *timeWindow) counts(now time.Time) Counts {
+	var c Counts
+	oldest := now.Unix() - int64(len(w.buckets))
+	for _, b := range w.buckets {
+		if b.second <= oldest {
+			continue
+		}
+		c.TotalSuccesses += b.successes
+		c.TotalFailures += b.failures
+		c.TotalSlowCalls += b.slowCalls
+	}
+	c.Requests = c.TotalSuccesses + c.TotalFailures
+	return c
+}
+
+func (w *timeWindow) clear() {
+	for i := range w.buckets {
+		w.buckets[i] = bucket{}
+	}
+}
+
+const (
+	resultFailure uint8 = 1 << iota
+	resultSlow
+)
+
+// countWindow keeps the results of the last len(results) requests in a ring.
+type countWindow struct {
+	results []uint8
+	next    int
+	full    bool
+	c       Counts
+}
+
+func newCountWindow(calls uint32) *countWindow {
+	return &countWindow{results: make([]uint8, calls)}
+}
+
+func (w *countWindow) onResult(now time.Time, success bool, slow bool) {
+	if w.full {
+		w.remove(w.results[w.next])
+	}
+
+	var r uint8
+	if !success {
+		r |= resultFailure
+	}
+	if slow {
+		r |= resultSlow
+	}
+	w.add(r)
+
+	w.results[w.next] = r
+	w.next++
+	if w.next == len(w.results) {
+		w.next = 0
+		w.full = true
+	}
+}
+
+func (w *countWindow) add(r uint8) {
+	w.c.Requests++
+	if r&resultFailure != 0 {
+		w.c.TotalFailures++
+	} else {
+		w.c.TotalSuccesses++
+	}
+	if r&resultSlow != 0 {
+		w.c.TotalSlowCalls++
+	}
+}
+
+func (w *countWindow) remove(r uint8) {
+	w.c.Requests--
+	if r&resultFailure != 0 {
+		w.c.TotalFailures--
+	} else {
+		w.c.TotalSuccesses--
+	}
+	if r&resultSlow != 0 {
+		w.c.TotalSlowCalls--
+	}
+}
+
+func (w *countWindow) counts(now time.Time) Counts {
+	return w.c
+}
+
+func (w *countWindow) clear() {
+	w.next = 0
+	w.full = false
+	w.c.clear()
+}
+
+// RateReadyToTrip returns a ReadyToTrip function that trips once at least minRequests
+// have finished and either the failure rate or the slow-call rate reaches its threshold.
+// A threshold less than or equal to 0 is ignored.
+func RateReadyToTrip(minRequests uint32, failureRate, slowCallRate float64) func(counts Counts) bool {
+	return func(counts Counts) bool {
+		if counts.Requests < minRequests {
+			return false
+		}
+		if failureRate > 0 && counts.FailureRate() >= failureRate {
+			return true
+		}
+		return slowCallRate > 0 && counts.SlowCallRate() >= slowCallRate
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"runtime"
 
+	"app/model"
 	"app/route"
 	"app/shared/database"
 	"app/shared/email"
@@ -13,6 +14,7 @@ import (
 	"app/shared/recaptcha"
 	"app/shared/server"
 	"app/shared/session"
+	"app/shared/token"
 	"app/shared/view"
 	"app/shared/view/plugin"
 )
@@ -34,11 +36,20 @@ func main() {
 	jsonconfig.Load("config"+string(os.PathSeparator)+"config.json", config)
 
 	// Configure the session cookie store
-	session.Configure(config.session)
+	session.Configure(config.Session)
 
 	// Connect to database
 	database.Connect(config.Database)
 
+	// Use the note and user repositories of the database
+	if err := model.Configure(config.Database); err != nil {
+		log.Println(err)
+	}
+
+	// Configure the mailer and the signing key of the emailed links
+	email.Configure(config.Email)
+	token.Configure(config.Token)
+
 	// Configure the Google reCAPTCHA prior to loading view plugins
 	recaptcha.Configure(config.Recaptcha)
 
@@ -71,6 +82,7 @@ type configuration struct {
 	Server    server.Server   `json:"Server"`
 	Session   session.Session `json:"Session"`
 	Template  view.Template   `json:"Template"`
+	Token     token.Info      `json:"Token"`
 	View      view.View       `json:"View"`
 }
 
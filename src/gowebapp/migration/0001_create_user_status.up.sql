@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_status (
+    id TINYINT(1) UNSIGNED NOT NULL AUTO_INCREMENT,
+
+    status VARCHAR(25) NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    deleted TINYINT(1) UNSIGNED NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (id)
+);
+
+INSERT IGNORE INTO `user_status` (`id`, `status`, `created_at`, `updated_at`, `deleted`) VALUES
+(1, 'active',   CURRENT_TIMESTAMP,  CURRENT_TIMESTAMP,  0),
+(2, 'inactive', CURRENT_TIMESTAMP,  CURRENT_TIMESTAMP,  0);
@@ -0,0 +1,9 @@
+ALTER TABLE user
+    DROP COLUMN locked_until,
+    DROP COLUMN failed_logins,
+    DROP COLUMN totp_step,
+    DROP COLUMN totp_secret;
+
+-- the users cascade with their status
+UPDATE user SET status_id = 1 WHERE status_id = 3;
+DELETE FROM user_status WHERE id = 3;
@@ -0,0 +1,8 @@
+INSERT INTO `user_status` (`id`, `status`, `created_at`, `updated_at`, `deleted`) VALUES
+(3, 'unverified', CURRENT_TIMESTAMP,  CURRENT_TIMESTAMP,  0);
+
+ALTER TABLE user
+    ADD COLUMN totp_secret VARCHAR(32) NOT NULL DEFAULT '' AFTER status_id,
+    ADD COLUMN totp_step BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER totp_secret,
+    ADD COLUMN failed_logins TINYINT UNSIGNED NOT NULL DEFAULT 0 AFTER totp_step,
+    ADD COLUMN locked_until DATETIME NULL AFTER failed_logins;
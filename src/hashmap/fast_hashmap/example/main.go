@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	hashmap "github.com/pathbox/learning-go/src/hashmap/fast_hashmap"
+)
+
+func main() {
+	m := &hashmap.HashMap[string, *int64]{}
+
+	var i int64
+	counter, _ := m.GetOrInsert("api/123", &i)
+	atomic.AddInt64(counter, 1)
+
+	count := atomic.LoadInt64(counter)
+	fmt.Println("count:", count)
+
+	hits := hashmap.NewWithHasher[string, int](16, hashmap.XXH3[string]())
+	for _, path := range []string{"/", "/about", "/"} {
+		hits.Compute(path, func(n int, loaded bool) (int, bool) {
+			return n + 1, true
+		})
+	}
+	hits.Range(func(path string, n int) bool {
+		fmt.Println(path, n)
+		return true
+	})
+}
@@ -3,7 +3,6 @@ package hashmap
 import (
 	"bytes"
 	"fmt"
-	"reflect"
 	"strconv"
 	"sync/atomic"
 	"unsafe"
@@ -16,47 +15,51 @@ const DefaultSize = 8
 const MaxFillRate = 50
 
 type (
-	hashMapData struct {
+	hashMapData[K hashable, V any] struct {
 		keyshifts uintptr
 		count     uintptr
 		data      unsafe.Pointer
-		inde      []*ListElement
+		index     []*ListElement[K, V]
+		hasher    Hasher[K]
 	}
 
 	// HashMap implements a read optimized hash map.
-	HashMap struct {
+	// The zero value is an empty map hashing keys with SipHash.
+	HashMap[K hashable, V any] struct {
 		datamap    unsafe.Pointer
 		linkedlist unsafe.Pointer
 		resizing   uintptr
-	}
-
-	KeyValue struct {
-		Key   interface{}
-		Value interface{}
+		hasher     Hasher[K]
 	}
 )
 
-func New(size uintptr) *HashMap {
-	m := &HashMap{}
+// New returns a map with room for size items before a resize, hashing keys with SipHash.
+func New[K hashable, V any](size uintptr) *HashMap[K, V] {
+	return NewWithHasher[K, V](size, nil)
+}
+
+// NewWithHasher returns a map hashing keys with hasher, SipHash if it is nil.
+func NewWithHasher[K hashable, V any](size uintptr, hasher Hasher[K]) *HashMap[K, V] {
+	m := &HashMap[K, V]{hasher: hasher}
 	m.allocate(size)
 	return m
 }
 
-func (m *HashMap) Len() int {
+func (m *HashMap[K, V]) Len() int {
 	list := m.list()
 	return list.Len()
 }
 
-func (m *HashMap) mapData() *hashMapData {
-	return (*hashMapData)(atomic.LoadPointer(&m.datamap))
+func (m *HashMap[K, V]) mapData() *hashMapData[K, V] {
+	return (*hashMapData[K, V])(atomic.LoadPointer(&m.datamap))
 }
 
-func (m *HashMap) list() *List {
-	return (*List)(atomic.LoadPointer(&m.linkedlist))
+func (m *HashMap[K, V]) list() *List[K, V] {
+	return (*List[K, V])(atomic.LoadPointer(&m.linkedlist))
 }
 
-func (m *HashMap) allocate(newSize uintptr) {
-	list := NewList()
+func (m *HashMap[K, V]) allocate(newSize uintptr) {
+	list := NewList[K, V]()
 
 	if atomic.CompareAndSwapPointer(&m.linkedlist, nil, unsafe.Pointer(list)) {
 		if atomic.CompareAndSwapUintptr(&m.resizing, uintptr(0), uintptr(1)) {
@@ -65,14 +68,27 @@ func (m *HashMap) allocate(newSize uintptr) {
 	}
 }
 
-func (m *HashMap) Fillrate() uintptr {
+// initialized returns the map data, allocating it for a zero value map.
+func (m *HashMap[K, V]) initialized() *hashMapData[K, V] {
+	for {
+		if data := m.mapData(); data != nil {
+			return data
+		}
+		m.allocate(DefaultSize) // read mapdata again, another goroutine may allocate it
+	}
+}
+
+func (m *HashMap[K, V]) Fillrate() uintptr {
 	data := m.mapData()
-	count := atomic.LoadUintptr(&data.index)
+	if data == nil {
+		return 0
+	}
+	count := atomic.LoadUintptr(&data.count)
 	l := uintptr(len(data.index))
 	return (count * 100) / l
 }
 
-func (m *HashMap) resizeNeeded(data *hashMapData, count uintptr) bool {
+func (m *HashMap[K, V]) resizeNeeded(data *hashMapData[K, V], count uintptr) bool {
 	l := uintptr(len(data.index))
 	if l == 0 {
 		return false
@@ -81,68 +97,74 @@ func (m *HashMap) resizeNeeded(data *hashMapData, count uintptr) bool {
 	return fillRate > MaxFillRate
 }
 
-func (m *HashMap) indexElement(hashedKey uintptr) (data *hashMapData, item *ListElement) {
-	data = m.mapData()
-	if data == nil {
-		return nil, nil
+// searchStart returns an element before the key to start a list search from:
+// the one of its index slot, or of the closest slot before.
+func (data *hashMapData[K, V]) searchStart(hashedKey uintptr, key K) *ListElement[K, V] {
+	for index := hashedKey >> data.keyshifts; ; index-- {
+		ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(data.data) + index*intSizeBytes))
+		if element := (*ListElement[K, V])(atomic.LoadPointer(ptr)); element != nil && element.before(hashedKey, key) && !element.deleted() {
+			return element
+		}
+		if index == 0 {
+			return nil
+		}
 	}
-
-	idnex := hashedKey >> data.keyshifts
-	ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(data.data) + index*intSizeBytes))
-	item = (*ListElement)(atomic.LoadPointer(ptr))
-	return data, item
 }
 
-func (m *HashMap) Del(key interface{}) {
-	list := m.list()
-	if list == nil {
-		return
+// Get returns the value of a key and whether it was found.
+func (m *HashMap[K, V]) Get(key K) (value V, ok bool) {
+	data := m.mapData()
+	if data == nil {
+		return value, false
 	}
 
-	h := getKeyHash(key)
-
-	var element *ListElement
-	for _, element = m.indexElement(h); element != nil; element = element.Next() {
+	h := data.hasher(key)
+	element := data.searchStart(h, key)
+	if element == nil {
+		element = m.list().First()
+	}
+	for ; element != nil; element = element.Next() {
 		if element.keyHash == h && element.key == key {
-			break
+			if value, ok = element.load(); ok {
+				return value, true
+			}
+			continue // deleted, the key may have been added again
 		}
-
-		if element.keyHash > h {
-			return
+		if !element.before(h, key) {
+			break
 		}
 	}
-	if element == nil {
-		return
-	}
-	m.deleteElement(element)
-	list.Delete(element)
+	return value, false
 }
 
-// DelHashedKey deletes the hashed key from the map.
-func (m *HashMap) DelHashedKey(hashedKey uintptr) {
-	list := m.list()
-	if list == nil {
+// Del deletes the key from the map.
+func (m *HashMap[K, V]) Del(key K) {
+	data := m.mapData()
+	if data == nil {
 		return
 	}
 
-	_, element := m.indexElement(hashedKey)
+	h := data.hasher(key)
+	list := m.list()
+	start := data.searchStart(h, key)
+	element := list.Find(h, key, start)
 	if element == nil {
 		return
 	}
-
-	m.deleteElement(element)
-	list.Delete(element)
+	if list.Delete(element, start) {
+		m.deleteElement(element)
+	}
 }
 
 // deleteElement deletes an element from index
-func (m *HashMap) deleteElement(element *ListElement) {
+func (m *HashMap[K, V]) deleteElement(element *ListElement[K, V]) {
 	for {
 		data := m.mapData()
 		index := element.keyHash >> data.keyshifts
 		ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(data.data) + index*intSizeBytes))
 
 		next := element.Next()
-		if next != nil && element.keyHash>>data.keyshifts != index {
+		if next != nil && next.keyHash>>data.keyshifts != index {
 			next = nil // do not set index to next item if it's not the same slice index
 		}
 		atomic.CompareAndSwapPointer(ptr, unsafe.Pointer(element), unsafe.Pointer(next))
@@ -157,114 +179,158 @@ func (m *HashMap) deleteElement(element *ListElement) {
 // Insert sets the value under the specified key to the map if it does not exist yet.
 // If a resizing operation is happening concurrently while calling Set, the item might show up in the map only after the resize operation is finished.
 // Returns true if the item was inserted or false if it existed.
-func (m *HashMap) Insert(key interface{}, value interface{}) bool {
-	h := getKeyHash(key)
-	element := &ListElement{
-		key:     key,
-		keyHash: h,
-		value:   unsafe.Pointer(&value),
-	}
-	return m.insertListElement(element, false)
+func (m *HashMap[K, V]) Insert(key K, value V) bool {
+	_, loaded := m.GetOrInsert(key, value)
+	return !loaded
 }
 
 // Set sets the value under the specified key to the map. An existing item for this key will be overwritten.
 // If a resizing operation is happening concurrently while calling Set, the item might show up in the map only after the resize operation is finished.
-func (m *HashMap) Set(key interface{}, value interface{}) {
-	h := getKeyHash(key)
-	element := &ListElement{
+func (m *HashMap[K, V]) Set(key K, value V) {
+	data := m.initialized()
+	element := &ListElement[K, V]{
 		key:     key,
-		keyHash: h,
+		keyHash: data.hasher(key),
 		value:   unsafe.Pointer(&value),
 	}
-	m.insertListElement(element, true)
+	for {
+		found, inserted := m.list().Add(element, m.mapData().searchStart(element.keyHash, key))
+		if found != nil {
+			if found.setValue(element.value) {
+				return
+			}
+			continue // deleted concurrently, add it again
+		}
+		if inserted {
+			m.indexListElement(element)
+			return
+		}
+	}
 }
 
-// SetHashedKey sets the value under the specified hash key to the map. An existing item for this key will be overwritten.
-// You can use this function if your keys are already hashes and you want to avoid another hashing of the key.
-// Do not use non hashes as keys for this function, the performance would decrease!
-// If a resizing operation is happening concurrently while calling Set, the item might show up in the map only after the resize operation is finished.
-func (m *HashMap) SetHashedKey(hashedKey uintptr, value interface{}) {
-	element := &ListElement{
-		key:     hashedKey,
-		keyHash: hashedKey,
+// GetOrInsert returns the value of the key if it exists, otherwise it inserts value and returns it.
+// loaded is true if the value was found.
+func (m *HashMap[K, V]) GetOrInsert(key K, value V) (actual V, loaded bool) {
+	data := m.initialized()
+	element := &ListElement[K, V]{
+		key:     key,
+		keyHash: data.hasher(key),
 		value:   unsafe.Pointer(&value),
 	}
-	m.insertListElement(element, true)
-}
-
-func (m *HashMap) insertListElement(element *ListElement, update bool) bool {
 	for {
-		data, existing := m.indexElement(element.keyHash)
-		if data == nil {
-			m.allocate(DefaultSize)
-			continue // read mapdata and slice item again
+		found, inserted := m.list().Add(element, m.mapData().searchStart(element.keyHash, key))
+		if found != nil {
+			if actual, ok := found.load(); ok {
+				return actual, true
+			}
+			continue // deleted concurrently
 		}
-		list := m.list()
+		if inserted {
+			m.indexListElement(element)
+			return value, false
+		}
+	}
+}
 
-		if update {
-			if !list.AddOrUpdate(element, existing) {
-				continue // a concurrent add did interfere, try again
-			}
-		} else {
-			existed, inserted := list.Add(element, existing)
-			if existed {
-				return false
+// Compute atomically sets the value of the key to the result of fn, which gets the
+// current value and whether it exists. The key is deleted if fn returns false for keep.
+// fn may be called several times when the key is modified concurrently.
+// It returns the new value and whether the key exists.
+func (m *HashMap[K, V]) Compute(key K, fn func(value V, loaded bool) (newValue V, keep bool)) (V, bool) {
+	data := m.initialized()
+	h := data.hasher(key)
+	list := m.list()
+	for {
+		start := m.mapData().searchStart(h, key)
+		if found := list.Find(h, key, start); found != nil {
+			old := atomic.LoadPointer(&found.value)
+			if old == tombstone {
+				continue // deleted concurrently
 			}
-			if !inserted {
+			newValue, keep := fn(*(*V)(old), true)
+			if keep {
+				if atomic.CompareAndSwapPointer(&found.value, old, unsafe.Pointer(&newValue)) {
+					return newValue, true
+				}
 				continue
 			}
+			if list.deleteValue(found, old, start) {
+				m.deleteElement(found)
+				var zero V
+				return zero, false
+			}
+			continue
 		}
 
-		count := data.addItemToIndex(element)
-		if m.resizeNeeded(data, count) {
-			if atomic.CompareAndSwapUintptr(&m.resizing, uintptr(0), uintptr(1)) {
-				go m.grow(0, true)
-			}
+		var zero V
+		newValue, keep := fn(zero, false)
+		if !keep {
+			return zero, false
+		}
+		element := &ListElement[K, V]{
+			key:     key,
+			keyHash: h,
+			value:   unsafe.Pointer(&newValue),
+		}
+		if found, inserted := list.Add(element, start); found == nil && inserted {
+			m.indexListElement(element)
+			return newValue, true
 		}
-		return true
 	}
 }
 
-// CasHashedKey performs a compare and swap operation sets the value under the specified hash key to the map. An existing item for this key will be overwritten.
-func (m *HashMap) CasHashedKey(hashedKey uintptr, from, to interface{}) bool {
-	data, existing := m.indexElement(hashedKey)
+// Cas performs a compare and swap of the value of an existing key: it sets the value to to
+// if the current one is from. It returns false if the key does not exist or has another value.
+// Values are compared with == through interface{}, so V must be comparable.
+func (m *HashMap[K, V]) Cas(key K, from, to V) bool {
+	data := m.mapData()
 	if data == nil {
 		return false
 	}
-	list := m.list()
-	if list == nil {
-		return false
-	}
 
-	element := &ListElement{
-		key:     hashedKey,
-		keyHash: hashedKey,
-		value:   unsafe.Pointer(&to),
+	h := data.hasher(key)
+	list := m.list()
+	for {
+		found := list.Find(h, key, m.mapData().searchStart(h, key))
+		if found == nil {
+			return false
+		}
+		old := atomic.LoadPointer(&found.value)
+		if old == tombstone || any(*(*V)(old)) != any(from) {
+			return false
+		}
+		if atomic.CompareAndSwapPointer(&found.value, old, unsafe.Pointer(&to)) {
+			return true
+		}
 	}
-	return list.Cas(element, from, existing)
 }
 
-// Cas performs a compare and swap operation sets the value under the specified hash key to the map. An existing item for this key will be overwritten.
-func (m *HashMap) Cas(key, from, to interface{}) bool {
-	h := getKeyHash(key)
-	return m.CasHashedKey(h, from, to)
+// indexListElement adds a new element of the list to the index and grows the index if needed.
+func (m *HashMap[K, V]) indexListElement(element *ListElement[K, V]) {
+	data := m.mapData()
+	count := data.addItemToIndex(element)
+	if m.resizeNeeded(data, count) {
+		if atomic.CompareAndSwapUintptr(&m.resizing, uintptr(0), uintptr(1)) {
+			go m.grow(0, true)
+		}
+	}
 }
 
 // adds an item to the index if needed and returns the new item counter if it changed, otherwise 0
-func (mapData *hashMapData) addItemToIndex(item *ListElement) uintptr {
+func (mapData *hashMapData[K, V]) addItemToIndex(item *ListElement[K, V]) uintptr {
 	index := item.keyHash >> mapData.keyshifts
 	ptr := (*unsafe.Pointer)(unsafe.Pointer(uintptr(mapData.data) + index*intSizeBytes))
 
 	for { // loop until the smallest key hash is in the index
-		element := (*ListElement)(atomic.LoadPointer(ptr)) // get the current item in the index
-		if element == nil {                                // no item yet at this index
+		element := (*ListElement[K, V])(atomic.LoadPointer(ptr)) // get the current item in the index
+		if element == nil {                                      // no item yet at this index
 			if atomic.CompareAndSwapPointer(ptr, nil, unsafe.Pointer(item)) {
 				return atomic.AddUintptr(&mapData.count, 1)
 			}
 			continue // a new item was inserted concurrently, retry
 		}
 
-		if item.keyHash < element.keyHash {
+		if item.before(element.keyHash, element.key) || element.deleted() {
 			// the new item is the smallest for this index?
 			if !atomic.CompareAndSwapPointer(ptr, unsafe.Pointer(element), unsafe.Pointer(item)) {
 				continue // a new item was inserted concurrently, retry
@@ -278,30 +344,37 @@ func (mapData *hashMapData) addItemToIndex(item *ListElement) uintptr {
 // To double the size of the hashmap use newSize 0.
 // This function returns immediately, the resize operation is done in a goroutine.
 // No resizing is done in case of another resize operation already being in progress.
-func (m *HashMap) Grow(newSize uintptr) {
+func (m *HashMap[K, V]) Grow(newSize uintptr) {
 	if atomic.CompareAndSwapUintptr(&m.resizing, uintptr(0), uintptr(1)) {
 		go m.grow(newSize, true)
 	}
 }
 
-func (m *HashMap) grow(newSize uintptr, loop bool) {
+func (m *HashMap[K, V]) grow(newSize uintptr, loop bool) {
 	defer atomic.CompareAndSwapUintptr(&m.resizing, uintptr(1), uintptr(0))
 
 	for {
 		data := m.mapData()
-		if newSize == 0 {
+		hasher := m.hasher
+		if data != nil {
+			hasher = data.hasher
+		} else if hasher == nil {
+			hasher = SipHash[K]()
+		}
+		if newSize == 0 && data == nil {
+			newSize = DefaultSize
+		} else if newSize == 0 {
 			newSize = uintptr(len(data.index)) << 1
 		} else {
 			newSize = roundUpPower2(newSize)
 		}
 
-		index := make([]*ListElement, newSize)
-		header := (*reflect.SliceHeader)(unsafe.Pointer(&index))
-
-		newdata := &hashMapData{
+		index := make([]*ListElement[K, V], newSize)
+		newdata := &hashMapData[K, V]{
 			keyshifts: strconv.IntSize - log2(newSize),
-			data:      unsafe.Pointer(header.Data), // use address of slice data storage
+			data:      unsafe.Pointer(&index[0]), // use address of slice data storage
 			index:     index,
+			hasher:    hasher,
 		}
 
 		m.fillIndexItems(newdata) // initialize new index slice with longer keys
@@ -323,7 +396,7 @@ func (m *HashMap) grow(newSize uintptr, loop bool) {
 	}
 }
 
-func (m *HashMap) fillIndexItems(mapData *hashMapData) {
+func (m *HashMap[K, V]) fillIndexItems(mapData *hashMapData[K, V]) {
 	list := m.list()
 	if list == nil {
 		return
@@ -343,7 +416,7 @@ func (m *HashMap) fillIndexItems(mapData *hashMapData) {
 }
 
 // String returns the map as a string, only hashed keys are printed.
-func (m *HashMap) String() string {
+func (m *HashMap[K, V]) String() string {
 	list := m.list()
 	if list == nil {
 		return "[]"
@@ -366,25 +439,20 @@ func (m *HashMap) String() string {
 	return buffer.String()
 }
 
-// Iter returns an iterator which could be used in a for range loop.
-// The order of the items is sorted by hash keys.
-func (m *HashMap) Iter() <-chan KeyValue {
-	ch := make(chan KeyValue) // do not use a size here since items can get added during iteration
-
-	go func() {
-		list := m.list()
-		if list == nil {
-			close(ch)
-			return
+// Range calls fn for every item of the map, ordered by key hashes, until fn returns false.
+// Items added or deleted during the iteration may or may not be seen.
+func (m *HashMap[K, V]) Range(fn func(key K, value V) bool) {
+	list := m.list()
+	if list == nil {
+		return
+	}
+	for item := list.First(); item != nil; item = item.Next() {
+		value, ok := item.load()
+		if !ok {
+			continue // deleted meanwhile
 		}
-		item := list.First()
-		for item != nil {
-			value := item.Value()
-			ch <- KeyValue{item.key, value}
-			item = item.Next()
+		if !fn(item.key, value) {
+			return
 		}
-		close(ch)
-	}()
-
-	return ch
+	}
 }
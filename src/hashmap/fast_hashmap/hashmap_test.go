@@ -0,0 +1,293 @@
+package hashmap
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+)
+
+func TestSetGetDel(t *testing.T) {
+	for name, hasher := range map[string]Hasher[string]{
+		"siphash": nil,
+		"xxh3":    XXH3[string](),
+		"murmur3": Murmur3[string](),
+		"collide": func(string) uintptr { return 42 }, // every key in one hash
+	} {
+		m := NewWithHasher[string, int](4, hasher)
+		for i := 0; i < 1000; i++ {
+			m.Set(strconv.Itoa(i), i)
+		}
+		m.Set("7", 700)
+		for i := 0; i < 1000; i += 2 {
+			m.Del(strconv.Itoa(i))
+		}
+		m.Del("missing")
+
+		if m.Len() != 500 {
+			t.Fatalf("%s: Len() = %d, want 500", name, m.Len())
+		}
+		for i := 0; i < 1000; i++ {
+			v, ok := m.Get(strconv.Itoa(i))
+			want := i
+			if i == 7 {
+				want = 700
+			}
+			if ok != (i%2 == 1) || (ok && v != want) {
+				t.Fatalf("%s: Get(%d) = %v, %v", name, i, v, ok)
+			}
+		}
+	}
+}
+
+func TestCas(t *testing.T) {
+	m := NewWithHasher[uintptr, string](4, func(key uintptr) uintptr { return key })
+	if m.Cas(1, "", "a") {
+		t.Fatal("Cas of a missing key succeeded")
+	}
+	m.Set(1, "a")
+	if m.Cas(1, "b", "c") {
+		t.Fatal("Cas with another value succeeded")
+	}
+	if !m.Cas(1, "a", "b") {
+		t.Fatal("Cas failed")
+	}
+	if v, _ := m.Get(1); v != "b" {
+		t.Fatalf("Get(1) = %q", v)
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	var m HashMap[int64, string]
+	if _, ok := m.Get(1); ok {
+		t.Fatal("found a key in an empty map")
+	}
+	m.Del(1)
+	if !m.Insert(1, "one") || m.Insert(1, "uno") {
+		t.Fatal("Insert should only add missing keys")
+	}
+	if v, ok := m.Get(1); !ok || v != "one" {
+		t.Fatalf("Get(1) = %v, %v", v, ok)
+	}
+}
+
+func TestRange(t *testing.T) {
+	m := New[int, int](8)
+	for i := 0; i < 100; i++ {
+		m.Set(i, i*i)
+	}
+
+	seen := map[int]bool{}
+	m.Range(func(k, v int) bool {
+		if v != k*k {
+			t.Fatalf("Range(%d) = %d", k, v)
+		}
+		seen[k] = true
+		return true
+	})
+	if len(seen) != 100 {
+		t.Fatalf("Range visited %d keys", len(seen))
+	}
+
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		n := 0
+		m.Range(func(k, v int) bool {
+			n++
+			return n < 3
+		})
+		if n != 3 {
+			t.Fatalf("Range did not stop, %d calls", n)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if runtime.NumGoroutine() > goroutines {
+		t.Fatalf("Range leaked goroutines: %d > %d", runtime.NumGoroutine(), goroutines)
+	}
+}
+
+func TestGetOrInsertConcurrent(t *testing.T) {
+	m := New[string, *int64](8)
+	var wg sync.WaitGroup
+	winners := make([]*int64, 16)
+	for g := range winners {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			v := new(int64)
+			winners[g], _ = m.GetOrInsert("key", v)
+		}(g)
+	}
+	wg.Wait()
+	for _, w := range winners {
+		if w != winners[0] {
+			t.Fatal("GetOrInsert returned different values for one key")
+		}
+	}
+}
+
+func TestComputeConcurrent(t *testing.T) {
+	m := New[string, int](8)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				m.Compute("counter"+strconv.Itoa(i%10), func(v int, loaded bool) (int, bool) {
+					return v + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	for i := 0; i < 10; i++ {
+		if v, _ := m.Get("counter" + strconv.Itoa(i)); v != 800 {
+			t.Fatalf("counter%d = %d, want 800", i, v)
+		}
+	}
+
+	if v, ok := m.Compute("counter0", func(v int, loaded bool) (int, bool) { return 0, false }); ok || v != 0 {
+		t.Fatalf("Compute delete = %v, %v", v, ok)
+	}
+	if _, ok := m.Get("counter0"); ok || m.Len() != 9 {
+		t.Fatalf("counter0 not deleted, Len() = %d", m.Len())
+	}
+	if _, ok := m.Compute("absent", func(v int, loaded bool) (int, bool) { return 1, false }); ok {
+		t.Fatal("Compute added a key without keep")
+	}
+}
+
+func TestConcurrentSetDel(t *testing.T) {
+	m := New[int, int](2)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				key := g*10000 + i
+				m.Set(key, i)
+				if i%3 == 0 {
+					m.Del(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	want := 0
+	for g := 0; g < 8; g++ {
+		for i := 0; i < 2000; i++ {
+			_, ok := m.Get(g*10000 + i)
+			if ok != (i%3 != 0) {
+				t.Fatalf("Get(%d) found = %v", g*10000+i, ok)
+			}
+			if ok {
+				want++
+			}
+		}
+	}
+	if m.Len() != want {
+		t.Fatalf("Len() = %d, want %d", m.Len(), want)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+const benchmarkItems = 1024
+
+func BenchmarkReadHashMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	m := New[string, int](benchmarkItems)
+	for i, k := range keys {
+		m.Set(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Get(keys[i%benchmarkItems])
+		}
+	})
+}
+
+func BenchmarkReadHashMapXXH3(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	m := NewWithHasher[string, int](benchmarkItems, XXH3[string]())
+	for i, k := range keys {
+		m.Set(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Get(keys[i%benchmarkItems])
+		}
+	})
+}
+
+func BenchmarkReadSyncMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	var m sync.Map
+	for i, k := range keys {
+		m.Store(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Load(keys[i%benchmarkItems])
+		}
+	})
+}
+
+func BenchmarkReadConcurrentMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	m := cmap.New()
+	for i, k := range keys {
+		m.Set(k, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Get(keys[i%benchmarkItems])
+		}
+	})
+}
+
+func BenchmarkWriteHashMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	m := New[string, int](benchmarkItems)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Set(keys[i%benchmarkItems], i)
+		}
+	})
+}
+
+func BenchmarkWriteSyncMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	var m sync.Map
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Store(keys[i%benchmarkItems], i)
+		}
+	})
+}
+
+func BenchmarkWriteConcurrentMap(b *testing.B) {
+	keys := benchmarkKeys(benchmarkItems)
+	m := cmap.New()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			m.Set(keys[i%benchmarkItems], i)
+		}
+	})
+}
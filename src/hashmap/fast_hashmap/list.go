@@ -5,19 +5,25 @@ import (
 	"unsafe"
 )
 
-// List is a sorted doubly linked list.
-type List struct {
+// List is a sorted lock-free linked list.
+//
+// Deleting an element takes three steps, like in Java's ConcurrentSkipListMap:
+// its value is swapped for the tombstone, then a marker element is appended
+// to it so no element can be inserted after it anymore, and finally it is
+// unlinked from its predecessor. Any traversal meeting a deleted element
+// helps with the remaining steps.
+type List[K hashable, V any] struct {
 	count uintptr
-	head  *ListElement
+	head  *ListElement[K, V]
 }
 
 // NewList returns an initialized list.
-func NewList() *List {
-	return &List{head: &ListElement{}}
+func NewList[K hashable, V any]() *List[K, V] {
+	return &List[K, V]{head: &ListElement[K, V]{}}
 }
 
 // Len returns the number of elements within the list.
-func (l *List) Len() int {
+func (l *List[K, V]) Len() int {
 	if l == nil { // not initialized yet?
 		return 0
 	}
@@ -26,7 +32,7 @@ func (l *List) Len() int {
 }
 
 // First returns the first item of the list.
-func (l *List) First() *ListElement {
+func (l *List[K, V]) First() *ListElement[K, V] {
 	if l == nil { // not initialized yet?
 		return nil
 	}
@@ -34,116 +40,110 @@ func (l *List) First() *ListElement {
 	return l.head.Next()
 }
 
-// Add adds an item to the list and returns false if an item for the hash existed.
+// Add adds an element to the list unless an element with the same key exists,
+// which is returned. inserted is false if the list was modified concurrently.
 // searchStart = nil will start to search at the head item
-func (l *List) Add(element *ListElement, searchStart *ListElement) (existed bool, inserted bool) {
-	left, found, right := l.search(searchStart, element)
+func (l *List[K, V]) Add(element *ListElement[K, V], searchStart *ListElement[K, V]) (found *ListElement[K, V], inserted bool) {
+	left, found, right := l.search(searchStart, element.keyHash, element.key)
 	if found != nil { // existing item found
-		return true, false
+		return found, false
 	}
 
-	return false, l.insertAt(element, left, right)
+	return nil, l.insertAt(element, left, right)
 }
 
-// AddOrUpdate adds or updates an item to the list.
-func (l *List) AddOrUpdate(element *ListElement, searchStart *ListElement) bool {
-	left, found, right := l.search(searchStart, element)
-	if found != nil { // existing item found
-		found.setValue(element.value) // update the value
-		return true
-	}
-
-	return l.insertAt(element, left, right)
-}
-
-// Cas compares and swaps the value of an item in the list.
-func (l *List) Cas(element *ListElement, oldValue interface{}, searchStart *ListElement) bool {
-	_, found, _ := l.search(searchStart, element)
-	if found == nil { // no existing item found
-		return false
-	}
-
-	if found.casValue(oldValue, element.value) {
-		atomic.AddUintptr(&l.count, 1)
-		return true
-	}
-	return false
+// Find returns the element of a key, nil if there is none.
+func (l *List[K, V]) Find(keyHash uintptr, key K, searchStart *ListElement[K, V]) *ListElement[K, V] {
+	_, found, _ := l.search(searchStart, keyHash, key)
+	return found
 }
 
-func (l *List) search(searchStart *ListElement, item *ListElement) (left *ListElement, found *ListElement, right *ListElement) {
-	if searchStart != nil && item.keyHash < searchStart.keyHash { // key would remain left from item? {
-		searchStart = nil // start search at head
-	}
-
-	if searchStart == nil { // start search at head?
+// search returns the element of the key, or the elements left and right
+// of where it would be inserted. Deleted elements met on the way are unlinked.
+func (l *List[K, V]) search(searchStart *ListElement[K, V], keyHash uintptr, key K) (left, found, right *ListElement[K, V]) {
+restart:
+	for {
 		left = l.head
-		found = left.Next()
-		if found == nil { // no items beside head?
-			return nil, nil, nil
+		if searchStart != nil && searchStart.before(keyHash, key) && !searchStart.deleted() {
+			left = searchStart
 		}
-	} else {
-		found = searchStart
-	}
+		searchStart = nil // start at head if the search is restarted
 
-	for {
-		if item.keyHash == found.keyHash { // key already exists
-			return nil, found, nil
-		}
+		found = left.rawNext()
+		for {
+			if found == nil { // no more items on the right
+				return left, nil, nil
+			}
+			next := found.rawNext()
+			if found.marker || left.rawNext() != found { // left is deleted or was modified concurrently
+				continue restart
+			}
+			if found.deleted() {
+				l.helpDelete(left, found, next)
+				found = left.rawNext()
+				continue
+			}
+			if left != l.head && left.deleted() {
+				continue restart
+			}
 
-		if item.keyHash < found.keyHash { // new item needs to be inserted before the found value
-			return left, nil, found
+			if found.before(keyHash, key) { // go to next element in sorted linked list
+				left = found
+				found = next
+				continue
+			}
+			if found.keyHash == keyHash && found.key == key { // key already exists
+				return left, found, next
+			}
+			return left, nil, found // new item needs to be inserted before the found value
 		}
+	}
+}
 
-		// go to next element in sorted linked list
-		left = found
-		found = left.Next()
-		if found == nil { // no more items on the right
-			return left, nil, nil
-		}
+// helpDelete appends a marker to the deleted element, or unlinks it if it is
+// already marked.
+func (l *List[K, V]) helpDelete(left, element, next *ListElement[K, V]) {
+	if next != element.rawNext() || left.rawNext() != element {
+		return // modified concurrently
 	}
+	if next == nil || !next.marker {
+		marker := &ListElement[K, V]{marker: true, nextElement: unsafe.Pointer(next)}
+		atomic.CompareAndSwapPointer(&element.nextElement, unsafe.Pointer(next), unsafe.Pointer(marker))
+		return
+	}
+	atomic.CompareAndSwapPointer(&left.nextElement, unsafe.Pointer(element), atomic.LoadPointer(&next.nextElement))
 }
 
-func (l *List) insertAt(element *ListElement, left *ListElement, right *ListElement) bool {
-	if left == nil { // insert at head
-		if !atomic.CompareAndSwapPointer(&l.head.nextElement, unsafe.Pointer(nil), unsafe.Pointer(element)) {
-			return false // item was modified concurrently
-		}
-	} else {
-		element.previousElement = unsafe.Pointer(left)
-		element.nextElement = unsafe.Pointer(right)
-		if !atomic.CompareAndSwapPointer(&left.nextElement, unsafe.Pointer(right), unsafe.Pointer(element)) {
-			return false // item was modified concurrently
-		}
+func (l *List[K, V]) insertAt(element *ListElement[K, V], left *ListElement[K, V], right *ListElement[K, V]) bool {
+	element.nextElement = unsafe.Pointer(right)
+	if !atomic.CompareAndSwapPointer(&left.nextElement, unsafe.Pointer(right), unsafe.Pointer(element)) {
+		return false // item was modified concurrently
 	}
 
 	atomic.AddUintptr(&l.count, 1)
 	return true
 }
 
-// Delete deletes an element from the list.
-func (l *List) Delete(element *ListElement) {
-	if !atomic.CompareAndSwapUintptr(&element.deleted, uintptr(0), uintptr(1)) {
-		return // concurrent delete of the item in progress
-	}
-
+// Delete deletes an element from the list and returns false if it was already deleted.
+func (l *List[K, V]) Delete(element *ListElement[K, V], searchStart *ListElement[K, V]) bool {
 	for {
-		left := element.Previous()
-		right := element.Next()
-
-		if left == nil { // element is first item in list?
-			if !atomic.CompareAndSwapPointer(&l.head.nextElement, unsafe.Pointer(element), unsafe.Pointer(right)) {
-				continue // now head item was inserted concurrently
-			}
-		} else {
-			if !atomic.CompareAndSwapPointer(&left.nextElement, unsafe.Pointer(element), unsafe.Pointer(right)) {
-				continue // item was modified concurrently
-			}
+		value := atomic.LoadPointer(&element.value)
+		if value == tombstone {
+			return false // concurrent delete of the item
 		}
-		if right != nil {
-			atomic.CompareAndSwapPointer(&right.previousElement, unsafe.Pointer(element), unsafe.Pointer(left))
+		if l.deleteValue(element, value, searchStart) {
+			return true
 		}
-		break
 	}
+}
 
+// deleteValue deletes an element if its value is still value.
+func (l *List[K, V]) deleteValue(element *ListElement[K, V], value unsafe.Pointer, searchStart *ListElement[K, V]) bool {
+	if !atomic.CompareAndSwapPointer(&element.value, value, tombstone) {
+		return false
+	}
 	atomic.AddUintptr(&l.count, ^uintptr(0)) // decrease counter
+
+	l.search(searchStart, element.keyHash, element.key) // unlink it
+	return true
 }
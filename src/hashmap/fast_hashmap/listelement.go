@@ -5,35 +5,70 @@ import (
 	"unsafe"
 )
 
-type ListElement struct {
-	keyHash         uintptr
-	previousElement unsafe.Pointer
-	nextElement     unsafe.Pointer
-	key             interface{}
-	value           unsafe.Pointer
-	deleted         uintptr
+// tombstone is the value of deleted elements, an element is deleted
+// as soon as its value is swapped for it.
+var tombstone = unsafe.Pointer(new(byte))
+
+type ListElement[K hashable, V any] struct {
+	keyHash     uintptr
+	nextElement unsafe.Pointer
+	key         K
+	value       unsafe.Pointer // *V or tombstone
+	marker      bool           // appended to deleted elements
 }
 
-func (e *ListElement) Value() (value interface{}) {
-	return *(*interface{})(atomic.LoadPointer(&e.value))
+// Key returns the key of the element.
+func (e *ListElement[K, V]) Key() K {
+	return e.key
 }
 
-func (e *ListElement) Next() *ListElement {
-	return (*ListElement)(atomic.LoadPointer(&e.nextElement))
+// Value returns the value of the element, the zero value if it was deleted.
+func (e *ListElement[K, V]) Value() (value V) {
+	v, _ := e.load()
+	return v
 }
 
-func (e *ListElement) Previous() *ListElement {
-	return (*ListElement)(atomic.LoadPointer(&e.previousElement))
+func (e *ListElement[K, V]) load() (value V, ok bool) {
+	p := atomic.LoadPointer(&e.value)
+	if p == tombstone {
+		return value, false
+	}
+	return *(*V)(p), true
 }
 
-func (e *ListElement) setValue(value unsafe.Pointer) {
-	atomic.StorePointer(&e.value, value)
+func (e *ListElement[K, V]) deleted() bool {
+	return atomic.LoadPointer(&e.value) == tombstone
 }
 
-func (e *ListElement) casValue(from interface{}, to unsafe.Pointer) bool {
-	old := atomic.LoadPointer(&e.value)
-	if *(*interface{})(old) != from {
-		return false
+// Next returns the next element which is not deleted.
+func (e *ListElement[K, V]) Next() *ListElement[K, V] {
+	next := e.rawNext()
+	for next != nil && (next.marker || next.deleted()) {
+		next = next.rawNext()
 	}
-	return atomic.CompareAndSwapPointer(&e.value, old, to)
+	return next
+}
+
+// rawNext returns the next element, deleted and marker ones included.
+func (e *ListElement[K, V]) rawNext() *ListElement[K, V] {
+	return (*ListElement[K, V])(atomic.LoadPointer(&e.nextElement))
+}
+
+// setValue replaces the value unless the element was deleted.
+func (e *ListElement[K, V]) setValue(value unsafe.Pointer) bool {
+	for {
+		old := atomic.LoadPointer(&e.value)
+		if old == tombstone {
+			return false
+		}
+		if atomic.CompareAndSwapPointer(&e.value, old, value) {
+			return true
+		}
+	}
+}
+
+// before tells if the element is ordered before the hash and key.
+// Elements are sorted by hash, then by key for colliding hashes.
+func (e *ListElement[K, V]) before(keyHash uintptr, key K) bool {
+	return e.keyHash < keyHash || (e.keyHash == keyHash && e.key < key)
 }
@@ -1,12 +1,13 @@
 package hashmap
 
 import (
-	"fmt"
 	"reflect"
 	"strconv"
 	"unsafe"
 
 	"github.com/dchest/siphash"
+	"github.com/pathbox/learning-go/src/hashmap/murmur3"
+	"github.com/pathbox/learning-go/src/xxh3"
 )
 
 const (
@@ -16,8 +17,56 @@ const (
 	// generated by splitting the md5 sum of "hashmap"
 	sipHashKey1 = 0xdda7806a4847ec61
 	sipHashKey2 = 0xb5940c2623a5aabd
+
+	// second murmur3 seed, two 32 bit hashes make a 64 bit one
+	murmur3Seed2 = 0x9747b28c
 )
 
+// hashable are the key types supported by the map: strings and integers
+type hashable interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Hasher returns the hash of a key.
+// The high bits of the hash select the index slot, so they must be well distributed.
+type Hasher[K hashable] func(key K) uintptr
+
+// SipHash returns a hasher using SipHash-2-4, the default one.
+func SipHash[K hashable]() Hasher[K] {
+	return bytesHasher[K](func(b []byte) uintptr {
+		return uintptr(siphash.Hash(sipHashKey1, sipHashKey2, b))
+	})
+}
+
+// XXH3 returns a hasher using xxh3.
+func XXH3[K hashable]() Hasher[K] {
+	return bytesHasher[K](func(b []byte) uintptr {
+		return uintptr(xxh3.Hash(b, 0))
+	})
+}
+
+// Murmur3 returns a hasher using murmur3 with two seeds.
+func Murmur3[K hashable]() Hasher[K] {
+	return bytesHasher[K](func(b []byte) uintptr {
+		return uintptr(uint64(murmur3.Sum32SeedBytes(b, 0))<<32 | uint64(murmur3.Sum32SeedBytes(b, murmur3Seed2)))
+	})
+}
+
+// bytesHasher hashes the bytes of string keys, or the memory of integer keys.
+func bytesHasher[K hashable](hash func([]byte) uintptr) Hasher[K] {
+	var zero K
+	if reflect.TypeOf(zero).Kind() == reflect.String {
+		return func(key K) uintptr {
+			s := *(*string)(unsafe.Pointer(&key))
+			return hash(unsafe.Slice(*(**byte)(unsafe.Pointer(&s)), len(s)))
+		}
+	}
+	return func(key K) uintptr {
+		return hash(unsafe.Slice((*byte)(unsafe.Pointer(&key)), unsafe.Sizeof(key)))
+	}
+}
+
 // roundUpPower2 rounds a number to the next power of 2.
 func roundUpPower2(i uintptr) uintptr {
 	i--
@@ -38,57 +87,3 @@ func log2(i uintptr) uintptr {
 	}
 	return n
 }
-
-// getKeyHash returns a hash for the key. Only string and number types are supported.
-func getKeyHash(key interface{}) uintptr {
-	switch x := key.(type) {
-	case string:
-		return getStringHash(x)
-	case []byte:
-		return uintptr(siphash.Hash(sipHashKey1, sipHashKey2, x))
-	case int:
-		return getUintptrHash(uintptr(x))
-	case int8:
-		return getUintptrHash(uintptr(x))
-	case int16:
-		return getUintptrHash(uintptr(x))
-	case int32:
-		return getUintptrHash(uintptr(x))
-	case int64:
-		return getUintptrHash(uintptr(x))
-	case uint:
-		return getUintptrHash(uintptr(x))
-	case uint8:
-		return getUintptrHash(uintptr(x))
-	case uint16:
-		return getUintptrHash(uintptr(x))
-	case uint32:
-		return getUintptrHash(uintptr(x))
-	case uint64:
-		return getUintptrHash(uintptr(x))
-	case uintptr:
-		return getUintptrHash(x)
-	}
-	panic(fmt.Errorf("unsupported key type %T", key))
-}
-
-func getStringHash(s string) uintptr {
-	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
-	bh := reflect.SliceHeader{
-		Data: sh.Data,
-		Len:  sh.Len,
-		Cap:  sh.Len,
-	}
-	buf := *(*[]byte)(unsafe.Pointer(&bh))
-	return uintptr(siphash.Hash(sipHashKey1, sipHashKey2, buf))
-}
-
-func getUintptrHash(num uintptr) uintptr {
-	bh := reflect.SliceHeader{
-		Data: uintptr(unsafe.Pointer(&num)),
-		Len:  intSizeBytes,
-		Cap:  intSizeBytes,
-	}
-	buf := *(*[]byte)(unsafe.Pointer(&bh))
-	return uintptr(siphash.Hash(sipHashKey1, sipHashKey2, buf))
-}
@@ -1,7 +1,6 @@
 package murmur3
 
 import (
-	"reflect"
 	"unsafe"
 )
 
@@ -11,10 +10,7 @@ func Sum32Bytes(key []byte) uint32 {
 
 // Sum32SeedBytes returns a hash from the provided key using the specified seed.
 func Sum32SeedBytes(key []byte, seed uint32) uint32 {
-	return Sum32Seed(*(*string)((unsafe.Pointer)(&reflect.StringHeader{
-		Len:  len(key),
-		Data: (*reflect.SliceHeader)(unsafe.Pointer(&key)).Data,
-	})), seed)
+	return Sum32Seed(*(*string)(unsafe.Pointer(&key)), seed)
 }
 
 // Sum32 returns a hash from the provided key.
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Algorithm picks the backend for a request among the alive backends
+type Algorithm interface {
+	Next(backends []*Backend, r *http.Request) *Backend
+}
+
+// NewAlgorithm returns the algorithm with the given name.
+// hashKey is only used by consistent_hash and has the form "header:<name>" or "cookie:<name>".
+func NewAlgorithm(name, hashKey string) (Algorithm, error) {
+	switch name {
+	case "", "round_robin":
+		return &RoundRobin{}, nil
+	case "weighted_round_robin":
+		return &WeightedRoundRobin{}, nil
+	case "least_conn":
+		return LeastConnections{}, nil
+	case "p2c":
+		return &PowerOfTwoChoices{rand: rand.New(rand.NewSource(rand.Int63()))}, nil
+	case "consistent_hash":
+		parts := strings.SplitN(hashKey, ":", 2)
+		if len(parts) != 2 || (parts[0] != "header" && parts[0] != "cookie") || parts[1] == "" {
+			return nil, fmt.Errorf("invalid hash key %q, want header:<name> or cookie:<name>", hashKey)
+		}
+		return &ConsistentHash{FromCookie: parts[0] == "cookie", Key: parts[1]}, nil
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", name)
+	}
+}
+
+// RoundRobin sends requests to the backends in turn
+type RoundRobin struct {
+	current uint64
+}
+
+// Next implements Algorithm
+func (a *RoundRobin) Next(backends []*Backend, r *http.Request) *Backend {
+	next := atomic.AddUint64(&a.current, uint64(1))
+	return backends[next%uint64(len(backends))]
+}
+
+// WeightedRoundRobin is the smooth weighted round-robin used by nginx.
+// A backend with weight 0 is treated as weight 1.
+// The current weights belong to the algorithm, not to the backends, which
+// are shared with the pool being replaced during a reload.
+type WeightedRoundRobin struct {
+	mux     sync.Mutex
+	current map[*Backend]int
+}
+
+// Next implements Algorithm
+func (a *WeightedRoundRobin) Next(backends []*Backend, r *http.Request) *Backend {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+
+	if a.current == nil {
+		a.current = make(map[*Backend]int)
+	}
+	var best *Backend
+	total := 0
+	for _, b := range backends {
+		w := b.weight()
+		if w <= 0 {
+			w = 1
+		}
+		total += w
+		a.current[b] += w
+		if best == nil || a.current[b] > a.current[best] {
+			best = b
+		}
+	}
+	a.current[best] -= total
+	return best
+}
+
+// LeastConnections sends requests to the backend with the fewest in-flight requests
+type LeastConnections struct{}
+
+// Next implements Algorithm
+func (LeastConnections) Next(backends []*Backend, r *http.Request) *Backend {
+	best := backends[0]
+	for _, b := range backends[1:] {
+		if b.Connections() < best.Connections() {
+			best = b
+		}
+	}
+	return best
+}
+
+// PowerOfTwoChoices picks two random backends and sends the request to the less loaded one
+type PowerOfTwoChoices struct {
+	mux  sync.Mutex
+	rand *rand.Rand
+}
+
+// Next implements Algorithm
+func (a *PowerOfTwoChoices) Next(backends []*Backend, r *http.Request) *Backend {
+	if len(backends) == 1 {
+		return backends[0]
+	}
+
+	a.mux.Lock()
+	i := a.rand.Intn(len(backends))
+	j := a.rand.Intn(len(backends) - 1)
+	a.mux.Unlock()
+	if j >= i {
+		j++
+	}
+
+	if backends[j].Connections() < backends[i].Connections() {
+		return backends[j]
+	}
+	return backends[i]
+}
+
+// ConsistentHash sends requests with the same header or cookie value to the same backend.
+// It uses rendezvous hashing, so only the keys of a removed backend move elsewhere.
+// Requests without the key are spread with round-robin instead of all hashing to one backend.
+type ConsistentHash struct {
+	FromCookie bool
+	Key        string
+
+	fallback RoundRobin
+}
+
+// Next implements Algorithm
+func (a *ConsistentHash) Next(backends []*Backend, r *http.Request) *Backend {
+	key := a.value(r)
+	if key == "" {
+		return a.fallback.Next(backends, r)
+	}
+
+	var best *Backend
+	var bestScore uint64
+	for _, b := range backends {
+		h := fnv.New64a()
+		h.Write([]byte(b.URL.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		score := h.Sum64()
+		if best == nil || score > bestScore {
+			best, bestScore = b, score
+		}
+	}
+	return best
+}
+
+func (a *ConsistentHash) value(r *http.Request) string {
+	if !a.FromCookie {
+		return r.Header.Get(a.Key)
+	}
+	c, err := r.Cookie(a.Key)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
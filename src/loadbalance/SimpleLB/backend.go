@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend holds the data about a server
+type Backend struct {
+	URL          *url.URL
+	Weight       int
+	Alive        bool
+	mux          sync.RWMutex
+	ReverseProxy *httputil.ReverseProxy
+
+	// conns is the number of requests currently proxied to the backend
+	conns int64
+	// ejectedUntil is set by passive health checking on proxy errors
+	ejectedUntil time.Time
+	// passes and fails count consecutive active health check results
+	passes int
+	fails  int
+}
+
+// SetAlive for this backend
+func (b *Backend) SetAlive(alive bool) {
+	b.mux.Lock()
+	b.Alive = alive
+	if alive {
+		b.ejectedUntil = time.Time{}
+	}
+	b.mux.Unlock()
+}
+
+// weight returns the configured weight of the backend
+func (b *Backend) weight() int {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+	return b.Weight
+}
+
+// IsAlive returns true when backend is alive and not ejected
+func (b *Backend) IsAlive() (alive bool) {
+	b.mux.RLock()
+	alive = b.Alive && !time.Now().Before(b.ejectedUntil)
+	b.mux.RUnlock()
+	return
+}
+
+// Eject takes the backend out of rotation for d, or until the next successful health check if d is 0
+func (b *Backend) Eject(d time.Duration) {
+	b.mux.Lock()
+	if d > 0 {
+		b.ejectedUntil = time.Now().Add(d)
+	} else {
+		b.Alive = false
+	}
+	b.mux.Unlock()
+}
+
+// Connections returns the number of in-flight requests on the backend
+func (b *Backend) Connections() int64 {
+	return atomic.LoadInt64(&b.conns)
+}
+
+// ServeHTTP proxies the request to the backend and keeps track of in-flight requests
+func (b *Backend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt64(&b.conns, 1)
+	defer atomic.AddInt64(&b.conns, -1)
+	b.ReverseProxy.ServeHTTP(w, r)
+}
+
+// recordCheck counts a health check result and returns the resulting status.
+// The status only flips after healthy or unhealthy consecutive results.
+func (b *Backend) recordCheck(ok bool, healthy, unhealthy int) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if ok {
+		b.passes++
+		b.fails = 0
+		if !b.Alive && b.passes >= healthy {
+			b.Alive = true
+			b.ejectedUntil = time.Time{}
+		}
+	} else {
+		b.fails++
+		b.passes = 0
+		if b.Alive && b.fails >= unhealthy {
+			b.Alive = false
+		}
+	}
+	return b.Alive
+}
+
+// ServerPool holds information about reachable backends
+type ServerPool struct {
+	backends      []*Backend
+	algorithm     Algorithm
+	ejectDuration time.Duration
+}
+
+// NewServerPool returns an empty pool balancing with the given algorithm
+func NewServerPool(algorithm Algorithm, ejectDuration time.Duration) *ServerPool {
+	return &ServerPool{algorithm: algorithm, ejectDuration: ejectDuration}
+}
+
+// AddBackend to the server pool
+func (s *ServerPool) AddBackend(backend *Backend) {
+	s.backends = append(s.backends, backend)
+}
+
+// Backends returns every backend of the pool
+func (s *ServerPool) Backends() []*Backend {
+	return s.backends
+}
+
+// MarkBackendStatus changes a status of a backend.
+// Marking a backend down ejects it for the pool's eject duration.
+func (s *ServerPool) MarkBackendStatus(backendUrl *url.URL, alive bool) {
+	for _, b := range s.backends {
+		if b.URL.String() == backendUrl.String() {
+			if alive {
+				b.SetAlive(true)
+			} else {
+				b.Eject(s.ejectDuration)
+			}
+			break
+		}
+	}
+}
+
+// GetNextPeer returns next active peer to take a connection
+func (s *ServerPool) GetNextPeer(r *http.Request) *Backend {
+	alive := make([]*Backend, 0, len(s.backends))
+	for _, b := range s.backends {
+		if b.IsAlive() {
+			alive = append(alive, b)
+		}
+	}
+	if len(alive) == 0 {
+		return nil
+	}
+	return s.algorithm.Next(alive, r)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the YAML configuration of the load balancer.
+//
+//	port: 3030
+//	algorithm: consistent_hash
+//	hash_key: header:X-User-ID
+//	eject_duration: 30s
+//	health_check:
+//	  path: /health
+//	  interval: 10s
+//	  timeout: 2s
+//	  healthy_threshold: 2
+//	  unhealthy_threshold: 3
+//	backends:
+//	  - url: http://localhost:3031
+//	    weight: 3
+//	  - url: http://localhost:3032
+type Config struct {
+	Port          int               `yaml:"port"`
+	Algorithm     string            `yaml:"algorithm"`
+	HashKey       string            `yaml:"hash_key"`
+	EjectDuration time.Duration     `yaml:"eject_duration"`
+	HealthCheck   HealthCheckConfig `yaml:"health_check"`
+	Backends      []BackendConfig   `yaml:"backends"`
+}
+
+// HealthCheckConfig configures active health checking.
+// An empty Path checks the backend by establishing a TCP connection.
+type HealthCheckConfig struct {
+	Path               string        `yaml:"path"`
+	Interval           time.Duration `yaml:"interval"`
+	Timeout            time.Duration `yaml:"timeout"`
+	HealthyThreshold   int           `yaml:"healthy_threshold"`
+	UnhealthyThreshold int           `yaml:"unhealthy_threshold"`
+}
+
+// BackendConfig describes one backend
+type BackendConfig struct {
+	URL    string `yaml:"url"`
+	Weight int    `yaml:"weight"`
+}
+
+// LoadConfig reads and validates the config file at path
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.Port == 0 {
+		c.Port = 3030
+	}
+	if c.EjectDuration <= 0 {
+		c.EjectDuration = 30 * time.Second
+	}
+	if c.HealthCheck.Interval <= 0 {
+		c.HealthCheck.Interval = 2 * time.Minute
+	}
+	if c.HealthCheck.Timeout <= 0 {
+		c.HealthCheck.Timeout = 2 * time.Second
+	}
+	if c.HealthCheck.HealthyThreshold <= 0 {
+		c.HealthCheck.HealthyThreshold = 1
+	}
+	if c.HealthCheck.UnhealthyThreshold <= 0 {
+		c.HealthCheck.UnhealthyThreshold = 1
+	}
+}
+
+func (c *Config) validate() error {
+	if len(c.Backends) == 0 {
+		return fmt.Errorf("no backends configured")
+	}
+	for _, b := range c.Backends {
+		if _, err := url.Parse(b.URL); err != nil {
+			return err
+		}
+		if b.Weight < 0 {
+			return fmt.Errorf("backend %s: negative weight", b.URL)
+		}
+	}
+	_, err := NewAlgorithm(c.Algorithm, c.HashKey)
+	return err
+}
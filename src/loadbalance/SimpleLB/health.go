@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HealthCheck pings the backends and update the status
+func (s *ServerPool) HealthCheck(hc HealthCheckConfig) {
+	for _, b := range s.backends {
+		status := "up"
+		alive := b.recordCheck(isBackendAlive(b.URL, hc), hc.HealthyThreshold, hc.UnhealthyThreshold)
+		if !alive {
+			status = "down"
+		}
+		log.Printf("%s [%s]\n", b.URL, status)
+	}
+}
+
+// isBackendAlive checks whether a backend is alive with a GET of the health check path,
+// or by establishing a TCP connection when no path is configured
+func isBackendAlive(u *url.URL, hc HealthCheckConfig) bool {
+	if hc.Path == "" {
+		conn, err := net.DialTimeout("tcp", u.Host, hc.Timeout)
+		if err != nil {
+			log.Println("Site unreachable, error: ", err)
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	client := http.Client{Timeout: hc.Timeout}
+	resp, err := client.Get(u.ResolveReference(&url.URL{Path: hc.Path}).String())
+	if err != nil {
+		log.Println("Site unreachable, error: ", err)
+		return false
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 400
+}
+
+// healthCheck runs a routine for check status of the backends at the configured interval.
+// The interval is read again after every round so reloads take effect.
+func healthCheck(l *LoadBalancer) {
+	for {
+		time.Sleep(l.Config().HealthCheck.Interval)
+
+		log.Println("Starting health check...")
+		l.Pool().HealthCheck(l.Config().HealthCheck)
+		log.Println("Health check completed")
+	}
+}
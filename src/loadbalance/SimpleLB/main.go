@@ -5,13 +5,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"os/signal"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -20,102 +22,132 @@ const (
 	Retry
 )
 
-// Backend holds the data about a server
-type Backend struct {
-	URL          *url.URL
-	Alive        bool
-	mux          sync.RWMutex
-	ReverseProxy *httputilReverseProxy
+// GetAttemptsFromContext returns the attempts for request
+func GetAttemptsFromContext(r *http.Request) int {
+	if attempts, ok := r.Context().Value(Attempts).(int); ok {
+		return attempts
+	}
+	return 1
+}
+
+// GetAttemptsFromContext returns the attempts for request
+func GetRetryFromContext(r *http.Request) int {
+	if retry, ok := r.Context().Value(Retry).(int); ok {
+		return retry
+	}
+	return 0
 }
 
-// SetAlive for this backend
-func (b *Backend) SetAlive(alive bool) {
-	b.mux.Lock()
-	b.Alive = alive
-	b.mux.Unlock()
+// LoadBalancer serves requests from the current server pool.
+// Reload swaps the pool and config atomically, so in-flight requests are not dropped.
+type LoadBalancer struct {
+	state atomic.Value // *lbState
+	mux   sync.Mutex   // serializes reloads
 }
 
-// IsAlive returns true when backend is alive
-func (b *Backend) IsAlive() (alive bool) {
-	b.mux.RLock()
-	alive = b.Alive
-	b.mux.RUnlock()
-	return
+type lbState struct {
+	cfg  *Config
+	pool *ServerPool
 }
 
-// ServerPool holds information about reachable backends
-type ServerPool struct {
-	backends []*Backend
-	current  uint64
+// NewLoadBalancer returns a load balancer configured with cfg
+func NewLoadBalancer(cfg *Config) (*LoadBalancer, error) {
+	l := &LoadBalancer{}
+	if err := l.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
 }
 
-// AddBackend to the server pool
-func (s *ServerPool) AddBackend(backend *Backend) {
-	s.backends = append(s.backends, backend)
+// Pool returns the current server pool
+func (l *LoadBalancer) Pool() *ServerPool {
+	return l.state.Load().(*lbState).pool
 }
 
-// NextIndex atomically increase the counter and return an index
-func (s *ServerPool) NextIndex() int {
-	return int(atomic.AddUint64(&s.current, uint64(1)) % uint64(len(s.backends)))
+// Config returns the current config
+func (l *LoadBalancer) Config() *Config {
+	return l.state.Load().(*lbState).cfg
 }
 
-// MarkBackendStatus changes a status of a backend
-func (s *ServerPool) MarkBackendStatus(backendUrl *url.URL, alive bool) {
-	for _, b := range s.backends {
-		if b.URL.String() == backendUrl.String() {
-			b.SetAlive(alive)
-			break
+// Reload builds a new server pool from cfg and swaps it in.
+// Backends with an unchanged URL keep their health state and connection counts.
+func (l *LoadBalancer) Reload(cfg *Config) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	algorithm, err := NewAlgorithm(cfg.Algorithm, cfg.HashKey)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*Backend)
+	if old, ok := l.state.Load().(*lbState); ok {
+		if old.cfg.Port != cfg.Port {
+			log.Printf("Port change to %d is ignored until restart\n", cfg.Port)
+			cfg.Port = old.cfg.Port
+		}
+		for _, b := range old.pool.Backends() {
+			existing[b.URL.String()] = b
 		}
 	}
-}
 
-// GetNextPeer returns next active peer to take a connection
-func (s *ServerPool) GetNextPeer() *Backend {
-	next := s.NextIndex()
-	l := len(s.backends) + next
-	for i := next; i < l; i++ {
-		idx := i % len(s.backends)
-		if s.backends[idx].IsAlive() {
-			if i != next {
-				atomic.StoreUint64(&s.current, uint64(idx))
-			}
-			return s.backends[idx]
+	pool := NewServerPool(algorithm, cfg.EjectDuration)
+	for _, bc := range cfg.Backends {
+		serverUrl, err := url.Parse(bc.URL)
+		if err != nil {
+			return err
+		}
+
+		b, ok := existing[serverUrl.String()]
+		if ok {
+			b.mux.Lock()
+			b.Weight = bc.Weight
+			b.mux.Unlock()
+		} else {
+			b = l.newBackend(serverUrl, bc.Weight)
 		}
+		pool.AddBackend(b)
+		log.Printf("Configured server: %s\n", serverUrl)
 	}
+
+	l.state.Store(&lbState{cfg: cfg, pool: pool})
 	return nil
 }
 
-// HealthCheck pings the backends and update the status
-func (s *ServerPool) HealthCheck() {
-	for _, b := range s.backends {
-		status := "up"
-		alive := isBackendAlive(b.URL)
-		b.SetAlive(alive)
-		if !alive {
-			status = "down"
+func (l *LoadBalancer) newBackend(serverUrl *url.URL, weight int) *Backend {
+	proxy := httputil.NewSingleHostReverseProxy(serverUrl)
+	proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, e error) {
+		log.Printf("[%s] %s\n", serverUrl.Host, e.Error())
+		retries := GetRetryFromContext(request)
+		if retries < 3 {
+			select {
+			case <-time.After(10 * time.Millisecond):
+				ctx := context.WithValue(request.Context(), Retry, retries+1)
+				proxy.ServeHTTP(writer, request.WithContext(ctx))
+			}
+			return
 		}
-		log.Printf("%s [%s]\n", b.URL, status)
-	}
-}
 
-// GetAttemptsFromContext returns the attempts for request
-func GetAttemptsFromContext(r *http.Request) int {
-	if attempts, ok := r.Context().Value(Attempts).(int); ok {
-		return attempts
+		// after 3 retries, eject this backend
+		l.Pool().MarkBackendStatus(serverUrl, false)
+
+		// if the same request routing for few attempts with different backends, increase the count
+		attempts := GetAttemptsFromContext(request)
+		log.Printf("%s(%s) Attempting retry %d\n", request.RemoteAddr, request.URL.Path, attempts)
+		ctx := context.WithValue(request.Context(), Attempts, attempts+1)
+		l.ServeHTTP(writer, request.WithContext(ctx))
 	}
-	return 1
-}
 
-// GetAttemptsFromContext returns the attempts for request
-func GetRetryFromContext(r *http.Request) int {
-	if retry, ok := r.Context().Value(Retry).(int); ok {
-		return retry
+	return &Backend{
+		URL:          serverUrl,
+		Weight:       weight,
+		Alive:        true,
+		ReverseProxy: proxy,
 	}
-	return 0
 }
 
-// lb load balances the incoming request
-func lb(w http.ResponseWriter, r *http.Request) {
+// ServeHTTP load balances the incoming request
+func (l *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	attempts := GetAttemptsFromContext(r)
 	if attempts > 3 {
 		log.Printf("%s(%s) Max attempts reached, terminating\n", r.RemoteAddr, r.URL.Path)
@@ -123,102 +155,81 @@ func lb(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	peer := serverPool.GetNextPeer()
+	peer := l.Pool().GetNextPeer(r)
 	if peer != nil {
-		peer.ReverseProxy.ServeHTTP(w, r)
+		peer.ServeHTTP(w, r)
 		return
 	}
 	http.Error(w, "Service not available", http.StatusServiceUnavailable)
 }
 
-// isAlive checks whether a backend is Alive by establishing a TCP connection
-func isBackendAlive(u *url.URL) bool {
-	timeout := 2 * time.Second
-	conn, err := net.DialTimeout("tcp", u.Host, timeout)
-	if err != nil {
-		log.Println("Site unreachable, error: ", err)
-		return false
-	}
-	_ = conn.Close()
-	return true
-}
-
-// healthCheck runs a routine for check status of the backends every 2 mins
-func healthCheck() {
-	t := time.NewTicker(time.Minute * 2)
-	for {
-		select {
-		case <-t.C:
-
-			log.Println("Starting health check...")
-			serverPool.HealthCheck()
-			log.Println("Health check completed")
+// reloadOnSignal reloads the config file whenever the process receives SIGHUP
+func reloadOnSignal(l *LoadBalancer, configPath string) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			log.Printf("Reload failed, keeping current config: %v\n", err)
+			continue
 		}
+		if err := l.Reload(cfg); err != nil {
+			log.Printf("Reload failed, keeping current config: %v\n", err)
+			continue
+		}
+		log.Println("Config reloaded")
 	}
 }
 
-var serverPool ServerPool
-
 func main() {
 	var serverList string
+	var configPath string
 	var port int
 	flag.StringVar(&serverList, "backends", "", "Load balanced backends, use commas to separate")
+	flag.StringVar(&configPath, "config", "", "YAML config file, reloaded on SIGHUP")
 	flag.IntVar(&port, "port", 3030, "Port to serve")
 	flag.Parse()
 
-	if len(serverList) == 0 {
-		log.Fatal("Please provide one or more backends to load balance")
-	}
-
-	// parse servers
-	tokens := strings.Split(serverList, ",")
-	for _, tok := range tokens {
-		serverUrl, err := url.Parse(tok)
+	var cfg *Config
+	if configPath != "" {
+		var err error
+		cfg, err = LoadConfig(configPath)
 		if err != nil {
 			log.Fatal(err)
 		}
+	} else {
+		if len(serverList) == 0 {
+			log.Fatal("Please provide one or more backends to load balance")
+		}
 
-		proxy := httputil.NewSingleHostReverseProxy(serverUrl)
-		proxy.ErrorHandler = func(writer http.ResponseWriter, request *http.Request, e error) {
-			log.Printf("[%s] %s\n", serverUrl.Host, e.Error())
-			retries := GetRetryFromContext(request)
-			if retries < 3 {
-				select {
-				case <-time.After(10 * time.Millisecond):
-					ctx := context.WithValue(request.Context(), Retry, retries+1)
-					proxy.ServeHTTP(writer, request.WithContext(ctx))
-				}
-				return
-			}
-
-			// after 3 retries, mark this backend as down
-			serverPool.MarkBackendStatus(serverUrl, false)
-
-			// if the same request routing for few attempts with different backends, increase the count
-			attempts := GetAttemptsFromContext(request)
-			log.Printf("%s(%s) Attempting retry %d\n", request.RemoteAddr, request.URL.Path, attempts)
-			ctx := context.WithValue(request.Context(), Attempts, attempts+1)
-			lb(writer, request.WithContext(ctx))
+		cfg = &Config{Port: port}
+		for _, tok := range strings.Split(serverList, ",") {
+			cfg.Backends = append(cfg.Backends, BackendConfig{URL: tok})
 		}
+		cfg.setDefaults()
+		if err := cfg.validate(); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-		serverPool.AddBackend(&Backend{
-			URL:          serverUrl,
-			Alive:        true,
-			ReverseProxy: proxy,
-		})
-		log.Printf("Configured server: %s\n", serverUrl)
+	l, err := NewLoadBalancer(cfg)
+	if err != nil {
+		log.Fatal(err)
 	}
 
 	// create http server
 	server := http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: http.HandlerFunc(lb),
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		Handler: l,
 	}
 
 	// start health checking
-	go healthCheck()
+	go healthCheck(l)
+	if configPath != "" {
+		go reloadOnSignal(l, configPath)
+	}
 
-	log.Printf("Load Balancer started at :%d\n", port)
+	log.Printf("Load Balancer started at :%d\n", cfg.Port)
 	if err := server.ListenAndServe(); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestBackend returns a backend server answering with its name
+func newTestBackend(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}))
+}
+
+func newTestBalancer(t *testing.T, cfg *Config) *LoadBalancer {
+	cfg.setDefaults()
+	l, err := NewLoadBalancer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func get(t *testing.T, h http.Handler, header http.Header) string {
+	r := httptest.NewRequest("GET", "/", nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Body.String()
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	a, b := newTestBackend("a"), newTestBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	l := newTestBalancer(t, &Config{
+		Algorithm: "weighted_round_robin",
+		Backends:  []BackendConfig{{URL: a.URL, Weight: 3}, {URL: b.URL, Weight: 1}},
+	})
+
+	var got string
+	for i := 0; i < 8; i++ {
+		got += get(t, l, nil)
+	}
+	if got != "aabaaaba" {
+		t.Fatalf("got %q, want smooth 3:1 distribution", got)
+	}
+}
+
+func TestWeightedRoundRobinSharedBackends(t *testing.T) {
+	// during a reload the old and the new pool pick from the same backends
+	backends := []*Backend{{Weight: 3, Alive: true}, {Weight: 1, Alive: true}}
+	old, cur := &WeightedRoundRobin{}, &WeightedRoundRobin{}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			old.Next(backends, nil)
+		}
+		close(done)
+	}()
+	picks := map[*Backend]int{}
+	for i := 0; i < 400; i++ {
+		picks[cur.Next(backends, nil)]++
+	}
+	<-done
+
+	if picks[backends[0]] != 300 || picks[backends[1]] != 100 {
+		t.Fatalf("picks = %d:%d, want 300:100", picks[backends[0]], picks[backends[1]])
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	a, b := newTestBackend("a"), newTestBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	l := newTestBalancer(t, &Config{
+		Algorithm: "least_conn",
+		Backends:  []BackendConfig{{URL: a.URL}, {URL: b.URL}},
+	})
+
+	atomic.AddInt64(&l.Pool().Backends()[0].conns, 5)
+	for i := 0; i < 3; i++ {
+		if got := get(t, l, nil); got != "b" {
+			t.Fatalf("got %q, want least loaded backend b", got)
+		}
+	}
+}
+
+func TestPowerOfTwoChoices(t *testing.T) {
+	a, b := newTestBackend("a"), newTestBackend("b")
+	defer a.Close()
+	defer b.Close()
+
+	l := newTestBalancer(t, &Config{
+		Algorithm: "p2c",
+		Backends:  []BackendConfig{{URL: a.URL}, {URL: b.URL}},
+	})
+
+	// with two backends both are always compared
+	atomic.AddInt64(&l.Pool().Backends()[1].conns, 1)
+	for i := 0; i < 10; i++ {
+		if got := get(t, l, nil); got != "a" {
+			t.Fatalf("got %q, want a", got)
+		}
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	servers := map[string]*httptest.Server{}
+	var backends []BackendConfig
+	for _, name := range []string{"a", "b", "c"} {
+		s := newTestBackend(name)
+		defer s.Close()
+		servers[name] = s
+		backends = append(backends, BackendConfig{URL: s.URL})
+	}
+
+	l := newTestBalancer(t, &Config{
+		Algorithm: "consistent_hash",
+		HashKey:   "header:X-User-ID",
+		Backends:  backends,
+	})
+
+	users := []string{"u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8"}
+	owner := map[string]string{}
+	for _, u := range users {
+		owner[u] = get(t, l, http.Header{"X-User-Id": {u}})
+		if again := get(t, l, http.Header{"X-User-Id": {u}}); again != owner[u] {
+			t.Fatalf("user %s moved from %s to %s", u, owner[u], again)
+		}
+	}
+
+	// ejecting one backend only moves the users it owned
+	l.Pool().Backends()[0].Eject(time.Hour)
+	for _, u := range users {
+		got := get(t, l, http.Header{"X-User-Id": {u}})
+		if owner[u] != "a" && got != owner[u] {
+			t.Fatalf("user %s moved from %s to %s", u, owner[u], got)
+		}
+		if got == "a" {
+			t.Fatalf("user %s sent to ejected backend", u)
+		}
+	}
+}
+
+func TestConsistentHashWithoutKey(t *testing.T) {
+	var backends []BackendConfig
+	for _, name := range []string{"a", "b", "c"} {
+		s := newTestBackend(name)
+		defer s.Close()
+		backends = append(backends, BackendConfig{URL: s.URL})
+	}
+
+	l := newTestBalancer(t, &Config{
+		Algorithm: "consistent_hash",
+		HashKey:   "cookie:session",
+		Backends:  backends,
+	})
+
+	// requests without the cookie fall back to round-robin
+	seen := map[string]int{}
+	for i := 0; i < 6; i++ {
+		seen[get(t, l, nil)]++
+	}
+	if len(seen) != 3 || seen["a"] != 2 || seen["b"] != 2 || seen["c"] != 2 {
+		t.Fatalf("requests without key = %v", seen)
+	}
+}
+
+func TestHealthCheckThresholds(t *testing.T) {
+	var healthy int32 = 1
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	l := newTestBalancer(t, &Config{
+		Backends: []BackendConfig{{URL: s.URL}},
+		HealthCheck: HealthCheckConfig{
+			Path:               "/health",
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 2,
+		},
+	})
+	pool, hc := l.Pool(), l.Config().HealthCheck
+	b := pool.Backends()[0]
+
+	atomic.StoreInt32(&healthy, 0)
+	pool.HealthCheck(hc)
+	if !b.IsAlive() {
+		t.Fatal("backend down after a single failed check")
+	}
+	pool.HealthCheck(hc)
+	if b.IsAlive() {
+		t.Fatal("backend alive after two failed checks")
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	pool.HealthCheck(hc)
+	if b.IsAlive() {
+		t.Fatal("backend up after a single passed check")
+	}
+	pool.HealthCheck(hc)
+	if !b.IsAlive() {
+		t.Fatal("backend down after two passed checks")
+	}
+}
+
+func TestPassiveEjection(t *testing.T) {
+	a, b := newTestBackend("a"), newTestBackend("b")
+	defer b.Close()
+	a.Close()
+
+	l := newTestBalancer(t, &Config{
+		EjectDuration: time.Hour,
+		Backends:      []BackendConfig{{URL: a.URL}, {URL: b.URL}},
+	})
+
+	for i := 0; i < 4; i++ {
+		if got := get(t, l, nil); got != "b" {
+			t.Fatalf("got %q, want b", got)
+		}
+	}
+	if l.Pool().Backends()[0].IsAlive() {
+		t.Fatal("failing backend was not ejected")
+	}
+}
+
+func TestReloadKeepsInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := newTestBackend("fast")
+	defer fast.Close()
+
+	l := newTestBalancer(t, &Config{Backends: []BackendConfig{{URL: slow.URL}}})
+	front := httptest.NewServer(l)
+	defer front.Close()
+
+	done := make(chan string)
+	go func() {
+		resp, err := http.Get(front.URL)
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		done <- string(body)
+	}()
+	for l.Pool().Backends()[0].Connections() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cfg := &Config{Backends: []BackendConfig{{URL: fast.URL}}}
+	cfg.setDefaults()
+	if err := l.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := get(t, l, nil); got != "fast" {
+		t.Fatalf("got %q after reload, want fast", got)
+	}
+
+	close(release)
+	if got := <-done; got != "slow" {
+		t.Fatalf("in-flight request got %q, want slow", got)
+	}
+}
+
+func TestReloadKeepsBackendState(t *testing.T) {
+	a := newTestBackend("a")
+	defer a.Close()
+
+	l := newTestBalancer(t, &Config{Backends: []BackendConfig{{URL: a.URL}}})
+	before := l.Pool().Backends()[0]
+	before.Eject(time.Hour)
+
+	cfg := &Config{Algorithm: "least_conn", Backends: []BackendConfig{{URL: a.URL, Weight: 2}}}
+	cfg.setDefaults()
+	if err := l.Reload(cfg); err != nil {
+		t.Fatal(err)
+	}
+	after := l.Pool().Backends()[0]
+	if after != before || after.IsAlive() || after.weight() != 2 {
+		t.Fatal("backend state was not carried over the reload")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "simplelb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "lb.yaml")
+	data := `
+algorithm: consistent_hash
+hash_key: cookie:session
+health_check:
+  path: /health
+  interval: 10s
+backends:
+  - url: http://localhost:3031
+    weight: 3
+`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 3030 || cfg.EjectDuration != 30*time.Second || cfg.HealthCheck.Interval != 10*time.Second || cfg.Backends[0].Weight != 3 {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("algorithm: random\nbackends:\n  - url: http://x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("unknown algorithm accepted")
+	}
+}
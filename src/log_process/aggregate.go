@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Stat 一个时间窗口内某个 path、status 的请求汇总
+type Stat struct {
+	Start        time.Time     `json:"start"`
+	Window       time.Duration `json:"window"`
+	Path         string        `json:"path"`
+	Status       string        `json:"status"`
+	Count        int           `json:"count"`
+	BytesSent    int           `json:"bytesSent"`
+	P50          float64       `json:"p50"` // RequestTime 的分位数
+	P95          float64       `json:"p95"`
+	P99          float64       `json:"p99"`
+	Max          float64       `json:"max"`
+	UpstreamTime float64       `json:"upstreamTime"` // 平均值
+}
+
+type statKey struct {
+	start        int64
+	path, status string
+}
+
+type bucket struct {
+	stat         *Stat
+	requestTimes []float64
+	upstreamTime float64
+}
+
+// Aggregator 按日志时间把 Message 汇总到固定长度的窗口中。
+// 窗口在比已见到的最新日志时间早一个窗口以上时输出，以容纳稍晚到达的日志；
+// 整个窗口长度内没有收到日志时才按系统时间输出，回放积压的旧日志时窗口
+// 不会在还有日志到达时被提前输出。
+type Aggregator struct {
+	window    time.Duration
+	buckets   map[statKey]*bucket
+	watermark time.Time
+}
+
+func NewAggregator(window time.Duration) *Aggregator {
+	return &Aggregator{
+		window:  window,
+		buckets: make(map[statKey]*bucket),
+	}
+}
+
+func (a *Aggregator) Add(m *Message) {
+	start := m.TimeLocal.Truncate(a.window)
+	key := statKey{start.UnixNano(), m.Path, m.Status}
+	b := a.buckets[key]
+	if b == nil {
+		b = &bucket{stat: &Stat{Start: start, Window: a.window, Path: m.Path, Status: m.Status}}
+		a.buckets[key] = b
+	}
+	b.stat.Count++
+	b.stat.BytesSent += m.BytesSent
+	b.requestTimes = append(b.requestTimes, m.RequestTime)
+	b.upstreamTime += m.UpstreamTime
+
+	if m.TimeLocal.After(a.watermark) {
+		a.watermark = m.TimeLocal
+	}
+}
+
+// Flush 返回结束时间不晚于 before 的窗口，按开始时间、path、status 排序
+func (a *Aggregator) Flush(before time.Time) []*Stat {
+	var stats []*Stat
+	for key, b := range a.buckets {
+		if b.stat.Start.Add(a.window).After(before) {
+			continue
+		}
+		delete(a.buckets, key)
+
+		sort.Float64s(b.requestTimes)
+		b.stat.P50 = percentile(b.requestTimes, 50)
+		b.stat.P95 = percentile(b.requestTimes, 95)
+		b.stat.P99 = percentile(b.requestTimes, 99)
+		b.stat.Max = b.requestTimes[len(b.requestTimes)-1]
+		b.stat.UpstreamTime = b.upstreamTime / float64(b.stat.Count)
+		stats = append(stats, b.stat)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		si, sj := stats[i], stats[j]
+		if !si.Start.Equal(sj.Start) {
+			return si.Start.Before(sj.Start)
+		}
+		if si.Path != sj.Path {
+			return si.Path < sj.Path
+		}
+		return si.Status < sj.Status
+	})
+	return stats
+}
+
+// Run 汇总 wc 中的 Message 并把输出的窗口交给 out，wc 关闭后输出全部窗口
+func (a *Aggregator) Run(wc chan *Message, out func(*Stat)) {
+	ticker := time.NewTicker(a.window)
+	defer ticker.Stop()
+
+	// 只在水位进入新窗口时检查，避免每条日志都遍历全部窗口
+	var flushed time.Time
+	// 上次 tick 之后是否收到过日志
+	var received bool
+	for {
+		var stats []*Stat
+		select {
+		case m, ok := <-wc:
+			if !ok {
+				for _, s := range a.Flush(time.Unix(math.MaxInt32, 0)) {
+					out(s)
+				}
+				return
+			}
+			a.Add(m)
+			received = true
+			if before := a.watermark.Add(-a.window).Truncate(a.window); before.After(flushed) {
+				stats = a.Flush(before)
+				flushed = before
+			}
+		case now := <-ticker.C:
+			// 日志时间可能远早于系统时间，仍有日志到达时只按水位输出
+			if !received {
+				stats = a.Flush(now.Add(-a.window))
+			}
+			received = false
+		}
+		for _, s := range stats {
+			out(s)
+		}
+	}
+}
+
+// percentile 最近秩法求已排序数据的第 p 百分位数
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregator(t *testing.T) {
+	base := time.Date(2018, 3, 1, 10, 20, 0, 0, time.UTC)
+	a := NewAggregator(10 * time.Second)
+	for i := 1; i <= 100; i++ {
+		a.Add(&Message{TimeLocal: base.Add(time.Duration(i%10) * time.Second), Path: "/a", Status: "200",
+			BytesSent: 10, RequestTime: float64(i) / 1000, UpstreamTime: 0.5})
+	}
+	a.Add(&Message{TimeLocal: base.Add(5 * time.Second), Path: "/a", Status: "500", RequestTime: 1})
+	a.Add(&Message{TimeLocal: base.Add(15 * time.Second), Path: "/a", Status: "200", RequestTime: 2})
+
+	if stats := a.Flush(base.Add(9 * time.Second)); len(stats) != 0 {
+		t.Fatalf("flushed an open window: %+v", stats[0])
+	}
+
+	stats := a.Flush(base.Add(10 * time.Second))
+	if len(stats) != 2 {
+		t.Fatalf("flushed %d windows, want 2", len(stats))
+	}
+	want := Stat{Start: base, Window: 10 * time.Second, Path: "/a", Status: "200", Count: 100, BytesSent: 1000,
+		P50: 0.05, P95: 0.095, P99: 0.099, Max: 0.1, UpstreamTime: 0.5}
+	if *stats[0] != want {
+		t.Errorf("got %+v\nwant %+v", *stats[0], want)
+	}
+	if s := stats[1]; s.Status != "500" || s.Count != 1 || s.P50 != 1 || s.P99 != 1 {
+		t.Errorf("got %+v", *s)
+	}
+
+	stats = a.Flush(base.Add(time.Minute))
+	if len(stats) != 1 || !stats[0].Start.Equal(base.Add(10*time.Second)) || stats[0].Max != 2 {
+		t.Errorf("last window %+v", stats)
+	}
+}
+
+func TestAggregatorRun(t *testing.T) {
+	base := time.Date(2018, 3, 1, 10, 20, 0, 0, time.UTC)
+	wc := make(chan *Message)
+	out := make(chan *Stat, 10)
+	go func() {
+		NewAggregator(10*time.Second).Run(wc, func(s *Stat) { out <- s })
+		close(out)
+	}()
+
+	// 最新日志晚于窗口结束一个窗口后，窗口不等待关闭就输出
+	wc <- &Message{TimeLocal: base, Path: "/a"}
+	wc <- &Message{TimeLocal: base.Add(20 * time.Second), Path: "/b"}
+	if s := <-out; s.Path != "/a" {
+		t.Errorf("first window %+v", s)
+	}
+
+	close(wc)
+	if s := <-out; s == nil || s.Path != "/b" {
+		t.Errorf("window flushed on close %+v", s)
+	}
+	if s, ok := <-out; ok {
+		t.Errorf("unexpected window %+v", s)
+	}
+}
+
+func TestAggregatorRunBacklog(t *testing.T) {
+	// 回放积压的旧日志，同一个窗口的日志跨过了 tick
+	window := 100 * time.Millisecond
+	base := time.Date(2018, 3, 1, 10, 20, 0, 0, time.UTC)
+	wc := make(chan *Message)
+	out := make(chan *Stat, 10)
+	go func() {
+		NewAggregator(window).Run(wc, func(s *Stat) { out <- s })
+		close(out)
+	}()
+
+	wc <- &Message{TimeLocal: base, Path: "/a"}
+	time.Sleep(window * 3 / 2)
+	wc <- &Message{TimeLocal: base.Add(window / 2), Path: "/a"}
+	select {
+	case s := <-out:
+		t.Fatalf("window flushed while receiving %+v", s)
+	default:
+	}
+
+	// 没有新日志后按系统时间输出
+	select {
+	case s := <-out:
+		if s.Path != "/a" || s.Count != 2 {
+			t.Errorf("window %+v", s)
+		}
+	case <-time.After(4 * window):
+		t.Fatal("idle window not flushed")
+	}
+
+	close(wc)
+	if s, ok := <-out; ok {
+		t.Errorf("unexpected window %+v", s)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// Config -config 指定的 JSON 配置文件
+type Config struct {
+	Path       string       `json:"path"`       // 日志文件，可以是 glob，如 /var/log/nginx/*.log
+	Checkpoint string       `json:"checkpoint"` // 记录读取位置的文件
+	Queue      int          `json:"queue"`      // 各阶段之间 channel 的容量
+	Format     Format       `json:"format"`
+	Window     string       `json:"window"` // 汇总窗口，如 "10s"
+	Writers    []WriterConf `json:"writers"`
+}
+
+const (
+	DefaultWindow     = 10 * time.Second // 默认的汇总窗口
+	DefaultCheckpoint = "log_process.checkpoint"
+	DefaultQueue      = 200
+)
+
+func LoadConfig(file string) (*Config, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &Config{}
+	if err := json.NewDecoder(f).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) window() (time.Duration, error) {
+	if c.Window == "" {
+		return DefaultWindow, nil
+	}
+	d, err := time.ParseDuration(c.Window)
+	if err == nil && d <= 0 {
+		err = errors.New("window must be positive")
+	}
+	return d, err
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parser 把一行日志解析为 Message
+type Parser interface {
+	Parse(line []byte) (*Message, error)
+}
+
+// Format 日志格式定义
+//
+// Type 为 nginx 时 LogFormat 是 nginx 的 log_format 字符串，其中的变量被编译为正则；
+// 为 json 或 logfmt 时每行是一个对象，字段名默认与 nginx 变量同名，可用 Fields 改名，
+// 如 {"request_time": "duration"}。
+// 认识的变量: time_local, time_iso8601, msec, request, request_method, request_uri,
+// uri, scheme, status, body_bytes_sent, bytes_sent, upstream_response_time, request_time
+type Format struct {
+	Type       string            `json:"type"`
+	LogFormat  string            `json:"log_format"`
+	Fields     map[string]string `json:"fields"`
+	TimeLayout string            `json:"time_layout"` // time_local 的格式
+	Location   string            `json:"location"`    // 时间中没有时区时使用
+}
+
+// DefaultLogFormat 默认的 nginx log_format
+const DefaultLogFormat = `$remote_addr	$http_x_forwarded_for	$remote_user	[$time_local]	$scheme	"$request"	$status	$body_bytes_sent	"$http_referer"	"$http_user_agent"	"$gzip_ratio"	$upstream_response_time	$request_time`
+
+// DefaultTimeLayout nginx $time_local 的格式
+const DefaultTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+var varRe = regexp.MustCompile(`\$(\{[a-z0-9_]+\}|[a-z0-9_]+)`)
+
+func NewParser(f Format) (Parser, error) {
+	conv := &converter{fields: f.Fields, layout: f.TimeLayout, loc: time.Local}
+	if conv.layout == "" {
+		conv.layout = DefaultTimeLayout
+	}
+	if f.Location != "" {
+		loc, err := time.LoadLocation(f.Location)
+		if err != nil {
+			return nil, err
+		}
+		conv.loc = loc
+	}
+
+	switch f.Type {
+	case "", "nginx":
+		logFormat := f.LogFormat
+		if logFormat == "" {
+			logFormat = DefaultLogFormat
+		}
+		return newNginxParser(logFormat, conv)
+	case "json":
+		return &jsonParser{conv}, nil
+	case "logfmt":
+		return &logfmtParser{conv}, nil
+	}
+	return nil, fmt.Errorf("unknown log format type: %s", f.Type)
+}
+
+// nginxParser 由 log_format 编译出的正则解析日志
+type nginxParser struct {
+	*converter
+	re    *regexp.Regexp
+	names []string
+}
+
+// newNginxParser 把 log_format 中的每个变量编译为一个分组，
+// 变量匹配到其后面第一个字面字符之前
+func newNginxParser(logFormat string, conv *converter) (*nginxParser, error) {
+	p := &nginxParser{converter: conv}
+	pattern := "^"
+	locs := varRe.FindAllStringSubmatchIndex(logFormat, -1)
+	if len(locs) == 0 {
+		return nil, errors.New("log_format has no variable")
+	}
+
+	last := 0
+	for i, loc := range locs {
+		pattern += regexp.QuoteMeta(logFormat[last:loc[0]])
+		p.names = append(p.names, strings.Trim(logFormat[loc[2]:loc[3]], "{}"))
+		last = loc[1]
+
+		next := len(logFormat)
+		if i+1 < len(locs) {
+			next = locs[i+1][0]
+		}
+		if last < next {
+			pattern += "([^" + regexp.QuoteMeta(logFormat[last:last+1]) + "]*)"
+		} else if next == len(logFormat) {
+			pattern += "(.*)"
+		} else {
+			// 两个变量相连时无法确定分界，尽量少匹配
+			pattern += "(.*?)"
+		}
+	}
+	pattern += regexp.QuoteMeta(logFormat[last:])
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	p.re = re
+	return p, nil
+}
+
+func (p *nginxParser) Parse(line []byte) (*Message, error) {
+	ret := p.re.FindSubmatch(line)
+	if ret == nil {
+		return nil, errors.New("line does not match log_format")
+	}
+	return p.message(func(name string) (string, bool) {
+		for i, n := range p.names {
+			if n == name {
+				return string(ret[i+1]), true
+			}
+		}
+		return "", false
+	})
+}
+
+// jsonParser 解析 JSON lines
+type jsonParser struct {
+	*converter
+}
+
+func (p *jsonParser) Parse(line []byte) (*Message, error) {
+	obj := map[string]interface{}{}
+	d := json.NewDecoder(bytes.NewReader(line))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return p.message(func(name string) (string, bool) {
+		v, ok := obj[p.key(name)]
+		if !ok || v == nil {
+			return "", false
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		return fmt.Sprint(v), true
+	})
+}
+
+// logfmtParser 解析 key=value 或 key="quoted value" 组成的行
+type logfmtParser struct {
+	*converter
+}
+
+func (p *logfmtParser) Parse(line []byte) (*Message, error) {
+	obj, err := parseLogfmt(string(line))
+	if err != nil {
+		return nil, err
+	}
+	return p.message(func(name string) (string, bool) {
+		v, ok := obj[p.key(name)]
+		return v, ok
+	})
+}
+
+func parseLogfmt(line string) (map[string]string, error) {
+	obj := map[string]string{}
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return obj, nil
+		}
+
+		end := strings.IndexAny(line, "= \t")
+		if end == 0 {
+			return nil, fmt.Errorf("logfmt: missing key at %q", line)
+		}
+		if end < 0 || line[end] != '=' {
+			// 没有值的 key
+			if end < 0 {
+				end = len(line)
+			}
+			obj[line[:end]] = ""
+			line = line[end:]
+			continue
+		}
+		key := line[:end]
+		line = line[end+1:]
+
+		if strings.HasPrefix(line, `"`) {
+			i := 1
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("logfmt: unterminated value of %s", key)
+			}
+			v, err := strconv.Unquote(line[:i+1])
+			if err != nil {
+				return nil, fmt.Errorf("logfmt: value of %s: %v", key, err)
+			}
+			obj[key] = v
+			line = line[i+1:]
+			continue
+		}
+
+		end = strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		obj[key] = line[:end]
+		line = line[end:]
+	}
+}
+
+// converter 把 nginx 变量名对应的值转换为 Message
+type converter struct {
+	fields map[string]string
+	layout string
+	loc    *time.Location
+}
+
+func (c *converter) key(name string) string {
+	if k, ok := c.fields[name]; ok {
+		return k
+	}
+	return name
+}
+
+func (c *converter) message(get func(name string) (string, bool)) (*Message, error) {
+	m := &Message{}
+
+	var err error
+	if v, ok := get("time_local"); ok {
+		m.TimeLocal, err = time.ParseInLocation(c.layout, v, c.loc)
+	} else if v, ok := get("time_iso8601"); ok {
+		m.TimeLocal, err = time.Parse(time.RFC3339, v)
+	} else if v, ok := get("msec"); ok {
+		var sec float64
+		sec, err = strconv.ParseFloat(v, 64)
+		m.TimeLocal = time.Unix(0, int64(sec*1e9))
+	} else {
+		err = errors.New("no time_local, time_iso8601 or msec")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("time parse error: %v", err)
+	}
+
+	uri, hasUri := get("request_uri")
+	if !hasUri {
+		uri, hasUri = get("uri")
+	}
+	m.Method, _ = get("request_method")
+	if request, ok := get("request"); ok {
+		requestSli := strings.Split(request, " ")
+		if len(requestSli) < 3 {
+			return nil, fmt.Errorf("input request wrong: %s", request)
+		}
+		m.Method, uri, hasUri = requestSli[0], requestSli[1], true
+	}
+	if !hasUri {
+		return nil, errors.New("no request, request_uri or uri")
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("input url parse error: %v", err)
+	}
+	m.Path = u.Path
+
+	m.Scheme, _ = get("scheme")
+	m.Status, _ = get("status")
+	if v, ok := get("body_bytes_sent"); ok {
+		m.BytesSent, _ = strconv.Atoi(v)
+	} else if v, ok := get("bytes_sent"); ok {
+		m.BytesSent, _ = strconv.Atoi(v)
+	}
+	if v, ok := get("upstream_response_time"); ok {
+		m.UpstreamTime = sumTimes(v)
+	}
+	if v, ok := get("request_time"); ok {
+		m.RequestTime, _ = strconv.ParseFloat(v, 64)
+	}
+	return m, nil
+}
+
+// sumTimes 累加 "0.010, 0.020 : 0.003" 形式的多个 upstream 的时间，"-" 为 0
+func sumTimes(v string) float64 {
+	var sum float64
+	for _, s := range strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ':' || r == ' ' }) {
+		f, _ := strconv.ParseFloat(s, 64)
+		sum += f
+	}
+	return sum
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsers(t *testing.T) {
+	want := Message{
+		TimeLocal:    time.Date(2018, 3, 1, 10, 20, 30, 0, time.FixedZone("", 8*3600)),
+		Path:         "/api/users",
+		Method:       "GET",
+		Scheme:       "https",
+		Status:       "200",
+		BytesSent:    512,
+		UpstreamTime: 0.03,
+		RequestTime:  0.031,
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		line   string
+	}{
+		{"default", Format{},
+			"10.0.0.1\t-\t-\t[01/Mar/2018:10:20:30 +0800]\thttps\t\"GET /api/users?id=1 HTTP/1.1\"\t200\t512\t\"-\"\t\"curl/7.54\"\t\"-\"\t0.010, 0.020\t0.031"},
+		{"combined", Format{LogFormat: `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" $scheme $upstream_response_time $request_time`},
+			`10.0.0.1 - - [01/Mar/2018:10:20:30 +0800] "GET /api/users HTTP/2.0" 200 512 "-" "Mozilla/5.0 (X11; Linux)" https 0.030 0.031`},
+		{"iso8601", Format{LogFormat: `${time_iso8601}|$request_method|$uri|$scheme|$status|$bytes_sent|$upstream_response_time|$request_time`},
+			`2018-03-01T10:20:30+08:00|GET|/api/users|https|200|512|0.030|0.031`},
+		{"json", Format{Type: "json", Fields: map[string]string{"request_time": "duration"}},
+			`{"time_iso8601":"2018-03-01T10:20:30+08:00","request":"GET /api/users HTTP/1.1","scheme":"https","status":200,"body_bytes_sent":512,"upstream_response_time":"0.030","duration":0.031}`},
+		{"logfmt", Format{Type: "logfmt", TimeLayout: "2006-01-02 15:04:05", Location: "Asia/Shanghai"},
+			`time_local="2018-03-01 10:20:30" request="GET /api/users HTTP/1.1" scheme=https status=200 body_bytes_sent=512 upstream_response_time=0.030 request_time=0.031 cached`},
+	}
+	for _, test := range tests {
+		p, err := NewParser(test.format)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		m, err := p.Parse([]byte(test.line))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !m.TimeLocal.Equal(want.TimeLocal) {
+			t.Errorf("%s: time %v, want %v", test.name, m.TimeLocal, want.TimeLocal)
+		}
+		m.TimeLocal = want.TimeLocal
+		if *m != want {
+			t.Errorf("%s: %+v, want %+v", test.name, *m, want)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	if _, err := NewParser(Format{Type: "xml"}); err == nil {
+		t.Error("unknown type accepted")
+	}
+	if _, err := NewParser(Format{LogFormat: "no variable"}); err == nil {
+		t.Error("log_format without variable accepted")
+	}
+
+	p, _ := NewParser(Format{})
+	for _, line := range []string{
+		"hello world",
+		"10.0.0.1\t-\t-\t[yesterday]\thttps\t\"GET / HTTP/1.1\"\t200\t512\t\"-\"\t\"-\"\t\"-\"\t0.01\t0.01",
+		"10.0.0.1\t-\t-\t[01/Mar/2018:10:20:30 +0800]\thttps\t\"GET\"\t200\t512\t\"-\"\t\"-\"\t\"-\"\t0.01\t0.01",
+	} {
+		if m, err := p.Parse([]byte(line)); err == nil {
+			t.Errorf("%q parsed as %+v", line, m)
+		}
+	}
+
+	p, _ = NewParser(Format{Type: "logfmt"})
+	if _, err := p.Parse([]byte(`msec=1 uri="/unterminated`)); err == nil {
+		t.Error("unterminated logfmt value accepted")
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// fileID 文件的身份，改名后不变
+type fileID struct {
+	Dev   uint64 `json:"dev"`
+	Inode uint64 `json:"inode"`
+}
+
+func idOf(fi os.FileInfo) fileID {
+	stat := fi.Sys().(*syscall.Stat_t)
+	return fileID{Dev: uint64(stat.Dev), Inode: uint64(stat.Ino)}
+}
+
+// Position 一个文件已读取到的位置
+type Position struct {
+	fileID
+	Path   string `json:"path"`
+	Offset int64  `json:"offset"`
+}
+
+// Checkpoint 按文件身份记录读取位置，重启后从上次的位置继续读
+type Checkpoint struct {
+	file      string
+	loaded    bool // checkpoint 文件已存在，不是第一次运行
+	positions map[fileID]Position
+}
+
+func LoadCheckpoint(file string) (*Checkpoint, error) {
+	c := &Checkpoint{file: file, positions: make(map[fileID]Position)}
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	c.loaded = true
+
+	var positions []Position
+	if err := json.Unmarshal(b, &positions); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %v", file, err)
+	}
+	for _, p := range positions {
+		c.positions[p.fileID] = p
+	}
+	return c, nil
+}
+
+// Save 先写临时文件再改名，进程崩溃时不会留下写了一半的 checkpoint
+func (c *Checkpoint) Save() error {
+	positions := make([]Position, 0, len(c.positions))
+	for _, p := range c.positions {
+		positions = append(positions, p)
+	}
+	b, err := json.MarshalIndent(positions, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := c.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.file)
+}
+
+// FileStatus 一个文件的读取进度，Backlog 是还没读取的字节数
+type FileStatus struct {
+	Path    string `json:"path"`
+	Offset  int64  `json:"offset"`
+	Size    int64  `json:"size"`
+	Backlog int64  `json:"backlog"`
+}
+
+// ReadFromTail 读取 glob 匹配的所有文件新增的行。
+//
+// 已有 checkpoint 的文件从记录的位置继续读。第一次运行（没有 checkpoint 文件）
+// 时已有的文件从末尾开始读，其他情况下新文件都从头读。logrotate 改名切割时
+// 读完旧文件再读新文件，停止期间发生的切割也是如此；copytruncate 截断时从头读。
+// 文件删除后读完剩下的内容就关闭。rc 满时停止读取，未读的部分计入 Backlog。
+type ReadFromTail struct {
+	pattern    string
+	checkpoint *Checkpoint
+	interval   time.Duration // 检查新数据、新文件和切割的间隔
+	done       chan struct{}
+	stopOnce   sync.Once
+
+	mu    sync.Mutex
+	tails map[string]*tailFile // path 到正在读的文件
+}
+
+func NewReader(pattern, checkpointFile string) (*ReadFromTail, error) {
+	if _, err := filepath.Glob(pattern); err != nil {
+		return nil, err
+	}
+	checkpoint, err := LoadCheckpoint(checkpointFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReadFromTail{
+		pattern:    pattern,
+		checkpoint: checkpoint,
+		interval:   1 * time.Second,
+		done:       make(chan struct{}),
+		tails:      make(map[string]*tailFile),
+	}, nil
+}
+
+func (r *ReadFromTail) Read(rc chan []byte) {
+	defer close(rc)
+
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for first := true; ; first = false {
+		r.scan(rc, &wg, first)
+		r.save()
+
+		select {
+		case <-r.done:
+			wg.Wait()
+			r.save()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 停止读取，已经交给 rc 的行都记入 checkpoint 后 Read 关闭 rc 返回
+func (r *ReadFromTail) Stop() {
+	r.stopOnce.Do(func() { close(r.done) })
+}
+
+func (r *ReadFromTail) Status() []FileStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ret []FileStatus
+	for _, t := range r.tails {
+		ret = append(ret, t.status())
+	}
+	return ret
+}
+
+// scan 开始读取新匹配到的文件
+func (r *ReadFromTail) scan(rc chan []byte, wg *sync.WaitGroup, first bool) {
+	paths, _ := filepath.Glob(r.pattern)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active := make(map[fileID]bool)
+	for _, t := range r.tails {
+		active[t.position().fileID] = true
+	}
+	// 保留仍然存在的文件的位置，改名后还能匹配到的旧文件不会重读
+	live := make(map[fileID]bool)
+	for id := range active {
+		live[id] = true
+	}
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil {
+			live[idOf(fi)] = true
+		}
+	}
+	for id := range r.checkpoint.positions {
+		if !live[id] {
+			delete(r.checkpoint.positions, id)
+		}
+	}
+
+	if first {
+		r.adoptRotated(paths, active, rc, wg)
+	}
+
+	for _, path := range paths {
+		if r.tails[path] != nil {
+			continue
+		}
+		fd, fi, err := open(path)
+		if err != nil {
+			log.Printf("readFromTail open err: %s", err.Error())
+			TypeMonitorChan <- TypeReadErr
+			continue
+		}
+		if fi.IsDir() || active[idOf(fi)] {
+			// 目录，或者是改名后还在被读的文件
+			fd.Close()
+			continue
+		}
+
+		id := idOf(fi)
+		var offset int64
+		if p, ok := r.checkpoint.positions[id]; ok {
+			if p.Offset <= fi.Size() {
+				offset = p.Offset
+			}
+		} else if first && !r.checkpoint.loaded {
+			offset = fi.Size()
+		}
+		if _, err := fd.Seek(offset, io.SeekStart); err != nil {
+			log.Printf("readFromTail seek err: %s", err.Error())
+			fd.Close()
+			continue
+		}
+
+		r.start(&tailFile{path: path, fd: fd, id: id, offset: offset}, rc, wg)
+		active[id] = true
+	}
+}
+
+// adoptRotated 处理停止期间的改名切割：path 上记录的文件改名后仍能匹配到时，
+// 先读完这个旧文件，之后的 scan 再从头读 path 上的新文件
+func (r *ReadFromTail) adoptRotated(paths []string, active map[fileID]bool, rc chan []byte, wg *sync.WaitGroup) {
+	current := make(map[string]fileID)
+	pathOf := make(map[fileID]string)
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			current[path] = idOf(fi)
+			pathOf[idOf(fi)] = path
+		}
+	}
+
+	for id, p := range r.checkpoint.positions {
+		cur, ok := current[p.Path]
+		oldPath := pathOf[id]
+		if !ok || cur == id || oldPath == "" || active[id] || r.tails[p.Path] != nil {
+			continue
+		}
+		fd, fi, err := open(oldPath)
+		if err != nil {
+			continue
+		}
+		if idOf(fi) != id || p.Offset > fi.Size() {
+			fd.Close()
+			continue
+		}
+		if _, err := fd.Seek(p.Offset, io.SeekStart); err != nil {
+			fd.Close()
+			continue
+		}
+
+		r.start(&tailFile{path: p.Path, fd: fd, id: id, offset: p.Offset, old: true}, rc, wg)
+		active[id] = true
+	}
+}
+
+// start 开始读取 t，调用时持有 r.mu
+func (r *ReadFromTail) start(t *tailFile, rc chan []byte, wg *sync.WaitGroup) {
+	t.interval, t.done, t.release = r.interval, r.done, r.release
+	r.tails[t.path] = t
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t.run(rc)
+	}()
+}
+
+// release 记录读完的旧文件或已删除的文件的位置，不再读取它，
+// 之后的 scan 重新打开 path 上的文件
+func (r *ReadFromTail) release(t *tailFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := t.position()
+	r.checkpoint.positions[p.fileID] = p
+	if r.tails[t.path] == t {
+		delete(r.tails, t.path)
+	}
+}
+
+// save 记录正在读的文件的位置
+func (r *ReadFromTail) save() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, t := range r.tails {
+		p := t.position()
+		r.checkpoint.positions[p.fileID] = p
+	}
+	if err := r.checkpoint.Save(); err != nil {
+		log.Printf("readFromTail save checkpoint err: %s", err.Error())
+		TypeMonitorChan <- TypeReadErr
+	}
+}
+
+// goneIntervals 文件删除后等待新文件出现的检查次数，超过后不再读取
+const goneIntervals = 60
+
+// tailFile 读取一个路径上的文件，直到它被切割或删除
+type tailFile struct {
+	path     string
+	interval time.Duration
+	done     chan struct{}
+	release  func(t *tailFile)
+	old      bool // fd 是 path 上的文件改名切割下来的旧文件
+
+	mu     sync.Mutex
+	fd     *os.File
+	id     fileID
+	offset int64 // 已交给 rc 的完整行的结束位置
+}
+
+func (t *tailFile) position() Position {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Position{fileID: t.id, Path: t.path, Offset: t.offset}
+}
+
+func (t *tailFile) status() FileStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := FileStatus{Path: t.path, Offset: t.offset, Size: t.offset}
+	if fi, err := t.fd.Stat(); err == nil {
+		s.Size = fi.Size()
+	}
+	if s.Size > s.Offset {
+		s.Backlog = s.Size - s.Offset
+	}
+	return s
+}
+
+func (t *tailFile) run(rc chan []byte) {
+	defer func() {
+		t.mu.Lock()
+		t.fd.Close()
+		t.mu.Unlock()
+	}()
+
+	bf := bufio.NewReader(t.fd)
+	var partial []byte // 还没有换行符的行
+	rotated := t.old
+	gone := 0 // 连续检查到文件不存在的次数
+
+	send := func(line []byte) bool {
+		select {
+		case rc <- line:
+			return true
+		case <-t.done:
+			return false
+		}
+	}
+
+	for {
+		line, err := bf.ReadBytes('\n')
+		if err == nil {
+			n := len(partial) + len(line)
+			line = append(partial, line[:len(line)-1]...)
+			partial = nil
+			if !send(line) {
+				return
+			}
+			t.mu.Lock()
+			t.offset += int64(n)
+			t.mu.Unlock()
+			continue
+		} else if err != io.EOF {
+			log.Printf("readFromTail ReadBytes err: %s", err.Error())
+			TypeMonitorChan <- TypeReadErr
+		}
+		partial = append(partial, line...)
+
+		if rotated {
+			// 旧文件已读完，最后一行没有换行符也交出去
+			if len(partial) > 0 {
+				if !send(partial) {
+					return
+				}
+				t.mu.Lock()
+				t.offset += int64(len(partial))
+				t.mu.Unlock()
+			}
+			t.release(t)
+			return
+		}
+
+		select {
+		case <-t.done:
+			return
+		case <-time.After(t.interval):
+		}
+
+		fi, err := os.Stat(t.path)
+		if err != nil {
+			// 文件切割，但新文件还没有生成；一直没有生成时读完后不再读取
+			if gone++; gone >= goneIntervals {
+				rotated = true
+			}
+			continue
+		}
+		gone = 0
+		t.mu.Lock()
+		if idOf(fi) != t.id {
+			// 改名切割，先读完旧文件中剩下的内容
+			rotated = true
+		} else if fi.Size() < t.offset {
+			// copytruncate 截断
+			t.fd.Seek(0, io.SeekStart)
+			t.offset = 0
+			bf.Reset(t.fd)
+			partial = nil
+		}
+		t.mu.Unlock()
+	}
+}
+
+func open(path string) (*os.File, os.FileInfo, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, nil, err
+	}
+	return fd, fi, nil
+}
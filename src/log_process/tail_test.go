@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendFile(t *testing.T, path, s string) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func startReader(t *testing.T, pattern, checkpoint string, queue int) (*ReadFromTail, chan []byte) {
+	r, err := NewReader(pattern, checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.interval = 10 * time.Millisecond
+	rc := make(chan []byte, queue)
+	go r.Read(rc)
+	// 等第一次扫描完成
+	time.Sleep(50 * time.Millisecond)
+	return r, rc
+}
+
+func expectLines(t *testing.T, rc chan []byte, lines ...string) {
+	t.Helper()
+	for _, want := range lines {
+		select {
+		case line := <-rc:
+			if string(line) != want {
+				t.Fatalf("read %q, want %q", line, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %q", want)
+		}
+	}
+}
+
+func stopReader(t *testing.T, r *ReadFromTail, rc chan []byte) {
+	t.Helper()
+	r.Stop()
+	for line := range rc {
+		t.Errorf("unexpected line %q", line)
+	}
+}
+
+func TestReadFromTailCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	log, checkpoint := filepath.Join(dir, "access.log"), filepath.Join(dir, "checkpoint")
+	appendFile(t, log, "old 1\nold 2\n")
+
+	// 没有 checkpoint 时从末尾开始读
+	r, rc := startReader(t, log, checkpoint, 10)
+	appendFile(t, log, "a\nb")
+	expectLines(t, rc, "a")
+	appendFile(t, log, "c\n")
+	expectLines(t, rc, "bc")
+	stopReader(t, r, rc)
+
+	// 停止期间写入的行在重启后读到
+	appendFile(t, log, "d\n")
+	r, rc = startReader(t, log, checkpoint, 10)
+	expectLines(t, rc, "d")
+	stopReader(t, r, rc)
+}
+
+func TestReadFromTailRotation(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "access.log")
+	appendFile(t, log, "")
+	r, rc := startReader(t, filepath.Join(dir, "*.log"), filepath.Join(dir, "checkpoint"), 10)
+	defer stopReader(t, r, rc)
+
+	appendFile(t, log, "before rename\n")
+	expectLines(t, rc, "before rename")
+
+	// logrotate 的改名切割：旧文件中最后写入的行也要读到
+	appendFile(t, log, "last of old\n")
+	if err := os.Rename(log, log+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, log, "first of new\n")
+	expectLines(t, rc, "last of old", "first of new")
+
+	// copytruncate
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Truncate(log, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, log, "truncated\n")
+	expectLines(t, rc, "truncated")
+
+	// 之后出现的文件从头读
+	appendFile(t, filepath.Join(dir, "error.log"), "new file\n")
+	expectLines(t, rc, "new file")
+}
+
+func TestReadFromTailRestartAfterRotation(t *testing.T) {
+	dir := t.TempDir()
+	log, checkpoint := filepath.Join(dir, "access.log"), filepath.Join(dir, "checkpoint")
+	appendFile(t, log, "")
+	r, rc := startReader(t, log+"*", checkpoint, 10)
+	appendFile(t, log, "a\n")
+	expectLines(t, rc, "a")
+	stopReader(t, r, rc)
+
+	// 停止期间写入旧文件后改名切割，新文件不是第一次运行时的已有文件，从头读
+	appendFile(t, log, "b\n")
+	if err := os.Rename(log, log+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, log, "c\n")
+	r, rc = startReader(t, log+"*", checkpoint, 10)
+	expectLines(t, rc, "b", "c")
+	appendFile(t, log+".1", "d\n")
+	expectLines(t, rc, "d")
+	stopReader(t, r, rc)
+}
+
+func TestReadFromTailRemoved(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "access.log")
+	appendFile(t, log, "")
+	r, rc := startReader(t, log, filepath.Join(dir, "checkpoint"), 10)
+	defer stopReader(t, r, rc)
+
+	appendFile(t, log, "a\nlast")
+	expectLines(t, rc, "a")
+	if err := os.Remove(log); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除后读完最后一行就关闭文件
+	expectLines(t, rc, "last")
+	time.Sleep(50 * time.Millisecond)
+	if status := r.Status(); len(status) != 0 {
+		t.Errorf("status %+v", status)
+	}
+
+	// 同名的新文件从头读
+	appendFile(t, log, "new\n")
+	expectLines(t, rc, "new")
+}
+
+func TestReadFromTailBacklog(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "access.log")
+	appendFile(t, log, "")
+	r, rc := startReader(t, log, filepath.Join(dir, "checkpoint"), 1)
+
+	// rc 满后不再读取，剩下的计入 backlog
+	for i := 0; i < 10; i++ {
+		appendFile(t, log, "0123456789\n")
+	}
+	time.Sleep(100 * time.Millisecond)
+	status := r.Status()
+	if len(status) != 1 || status[0].Size != 110 || status[0].Backlog < 80 {
+		t.Errorf("status %+v", status)
+	}
+
+	expectLines(t, rc, "0123456789", "0123456789")
+	r.Stop()
+	read := 2
+	for range rc {
+		read++
+	}
+
+	// 已交给 rc 的行记入 checkpoint
+	c, err := LoadCheckpoint(filepath.Join(dir, "checkpoint"))
+	if err != nil || len(c.positions) != 1 {
+		t.Fatalf("checkpoint %+v, %v", c, err)
+	}
+	for _, p := range c.positions {
+		if p.Path != log || p.Offset != int64(11*read) {
+			t.Errorf("position %+v", p)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// WriterConf 一个输出的配置，Type 为 influxdb、prometheus、file 或 stdout
+type WriterConf struct {
+	Type string `json:"type"`
+	InfluxConf
+	URL   string `json:"url"`   // prometheus remote write 地址
+	Path  string `json:"path"`  // file 的路径
+	Batch int    `json:"batch"` // 每次最多写入的窗口数
+	Retry int    `json:"retry"`
+}
+
+func NewWriterFromConf(c WriterConf) (Writer, error) {
+	b := batcher{batch: 50, retry: 3}
+	if c.Batch > 0 {
+		b.batch = c.Batch
+	}
+	if c.Retry > 0 {
+		b.retry = c.Retry
+	}
+
+	switch c.Type {
+	case "influxdb":
+		if c.Addr == "" || c.Database == "" {
+			return nil, fmt.Errorf("influxdb writer needs addr and database")
+		}
+		conf := c.InfluxConf
+		return &WriteToInfluxDB{batcher: b, influxConf: &conf}, nil
+	case "prometheus":
+		if c.URL == "" {
+			return nil, fmt.Errorf("prometheus writer needs url")
+		}
+		return &WriteToPrometheus{batcher: b, url: c.URL}, nil
+	case "file":
+		f, err := os.OpenFile(c.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &WriteToStream{batcher: b, w: f}, nil
+	case "stdout":
+		return &WriteToStream{batcher: b, w: os.Stdout}, nil
+	}
+	return nil, fmt.Errorf("unknown writer type: %s", c.Type)
+}
+
+// batcher 按批次写入，每秒至少写一次，失败时重试
+type batcher struct {
+	batch int
+	retry int
+}
+
+func (b batcher) run(name string, wc chan *Stat, flush func([]*Stat) error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	buf := make([]*Stat, 0, b.batch)
+	for {
+		select {
+		case s, ok := <-wc:
+			if !ok {
+				b.flush(name, buf, flush)
+				return
+			}
+			buf = append(buf, s)
+			if len(buf) < b.batch {
+				continue
+			}
+		case <-ticker.C:
+		}
+		b.flush(name, buf, flush)
+		buf = buf[:0]
+	}
+}
+
+func (b batcher) flush(name string, stats []*Stat, flush func([]*Stat) error) {
+	if len(stats) == 0 {
+		return
+	}
+	for i := 1; i <= b.retry; i++ {
+		err := flush(stats)
+		if err == nil {
+			return
+		}
+		TypeMonitorChan <- TypeWriteErr
+		log.Printf("%s Write error:%s, retry:%d", name, err.Error(), i)
+		if i < b.retry {
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
+
+func (w *WriteToInfluxDB) Write(wc chan *Stat) {
+	w.run("influxdb", wc, w.write)
+}
+
+// write 以 line protocol 写入 InfluxDB 1.x 的 /write 接口
+func (w *WriteToInfluxDB) write(stats []*Stat) error {
+	var body bytes.Buffer
+	for _, s := range stats {
+		fmt.Fprintf(&body, "nginx_log,path=%s,status=%s count=%di,bytes_sent=%di,request_time_p50=%s,request_time_p95=%s,request_time_p99=%s,request_time_max=%s,upstream_time=%s %d\n",
+			escapeTag(s.Path), escapeTag(s.Status), s.Count, s.BytesSent,
+			formatFloat(s.P50), formatFloat(s.P95), formatFloat(s.P99), formatFloat(s.Max), formatFloat(s.UpstreamTime),
+			timestamp(s.Start, w.influxConf.Precision))
+	}
+
+	q := url.Values{"db": {w.influxConf.Database}}
+	if w.influxConf.Precision != "" {
+		q.Set("precision", w.influxConf.Precision)
+	}
+	req, err := http.NewRequest("POST", strings.TrimRight(w.influxConf.Addr, "/")+"/write?"+q.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	if w.influxConf.Username != "" {
+		req.SetBasicAuth(w.influxConf.Username, w.influxConf.Password)
+	}
+	return post(req)
+}
+
+func escapeTag(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`).Replace(s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// timestamp 按 InfluxDB 的 precision 返回时间戳
+func timestamp(t time.Time, precision string) int64 {
+	switch precision {
+	case "u", "us":
+		return t.UnixNano() / 1e3
+	case "ms":
+		return t.UnixNano() / 1e6
+	case "s":
+		return t.Unix()
+	case "m":
+		return t.Unix() / 60
+	case "h":
+		return t.Unix() / 3600
+	}
+	return t.UnixNano()
+}
+
+func post(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// WriteToPrometheus 以 remote write 协议写入 Prometheus，每个窗口在其结束时间
+// 产生 nginx_log_requests、nginx_log_bytes_sent、nginx_log_request_time_seconds{quantile}
+// 和 nginx_log_upstream_time_seconds 样本
+type WriteToPrometheus struct {
+	batcher
+	url string
+}
+
+func (w *WriteToPrometheus) Write(wc chan *Stat) {
+	w.run("prometheus", wc, w.write)
+}
+
+func (w *WriteToPrometheus) write(stats []*Stat) error {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(snappy.Encode(nil, encodeWriteRequest(stats))))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return post(req)
+}
+
+// encodeWriteRequest 编码 prometheus.WriteRequest:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(stats []*Stat) []byte {
+	var b []byte
+	series := func(s *Stat, name string, value float64, labels ...string) {
+		labels = append(labels, "__name__", name, "path", s.Path, "status", s.Status)
+		pairs := make([][2]string, 0, len(labels)/2)
+		for i := 0; i < len(labels); i += 2 {
+			pairs = append(pairs, [2]string{labels[i], labels[i+1]})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+
+		var ts []byte
+		for _, p := range pairs {
+			var l []byte
+			l = protowire.AppendTag(l, 1, protowire.BytesType)
+			l = protowire.AppendString(l, p[0])
+			l = protowire.AppendTag(l, 2, protowire.BytesType)
+			l = protowire.AppendString(l, p[1])
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, l)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.Start.Add(s.Window).UnixNano()/1e6))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+
+	for _, s := range stats {
+		series(s, "nginx_log_requests", float64(s.Count))
+		series(s, "nginx_log_bytes_sent", float64(s.BytesSent))
+		series(s, "nginx_log_request_time_seconds", s.P50, "quantile", "0.5")
+		series(s, "nginx_log_request_time_seconds", s.P95, "quantile", "0.95")
+		series(s, "nginx_log_request_time_seconds", s.P99, "quantile", "0.99")
+		series(s, "nginx_log_upstream_time_seconds", s.UpstreamTime)
+	}
+	return b
+}
+
+// WriteToStream 把窗口以 JSON lines 写入文件或标准输出
+type WriteToStream struct {
+	batcher
+	mu sync.Mutex // 多个 Write 协程共用 w
+	w  io.Writer
+}
+
+func (w *WriteToStream) Write(wc chan *Stat) {
+	enc := json.NewEncoder(w.w)
+	w.run("stream", wc, func(stats []*Stat) error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, s := range stats {
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testStat = &Stat{
+	Start:  time.Unix(1519900800, 0),
+	Window: 10 * time.Second,
+	Path:   "/a b",
+	Status: "200",
+	Count:  3, BytesSent: 30, P50: 0.1, P95: 0.2, P99: 0.25, Max: 0.3, UpstreamTime: 0.05,
+}
+
+func writeOne(w Writer) {
+	sc := make(chan *Stat, 1)
+	sc <- testStat
+	close(sc)
+	w.Write(sc)
+}
+
+func TestWriteToInfluxDB(t *testing.T) {
+	var query, user, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		query, body = r.URL.RawQuery, string(b)
+		user, _, _ = r.BasicAuth()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(srv.URL + "@root@secret@nginx@s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeOne(w)
+
+	want := `nginx_log,path=/a\ b,status=200 count=3i,bytes_sent=30i,request_time_p50=0.1,request_time_p95=0.2,request_time_p99=0.25,request_time_max=0.3,upstream_time=0.05 1519900800` + "\n"
+	if body != want || query != "db=nginx&precision=s" || user != "root" {
+		t.Errorf("wrote %q to ?%s as %q", body, query, user)
+	}
+}
+
+func TestWriteToPrometheus(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, _ = snappy.Decode(nil, b)
+	}))
+	defer srv.Close()
+
+	w, _ := NewWriterFromConf(WriterConf{Type: "prometheus", URL: srv.URL})
+	writeOne(w)
+
+	// 第一个 TimeSeries 的 label 按名字排序，样本时间为窗口结束时间
+	series := fields([]byte(fields(body)[0]))
+	var labels []string
+	for _, l := range series[:len(series)-1] {
+		f := fields([]byte(l))
+		labels = append(labels, f[0]+"="+f[1])
+	}
+	sample := fields([]byte(series[len(series)-1]))
+	timestamp, _ := protowire.ConsumeVarint([]byte(sample[1]))
+	if got := strings.Join(labels, ","); got != "__name__=nginx_log_requests,path=/a b,status=200" {
+		t.Errorf("labels %s", got)
+	}
+	if timestamp != 1519900810000 {
+		t.Errorf("timestamp %d", timestamp)
+	}
+	if n := bytes.Count(body, []byte("nginx_log_request_time_seconds")); n != 3 {
+		t.Errorf("%d quantile series", n)
+	}
+}
+
+// fields 按顺序返回 protobuf 消息中各字段的值，bytes 字段为其内容，
+// 其他类型为其编码
+func fields(b []byte) []string {
+	var ret []string
+	for len(b) > 0 {
+		_, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(0, typ, b)
+		v := b[:n]
+		if typ == protowire.BytesType {
+			v, _ = protowire.ConsumeBytes(b)
+		}
+		ret = append(ret, string(v))
+		b = b[n:]
+	}
+	return ret
+}
+
+func TestWriteToStream(t *testing.T) {
+	var buf bytes.Buffer
+	writeOne(&WriteToStream{batcher: batcher{batch: 10, retry: 1}, w: &buf})
+	if !strings.HasPrefix(buf.String(), `{"start":"`) || !strings.Contains(buf.String(), `"path":"/a b","status":"200","count":3`) {
+		t.Errorf("wrote %s", buf.String())
+	}
+}
@@ -0,0 +1,173 @@
+package orderedmap
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+func (o OrderedMapG[K, V]) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := o.EncodeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeJSON writes the map as a JSON object to w, one entry at a time.
+// Keys are encoded like encoding/json encodes map keys: strings,
+// integers or encoding.TextMarshaler.
+func (o *OrderedMapG[K, V]) EncodeJSON(w io.Writer) error {
+	if _, err := io.WriteString(w, "{"); err != nil {
+		return err
+	}
+	for pair := o.first; pair != nil; pair = pair.next {
+		key, err := keyToString(pair.key)
+		if err != nil {
+			return err
+		}
+		kBytes, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		vBytes, err := json.Marshal(pair.value)
+		if err != nil {
+			return err
+		}
+		if pair != o.first {
+			kBytes = append([]byte{','}, kBytes...)
+		}
+		kBytes = append(kBytes, ':')
+		if _, err := w.Write(append(kBytes, vBytes...)); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}")
+	return err
+}
+
+func (o *OrderedMapG[K, V]) UnmarshalJSON(b []byte) error {
+	return o.DecodeJSON(json.NewDecoder(bytes.NewReader(b)))
+}
+
+// DecodeJSON replaces the content of the map with the next JSON object read from dec.
+// With interface{} values, nested objects are decoded as OrderedMap values and
+// arrays as []interface{}, so the order of every object is kept.
+func (o *OrderedMapG[K, V]) DecodeJSON(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("orderedmap: expected a JSON object, found %v", tok)
+	}
+	return o.decodeObject(dec)
+}
+
+// decodeObject decodes the entries of an object whose '{' was read
+func (o *OrderedMapG[K, V]) decodeObject(dec *json.Decoder) error {
+	*o = OrderedMapG[K, V]{pairs: map[K]*PairG[K, V]{}}
+	generic := isEmptyInterface[V]()
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key, err := stringToKey[K](tok.(string))
+		if err != nil {
+			return err
+		}
+
+		var value V
+		if generic {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return err
+			}
+			if v != nil {
+				value = v.(V)
+			}
+		} else if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		o.Set(key, value)
+	}
+	_, err := dec.Token() // '}'
+	return err
+}
+
+// decodeValue decodes any JSON value, keeping the order of the objects
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := OrderedMap{}
+		err := o.decodeObject(dec)
+		return o, err
+	case json.Delim('['):
+		s := []interface{}{}
+		for dec.More() {
+			v, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		_, err := dec.Token() // ']'
+		return s, err
+	default:
+		return tok, nil
+	}
+}
+
+func isEmptyInterface[V any]() bool {
+	t := reflect.TypeOf((*V)(nil)).Elem()
+	return t.Kind() == reflect.Interface && t.NumMethod() == 0
+}
+
+func keyToString[K comparable](key K) (string, error) {
+	if tm, ok := any(key).(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("orderedmap: unsupported JSON key type %T", key)
+}
+
+func stringToKey[K comparable](s string) (K, error) {
+	var key K
+	if tu, ok := any(&key).(encoding.TextUnmarshaler); ok {
+		err := tu.UnmarshalText([]byte(s))
+		return key, err
+	}
+	v := reflect.ValueOf(&key).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return key, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(n)
+		return key, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(n)
+		return key, err
+	}
+	return key, fmt.Errorf("orderedmap: unsupported JSON key type %T", key)
+}
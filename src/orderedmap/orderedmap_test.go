@@ -1,10 +1,16 @@
 package orderedmap
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v2"
 )
 
 func TestOrderedMap(t *testing.T) {
@@ -465,3 +471,143 @@ func TestOrderedMap_empty_map(t *testing.T) {
 		t.Error("Got", marshalledStr)
 	}
 }
+
+func TestDeleteKeepsOrder(t *testing.T) {
+	o := NewG[int, string]()
+	for i := 0; i < 10; i++ {
+		o.Set(i, strconv.Itoa(i))
+	}
+	for _, k := range []int{0, 9, 4, 4, 100} {
+		o.Delete(k)
+	}
+	o.Set(0, "again")
+	expected := []int{1, 2, 3, 5, 6, 7, 8, 0}
+	if k := o.Keys(); !reflect.DeepEqual(k, expected) || o.Len() != len(expected) {
+		t.Error("Keys after Delete", k, "!=", expected)
+	}
+	var back []int
+	for p := o.Back(); p != nil; p = p.Prev() {
+		back = append(back, p.Key())
+	}
+	if back[0] != 0 || back[len(back)-1] != 1 {
+		t.Error("Back/Prev order", back)
+	}
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestGenericJSON(t *testing.T) {
+	o := NewG[int, point]()
+	o.Set(3, point{1, 2})
+	o.Set(-1, point{3, 4})
+	b, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"3":{"X":1,"Y":2},"-1":{"X":3,"Y":4}}` {
+		t.Error("JSON Marshal generic map", string(b))
+	}
+
+	var decoded OrderedMapG[int, point]
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if k := decoded.Keys(); !reflect.DeepEqual(k, []int{3, -1}) {
+		t.Error("Unmarshal generic keys", k)
+	}
+	if v, _ := decoded.Get(-1); v != (point{3, 4}) {
+		t.Error("Unmarshal generic value", v)
+	}
+	if err := json.Unmarshal([]byte(`{"x":{"X":1}}`), &decoded); err == nil {
+		t.Error("Unmarshal accepted a non integer key")
+	}
+}
+
+func TestUnmarshalJSONEscapedKeys(t *testing.T) {
+	o := New()
+	err := json.Unmarshal([]byte(`{"b\"":1,"a\\":{"é":[{"z":1,"y":2}]},"c":null}`), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := o.Keys(); !reflect.DeepEqual(k, []string{`b"`, `a\`, "c"}) {
+		t.Error("Unmarshal escaped keys", k)
+	}
+	v, _ := o.Get(`a\`)
+	outer := v.(OrderedMap)
+	inner, _ := outer.Get("é")
+	first := inner.([]interface{})[0].(OrderedMap)
+	if k := first.Keys(); !reflect.DeepEqual(k, []string{"z", "y"}) {
+		t.Error("Unmarshal nested keys", k)
+	}
+	if v, ok := o.Get("c"); !ok || v != nil {
+		t.Error("Unmarshal null value", v, ok)
+	}
+}
+
+func TestDecodeJSONStream(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"b":1,"a":2} {"d":3,"c":4}`))
+	var keys [][]string
+	for dec.More() {
+		o := New()
+		if err := o.DecodeJSON(dec); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, o.Keys())
+	}
+	if !reflect.DeepEqual(keys, [][]string{{"b", "a"}, {"d", "c"}}) {
+		t.Error("DecodeJSON stream", keys)
+	}
+
+	var buf bytes.Buffer
+	o := New()
+	o.Set("y", 1)
+	o.Set("x", []int{2})
+	if err := o.EncodeJSON(&buf); err != nil || buf.String() != `{"y":1,"x":[2]}` {
+		t.Error("EncodeJSON", buf.String(), err)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	o := New()
+	o.Set("z", 1)
+	o.Set("a", "x")
+	nested := New()
+	nested.Set("n2", true)
+	nested.Set("n1", []interface{}{"v"})
+	o.Set("nested", nested)
+
+	b, err := yaml.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "z: 1\na: x\nnested:\n  n2: true\n  n1:\n  - v\n"
+	if string(b) != expected {
+		t.Error("YAML Marshal", string(b))
+	}
+
+	decoded := New()
+	if err := yaml.Unmarshal(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if k := decoded.Keys(); !reflect.DeepEqual(k, []string{"z", "a", "nested"}) {
+		t.Error("YAML Unmarshal keys", k)
+	}
+	v, _ := decoded.Get("nested")
+	decodedNested := v.(OrderedMap)
+	if k := decodedNested.Keys(); !reflect.DeepEqual(k, []string{"n2", "n1"}) {
+		t.Error("YAML Unmarshal nested keys", k)
+	}
+
+	typed := NewG[int, point]()
+	if err := yaml.Unmarshal([]byte("2: {x: 1, \"y\": 2}\n1: {x: 3}\n"), typed); err != nil {
+		t.Fatal(err)
+	}
+	if k := typed.Keys(); !reflect.DeepEqual(k, []int{2, 1}) {
+		t.Error("YAML Unmarshal generic keys", k)
+	}
+	if v, _ := typed.Get(2); v != (point{1, 2}) {
+		t.Error("YAML Unmarshal generic value", v)
+	}
+}
@@ -0,0 +1,93 @@
+package orderedmap
+
+import (
+	"gopkg.in/yaml.v2"
+)
+
+// MarshalYAML encodes the map as a YAML mapping keeping the order of the keys
+func (o OrderedMapG[K, V]) MarshalYAML() (interface{}, error) {
+	items := make(yaml.MapSlice, 0, len(o.pairs))
+	for pair := o.first; pair != nil; pair = pair.next {
+		items = append(items, yaml.MapItem{Key: pair.key, Value: pair.value})
+	}
+	return items, nil
+}
+
+// UnmarshalYAML replaces the content of the map with a YAML mapping.
+// With interface{} values, nested mappings are decoded as OrderedMap values.
+func (o *OrderedMapG[K, V]) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+
+	*o = OrderedMapG[K, V]{pairs: map[K]*PairG[K, V]{}}
+	generic := isEmptyInterface[V]()
+	for _, item := range items {
+		var key K
+		if err := convertYAML(item.Key, &key); err != nil {
+			return err
+		}
+
+		var value V
+		if generic {
+			if v := fromMapSlice(item.Value); v != nil {
+				value = v.(V)
+			}
+		} else if err := convertYAML(item.Value, &value); err != nil {
+			return err
+		}
+		o.Set(key, value)
+	}
+	return nil
+}
+
+// convertYAML converts a decoded YAML value to the type of out
+func convertYAML(in, out interface{}) error {
+	if ok := assign(in, out); ok {
+		return nil
+	}
+	b, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, out)
+}
+
+// assign sets out to in without a YAML round trip for plain strings
+func assign(in, out interface{}) bool {
+	s, ok := in.(string)
+	if !ok {
+		return false
+	}
+	p, ok := out.(*string)
+	if ok {
+		*p = s
+	}
+	return ok
+}
+
+// fromMapSlice replaces the mappings decoded as yaml.MapSlice by OrderedMap values
+func fromMapSlice(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		o := OrderedMap{}
+		for _, item := range v {
+			key, ok := item.Key.(string)
+			if !ok {
+				b, _ := yaml.Marshal(item.Key)
+				key = string(b[:len(b)-1])
+			}
+			o.Set(key, fromMapSlice(item.Value))
+		}
+		return o
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i := range v {
+			s[i] = fromMapSlice(v[i])
+		}
+		return s
+	default:
+		return v
+	}
+}
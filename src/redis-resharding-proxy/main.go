@@ -11,6 +11,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -19,6 +20,8 @@ var (
 	proxyPort  int
 	proxyHost  string
 	keyRegexp  *regexp.Regexp
+	targets    string
+	progress   time.Duration
 )
 
 const (
@@ -116,7 +119,7 @@ func masterWriter(conn net.Conn, masterchannel <-chan []byte) {
 
 // Connect to master, request replication and filter it
 func masterConnection(slavechannel chan<- []byte, masterchannel <-chan []byte) {
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", masterHost, masterPort))
+	conn, err := net.Dial("tcp", net.JoinHostPort(masterHost, strconv.Itoa(masterPort)))
 	if err != nil {
 		log.Printf("Failed to connect to master: %v\n", err)
 		return
@@ -243,8 +246,18 @@ func main() {
 	flag.IntVar(&masterPort, "master-port", 6379, "Master Redis port")
 	flag.StringVar(&proxyHost, "proxy-host", "", "Proxy listening interface, default is on all interfaces")
 	flag.IntVar(&proxyPort, "proxy-port", 6380, "Proxy port for listening")
+	flag.StringVar(&targets, "targets", "", "Reshard mode: split the master across these Redis instances (host:port, comma separated)")
+	flag.DurationVar(&progress, "progress", 5*time.Second, "Reshard mode: interval of progress reports")
 	flag.Parse()
 
+	if targets != "" {
+		log.Printf("Redis Resharding Proxy resharding master at %s:%d to %s\n", masterHost, masterPort, targets)
+		if err := reshardMaster(strings.Split(targets, ","), progress); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if flag.NArg() != 1 {
 		flag.Usage()
 		fmt.Fprintln(os.Stderr, "Please specify regular expression to match against the Redis keys as the only argument.")
@@ -1,4 +1,4 @@
-package main
+package rdb
 
 // Redis version of CRC64
 
@@ -0,0 +1,874 @@
+// Package rdb decodes Redis RDB files into typed events.
+//
+// Format reference: https://github.com/sripathikrishnan/redis-rdb-tools/wiki/Redis-RDB-Dump-File-Format
+// and rdb.h of Redis for encodings newer than the wiki (listpacks, quicklist 2, module 2).
+package rdb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	opFunction2   = 0xF5
+	opFunctionPre = 0xF6
+	opModuleAux   = 0xF7
+	opIdle        = 0xF8
+	opFreq        = 0xF9
+	opAux         = 0xFA
+	opResizeDB    = 0xFB
+	opExpiryMSec  = 0xFC
+	opExpirySec   = 0xFD
+	opSelectDB    = 0xFE
+	opEOF         = 0xFF
+	opSlotInfo    = 0xF4
+
+	len6Bit  = 0x0
+	len14Bit = 0x1
+	len32Bit = 0x80
+	len64Bit = 0x81
+	lenEnc   = 0x3
+
+	encInt8  = 0
+	encInt16 = 1
+	encInt32 = 2
+	encLZF   = 3
+
+	typeString          = 0
+	typeList            = 1
+	typeSet             = 2
+	typeZset            = 3
+	typeHash            = 4
+	typeZset2           = 5
+	typeModule          = 6
+	typeModule2         = 7
+	typeHashZipmap      = 9
+	typeListZiplist     = 10
+	typeSetIntset       = 11
+	typeZsetZiplist     = 12
+	typeHashZiplist     = 13
+	typeListQuicklist   = 14
+	typeStreamListpacks = 15
+	typeHashListpack    = 16
+	typeZsetListpack    = 17
+	typeListQuicklist2  = 18
+	typeStream2         = 19
+	typeSetListpack     = 20
+	typeStream3         = 21
+	// hashes with field expiration, written by Redis 7.4
+	typeHashMetadataPreGA   = 22
+	typeHashListpackExPreGA = 23
+	typeHashMetadata        = 24
+	typeHashListpackEx      = 25
+
+	moduleOpEOF    = 0
+	moduleOpSInt   = 1
+	moduleOpUInt   = 2
+	moduleOpFloat  = 3
+	moduleOpDouble = 4
+	moduleOpString = 5
+
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+
+	// MaxVersion is the highest RDB version the Decoder understands
+	MaxVersion = 12
+
+	// maxStringLen bounds the length of a single string, like proto-max-bulk-len of Redis
+	maxStringLen = 512 << 20
+)
+
+var signature = []byte("REDIS")
+
+var (
+	// ErrWrongSignature is returned when RDB signature can't be parsed
+	ErrWrongSignature = errors.New("rdb: wrong signature")
+	// ErrVersionUnsupported is returned when RDB version is too high (can't parse)
+	ErrVersionUnsupported = errors.New("rdb: version unsupported")
+	// ErrUnsupportedOp is returned when unsupported operation is encountered in RDB
+	ErrUnsupportedOp = errors.New("rdb: unsupported opcode")
+	// ErrUnsupportedType is returned for value types that can't be decoded, like streams
+	ErrUnsupportedType = errors.New("rdb: unsupported value type")
+	// ErrUnsupportedStringEnc is returned when unsupported string encoding is encountered in RDB
+	ErrUnsupportedStringEnc = errors.New("rdb: unsupported string encoding")
+	// ErrCorrupted is returned when an embedded encoding (ziplist, listpack, intset...) is malformed
+	ErrCorrupted = errors.New("rdb: corrupted value")
+	// ErrChecksum is returned when the CRC64 at the end of the file does not match
+	ErrChecksum = errors.New("rdb: checksum mismatch")
+)
+
+// Event is one of *AuxEvent, *SelectDBEvent, *ResizeDBEvent, *KeyEvent or *ModuleAuxEvent
+type Event interface {
+	event()
+}
+
+// AuxEvent is an auxiliary field like redis-ver or used-mem
+type AuxEvent struct {
+	Key   string
+	Value string
+}
+
+// SelectDBEvent starts the keys of a database
+type SelectDBEvent struct {
+	DB int
+}
+
+// ResizeDBEvent hints the number of keys of the current database
+type ResizeDBEvent struct {
+	DBSize      uint64
+	ExpiresSize uint64
+}
+
+// ModuleAuxEvent is auxiliary data stored by a module outside of any key
+type ModuleAuxEvent struct {
+	Module Module
+}
+
+// KeyEvent is a key with its value.
+// Value is one of String, List, Set, SortedSet, Hash or Module, whatever the encoding in the file was.
+type KeyEvent struct {
+	DB  int
+	Key string
+	// ExpireAt is the expiration time, zero if the key does not expire
+	ExpireAt time.Time
+	Value    interface{}
+}
+
+func (*AuxEvent) event()       {}
+func (*SelectDBEvent) event()  {}
+func (*ResizeDBEvent) event()  {}
+func (*ModuleAuxEvent) event() {}
+func (*KeyEvent) event()       {}
+
+// String is a string value
+type String string
+
+// List is a list value
+type List []string
+
+// Set is a set value
+type Set []string
+
+// SortedSet is a sorted set value in the order stored in the file
+type SortedSet []ZMember
+
+// ZMember is a member of a sorted set
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// Hash is a hash value in the order stored in the file
+type Hash []HashField
+
+// HashField is a field of a hash
+type HashField struct {
+	Field string
+	Value string
+	// ExpireAt is the expiration time of the field, zero if the field does not expire
+	ExpireAt time.Time
+}
+
+// Module is a value serialized by a module.
+// Values of the module 2 format are decoded into their primitive fields.
+type Module struct {
+	Name    string
+	Version int
+	Fields  []interface{} // int64, uint64, float32, float64 or string
+}
+
+// Decoder reads events from an RDB stream
+type Decoder struct {
+	reader   *bufio.Reader
+	version  int
+	db       int
+	expireAt time.Time
+	offset   int64
+	crc      uint64
+	done     bool
+}
+
+// NewDecoder returns a decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{reader: bufio.NewReaderSize(r, 16384)}
+}
+
+// Version returns the RDB version, it is known after the first call to Next
+func (d *Decoder) Version() int {
+	return d.version
+}
+
+// Offset returns the number of bytes consumed so far
+func (d *Decoder) Offset() int64 {
+	return d.offset
+}
+
+// Next returns the next event, or io.EOF once the end of the file and its checksum were read
+func (d *Decoder) Next() (Event, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+	if d.offset == 0 {
+		if err := d.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		op, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opAux:
+			key, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			return &AuxEvent{Key: key, Value: value}, nil
+		case opSelectDB:
+			db, err := d.readLen()
+			if err != nil {
+				return nil, err
+			}
+			d.db = int(db)
+			return &SelectDBEvent{DB: d.db}, nil
+		case opResizeDB:
+			size, err := d.readLen()
+			if err != nil {
+				return nil, err
+			}
+			expires, err := d.readLen()
+			if err != nil {
+				return nil, err
+			}
+			return &ResizeDBEvent{DBSize: size, ExpiresSize: expires}, nil
+		case opExpirySec:
+			data, err := d.read(4)
+			if err != nil {
+				return nil, err
+			}
+			d.expireAt = time.Unix(int64(binary.LittleEndian.Uint32(data)), 0)
+		case opExpiryMSec:
+			data, err := d.read(8)
+			if err != nil {
+				return nil, err
+			}
+			d.expireAt = msecTime(int64(binary.LittleEndian.Uint64(data)))
+		case opFreq:
+			if _, err := d.readByte(); err != nil {
+				return nil, err
+			}
+		case opIdle:
+			if _, err := d.readLen(); err != nil {
+				return nil, err
+			}
+		case opSlotInfo:
+			for i := 0; i < 3; i++ {
+				if _, err := d.readLen(); err != nil {
+					return nil, err
+				}
+			}
+		case opFunction2:
+			if _, err := d.readString(); err != nil {
+				return nil, err
+			}
+		case opModuleAux:
+			m, err := d.readModuleAux()
+			if err != nil {
+				return nil, err
+			}
+			return &ModuleAuxEvent{Module: m}, nil
+		case opEOF:
+			return nil, d.readEOF()
+		case opFunctionPre:
+			return nil, ErrUnsupportedOp
+		default:
+			return d.readKey(op)
+		}
+	}
+}
+
+func (d *Decoder) readHeader() error {
+	magic, err := d.read(5)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(magic, signature) {
+		return ErrWrongSignature
+	}
+
+	versionRaw, err := d.read(4)
+	if err != nil {
+		return err
+	}
+	version, err := strconv.Atoi(string(versionRaw))
+	if err != nil {
+		return ErrWrongSignature
+	}
+	if version > MaxVersion {
+		return ErrVersionUnsupported
+	}
+	d.version = version
+	return nil
+}
+
+func (d *Decoder) readEOF() error {
+	d.done = true
+	if d.version < 5 {
+		return io.EOF
+	}
+
+	expected := d.crc
+	data, err := d.read(8)
+	if err != nil {
+		return err
+	}
+	// a zero checksum means checksumming was disabled on the server
+	crc := binary.LittleEndian.Uint64(data)
+	if crc != 0 && crc != expected {
+		return ErrChecksum
+	}
+	return io.EOF
+}
+
+func (d *Decoder) readKey(valueType byte) (Event, error) {
+	key, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := d.readValue(valueType)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+
+	ev := &KeyEvent{DB: d.db, Key: key, ExpireAt: d.expireAt, Value: value}
+	d.expireAt = time.Time{}
+	return ev, nil
+}
+
+func (d *Decoder) readValue(valueType byte) (interface{}, error) {
+	switch valueType {
+	case typeString:
+		s, err := d.readString()
+		return String(s), err
+	case typeList:
+		l, err := d.readStringList()
+		return List(l), err
+	case typeSet:
+		l, err := d.readStringList()
+		return Set(l), err
+	case typeZset, typeZset2:
+		return d.readZset(valueType == typeZset2)
+	case typeHash:
+		return d.readHash()
+	case typeHashZipmap:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		return decodeZipmap(b)
+	case typeListZiplist:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodeZiplist(b)
+		return List(l), err
+	case typeSetIntset:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodeIntset(b)
+		return Set(l), err
+	case typeZsetZiplist, typeZsetListpack:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodePacked(b, valueType == typeZsetListpack)
+		if err != nil {
+			return nil, err
+		}
+		return pairsToSortedSet(l)
+	case typeHashZiplist, typeHashListpack:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodePacked(b, valueType == typeHashListpack)
+		if err != nil {
+			return nil, err
+		}
+		return pairsToHash(l)
+	case typeHashMetadata, typeHashMetadataPreGA:
+		return d.readHashMetadata(valueType == typeHashMetadataPreGA)
+	case typeHashListpackEx, typeHashListpackExPreGA:
+		if valueType == typeHashListpackEx {
+			// the minimal expiration time is only an optimization of the server
+			if _, err := d.read(8); err != nil {
+				return nil, err
+			}
+		}
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodeListpack(b)
+		if err != nil {
+			return nil, err
+		}
+		return triplesToHash(l)
+	case typeSetListpack:
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+		l, err := decodeListpack(b)
+		return Set(l), err
+	case typeListQuicklist, typeListQuicklist2:
+		return d.readQuicklist(valueType == typeListQuicklist2)
+	case typeModule2:
+		id, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		return d.readModule(id)
+	case typeModule, typeStreamListpacks, typeStream2, typeStream3:
+		return nil, ErrUnsupportedType
+	default:
+		return nil, ErrUnsupportedOp
+	}
+}
+
+func (d *Decoder) readStringList() ([]string, error) {
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, capHint(length))
+	for i := uint64(0); i < length; i++ {
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (d *Decoder) readZset(binaryScore bool) (SortedSet, error) {
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(SortedSet, 0, capHint(length))
+	for i := uint64(0); i < length; i++ {
+		member, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		var score float64
+		if binaryScore {
+			data, err := d.read(8)
+			if err != nil {
+				return nil, err
+			}
+			score = math.Float64frombits(binary.LittleEndian.Uint64(data))
+		} else {
+			score, err = d.readDoubleString()
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, ZMember{Member: member, Score: score})
+	}
+	return result, nil
+}
+
+// read a double stored as a length-prefixed ASCII string, used by the old zset encoding
+func (d *Decoder) readDoubleString() (float64, error) {
+	dlen, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch dlen {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+
+	data, err := d.read(int(dlen))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(data), 64)
+}
+
+func (d *Decoder) readHash() (Hash, error) {
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Hash, 0, capHint(length))
+	for i := uint64(0); i < length; i++ {
+		field, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, HashField{Field: field, Value: value})
+	}
+	return result, nil
+}
+
+// read a hash with field expiration in its hashtable encoding
+func (d *Decoder) readHashMetadata(preGA bool) (Hash, error) {
+	// TTLs are relative to the minimal expiration time, pre GA files store absolute times
+	var minExpire uint64
+	if !preGA {
+		data, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		minExpire = binary.LittleEndian.Uint64(data)
+	}
+
+	length, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(Hash, 0, capHint(length))
+	for i := uint64(0); i < length; i++ {
+		ttl, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		field, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+
+		f := HashField{Field: field, Value: value}
+		if ttl != 0 {
+			ms := ttl
+			if !preGA {
+				ms = ttl + minExpire - 1
+			}
+			f.ExpireAt = msecTime(int64(ms))
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func (d *Decoder) readQuicklist(v2 bool) (List, error) {
+	nodes, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	var result List
+	for i := uint64(0); i < nodes; i++ {
+		container := uint64(quicklistNodePacked)
+		if v2 {
+			container, err = d.readLen()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		b, err := d.readBytes()
+		if err != nil {
+			return nil, err
+		}
+
+		switch container {
+		case quicklistNodePlain:
+			result = append(result, string(b))
+		case quicklistNodePacked:
+			l, err := decodePacked(b, v2)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, l...)
+		default:
+			return nil, ErrCorrupted
+		}
+	}
+	return result, nil
+}
+
+func (d *Decoder) readModuleAux() (Module, error) {
+	id, err := d.readLen()
+	if err != nil {
+		return Module{}, err
+	}
+	// when_opcode and when
+	for i := 0; i < 2; i++ {
+		if _, err := d.readLen(); err != nil {
+			return Module{}, err
+		}
+	}
+	return d.readModule(id)
+}
+
+// read the opcodes of a module 2 value until the module EOF
+func (d *Decoder) readModule(id uint64) (Module, error) {
+	m := Module{Name: moduleName(id), Version: int(id & 1023)}
+	for {
+		op, err := d.readLen()
+		if err != nil {
+			return m, err
+		}
+
+		switch op {
+		case moduleOpEOF:
+			return m, nil
+		case moduleOpSInt:
+			v, err := d.readLen()
+			if err != nil {
+				return m, err
+			}
+			m.Fields = append(m.Fields, int64(v))
+		case moduleOpUInt:
+			v, err := d.readLen()
+			if err != nil {
+				return m, err
+			}
+			m.Fields = append(m.Fields, v)
+		case moduleOpFloat:
+			data, err := d.read(4)
+			if err != nil {
+				return m, err
+			}
+			m.Fields = append(m.Fields, math.Float32frombits(binary.LittleEndian.Uint32(data)))
+		case moduleOpDouble:
+			data, err := d.read(8)
+			if err != nil {
+				return m, err
+			}
+			m.Fields = append(m.Fields, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+		case moduleOpString:
+			s, err := d.readString()
+			if err != nil {
+				return m, err
+			}
+			m.Fields = append(m.Fields, s)
+		default:
+			return m, ErrCorrupted
+		}
+	}
+}
+
+const moduleCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// moduleName extracts the 9 character name packed in the upper 54 bits of a module id
+func moduleName(id uint64) string {
+	name := make([]byte, 9)
+	id >>= 10
+	for i := 8; i >= 0; i-- {
+		name[i] = moduleCharset[id&63]
+		id >>= 6
+	}
+	return string(name)
+}
+
+func msecTime(ms int64) time.Time {
+	return time.Unix(ms/1000, (ms%1000)*int64(time.Millisecond))
+}
+
+// Read exactly n bytes
+func (d *Decoder) read(n int) ([]byte, error) {
+	if n <= 65536 {
+		result := make([]byte, n)
+		if _, err := io.ReadFull(d.reader, result); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		d.consumed(result)
+		return result, nil
+	}
+
+	// grow the buffer with the data actually received, so a corrupted length can't exhaust memory
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, d.reader, int64(n)); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	d.consumed(buf.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Read a string of length bytes as stored in the stream
+func (d *Decoder) readLength(length uint64) ([]byte, error) {
+	if length > maxStringLen {
+		return nil, ErrCorrupted
+	}
+	return d.read(int(length))
+}
+
+func (d *Decoder) readByte() (byte, error) {
+	b, err := d.reader.ReadByte()
+	if err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	d.consumed([]byte{b})
+	return b, nil
+}
+
+func (d *Decoder) consumed(data []byte) {
+	d.offset += int64(len(data))
+	d.crc = CRC64Update(d.crc, data)
+}
+
+// Read length encoded prefix, encoding is -1 for plain lengths
+func (d *Decoder) readLenEnc() (length uint64, encoding int, err error) {
+	prefix, err := d.readByte()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch prefix >> 6 {
+	case len6Bit:
+		return uint64(prefix & 0x3F), -1, nil
+	case len14Bit:
+		next, err := d.readByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		return uint64(prefix&0x3F)<<8 | uint64(next), -1, nil
+	case lenEnc:
+		return 0, int(prefix & 0x3F), nil
+	}
+
+	switch prefix {
+	case len32Bit:
+		data, err := d.read(4)
+		if err != nil {
+			return 0, 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(data)), -1, nil
+	case len64Bit:
+		data, err := d.read(8)
+		if err != nil {
+			return 0, 0, err
+		}
+		return binary.BigEndian.Uint64(data), -1, nil
+	}
+	return 0, 0, ErrCorrupted
+}
+
+func (d *Decoder) readLen() (uint64, error) {
+	length, encoding, err := d.readLenEnc()
+	if err != nil {
+		return 0, err
+	}
+	if encoding != -1 {
+		return 0, ErrCorrupted
+	}
+	return length, nil
+}
+
+func (d *Decoder) readString() (string, error) {
+	b, err := d.readBytes()
+	return string(b), err
+}
+
+// read a string in any of its encodings
+func (d *Decoder) readBytes() ([]byte, error) {
+	length, encoding, err := d.readLenEnc()
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	case -1:
+		return d.readLength(length)
+	case encInt8:
+		data, err := d.read(1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int8(data[0])), 10), nil
+	case encInt16:
+		data, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int16(binary.LittleEndian.Uint16(data))), 10), nil
+	case encInt32:
+		data, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int32(binary.LittleEndian.Uint32(data))), 10), nil
+	case encLZF:
+		clength, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		length, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		// a back reference of 3 bytes expands to at most 264 bytes
+		if length > maxStringLen || length > 88*clength+64 {
+			return nil, ErrCorrupted
+		}
+		data, err := d.readLength(clength)
+		if err != nil {
+			return nil, err
+		}
+		result := LZFDecompress(data, uint32(length))
+		if result == nil {
+			return nil, ErrCorrupted
+		}
+		return result, nil
+	default:
+		return nil, ErrUnsupportedStringEnc
+	}
+}
+
+// capHint bounds preallocation so a corrupted length can't exhaust memory
+func capHint(length uint64) int {
+	if length > 1024 {
+		return 1024
+	}
+	return int(length)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
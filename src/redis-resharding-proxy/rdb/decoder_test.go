@@ -0,0 +1,402 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rdbBuilder writes RDB files for the tests
+type rdbBuilder struct {
+	bytes.Buffer
+}
+
+func newRDB(version string) *rdbBuilder {
+	b := &rdbBuilder{}
+	b.WriteString("REDIS" + version)
+	return b
+}
+
+func (b *rdbBuilder) length(l uint64) {
+	switch {
+	case l < 1<<6:
+		b.WriteByte(byte(l))
+	case l < 1<<14:
+		b.WriteByte(byte(l>>8) | 0x40)
+		b.WriteByte(byte(l))
+	case l <= math.MaxUint32:
+		b.WriteByte(len32Bit)
+		binary.Write(b, binary.BigEndian, uint32(l))
+	default:
+		b.WriteByte(len64Bit)
+		binary.Write(b, binary.BigEndian, l)
+	}
+}
+
+func (b *rdbBuilder) str(s string) {
+	b.length(uint64(len(s)))
+	b.WriteString(s)
+}
+
+func (b *rdbBuilder) blob(data []byte) {
+	b.length(uint64(len(data)))
+	b.Write(data)
+}
+
+func (b *rdbBuilder) eof() []byte {
+	b.WriteByte(opEOF)
+	crc := CRC64Update(0, b.Bytes())
+	binary.Write(b, binary.LittleEndian, crc)
+	return b.Bytes()
+}
+
+// listpack builds a listpack of strings and small integers
+func listpack(elements ...interface{}) []byte {
+	var body bytes.Buffer
+	for _, e := range elements {
+		var entry []byte
+		switch v := e.(type) {
+		case int:
+			if v >= 0 && v < 128 {
+				entry = []byte{byte(v)}
+			} else if int64(v) != int64(int32(v)) {
+				entry = make([]byte, 9)
+				entry[0] = 0xF4
+				binary.LittleEndian.PutUint64(entry[1:], uint64(v))
+			} else {
+				entry = []byte{0xF3, 0, 0, 0, 0}
+				binary.LittleEndian.PutUint32(entry[1:], uint32(int32(v)))
+			}
+		case string:
+			if len(v) < 64 {
+				entry = append([]byte{0x80 | byte(len(v))}, v...)
+			} else {
+				entry = append([]byte{0xE0 | byte(len(v)>>8), byte(len(v))}, v...)
+			}
+		}
+		body.Write(entry)
+		// backlen, entries in the tests stay below 16383 bytes
+		if len(entry) <= 127 {
+			body.WriteByte(byte(len(entry)))
+		} else {
+			body.Write([]byte{byte(len(entry) >> 7), byte(len(entry)&127) | 128})
+		}
+	}
+	body.WriteByte(0xFF)
+
+	header := make([]byte, 6)
+	binary.LittleEndian.PutUint32(header, uint32(6+body.Len()))
+	binary.LittleEndian.PutUint16(header[4:], uint16(len(elements)))
+	return append(header, body.Bytes()...)
+}
+
+// ziplist builds a ziplist of strings and integers
+func ziplist(elements ...interface{}) []byte {
+	var body bytes.Buffer
+	prev := 0
+	for _, e := range elements {
+		var entry []byte
+		switch v := e.(type) {
+		case int:
+			switch {
+			case v >= 0 && v <= 12:
+				entry = []byte{0xF1 + byte(v)}
+			default:
+				entry = []byte{0xC0, byte(v), byte(v >> 8)}
+			}
+		case string:
+			entry = append([]byte{byte(len(v))}, v...)
+		}
+		body.WriteByte(byte(prev))
+		body.Write(entry)
+		prev = len(entry) + 1
+	}
+	body.WriteByte(0xFF)
+
+	header := make([]byte, 10)
+	binary.LittleEndian.PutUint32(header, uint32(10+body.Len()))
+	binary.LittleEndian.PutUint16(header[8:], uint16(len(elements)))
+	return append(header, body.Bytes()...)
+}
+
+func decodeAll(t *testing.T, data []byte) []Event {
+	d := NewDecoder(bytes.NewReader(data))
+	var events []Event
+	for {
+		ev, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		events = append(events, ev)
+	}
+	if d.Offset() != int64(len(data)) {
+		t.Fatalf("consumed %d bytes of %d", d.Offset(), len(data))
+	}
+	return events
+}
+
+func TestCRC64(t *testing.T) {
+	// check value from Redis crc64.c
+	if crc := CRC64Update(0, []byte("123456789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Fatalf("crc = %x", crc)
+	}
+}
+
+func TestDecodeClassicEncodings(t *testing.T) {
+	b := newRDB("0006")
+	b.WriteByte(opSelectDB)
+	b.length(2)
+
+	b.WriteByte(opExpiryMSec)
+	binary.Write(b, binary.LittleEndian, uint64(1500000000123))
+	b.WriteByte(typeString)
+	b.str("str")
+	b.str("value")
+
+	b.WriteByte(typeString)
+	b.str("int")
+	b.WriteByte(0xC0 | encInt16)
+	binary.Write(b, binary.LittleEndian, int16(-300))
+
+	b.WriteByte(typeList)
+	b.str("list")
+	b.length(2)
+	b.str("a")
+	b.str("b")
+
+	b.WriteByte(typeZset)
+	b.str("zset")
+	b.length(2)
+	b.str("m1")
+	b.WriteByte(3)
+	b.WriteString("1.5")
+	b.str("m2")
+	b.WriteByte(254)
+
+	b.WriteByte(typeHash)
+	b.str("hash")
+	b.length(1)
+	b.str("f")
+	b.str("v")
+
+	b.WriteByte(typeSetIntset)
+	b.str("intset")
+	intset := []byte{2, 0, 0, 0, 2, 0, 0, 0}
+	intset = append(intset, 0xFF, 0xFF, 7, 0)
+	b.blob(intset)
+
+	b.WriteByte(typeHashZipmap)
+	b.str("zipmap")
+	b.blob([]byte{1, 1, 'k', 2, 1, 'v', 'v', 0, 0xFF})
+
+	b.WriteByte(typeZsetZiplist)
+	b.str("zsetzl")
+	b.blob(ziplist("x", 3, "y", "2.5"))
+
+	b.WriteByte(typeListQuicklist)
+	b.str("quicklist")
+	b.length(2)
+	b.blob(ziplist("a", 300))
+	b.blob(ziplist("b"))
+
+	events := decodeAll(t, b.eof())
+
+	want := []Event{
+		&SelectDBEvent{DB: 2},
+		&KeyEvent{DB: 2, Key: "str", ExpireAt: time.Unix(1500000000, 123000000), Value: String("value")},
+		&KeyEvent{DB: 2, Key: "int", Value: String("-300")},
+		&KeyEvent{DB: 2, Key: "list", Value: List{"a", "b"}},
+		&KeyEvent{DB: 2, Key: "zset", Value: SortedSet{{"m1", 1.5}, {"m2", math.Inf(1)}}},
+		&KeyEvent{DB: 2, Key: "hash", Value: Hash{{Field: "f", Value: "v"}}},
+		&KeyEvent{DB: 2, Key: "intset", Value: Set{"-1", "7"}},
+		&KeyEvent{DB: 2, Key: "zipmap", Value: Hash{{Field: "k", Value: "vv"}}},
+		&KeyEvent{DB: 2, Key: "zsetzl", Value: SortedSet{{"x", 3}, {"y", 2.5}}},
+		&KeyEvent{DB: 2, Key: "quicklist", Value: List{"a", "300", "b"}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		for i := range events {
+			t.Logf("%d: %#v", i, events[i])
+		}
+		t.Fatal("unexpected events")
+	}
+}
+
+func TestDecodeListpackEncodings(t *testing.T) {
+	long := string(bytes.Repeat([]byte("x"), 200))
+
+	b := newRDB("0011")
+	b.WriteByte(opAux)
+	b.str("redis-ver")
+	b.str("7.2.0")
+	b.WriteByte(opSelectDB)
+	b.length(0)
+	b.WriteByte(opResizeDB)
+	b.length(5)
+	b.length(0)
+
+	b.WriteByte(typeHashListpack)
+	b.str("hash")
+	b.blob(listpack("f1", "v1", "f2", 100000))
+
+	b.WriteByte(opFreq)
+	b.WriteByte(5)
+	b.WriteByte(typeZsetListpack)
+	b.str("zset")
+	b.blob(listpack("a", 1, "b", "-2.25"))
+
+	b.WriteByte(typeSetListpack)
+	b.str("set")
+	b.blob(listpack("x", 42))
+
+	b.WriteByte(opIdle)
+	b.length(10)
+	b.WriteByte(typeListQuicklist2)
+	b.str("list")
+	b.length(2)
+	b.length(quicklistNodePacked)
+	b.blob(listpack("a", long))
+	b.length(quicklistNodePlain)
+	b.str("plain")
+
+	b.WriteByte(typeZset2)
+	b.str("zset2")
+	b.length(1)
+	b.str("m")
+	binary.Write(b, binary.LittleEndian, math.Float64bits(-0.5))
+
+	events := decodeAll(t, b.eof())
+
+	want := []Event{
+		&AuxEvent{Key: "redis-ver", Value: "7.2.0"},
+		&SelectDBEvent{DB: 0},
+		&ResizeDBEvent{DBSize: 5},
+		&KeyEvent{Key: "hash", Value: Hash{{Field: "f1", Value: "v1"}, {Field: "f2", Value: "100000"}}},
+		&KeyEvent{Key: "zset", Value: SortedSet{{"a", 1}, {"b", -2.25}}},
+		&KeyEvent{Key: "set", Value: Set{"x", "42"}},
+		&KeyEvent{Key: "list", Value: List{"a", long, "plain"}},
+		&KeyEvent{Key: "zset2", Value: SortedSet{{"m", -0.5}}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		for i := range events {
+			t.Logf("%d: %#v", i, events[i])
+		}
+		t.Fatal("unexpected events")
+	}
+}
+
+func TestDecodeModule(t *testing.T) {
+	// "ReJSON-RL" version 3
+	var id uint64
+	for _, c := range "ReJSON-RL" {
+		id = id<<6 | uint64(bytes.IndexRune([]byte(moduleCharset), c))
+	}
+	id = id<<10 | 3
+
+	b := newRDB("0009")
+	b.WriteByte(typeModule2)
+	b.str("doc")
+	b.length(id)
+	b.length(moduleOpUInt)
+	b.length(7)
+	b.length(moduleOpString)
+	b.str("{}")
+	b.length(moduleOpEOF)
+
+	events := decodeAll(t, b.eof())
+	want := []Event{
+		&KeyEvent{Key: "doc", Value: Module{Name: "ReJSON-RL", Version: 3, Fields: []interface{}{uint64(7), "{}"}}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("events = %#v", events[0])
+	}
+}
+
+func TestDecodeHashFieldExpiration(t *testing.T) {
+	b := newRDB("0012")
+	b.WriteByte(typeHashMetadata)
+	b.str("hfe")
+	binary.Write(b, binary.LittleEndian, uint64(1700000000000))
+	b.length(2)
+	b.length(0)
+	b.str("f1")
+	b.str("v1")
+	b.length(501)
+	b.str("f2")
+	b.str("v2")
+
+	b.WriteByte(typeHashListpackEx)
+	b.str("hfe-lp")
+	binary.Write(b, binary.LittleEndian, uint64(1700000000500))
+	b.blob(listpack("f1", "v1", 0, "f2", 2, 1700000000500))
+
+	b.WriteByte(typeHashMetadataPreGA)
+	b.str("hfe-pre")
+	b.length(1)
+	b.length(1700000000500)
+	b.str("f")
+	b.str("v")
+
+	events := decodeAll(t, b.eof())
+	at := time.Unix(1700000000, 500*int64(time.Millisecond))
+	want := []Event{
+		&KeyEvent{Key: "hfe", Value: Hash{{Field: "f1", Value: "v1"}, {Field: "f2", Value: "v2", ExpireAt: at}}},
+		&KeyEvent{Key: "hfe-lp", Value: Hash{{Field: "f1", Value: "v1"}, {Field: "f2", Value: "2", ExpireAt: at}}},
+		&KeyEvent{Key: "hfe-pre", Value: Hash{{Field: "f", Value: "v", ExpireAt: at}}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		for i := range events {
+			t.Logf("%d: %#v", i, events[i])
+		}
+		t.Fatal("unexpected events")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := NewDecoder(bytes.NewReader([]byte("RODIS0006"))).Next(); err != ErrWrongSignature {
+		t.Fatalf("err = %v, want %v", err, ErrWrongSignature)
+	}
+	if _, err := NewDecoder(bytes.NewReader([]byte("REDIS0099"))).Next(); err != ErrVersionUnsupported {
+		t.Fatalf("err = %v, want %v", err, ErrVersionUnsupported)
+	}
+
+	b := newRDB("0006")
+	b.WriteByte(typeString)
+	b.str("k")
+	b.str("v")
+	data := b.eof()
+	data[len(data)-1] ^= 0xFF
+
+	d := NewDecoder(bytes.NewReader(data))
+	if _, err := d.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Next(); err != ErrChecksum {
+		t.Fatalf("err = %v, want %v", err, ErrChecksum)
+	}
+
+	d = NewDecoder(bytes.NewReader(data[:12]))
+	if _, err := d.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// corrupted lengths must fail without allocating what they claim
+	for _, length := range []uint64{math.MaxUint64, math.MaxUint32, 1 << 30} {
+		b := newRDB("0006")
+		b.WriteByte(typeString)
+		b.str("k")
+		b.length(length)
+		b.WriteString("short")
+		_, err := NewDecoder(bytes.NewReader(b.Bytes())).Next()
+		if !errors.Is(err, ErrCorrupted) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("length %d: err = %v", length, err)
+		}
+	}
+}
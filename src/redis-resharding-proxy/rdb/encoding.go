@@ -0,0 +1,340 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// decodePacked decodes a listpack or a ziplist into its elements
+func decodePacked(b []byte, listpack bool) ([]string, error) {
+	if listpack {
+		return decodeListpack(b)
+	}
+	return decodeZiplist(b)
+}
+
+// decodeZiplist decodes a ziplist:
+// <zlbytes uint32><zltail uint32><zllen uint16><entry>...<0xFF>
+// where every entry is <prevlen><encoding><data>
+func decodeZiplist(b []byte) ([]string, error) {
+	if len(b) < 11 {
+		return nil, ErrCorrupted
+	}
+
+	var result []string
+	pos := 10
+	for {
+		if pos >= len(b) {
+			return nil, ErrCorrupted
+		}
+		if b[pos] == 0xFF {
+			return result, nil
+		}
+
+		// prevlen is 1 byte, or 0xFE followed by 4 bytes
+		if b[pos] == 0xFE {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(b) {
+			return nil, ErrCorrupted
+		}
+
+		enc := b[pos]
+		pos++
+
+		var strLen int
+		switch enc >> 6 {
+		case 0:
+			strLen = int(enc & 0x3F)
+		case 1:
+			if pos+1 > len(b) {
+				return nil, ErrCorrupted
+			}
+			strLen = int(enc&0x3F)<<8 | int(b[pos])
+			pos++
+		case 2:
+			if pos+4 > len(b) {
+				return nil, ErrCorrupted
+			}
+			strLen = int(binary.BigEndian.Uint32(b[pos:]))
+			pos += 4
+		default:
+			var v int64
+			var n int
+			switch enc {
+			case 0xC0:
+				n = 2
+			case 0xD0:
+				n = 4
+			case 0xE0:
+				n = 8
+			case 0xF0:
+				n = 3
+			case 0xFE:
+				n = 1
+			default:
+				if enc < 0xF1 || enc > 0xFD {
+					return nil, ErrCorrupted
+				}
+				// 4 bit immediate integer between 0 and 12
+				v = int64(enc&0x0F) - 1
+			}
+			if pos+n > len(b) {
+				return nil, ErrCorrupted
+			}
+			if n > 0 {
+				v = readIntLE(b[pos : pos+n])
+			}
+			pos += n
+			result = append(result, strconv.FormatInt(v, 10))
+			continue
+		}
+
+		if pos+strLen > len(b) {
+			return nil, ErrCorrupted
+		}
+		result = append(result, string(b[pos:pos+strLen]))
+		pos += strLen
+	}
+}
+
+// decodeListpack decodes a listpack:
+// <total bytes uint32><num elements uint16><entry>...<0xFF>
+// where every entry is <encoding><data><backlen>
+func decodeListpack(b []byte) ([]string, error) {
+	if len(b) < 7 {
+		return nil, ErrCorrupted
+	}
+
+	var result []string
+	pos := 6
+	for {
+		if pos >= len(b) {
+			return nil, ErrCorrupted
+		}
+		enc := b[pos]
+		if enc == 0xFF {
+			return result, nil
+		}
+
+		start := pos
+		var value string
+		var header, strLen, intLen int
+		isString := false
+		switch {
+		case enc&0x80 == 0:
+			// 7 bit unsigned integer
+			value = strconv.Itoa(int(enc & 0x7F))
+			header = 1
+		case enc&0xC0 == 0x80:
+			strLen = int(enc & 0x3F)
+			header = 1
+			isString = true
+		case enc&0xE0 == 0xC0:
+			// 13 bit signed integer
+			if pos+2 > len(b) {
+				return nil, ErrCorrupted
+			}
+			v := int(enc&0x1F)<<8 | int(b[pos+1])
+			if v >= 1<<12 {
+				v -= 1 << 13
+			}
+			value = strconv.Itoa(v)
+			header = 2
+		case enc&0xF0 == 0xE0:
+			if pos+2 > len(b) {
+				return nil, ErrCorrupted
+			}
+			strLen = int(enc&0x0F)<<8 | int(b[pos+1])
+			header = 2
+			isString = true
+		case enc == 0xF0:
+			if pos+5 > len(b) {
+				return nil, ErrCorrupted
+			}
+			strLen = int(binary.LittleEndian.Uint32(b[pos+1:]))
+			header = 5
+			isString = true
+		case enc == 0xF1:
+			intLen = 2
+		case enc == 0xF2:
+			intLen = 3
+		case enc == 0xF3:
+			intLen = 4
+		case enc == 0xF4:
+			intLen = 8
+		default:
+			return nil, ErrCorrupted
+		}
+
+		if intLen > 0 {
+			if pos+1+intLen > len(b) {
+				return nil, ErrCorrupted
+			}
+			value = strconv.FormatInt(readIntLE(b[pos+1:pos+1+intLen]), 10)
+			pos += 1 + intLen
+		} else {
+			pos += header
+			if isString {
+				if pos+strLen > len(b) {
+					return nil, ErrCorrupted
+				}
+				value = string(b[pos : pos+strLen])
+				pos += strLen
+			}
+		}
+
+		pos += backlenSize(pos - start)
+		result = append(result, value)
+	}
+}
+
+// backlenSize returns the number of bytes used to store the length of an entry of size l
+func backlenSize(l int) int {
+	switch {
+	case l <= 127:
+		return 1
+	case l < 16383:
+		return 2
+	case l < 2097151:
+		return 3
+	case l < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// decodeIntset decodes an intset: <encoding uint32><length uint32><contents>
+func decodeIntset(b []byte) ([]string, error) {
+	if len(b) < 8 {
+		return nil, ErrCorrupted
+	}
+
+	size := int(binary.LittleEndian.Uint32(b))
+	length := int(binary.LittleEndian.Uint32(b[4:]))
+	if size != 2 && size != 4 && size != 8 || len(b) < 8+size*length {
+		return nil, ErrCorrupted
+	}
+
+	result := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		pos := 8 + i*size
+		result = append(result, strconv.FormatInt(readIntLE(b[pos:pos+size]), 10))
+	}
+	return result, nil
+}
+
+// decodeZipmap decodes a zipmap: <zmlen><len>key<len><free>value...<0xFF>
+func decodeZipmap(b []byte) (Hash, error) {
+	var result Hash
+	pos := 1
+
+	readLen := func() (int, bool) {
+		if pos >= len(b) {
+			return 0, false
+		}
+		l := int(b[pos])
+		if l < 254 {
+			pos++
+			return l, true
+		}
+		if l == 254 && pos+5 <= len(b) {
+			l = int(binary.LittleEndian.Uint32(b[pos+1:]))
+			pos += 5
+			return l, true
+		}
+		return 0, false
+	}
+
+	for {
+		if pos >= len(b) {
+			return nil, ErrCorrupted
+		}
+		if b[pos] == 0xFF {
+			return result, nil
+		}
+
+		keyLen, ok := readLen()
+		if !ok || pos+keyLen > len(b) {
+			return nil, ErrCorrupted
+		}
+		key := string(b[pos : pos+keyLen])
+		pos += keyLen
+
+		valueLen, ok := readLen()
+		if !ok || pos >= len(b) {
+			return nil, ErrCorrupted
+		}
+		free := int(b[pos])
+		pos++
+		if pos+valueLen+free > len(b) {
+			return nil, ErrCorrupted
+		}
+		value := string(b[pos : pos+valueLen])
+		pos += valueLen + free
+
+		result = append(result, HashField{Field: key, Value: value})
+	}
+}
+
+func pairsToHash(l []string) (Hash, error) {
+	if len(l)%2 != 0 {
+		return nil, ErrCorrupted
+	}
+
+	result := make(Hash, 0, len(l)/2)
+	for i := 0; i < len(l); i += 2 {
+		result = append(result, HashField{Field: l[i], Value: l[i+1]})
+	}
+	return result, nil
+}
+
+// triplesToHash converts the field, value, TTL triples of a listpack with field expiration
+func triplesToHash(l []string) (Hash, error) {
+	if len(l)%3 != 0 {
+		return nil, ErrCorrupted
+	}
+
+	result := make(Hash, 0, len(l)/3)
+	for i := 0; i < len(l); i += 3 {
+		ms, err := strconv.ParseInt(l[i+2], 10, 64)
+		if err != nil || ms < 0 {
+			return nil, ErrCorrupted
+		}
+		f := HashField{Field: l[i], Value: l[i+1]}
+		if ms != 0 {
+			f.ExpireAt = msecTime(ms)
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+func pairsToSortedSet(l []string) (SortedSet, error) {
+	if len(l)%2 != 0 {
+		return nil, ErrCorrupted
+	}
+
+	result := make(SortedSet, 0, len(l)/2)
+	for i := 0; i < len(l); i += 2 {
+		score, err := strconv.ParseFloat(l[i+1], 64)
+		if err != nil {
+			return nil, ErrCorrupted
+		}
+		result = append(result, ZMember{Member: l[i], Score: score})
+	}
+	return result, nil
+}
+
+// readIntLE reads a little endian signed integer of 1 to 8 bytes
+func readIntLE(b []byte) int64 {
+	var v uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	shift := uint(64 - 8*len(b))
+	return int64(v<<shift) >> shift
+}
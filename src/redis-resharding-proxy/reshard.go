@@ -0,0 +1,538 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pathbox/learning-go/src/consistent-hashing/hashring"
+	"github.com/pathbox/learning-go/src/redis-resharding-proxy/rdb"
+)
+
+// maximum number of elements sent in one RPUSH/SADD/ZADD/HSET
+const batchSize = 512
+
+// target is a Redis instance receiving a share of the keys
+type target struct {
+	addr   string
+	conn   net.Conn
+	writer *bufio.Writer
+	db     int
+
+	// keys loaded from the RDB per database
+	keys map[int]int64
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	sent    int64
+	replies int64
+	errors  int64
+	err     error
+	// last integer reply, used by DBSIZE during verification
+	lastInt int64
+}
+
+func dialTarget(addr string) (*target, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{
+		addr:   addr,
+		conn:   conn,
+		writer: bufio.NewWriterSize(conn, bufSize),
+		keys:   make(map[int]int64),
+	}
+	t.cond = sync.NewCond(&t.mu)
+	go t.readReplies()
+	return t, nil
+}
+
+// readReplies consumes the replies of pipelined commands
+func (t *target) readReplies() {
+	reader := bufio.NewReaderSize(t.conn, bufSize)
+	for {
+		r, err := readReply(reader)
+
+		t.mu.Lock()
+		if err != nil {
+			t.err = err
+			t.cond.Broadcast()
+			t.mu.Unlock()
+			return
+		}
+		t.replies++
+		if r.err != "" {
+			t.errors++
+			log.Printf("[%s] %s\n", t.addr, r.err)
+		}
+		t.lastInt = r.integer
+		t.cond.Broadcast()
+		t.mu.Unlock()
+	}
+}
+
+// send pipelines a command, it is flushed by flush or wait
+func (t *target) send(args ...string) error {
+	fmt.Fprintf(t.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(t.writer, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	t.mu.Lock()
+	t.sent++
+	t.mu.Unlock()
+
+	if t.writer.Buffered() > bufSize {
+		return t.writer.Flush()
+	}
+	return nil
+}
+
+func (t *target) selectDB(db int) error {
+	if t.db == db {
+		return nil
+	}
+	t.db = db
+	return t.send("SELECT", strconv.Itoa(db))
+}
+
+// wait flushes pending commands and waits for all of their replies
+func (t *target) wait() error {
+	if err := t.writer.Flush(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for t.replies < t.sent && t.err == nil {
+		t.cond.Wait()
+	}
+	return t.err
+}
+
+func (t *target) close() {
+	t.writer.Flush()
+	t.conn.Close()
+}
+
+// Resharder splits the data of one master across several targets by consistent hashing of the keys
+type Resharder struct {
+	ring    *hashring.HashRing
+	targets map[string]*target
+	order   []*target
+	db      int
+
+	// progress, updated atomically
+	loaded  int64
+	keys    int64
+	skipped int64
+}
+
+// NewResharder connects to the targets
+func NewResharder(addrs []string) (*Resharder, error) {
+	r := &Resharder{
+		ring:    hashring.NewHashRing(0),
+		targets: make(map[string]*target),
+	}
+	for _, addr := range addrs {
+		t, err := dialTarget(addr)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("connect to target %s: %v", addr, err)
+		}
+		r.targets[addr] = t
+		r.order = append(r.order, t)
+		r.ring.AddNode(addr, 1)
+	}
+	return r, nil
+}
+
+// Close closes the connections to the targets
+func (r *Resharder) Close() {
+	for _, t := range r.order {
+		t.close()
+	}
+}
+
+// hashKey returns the part of key that is hashed, the {hash tag} if there is one,
+// so that related keys can be kept on the same target like in Redis Cluster
+func hashKey(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+func (r *Resharder) targetFor(key string) *target {
+	return r.targets[r.ring.GetNode(hashKey(key))]
+}
+
+// LoadRDB decodes the RDB of size bytes from reader and writes every key to its target
+func (r *Resharder) LoadRDB(reader io.Reader, size int64) error {
+	decoder := rdb.NewDecoder(reader)
+	for {
+		ev, err := decoder.Next()
+		atomic.StoreInt64(&r.loaded, decoder.Offset())
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		key, ok := ev.(*rdb.KeyEvent)
+		if !ok {
+			continue
+		}
+		if err := r.restore(key); err != nil {
+			return err
+		}
+	}
+
+	// drain what is left of the bulk transfer
+	_, err := io.Copy(ioutil.Discard, reader)
+	return err
+}
+
+// restore writes one key with commands matching its type
+func (r *Resharder) restore(ev *rdb.KeyEvent) error {
+	if !ev.ExpireAt.IsZero() && ev.ExpireAt.Before(time.Now()) {
+		atomic.AddInt64(&r.skipped, 1)
+		return nil
+	}
+	if _, ok := ev.Value.(rdb.Module); ok {
+		log.Printf("Skipping module value of key %q\n", ev.Key)
+		atomic.AddInt64(&r.skipped, 1)
+		return nil
+	}
+
+	t := r.targetFor(ev.Key)
+	if err := t.selectDB(ev.DB); err != nil {
+		return err
+	}
+
+	var err error
+	switch v := ev.Value.(type) {
+	case rdb.String:
+		err = t.send("SET", ev.Key, string(v))
+	case rdb.List:
+		err = r.sendBatches(t, "RPUSH", ev.Key, v, 1)
+	case rdb.Set:
+		err = r.sendBatches(t, "SADD", ev.Key, v, 1)
+	case rdb.SortedSet:
+		args := make([]string, 0, 2*len(v))
+		for _, m := range v {
+			args = append(args, strconv.FormatFloat(m.Score, 'g', -1, 64), m.Member)
+		}
+		err = r.sendBatches(t, "ZADD", ev.Key, args, 2)
+	case rdb.Hash:
+		now := time.Now()
+		args := make([]string, 0, 2*len(v))
+		var expiring []rdb.HashField
+		for _, f := range v {
+			if f.ExpireAt.IsZero() {
+				args = append(args, f.Field, f.Value)
+			} else if f.ExpireAt.After(now) {
+				args = append(args, f.Field, f.Value)
+				expiring = append(expiring, f)
+			}
+		}
+		if len(args) == 0 { // every field expired
+			atomic.AddInt64(&r.skipped, 1)
+			return nil
+		}
+		err = r.sendBatches(t, "HSET", ev.Key, args, 2)
+		for _, f := range expiring {
+			if err != nil {
+				break
+			}
+			ms := f.ExpireAt.UnixNano() / int64(time.Millisecond)
+			err = t.send("HPEXPIREAT", ev.Key, strconv.FormatInt(ms, 10), "FIELDS", "1", f.Field)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if !ev.ExpireAt.IsZero() {
+		ms := ev.ExpireAt.UnixNano() / int64(time.Millisecond)
+		if err := t.send("PEXPIREAT", ev.Key, strconv.FormatInt(ms, 10)); err != nil {
+			return err
+		}
+	}
+
+	t.keys[ev.DB]++
+	atomic.AddInt64(&r.keys, 1)
+	return nil
+}
+
+// sendBatches deletes key and recreates it with cmd in batches of batchSize elements of width arguments
+func (r *Resharder) sendBatches(t *target, cmd, key string, args []string, width int) error {
+	if err := t.send("DEL", key); err != nil {
+		return err
+	}
+	for len(args) > 0 {
+		n := batchSize * width
+		if n > len(args) {
+			n = len(args)
+		}
+		if err := t.send(append([]string{cmd, key}, args[:n]...)...); err != nil {
+			return err
+		}
+		args = args[n:]
+	}
+	return nil
+}
+
+// Verify waits for all targets to apply the loaded keys and compares their DBSIZE with the number of keys sent.
+// It assumes the targets were empty before the load.
+func (r *Resharder) Verify() error {
+	mismatches := 0
+	for _, t := range r.order {
+		if err := t.wait(); err != nil {
+			return fmt.Errorf("target %s: %v", t.addr, err)
+		}
+		if t.errors > 0 {
+			log.Printf("[%s] %d commands failed\n", t.addr, t.errors)
+			mismatches++
+		}
+
+		for db, expected := range t.keys {
+			if err := t.selectDB(db); err != nil {
+				return err
+			}
+			if err := t.send("DBSIZE"); err != nil {
+				return err
+			}
+			if err := t.wait(); err != nil {
+				return fmt.Errorf("target %s: %v", t.addr, err)
+			}
+
+			actual := t.lastInt
+			if actual != expected {
+				log.Printf("[%s] db %d: %d keys, expected %d\n", t.addr, db, actual, expected)
+				mismatches++
+			} else {
+				log.Printf("[%s] db %d: %d keys OK\n", t.addr, db, actual)
+			}
+		}
+	}
+
+	if mismatches > 0 {
+		return fmt.Errorf("verification failed on %d checks", mismatches)
+	}
+	return nil
+}
+
+// Forward routes a replicated command to the target owning its key
+func (r *Resharder) Forward(command []string) error {
+	if len(command) == 0 {
+		return nil
+	}
+
+	name := strings.ToUpper(command[0])
+	switch name {
+	case "PING", "REPLCONF":
+		return nil
+	case "SELECT":
+		if len(command) == 2 {
+			db, err := strconv.Atoi(command[1])
+			if err != nil {
+				return err
+			}
+			r.db = db
+		}
+		return nil
+	case "MULTI", "EXEC", "FLUSHDB", "FLUSHALL", "SWAPDB":
+		return r.broadcast(command)
+	case "DEL", "UNLINK", "TOUCH":
+		return r.split(command, 1)
+	case "MSET":
+		return r.split(command, 2)
+	case "EVAL", "EVALSHA":
+		if len(command) > 3 && command[2] != "0" {
+			return r.sendTo(r.targetFor(command[3]), command)
+		}
+		return r.broadcast(command)
+	}
+
+	if len(command) < 2 {
+		return r.broadcast(command)
+	}
+	return r.sendTo(r.targetFor(command[1]), command)
+}
+
+func (r *Resharder) sendTo(t *target, command []string) error {
+	if err := t.selectDB(r.db); err != nil {
+		return err
+	}
+	if err := t.send(command...); err != nil {
+		return err
+	}
+	return t.writer.Flush()
+}
+
+func (r *Resharder) broadcast(command []string) error {
+	for _, t := range r.order {
+		if err := r.sendTo(t, command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// split sends a multi-key command to every target with only the keys it owns,
+// the arguments after the command name are groups of width (key first)
+func (r *Resharder) split(command []string, width int) error {
+	groups := make(map[*target][]string)
+	for i := 1; i+width <= len(command); i += width {
+		t := r.targetFor(command[i])
+		groups[t] = append(groups[t], command[i:i+width]...)
+	}
+	for _, t := range r.order {
+		if args, ok := groups[t]; ok {
+			if err := r.sendTo(t, append([]string{command[0]}, args...)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reportProgress logs the progress of the load every interval until done is closed
+func (r *Resharder) reportProgress(size int64, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			loaded := atomic.LoadInt64(&r.loaded)
+			log.Printf("RDB progress: %d/%d bytes (%.1f%%), %d keys, %d skipped\n",
+				loaded, size, 100*float64(loaded)/float64(size), atomic.LoadInt64(&r.keys), atomic.LoadInt64(&r.skipped))
+		}
+	}
+}
+
+// reshardMaster replicates from the master and splits its data across targets.
+// The load is all or nothing: a key the decoder can not handle, like a stream or a value
+// of an unknown type, aborts the whole reshard and nothing is forwarded.
+func reshardMaster(addrs []string, progressInterval time.Duration) error {
+	resharder, err := NewResharder(addrs)
+	if err != nil {
+		return err
+	}
+	defer resharder.Close()
+
+	conn, err := net.Dial("tcp", net.JoinHostPort(masterHost, strconv.Itoa(masterPort)))
+	if err != nil {
+		return fmt.Errorf("connect to master: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("*1\r\n$4\r\nSYNC\r\n")); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReaderSize(conn, bufSize)
+	var size int64
+	for size == 0 {
+		command, err := readRedisCommand(reader)
+		if err != nil {
+			return fmt.Errorf("read SYNC reply: %v", err)
+		}
+		size = command.bulkSize
+	}
+
+	log.Printf("RDB size: %d, resharding to %d targets\n", size, len(addrs))
+	done := make(chan struct{})
+	go resharder.reportProgress(size, progressInterval, done)
+	err = resharder.LoadRDB(io.LimitReader(reader, size), size)
+	close(done)
+	if err != nil {
+		return fmt.Errorf("load RDB: %v", err)
+	}
+
+	log.Printf("RDB loaded: %d keys, %d skipped, verifying...\n", resharder.keys, resharder.skipped)
+	if err := resharder.Verify(); err != nil {
+		return err
+	}
+
+	log.Println("Verification finished, forwarding commands...")
+	for {
+		command, err := readRedisCommand(reader)
+		if err != nil {
+			return fmt.Errorf("read from master: %v", err)
+		}
+		if err := resharder.Forward(command.command); err != nil {
+			return err
+		}
+	}
+}
+
+// reply is a parsed RESP reply, only errors and integers are kept
+type reply struct {
+	err     string
+	integer int64
+}
+
+// readReply reads one RESP reply of any type
+func readReply(reader *bufio.Reader) (reply, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return reply{}, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return reply{}, fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return reply{}, nil
+	case '-':
+		return reply{err: line[1:]}, nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		return reply{integer: n}, err
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return reply{}, err
+		}
+		_, err = io.CopyN(ioutil.Discard, reader, int64(n)+2)
+		return reply{}, err
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return reply{}, err
+		}
+		var result reply
+		for i := 0; i < n; i++ {
+			r, err := readReply(reader)
+			if err != nil {
+				return reply{}, err
+			}
+			if r.err != "" {
+				result.err = r.err
+			}
+		}
+		return result, nil
+	default:
+		return reply{}, fmt.Errorf("unknown reply %q", line)
+	}
+}
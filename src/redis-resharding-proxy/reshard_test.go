@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is a Redis stand-in keeping the keys written per database
+type fakeRedis struct {
+	ln net.Listener
+
+	mu   sync.Mutex
+	keys map[int]map[string][]string
+	log  [][]string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, keys: make(map[int]map[string][]string)}
+	go f.serve()
+	return f
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	db := 0
+	for {
+		command, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		args := command.command
+
+		f.mu.Lock()
+		f.log = append(f.log, args)
+		if f.keys[db] == nil {
+			f.keys[db] = make(map[string][]string)
+		}
+		var reply string
+		switch args[0] {
+		case "SELECT":
+			db, _ = strconv.Atoi(args[1])
+			reply = "+OK\r\n"
+		case "DBSIZE":
+			reply = fmt.Sprintf(":%d\r\n", len(f.keys[db]))
+		case "DEL":
+			for _, k := range args[1:] {
+				delete(f.keys[db], k)
+			}
+			reply = ":1\r\n"
+		case "PEXPIREAT":
+			reply = ":1\r\n"
+		case "MSET":
+			for i := 1; i+1 < len(args); i += 2 {
+				f.keys[db][args[i]] = []string{args[i+1]}
+			}
+			reply = "+OK\r\n"
+		default:
+			f.keys[db][args[1]] = append(f.keys[db][args[1]], args[2:]...)
+			reply = "+OK\r\n"
+		}
+		f.mu.Unlock()
+
+		conn.Write([]byte(reply))
+	}
+}
+
+func (f *fakeRedis) count(db int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.keys[db])
+}
+
+func (f *fakeRedis) get(db int, key string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.keys[db][key]
+}
+
+// buildRDB writes an RDB with string keys k0...k<n-1> in db 0 and a list in db 1
+func buildRDB(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("REDIS0006")
+	b.Write([]byte{0xFE, 0})
+	for i := 0; i < n; i++ {
+		key := "k" + strconv.Itoa(i)
+		b.WriteByte(0)
+		b.WriteByte(byte(len(key)))
+		b.WriteString(key)
+		b.Write([]byte{1, 'v'})
+	}
+	b.Write([]byte{0xFE, 1})
+	b.Write([]byte{1, 4, 'l', 'i', 's', 't', 2, 1, 'a', 1, 'b'})
+	b.WriteByte(0xFF)
+	// zero checksum means checksumming is disabled
+	b.Write(make([]byte, 8))
+	return b.Bytes()
+}
+
+func TestHashKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"user:1":           "user:1",
+		"{user:1}:profile": "user:1",
+		"a{b}c{d}":         "b",
+		"a{}b":             "a{}b",
+	} {
+		if got := hashKey(key); got != want {
+			t.Errorf("hashKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestReshardLoadVerifyForward(t *testing.T) {
+	a, b := newFakeRedis(t), newFakeRedis(t)
+	defer a.ln.Close()
+	defer b.ln.Close()
+
+	r, err := NewResharder([]string{a.ln.Addr().String(), b.ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	data := buildRDB(50)
+	if err := r.LoadRDB(bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if r.keys != 51 || r.loaded != int64(len(data)) {
+		t.Fatalf("keys = %d, loaded = %d", r.keys, r.loaded)
+	}
+	if err := r.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	fakes := map[string]*fakeRedis{a.ln.Addr().String(): a, b.ln.Addr().String(): b}
+	total := 0
+	for addr, f := range fakes {
+		n := f.count(0)
+		if n == 0 {
+			t.Fatalf("target %s got no keys", addr)
+		}
+		total += n
+	}
+	if total != 50 {
+		t.Fatalf("%d keys in db 0, want 50", total)
+	}
+	owner := fakes[r.ring.GetNode("list")]
+	if got := owner.get(1, "list"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Fatalf("list = %v", got)
+	}
+
+	// replicated commands follow the keys
+	for _, cmd := range [][]string{
+		{"SELECT", "0"},
+		{"SET", "{tag}x", "1"},
+		{"SET", "{tag}y", "2"},
+		{"MSET", "k1", "v1", "k2", "v2"},
+	} {
+		if err := r.Forward(cmd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tg := range r.order {
+		if err := tg.wait(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tagged := fakes[r.ring.GetNode("tag")]
+	if len(tagged.get(0, "{tag}x")) == 0 || len(tagged.get(0, "{tag}y")) == 0 {
+		t.Fatal("hash tagged keys were not sent to the same target")
+	}
+	for _, key := range []string{"k1", "k2"} {
+		got := fakes[r.ring.GetNode(key)].get(0, key)
+		if got[len(got)-1] != "v"+key[1:] {
+			t.Fatalf("MSET %s went to the wrong target: %v", key, got)
+		}
+	}
+}
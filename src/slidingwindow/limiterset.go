@@ -0,0 +1,119 @@
+package slidingwindow
+
+import (
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// NewKeyWindow creates the current window of the limiter for key.
+type NewKeyWindow func(key string) (Window, StopFunc)
+
+// LocalKeyWindow creates in-memory windows, ignoring the key.
+func LocalKeyWindow(key string) (Window, StopFunc) {
+	return NewLocalWindow()
+}
+
+// RedisKeyWindow returns a NewKeyWindow creating windows synced to store
+// every syncInterval without blocking.
+func RedisKeyWindow(store Datastore, syncInterval time.Duration) NewKeyWindow {
+	return func(key string) (Window, StopFunc) {
+		return NewSyncWindow(store, key, false, syncInterval)
+	}
+}
+
+type setEntry struct {
+	lim  *Limiter
+	stop StopFunc
+}
+
+// LimiterSet holds one limiter per key (e.g. client ID, IP or API key).
+// The number of limiters is bounded, the least recently used one is
+// stopped and dropped when the bound is reached.
+type LimiterSet struct {
+	size      time.Duration
+	limit     int64
+	newWindow NewKeyWindow
+
+	// cache is safe for concurrent use on its own, mu only makes
+	// get-or-create atomic and guards evicted.
+	mu    sync.Mutex
+	cache *lru.Cache
+	// evicted collects the entries dropped by the cache while mu is held,
+	// they are stopped once it is released since stopping a synced window
+	// waits for its in-flight datastore call.
+	evicted []*setEntry
+}
+
+// NewLimiterSet creates a set of limiters permitting limit events per size
+// for every key, keeping at most maxKeys limiters in memory.
+func NewLimiterSet(size time.Duration, limit int64, maxKeys int, newWindow NewKeyWindow) (*LimiterSet, error) {
+	s := &LimiterSet{
+		size:      size,
+		limit:     limit,
+		newWindow: newWindow,
+	}
+
+	cache, err := lru.NewWithEvict(maxKeys, func(_, value interface{}) {
+		s.evicted = append(s.evicted, value.(*setEntry))
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.cache = cache
+	return s, nil
+}
+
+// Get returns the limiter for key, creating it if needed.
+func (s *LimiterSet) Get(key string) *Limiter {
+	if v, ok := s.cache.Get(key); ok {
+		return v.(*setEntry).lim
+	}
+
+	s.mu.Lock()
+	if v, ok := s.cache.Get(key); ok {
+		s.mu.Unlock()
+		return v.(*setEntry).lim
+	}
+
+	lim, stop := NewLimiter(s.size, s.limit, func() (Window, StopFunc) {
+		return s.newWindow(key)
+	})
+	s.cache.Add(key, &setEntry{lim: lim, stop: stop})
+	s.unlockAndStopEvicted()
+	return lim
+}
+
+// unlockAndStopEvicted releases mu, then stops the entries evicted while it was held.
+func (s *LimiterSet) unlockAndStopEvicted() {
+	evicted := s.evicted
+	s.evicted = nil
+	s.mu.Unlock()
+
+	for _, e := range evicted {
+		e.stop()
+	}
+}
+
+// Allow is shorthand for TakeN(key, time.Now(), 1).Allowed.
+func (s *LimiterSet) Allow(key string) bool {
+	return s.TakeN(key, time.Now(), 1).Allowed
+}
+
+// TakeN reports whether n events may happen at time now for key.
+func (s *LimiterSet) TakeN(key string, now time.Time, n int64) Result {
+	return s.Get(key).TakeN(now, n)
+}
+
+// Len returns the number of limiters in the set.
+func (s *LimiterSet) Len() int {
+	return s.cache.Len()
+}
+
+// Stop stops the sync behaviour of all the limiters and drops them.
+func (s *LimiterSet) Stop() {
+	s.mu.Lock()
+	s.cache.Purge()
+	s.unlockAndStopEvicted()
+}
@@ -0,0 +1,87 @@
+package slidingwindow
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc returns the limiter key of a request, an empty key disables limiting.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP keys requests by the IP of the remote address.
+// Note that behind a proxy this is the address of the proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByHeader keys requests by the value of header, e.g. a client ID or an API key.
+func KeyByHeader(header string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// setHeaders sets X-RateLimit-* headers, and Retry-After for denied requests.
+func setHeaders(h http.Header, res Result) {
+	h.Set("X-RateLimit-Limit", strconv.FormatInt(res.Limit, 10))
+	h.Set("X-RateLimit-Remaining", strconv.FormatInt(res.Remaining, 10))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+
+	if !res.Allowed {
+		// Retry-After is in whole seconds, round up to never retry too early.
+		seconds := int64((res.RetryAfter + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		h.Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+}
+
+// Middleware limits requests per key with set, denied requests get
+// 429 Too Many Requests.
+func Middleware(set *LimiterSet, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res := set.TakeN(key, time.Now(), 1)
+			setHeaders(w.Header(), res)
+			if !res.Allowed {
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GinMiddleware is Middleware for gin.
+func GinMiddleware(set *LimiterSet, keyFunc KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c.Request)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		res := set.TakeN(key, time.Now(), 1)
+		setHeaders(c.Writer.Header(), res)
+		if !res.Allowed {
+			c.AbortWithStatus(http.StatusTooManyRequests)
+			return
+		}
+		c.Next()
+	}
+}
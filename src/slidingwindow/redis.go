@@ -0,0 +1,71 @@
+package slidingwindow
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// The counter of a window is incremented and its expiration refreshed
+// atomically, so that concurrent limiters never see a counter without TTL.
+var addScript = redis.NewScript(1, `
+local count = redis.call("INCRBY", KEYS[1], ARGV[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return count
+`)
+
+var getScript = redis.NewScript(1, `
+return tonumber(redis.call("GET", KEYS[1]) or "0")
+`)
+
+// RedisDatastore is a Datastore that keeps the counters of windows in Redis.
+// Every window is stored under its own key "<key>@<start>".
+type RedisDatastore struct {
+	pool *redis.Pool
+	ttl  time.Duration
+}
+
+// ErrInvalidTTL is returned by NewRedisDatastore when counters would expire
+// before the limiters stop reading them.
+var ErrInvalidTTL = errors.New("slidingwindow: ttl must be at least twice the window size")
+
+// NewRedisDatastore creates a Redis datastore using connections from pool,
+// for limiters whose windows last size.
+// Counters expire after ttl, which must be at least twice size, since the
+// previous window is still read by the limiters. A zero ttl means 2*size.
+func NewRedisDatastore(pool *redis.Pool, size, ttl time.Duration) (*RedisDatastore, error) {
+	if ttl == 0 {
+		ttl = 2 * size
+	}
+	if size <= 0 || ttl < 2*size {
+		return nil, ErrInvalidTTL
+	}
+
+	return &RedisDatastore{
+		pool: pool,
+		ttl:  ttl,
+	}, nil
+}
+
+func (d *RedisDatastore) windowKey(key string, start int64) string {
+	return key + "@" + strconv.FormatInt(start, 10)
+}
+
+// Add adds delta to the count of the window represented
+// by start, and returns the new count.
+func (d *RedisDatastore) Add(key string, start, delta int64) (int64, error) {
+	conn := d.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(addScript.Do(conn, d.windowKey(key, start), delta, int64(d.ttl/time.Millisecond)))
+}
+
+// Get returns the count of the window represented by start.
+func (d *RedisDatastore) Get(key string, start int64) (int64, error) {
+	conn := d.pool.Get()
+	defer conn.Close()
+
+	return redis.Int64(getScript.Do(conn, d.windowKey(key, start)))
+}
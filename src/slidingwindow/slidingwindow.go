@@ -1,6 +1,7 @@
 package slidingwindow
 
 import (
+	"math"
 	"sync"
 	"time"
 )
@@ -92,6 +93,30 @@ func (lim *Limiter) Allow() bool {
 
 // AllowN reports whether n events may happen at time now.
 func (lim *Limiter) AllowN(now time.Time, n int64) bool {
+	return lim.TakeN(now, n).Allowed
+}
+
+// Result describes the decision made by TakeN.
+type Result struct {
+	// Allowed reports whether the events may happen.
+	Allowed bool
+
+	// Limit is the maximum events permitted during one window size.
+	Limit int64
+
+	// Remaining is the number of events still permitted at the time of decision.
+	Remaining int64
+
+	// Reset is the end boundary of the current window.
+	Reset time.Time
+
+	// RetryAfter is the time to wait before the denied events may be permitted,
+	// it is zero if the events are allowed.
+	RetryAfter time.Duration
+}
+
+// TakeN is like AllowN, but also reports the state of the limiter.
+func (lim *Limiter) TakeN(now time.Time, n int64) Result {
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 
@@ -99,17 +124,59 @@ func (lim *Limiter) AllowN(now time.Time, n int64) bool {
 
 	elapsed := now.Sub(lim.curr.Start())
 	weight := float64(lim.size-elapsed) / float64(lim.size)
-	count := int64(weight*float64(lim.prev.Count())) + lim.curr.Count()
+	prevCount := lim.prev.Count()
+	count := int64(weight*float64(prevCount)) + lim.curr.Count()
 
 	// Trigger the possible sync behaviour.
 	defer lim.curr.Sync(now)
 
+	res := Result{
+		Limit: lim.limit,
+		Reset: lim.curr.Start().Add(lim.size),
+	}
+
 	if count+n > lim.limit {
-		return false
+		res.Remaining = lim.limit - count
+		if res.Remaining < 0 {
+			res.Remaining = 0
+		}
+		res.RetryAfter = lim.retryAfter(elapsed, prevCount, lim.curr.Count(), n)
+		return res
 	}
 
 	lim.curr.AddCount(n)
-	return true
+	res.Allowed = true
+	res.Remaining = lim.limit - count - n
+	return res
+}
+
+// retryAfter estimates how long it takes for the weighted count to drop low
+// enough for n events, assuming no other events happen in the meantime.
+func (lim *Limiter) retryAfter(elapsed time.Duration, prevCount, currCount, n int64) time.Duration {
+	if n > lim.limit {
+		// Will never be permitted, suggest waiting for two windows anyway.
+		return 2*lim.size - elapsed
+	}
+
+	// The time offset within a window at which weight*count becomes small enough.
+	offset := func(count, room int64) time.Duration {
+		if count <= 0 || room >= count {
+			return 0
+		}
+		return time.Duration(math.Ceil(float64(lim.size) * float64(count-room) / float64(count)))
+	}
+
+	room := lim.limit - currCount - n
+	if room >= 0 {
+		// Waiting within the current window is enough.
+		if d := offset(prevCount, room) - elapsed; d > 0 {
+			return d
+		}
+		return 0
+	}
+
+	// The current window becomes the previous one.
+	return lim.size - elapsed + offset(currCount, lim.limit-n)
 }
 
 // advance updates the current/previous windows resulting from the passage of time.
@@ -0,0 +1,286 @@
+package slidingwindow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/garyburd/redigo/redis"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimiterTakeN(t *testing.T) {
+	lim, stop := NewLimiter(time.Second, 10, NewLocalWindow)
+	defer stop()
+
+	start := time.Unix(100, 0)
+	for i := int64(0); i < 10; i++ {
+		res := lim.TakeN(start, 1)
+		if !res.Allowed || res.Remaining != 9-i {
+			t.Fatalf("%d: %+v", i, res)
+		}
+	}
+
+	res := lim.TakeN(start.Add(100*time.Millisecond), 1)
+	if res.Allowed || res.Remaining != 0 || !res.Reset.Equal(start.Add(time.Second)) {
+		t.Fatalf("denied: %+v", res)
+	}
+	// 10 events in the current window, so wait until 10% of the next one has passed.
+	if res.RetryAfter != 1000*time.Millisecond {
+		t.Fatalf("RetryAfter = %v", res.RetryAfter)
+	}
+
+	retry := start.Add(100 * time.Millisecond).Add(res.RetryAfter)
+	if !lim.AllowN(retry, 1) {
+		t.Fatal("expected to be allowed after RetryAfter")
+	}
+	if lim.AllowN(retry, 1) {
+		t.Fatal("expected to be denied again")
+	}
+
+	if res := lim.TakeN(retry, 11); res.Allowed || res.RetryAfter <= time.Second {
+		t.Fatalf("more than limit: %+v", res)
+	}
+}
+
+func TestLimiterSetEviction(t *testing.T) {
+	var stopped int32
+	newWindow := func(key string) (Window, StopFunc) {
+		w, _ := NewLocalWindow()
+		return w, func() { atomic.AddInt32(&stopped, 1) }
+	}
+
+	set, err := NewLimiterSet(time.Minute, 1, 2, newWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !set.Allow("a") || set.Allow("a") {
+		t.Fatal("a: expected allow then deny")
+	}
+	if !set.Allow("b") || !set.Allow("c") {
+		t.Fatal("b, c: expected allow")
+	}
+	if set.Len() != 2 || atomic.LoadInt32(&stopped) != 1 {
+		t.Fatalf("Len() = %d, stopped = %d", set.Len(), stopped)
+	}
+
+	// "a" was evicted, so it starts over and evicts "b"
+	if !set.Allow("a") {
+		t.Fatal("a: expected allow after eviction")
+	}
+
+	set.Stop()
+	if set.Len() != 0 || atomic.LoadInt32(&stopped) != 4 {
+		t.Fatalf("Len() = %d, stopped = %d", set.Len(), stopped)
+	}
+}
+
+func newTestPool(t *testing.T) (*redis.Pool, *miniredis.Miniredis) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", mr.Addr())
+		},
+	}
+	return pool, mr
+}
+
+func TestLimiterSetStopsEvictedOutsideLock(t *testing.T) {
+	release := make(chan struct{})
+	stopping := make(chan struct{})
+	newWindow := func(key string) (Window, StopFunc) {
+		w, _ := NewLocalWindow()
+		if key != "slow" {
+			return w, func() {}
+		}
+		// like a synced window waiting for its in-flight datastore call
+		return w, func() {
+			close(stopping)
+			<-release
+		}
+	}
+
+	set, err := NewLimiterSet(time.Minute, 10, 1, newWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Get("slow")
+
+	done := make(chan struct{})
+	go func() {
+		set.Get("a") // evicts "slow" and blocks stopping it
+		close(done)
+	}()
+	<-stopping
+
+	finished := make(chan struct{})
+	go func() {
+		set.Get("a")
+		set.Get("b")
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Get blocked by the stop of an evicted limiter")
+	}
+
+	close(release)
+	<-done
+}
+
+func TestRedisDatastore(t *testing.T) {
+	pool, mr := newTestPool(t)
+	defer mr.Close()
+
+	if _, err := NewRedisDatastore(pool, time.Second, 0); err != nil {
+		t.Fatalf("default ttl: %v", err)
+	}
+	for _, ttl := range []time.Duration{-time.Second, time.Second} {
+		if _, err := NewRedisDatastore(pool, time.Second, ttl); err != ErrInvalidTTL {
+			t.Fatalf("ttl %v: err = %v", ttl, err)
+		}
+	}
+
+	store, err := NewRedisDatastore(pool, time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := store.Get("k", 1); err != nil || n != 0 {
+		t.Fatalf("Get = %d, %v", n, err)
+	}
+	if n, err := store.Add("k", 1, 3); err != nil || n != 3 {
+		t.Fatalf("Add = %d, %v", n, err)
+	}
+	if n, err := store.Add("k", 1, 2); err != nil || n != 5 {
+		t.Fatalf("Add = %d, %v", n, err)
+	}
+	if n, err := store.Get("k", 1); err != nil || n != 5 {
+		t.Fatalf("Get = %d, %v", n, err)
+	}
+	if n, err := store.Get("k", 2); err != nil || n != 0 {
+		t.Fatalf("Get other window = %d, %v", n, err)
+	}
+
+	if ttl := mr.TTL("k@1"); ttl != 2*time.Second {
+		t.Fatalf("TTL = %v", ttl)
+	}
+	mr.FastForward(3 * time.Second)
+	if n, err := store.Get("k", 1); err != nil || n != 0 {
+		t.Fatalf("Get after expiry = %d, %v", n, err)
+	}
+}
+
+func TestRedisDistributedLimit(t *testing.T) {
+	pool, mr := newTestPool(t)
+	defer mr.Close()
+
+	store, err := NewRedisDatastore(pool, time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newWindow := func() (Window, StopFunc) {
+		return NewSyncWindow(store, "shared", true, 0)
+	}
+
+	lim1, stop1 := NewLimiter(time.Minute, 10, newWindow)
+	defer stop1()
+	lim2, stop2 := NewLimiter(time.Minute, 10, newWindow)
+	defer stop2()
+
+	now := time.Now()
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		for _, lim := range []*Limiter{lim1, lim2} {
+			if lim.AllowN(now, 1) {
+				allowed++
+			}
+		}
+	}
+	// Blocking sync lags one event behind, so the limit may be exceeded by one per limiter.
+	if allowed < 10 || allowed > 12 {
+		t.Fatalf("allowed %d events across limiters, want about 10", allowed)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	set, err := NewLimiterSet(time.Minute, 2, 10, LocalKeyWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.Stop()
+
+	handler := Middleware(set, KeyByHeader("X-API-Key"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do("k1"); w.Code != http.StatusNoContent || w.Header().Get("X-RateLimit-Remaining") != "1" || w.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("first: %d %v", w.Code, w.Header())
+	}
+	do("k1")
+	w := do("k1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("third: %d %v", w.Code, w.Header())
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Reset") == "" {
+		t.Fatalf("missing headers: %v", w.Header())
+	}
+
+	if w := do("k2"); w.Code != http.StatusNoContent {
+		t.Fatalf("other key: %d", w.Code)
+	}
+	for i := 0; i < 5; i++ {
+		if w := do(""); w.Code != http.StatusNoContent || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("no key: %d %v", w.Code, w.Header())
+		}
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	set, err := NewLimiterSet(time.Minute, 1, 10, LocalKeyWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.Stop()
+
+	r := gin.New()
+	r.Use(GinMiddleware(set, KeyByIP))
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+		if i == 1 && w.Header().Get("Retry-After") == "" {
+			t.Fatalf("missing Retry-After: %v", w.Header())
+		}
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("codes = %v", codes)
+	}
+}
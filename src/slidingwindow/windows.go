@@ -102,10 +102,20 @@ func (w *SyncWindow) handleSyncOut(out syncOut) {
 // blockSync does syncing in blocking mode.
 func (w *SyncWindow) blockSync(now time.Time) {
 	if now.Sub(w.lastSynced) >= w.syncInterval {
-		w.syncer.InC <- w.makeSyncIn()
+		// Do not block forever if the window has been stopped,
+		// e.g. evicted from a LimiterSet while still in use.
+		select {
+		case w.syncer.InC <- w.makeSyncIn():
+		case <-w.syncer.exitC:
+			return
+		}
 		w.lastSynced = now
 
-		w.handleSyncOut(<-w.syncer.OutC)
+		select {
+		case out := <-w.syncer.OutC:
+			w.handleSyncOut(out)
+		case <-w.syncer.exitC:
+		}
 	}
 }
 
@@ -0,0 +1,75 @@
+package snowflake
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// RollbackPolicy tells a Node what to do when the clock goes backwards
+type RollbackPolicy int
+
+const (
+	// RollbackWait sleeps until the clock is back to the time of the last ID
+	RollbackWait RollbackPolicy = iota
+	// RollbackBorrow keeps going with the current time and increments a
+	// counter kept in Config.RollbackBits, so the IDs stay unique
+	RollbackBorrow
+	// RollbackError returns ErrClockRollback
+	RollbackError
+)
+
+var (
+	// ErrClockRollback is returned when the clock went backwards and the
+	// rollback policy of the node could not make a unique ID
+	ErrClockRollback = errors.New("snowflake: clock moved backwards")
+
+	// ErrTimeOverflow is returned when the time since the epoch does not fit in the time bits
+	ErrTimeOverflow = errors.New("snowflake: time overflows the time bits")
+)
+
+// Config holds the layout and the behaviour of a Node.
+// The IDs are made of, from the highest bits, the time in milliseconds since
+// Epoch, the rollback counter, the node number and the step. The four widths
+// share 63 bits, TimeBits takes the bits left by the others when it is 0.
+type Config struct {
+	Epoch time.Time // defaults to the global Epoch
+
+	TimeBits     uint8
+	RollbackBits uint8
+	NodeBits     uint8
+	StepBits     uint8
+
+	Rollback RollbackPolicy
+	// MaxWait is the longest rollback RollbackWait sleeps through, it returns
+	// ErrClockRollback for longer ones. 0 means no limit.
+	MaxWait time.Duration
+
+	// Clock replaces time.Now, mostly for testing
+	Clock func() time.Time
+}
+
+// withDefaults checks the layout of cfg and fills the zero fields
+func (cfg Config) withDefaults() (Config, error) {
+	if cfg.Epoch.IsZero() {
+		cfg.Epoch = time.Unix(Epoch/1000, (Epoch%1000)*1000000)
+	}
+	if cfg.NodeBits == 0 && cfg.StepBits == 0 {
+		cfg.NodeBits, cfg.StepBits = NodeBits, StepBits
+	}
+
+	used := int(cfg.RollbackBits) + int(cfg.NodeBits) + int(cfg.StepBits)
+	if cfg.TimeBits == 0 && used < 63 {
+		cfg.TimeBits = uint8(63 - used)
+	}
+	if cfg.TimeBits == 0 {
+		return cfg, errors.New("snowflake: no bits left for the time")
+	}
+	if total := used + int(cfg.TimeBits); total != 63 {
+		return cfg, errors.New("snowflake: the time, rollback, node and step bits add up to " + strconv.Itoa(total) + ", not 63")
+	}
+	if cfg.Rollback < RollbackWait || cfg.Rollback > RollbackError {
+		return cfg, errors.New("snowflake: unknown rollback policy " + strconv.Itoa(int(cfg.Rollback)))
+	}
+	return cfg, nil
+}
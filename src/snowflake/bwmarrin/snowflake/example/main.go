@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pathbox/learning-go/src/snowflake/bwmarrin/snowflake"
+)
+
+func main() {
+	n, _ := snowflake.NewNode(1)
+	fmt.Printf("%+v\n", n)
+
+	id := n.Generate()
+	fmt.Println(id.String())
+	fmt.Println(id.Base2())
+	fmt.Println(len(id.Base2()))
+	fmt.Println(id.Base32())
+	fmt.Println(id.Base36())
+	fmt.Println(id.Base58())
+	fmt.Println(id.Base64())
+
+	// 节点ID从文件锁租用，不再写死在配置里
+	cfg := snowflake.Config{
+		Epoch:        time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		RollbackBits: 2,
+		NodeBits:     8,
+		StepBits:     12,
+		Rollback:     snowflake.RollbackBorrow,
+	}
+	coord := &snowflake.FileCoordinator{Dir: os.TempDir()}
+	node, lease, err := snowflake.NewLeasedNode(context.Background(), coord, time.Minute, cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer lease.Release()
+
+	ids, err := node.GenerateN(3)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, id := range ids {
+		fmt.Println(id, node.Time(id), node.NodeID(id), node.Step(id))
+	}
+}
@@ -0,0 +1,307 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoNodeID is returned by a Coordinator when every node ID is leased
+var ErrNoNodeID = errors.New("snowflake: no node ID available")
+
+// ErrLeaseLost is returned by a leased node once its lease expired or was
+// released, another node may be using the node ID by then
+var ErrLeaseLost = errors.New("snowflake: node ID lease lost")
+
+// A Lease is a node ID held by this process. It must be renewed before its
+// TTL runs out and released when the node stops.
+type Lease interface {
+	NodeID() int64
+	Renew(ctx context.Context) error
+	Release() error
+}
+
+// A Coordinator hands out node IDs, so that no two running nodes share one
+type Coordinator interface {
+	// Acquire leases a node ID between 0 and max for ttl
+	Acquire(ctx context.Context, max int64, ttl time.Duration) (Lease, error)
+}
+
+// NewLeasedNode acquires a node ID fitting the node bits of cfg from c and
+// returns a node using it. The lease is renewed in the background three times
+// per ttl until it is released; once it is released or could not be renewed
+// before its ttl ran out, the node stops generating IDs with ErrLeaseLost.
+// A ttl of 0 is for coordinators which do not expire leases.
+func NewLeasedNode(ctx context.Context, c Coordinator, ttl time.Duration, cfg Config) (*Node, Lease, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the lease runs from before the request, the coordinator may take a while
+	start := time.Now()
+	lease, err := c.Acquire(ctx, -1^(-1<<cfg.NodeBits), ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := NewNodeWithConfig(lease.NodeID(), cfg)
+	if err != nil {
+		lease.Release()
+		return nil, nil, err
+	}
+	n.lease = keepLease(lease, ttl, start)
+	return n, n.lease, nil
+}
+
+// keptLease renews a lease in the background and tracks whether it still holds
+type keptLease struct {
+	Lease
+	ttl  time.Duration
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time // zero when the lease does not expire
+	lost     bool
+}
+
+func keepLease(l Lease, ttl time.Duration, start time.Time) *keptLease {
+	k := &keptLease{Lease: l, ttl: ttl, stop: make(chan struct{}), done: make(chan struct{})}
+	if ttl <= 0 {
+		close(k.done)
+		return k
+	}
+	k.deadline = start.Add(ttl)
+	go k.run()
+	return k
+}
+
+// run renews the lease until it is released or lost, a failed renewal is
+// retried on the next tick as long as the deadline is not reached
+func (k *keptLease) run() {
+	defer close(k.done)
+
+	interval := k.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		k.Renew(ctx)
+		cancel()
+		if k.err() != nil {
+			return
+		}
+	}
+}
+
+// Renew renews the lease and moves the deadline, ErrLeaseNotFound loses the lease at once
+func (k *keptLease) Renew(ctx context.Context) error {
+	start := time.Now()
+	err := k.Lease.Renew(ctx)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	switch {
+	case err == ErrLeaseNotFound:
+		k.lost = true
+	case err == nil && !k.deadline.IsZero() && k.deadline.After(start):
+		k.deadline = start.Add(k.ttl)
+	}
+	return err
+}
+
+// Release stops the renewals and releases the lease, the node stops generating IDs first
+func (k *keptLease) Release() error {
+	k.mu.Lock()
+	k.lost = true
+	k.mu.Unlock()
+
+	var err error
+	k.once.Do(func() {
+		close(k.stop)
+		<-k.done
+		err = k.Lease.Release()
+	})
+	return err
+}
+
+// err returns ErrLeaseLost once the lease is lost or past its deadline
+func (k *keptLease) err() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.lost || !k.deadline.IsZero() && !time.Now().Before(k.deadline) {
+		k.lost = true
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// KV is the part of an etcd like store used by KVCoordinator
+type KV interface {
+	// Grant creates a lease expiring after ttl
+	Grant(ctx context.Context, ttl time.Duration) (int64, error)
+	// KeepAlive extends the lease by its ttl
+	KeepAlive(ctx context.Context, lease int64) error
+	// Revoke ends the lease and deletes its keys
+	Revoke(ctx context.Context, lease int64) error
+	// PutIfAbsent sets key attached to lease unless it exists, and reports whether it did
+	PutIfAbsent(ctx context.Context, key, value string, lease int64) (bool, error)
+}
+
+// KVCoordinator leases node IDs as the keys Prefix+<id> of a KV store,
+// which delete themselves when their lease expires
+type KVCoordinator struct {
+	KV     KV
+	Prefix string
+	Owner  string // value of the keys, to tell who holds a node ID
+}
+
+// Acquire takes the lowest free node ID
+func (c *KVCoordinator) Acquire(ctx context.Context, max int64, ttl time.Duration) (Lease, error) {
+	id, err := c.KV.Grant(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	for node := int64(0); node <= max; node++ {
+		ok, err := c.KV.PutIfAbsent(ctx, c.Prefix+strconv.FormatInt(node, 10), c.Owner, id)
+		if err != nil {
+			c.KV.Revoke(ctx, id)
+			return nil, err
+		}
+		if ok {
+			return &kvLease{kv: c.KV, id: id, node: node}, nil
+		}
+	}
+	c.KV.Revoke(ctx, id)
+	return nil, ErrNoNodeID
+}
+
+type kvLease struct {
+	kv   KV
+	id   int64
+	node int64
+}
+
+func (l *kvLease) NodeID() int64 {
+	return l.node
+}
+
+func (l *kvLease) Renew(ctx context.Context) error {
+	return l.kv.KeepAlive(ctx, l.id)
+}
+
+func (l *kvLease) Release() error {
+	return l.kv.Revoke(context.Background(), l.id)
+}
+
+// ErrLeaseNotFound is returned by MemoryKV for an expired or revoked lease
+var ErrLeaseNotFound = errors.New("snowflake: lease not found")
+
+// MemoryKV is a KV in memory, standing in for etcd on a single process and in tests
+type MemoryKV struct {
+	// Now replaces time.Now
+	Now func() time.Time
+
+	mu     sync.Mutex
+	nextID int64
+	leases map[int64]*memoryLease
+	keys   map[string]int64 // key -> lease
+}
+
+type memoryLease struct {
+	ttl     time.Duration
+	expires time.Time
+	keys    []string
+}
+
+// NewMemoryKV returns an empty MemoryKV
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		leases: make(map[int64]*memoryLease),
+		keys:   make(map[string]int64),
+	}
+}
+
+func (kv *MemoryKV) now() time.Time {
+	if kv.Now != nil {
+		return kv.Now()
+	}
+	return time.Now()
+}
+
+// expire deletes the leases past their expiry time, with their keys
+func (kv *MemoryKV) expire() {
+	now := kv.now()
+	for id, l := range kv.leases {
+		if !now.Before(l.expires) {
+			kv.revoke(id)
+		}
+	}
+}
+
+func (kv *MemoryKV) revoke(id int64) {
+	for _, key := range kv.leases[id].keys {
+		delete(kv.keys, key)
+	}
+	delete(kv.leases, id)
+}
+
+func (kv *MemoryKV) Grant(ctx context.Context, ttl time.Duration) (int64, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.nextID++
+	kv.leases[kv.nextID] = &memoryLease{ttl: ttl, expires: kv.now().Add(ttl)}
+	return kv.nextID, nil
+}
+
+func (kv *MemoryKV) KeepAlive(ctx context.Context, id int64) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.expire()
+	l, ok := kv.leases[id]
+	if !ok {
+		return ErrLeaseNotFound
+	}
+	l.expires = kv.now().Add(l.ttl)
+	return nil
+}
+
+func (kv *MemoryKV) Revoke(ctx context.Context, id int64) error {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.expire()
+	if _, ok := kv.leases[id]; !ok {
+		return ErrLeaseNotFound
+	}
+	kv.revoke(id)
+	return nil
+}
+
+func (kv *MemoryKV) PutIfAbsent(ctx context.Context, key, value string, id int64) (bool, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	kv.expire()
+	l, ok := kv.leases[id]
+	if !ok {
+		return false, ErrLeaseNotFound
+	}
+	if _, ok := kv.keys[key]; ok {
+		return false, nil
+	}
+	kv.keys[key] = id
+	l.keys = append(l.keys, key)
+	return true, nil
+}
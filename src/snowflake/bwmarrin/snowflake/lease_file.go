@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+package snowflake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// FileCoordinator leases node IDs with a lock on the files Dir/node-<id>.lock,
+// for the nodes of a single host. The locks are held until Release or the
+// end of the process, so the ttl is not used.
+type FileCoordinator struct {
+	Dir string
+}
+
+// Acquire locks the lowest free node ID
+func (c *FileCoordinator) Acquire(ctx context.Context, max int64, ttl time.Duration) (Lease, error) {
+	for node := int64(0); node <= max; node++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := filepath.Join(c.Dir, "node-"+strconv.FormatInt(node, 10)+".lock")
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return &fileLease{f: f, node: node}, nil
+		}
+		f.Close()
+		if err != syscall.EWOULDBLOCK {
+			return nil, err
+		}
+	}
+	return nil, ErrNoNodeID
+}
+
+type fileLease struct {
+	f    *os.File
+	node int64
+}
+
+func (l *fileLease) NodeID() int64 {
+	return l.node
+}
+
+func (l *fileLease) Renew(ctx context.Context) error {
+	return nil
+}
+
+func (l *fileLease) Release() error {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	return l.f.Close()
+}
@@ -0,0 +1,17 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// FileCoordinator leases node IDs with file locks, which are not supported on windows
+type FileCoordinator struct {
+	Dir string
+}
+
+// Acquire always fails on windows
+func (c *FileCoordinator) Acquire(ctx context.Context, max int64, ttl time.Duration) (Lease, error) {
+	return nil, errors.New("snowflake: FileCoordinator is not supported on windows")
+}
@@ -0,0 +1,265 @@
+package snowflake
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock moved by hand
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time           { return c.t }
+func (c *fakeClock) Add(d time.Duration)      { c.t = c.t.Add(d) }
+func newFakeClock(epoch time.Time) *fakeClock { return &fakeClock{t: epoch.Add(time.Hour)} }
+
+var testEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestConfigLayout(t *testing.T) {
+	if _, err := NewNodeWithConfig(0, Config{TimeBits: 40, NodeBits: 10, StepBits: 12}); err == nil {
+		t.Error("expected an error for 62 bits")
+	}
+	if _, err := NewNodeWithConfig(0, Config{RollbackBits: 20, NodeBits: 20, StepBits: 23}); err == nil {
+		t.Error("expected an error without time bits")
+	}
+	if _, err := NewNodeWithConfig(16, Config{NodeBits: 4, StepBits: 8}); err == nil {
+		t.Error("expected an error for a node number over 4 bits")
+	}
+
+	clock := newFakeClock(testEpoch)
+	n, err := NewNodeWithConfig(5, Config{Epoch: testEpoch, RollbackBits: 2, NodeBits: 4, StepBits: 8, Clock: clock.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := n.Generate()
+	if n.NodeID(id) != 5 || n.Step(id) != 0 || n.Rollback(id) != 0 || !n.Time(id).Equal(clock.Now()) {
+		t.Errorf("got node %d step %d rollback %d time %v", n.NodeID(id), n.Step(id), n.Rollback(id), n.Time(id))
+	}
+	if int64(id) != int64(time.Hour/time.Millisecond)<<14|5<<8 {
+		t.Errorf("unexpected layout %b", id)
+	}
+}
+
+func TestRollbackError(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	n, _ := NewNodeWithConfig(1, Config{Epoch: testEpoch, Rollback: RollbackError, Clock: clock.Now})
+	n.Generate()
+	clock.Add(-time.Millisecond)
+	if _, err := n.Next(); err != ErrClockRollback {
+		t.Fatalf("got %v, expected ErrClockRollback", err)
+	}
+	clock.Add(time.Millisecond)
+	if _, err := n.Next(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRollbackWait(t *testing.T) {
+	n, _ := NewNodeWithConfig(1, Config{Epoch: testEpoch, MaxWait: 50 * time.Millisecond})
+	last := n.Generate()
+
+	// pretend the clock went back 20ms, then more than MaxWait
+	n.mu.Lock()
+	n.time += 20
+	n.mu.Unlock()
+	start := time.Now()
+	id, err := n.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) < 15*time.Millisecond || id <= last {
+		t.Errorf("did not wait for the clock, %v", time.Since(start))
+	}
+
+	n.mu.Lock()
+	n.time += 1000
+	n.mu.Unlock()
+	if _, err := n.Next(); err != ErrClockRollback {
+		t.Fatalf("got %v, expected ErrClockRollback", err)
+	}
+}
+
+func TestRollbackBorrow(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	n, _ := NewNodeWithConfig(1, Config{Epoch: testEpoch, RollbackBits: 1, NodeBits: 4, StepBits: 2, Rollback: RollbackBorrow, Clock: clock.Now})
+
+	seen := map[ID]bool{}
+	generate := func(count int) {
+		t.Helper()
+		ids, err := n.GenerateN(count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if seen[id] {
+				t.Fatalf("duplicate ID %d", id)
+			}
+			seen[id] = true
+		}
+	}
+
+	generate(3)
+	clock.Add(5 * time.Millisecond)
+	generate(3)
+	clock.Add(-5 * time.Millisecond) // back to the first IDs
+	generate(3)
+	if id := n.Generate(); n.Rollback(id) != 1 {
+		t.Errorf("rollback counter %d, expected 1", n.Rollback(id))
+	}
+
+	clock.Add(-time.Millisecond) // the single rollback bit is used up
+	if _, err := n.Next(); err != ErrClockRollback {
+		t.Fatalf("got %v, expected ErrClockRollback", err)
+	}
+
+	clock.Add(10 * time.Millisecond) // past every ID, the counter starts over
+	if id := n.Generate(); n.Rollback(id) != 0 {
+		t.Errorf("rollback counter %d, expected 0", n.Rollback(id))
+	}
+}
+
+func TestGenerateN(t *testing.T) {
+	n, _ := NewNodeWithConfig(3, Config{NodeBits: 10, StepBits: 4})
+	ids, err := n.GenerateN(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1000 {
+		t.Fatalf("got %d IDs", len(ids))
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("IDs not increasing at %d: %d <= %d", i, ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestGenerateNNegative(t *testing.T) {
+	n, _ := NewNodeWithConfig(3, Config{})
+	if _, err := n.GenerateN(-1); err == nil {
+		t.Fatal("expected error for a negative count")
+	}
+}
+
+func TestTimeOverflow(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	n, _ := NewNodeWithConfig(1, Config{Epoch: testEpoch, TimeBits: 20, RollbackBits: 21, NodeBits: 10, StepBits: 12, Clock: clock.Now})
+	if _, err := n.Next(); err != ErrTimeOverflow {
+		t.Fatalf("got %v, expected ErrTimeOverflow", err)
+	}
+}
+
+func TestFileCoordinator(t *testing.T) {
+	c := &FileCoordinator{Dir: t.TempDir()}
+	ctx := context.Background()
+
+	a, err := c.Acquire(ctx, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := c.Acquire(ctx, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NodeID() != 0 || b.NodeID() != 1 {
+		t.Fatalf("got node IDs %d and %d", a.NodeID(), b.NodeID())
+	}
+	if _, err := c.Acquire(ctx, 1, time.Minute); err != ErrNoNodeID {
+		t.Fatalf("got %v, expected ErrNoNodeID", err)
+	}
+
+	a.Release()
+	a, err = c.Acquire(ctx, 1, time.Minute)
+	if err != nil || a.NodeID() != 0 {
+		t.Fatalf("got %v, %v after release", a, err)
+	}
+	a.Release()
+	b.Release()
+}
+
+func TestKVCoordinator(t *testing.T) {
+	clock := newFakeClock(testEpoch)
+	kv := NewMemoryKV()
+	kv.Now = clock.Now
+	c := &KVCoordinator{KV: kv, Prefix: "/snowflake/", Owner: "test"}
+	ctx := context.Background()
+
+	n, a, err := NewLeasedNode(ctx, c, 10*time.Second, Config{NodeBits: 1, StepBits: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.NodeID() != 0 || n.NodeID(n.Generate()) != 0 {
+		t.Fatalf("got node ID %d", a.NodeID())
+	}
+	b, err := c.Acquire(ctx, 1, 10*time.Second)
+	if err != nil || b.NodeID() != 1 {
+		t.Fatalf("got %v, %v", b, err)
+	}
+	if _, err := c.Acquire(ctx, 1, 10*time.Second); err != ErrNoNodeID {
+		t.Fatalf("got %v, expected ErrNoNodeID", err)
+	}
+
+	// a is renewed, b expires
+	clock.Add(6 * time.Second)
+	if err := a.Renew(ctx); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(6 * time.Second)
+	if err := b.Renew(ctx); err != ErrLeaseNotFound {
+		t.Fatalf("got %v, expected ErrLeaseNotFound", err)
+	}
+	b, err = c.Acquire(ctx, 1, 10*time.Second)
+	if err != nil || b.NodeID() != 1 {
+		t.Fatalf("got %v, %v after expiry", b, err)
+	}
+
+	a.Release()
+	a, err = c.Acquire(ctx, 1, 10*time.Second)
+	if err != nil || a.NodeID() != 0 {
+		t.Fatalf("got %v, %v after release", a, err)
+	}
+}
+
+func TestLeaseKeepAlive(t *testing.T) {
+	kv := NewMemoryKV()
+	c := &KVCoordinator{KV: kv, Prefix: "/snowflake/", Owner: "test"}
+	ttl := 60 * time.Millisecond
+
+	n, lease, err := NewLeasedNode(context.Background(), c, ttl, Config{NodeBits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// renewed in the background past several ttls
+	time.Sleep(3 * ttl)
+	if _, err := n.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lease is gone from the store, the next renewal finds out
+	kv.Revoke(context.Background(), lease.(*keptLease).Lease.(*kvLease).id)
+	deadline := time.Now().Add(2 * ttl)
+	for {
+		_, err := n.Next()
+		if err == ErrLeaseLost {
+			break
+		}
+		if err != nil || time.Now().After(deadline) {
+			t.Fatalf("got %v, expected ErrLeaseLost", err)
+		}
+		time.Sleep(ttl / 10)
+	}
+}
+
+func TestLeaseRelease(t *testing.T) {
+	n, lease, err := NewLeasedNode(context.Background(), &FileCoordinator{Dir: t.TempDir()}, 0, Config{NodeBits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Next(); err != nil {
+		t.Fatal(err)
+	}
+	lease.Release()
+	if _, err := n.GenerateN(1); err != ErrLeaseLost {
+		t.Fatalf("got %v after release, expected ErrLeaseLost", err)
+	}
+}
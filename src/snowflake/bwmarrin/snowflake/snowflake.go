@@ -24,12 +24,12 @@ var (
 	StepBits uint8 = 12
 
 	// DEPRECATED: the below four variables will be removed in a future release.
-	mu sync.Mutex
-	nodeMax int64 = -1 ^ (-1 << NodeBits)
-	nodeMask = nodeMax << StepBits
-	stepMask int64 = -1 ^ (-1<<StepBits)
-	timeShift = NodeBits + StepBits
-	nodeShift = StepBits
+	mu        sync.Mutex
+	nodeMax   int64 = -1 ^ (-1 << NodeBits)
+	nodeMask        = nodeMax << StepBits
+	stepMask  int64 = -1 ^ (-1 << StepBits)
+	timeShift       = NodeBits + StepBits
+	nodeShift       = StepBits
 )
 
 const encodeBase32Map = "ybndrfg8ejkmcpqxot1uwisza345h769"
@@ -40,7 +40,7 @@ const encodeBase58Map = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVW
 
 var decodeBase58Map [256]byte
 
-type JSONSyntaxError struct{ original []byte}
+type JSONSyntaxError struct{ original []byte }
 
 func (j JSONSyntaxError) Error() string {
 	return fmt.Sprintf("invalid snowflake ID %q", string(j.original))
@@ -79,15 +79,25 @@ func init() {
 type Node struct {
 	mu    sync.Mutex
 	epoch time.Time
+	clock func() time.Time
 	time  int64
 	node  int64
 	step  int64
 
-	nodeMax   int64
-	nodeMask  int64
-	stepMask  int64
-	timeShift uint8
-	nodeShift uint8
+	lease         *keptLease // nil unless made by NewLeasedNode
+	policy        RollbackPolicy
+	maxWait       time.Duration
+	rollback      int64 // borrowed rollback counter
+	rollbackMax   int64
+	highest       int64 // highest time used, the counter is reset past it
+	timeMax       int64
+	nodeMax       int64
+	nodeMask      int64
+	stepMask      int64
+	timeShift     uint8
+	nodeShift     uint8
+	rollbackMask  int64
+	rollbackShift uint8
 }
 
 // An ID is a custom type used for a snowflake ID.  This is used so we can
@@ -108,21 +118,48 @@ func NewNode(node int64) (*Node, error) {
 	nodeShift = StepBits
 	mu.Unlock()
 
+	return NewNodeWithConfig(node, Config{
+		Epoch:    time.Unix(Epoch/1000, (Epoch%1000)*1000000),
+		NodeBits: NodeBits,
+		StepBits: StepBits,
+	})
+}
+
+// NewNodeWithConfig returns a new snowflake node with its own epoch, bit
+// layout and clock rollback policy
+func NewNodeWithConfig(node int64, cfg Config) (*Node, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
 	n := Node{}
 	n.node = node
-	n.nodeMax = -1 ^ (-1 << NodeBits)
-	n.nodeMask = n.nodeMax << StepBits
-	n.stepMask = -1 ^ (-1 << StepBits)
-	n.timeShift = NodeBits + StepBits
-	n.nodeShift = StepBits
+	n.clock = cfg.Clock
+	n.policy = cfg.Rollback
+	n.maxWait = cfg.MaxWait
+	n.timeMax = -1 ^ (-1 << cfg.TimeBits)
+	n.nodeMax = -1 ^ (-1 << cfg.NodeBits)
+	n.nodeMask = n.nodeMax << cfg.StepBits
+	n.stepMask = -1 ^ (-1 << cfg.StepBits)
+	n.rollbackMax = -1 ^ (-1 << cfg.RollbackBits)
+	n.rollbackShift = cfg.NodeBits + cfg.StepBits
+	n.rollbackMask = n.rollbackMax << n.rollbackShift
+	n.timeShift = cfg.RollbackBits + cfg.NodeBits + cfg.StepBits
+	n.nodeShift = cfg.StepBits
+	n.highest = -1
 
 	if n.node < 0 || n.node > n.nodeMax {
 		return nil, errors.New("Node number must be between 0 and " + strconv.FormatInt(n.nodeMax, 10))
 	}
 
-	var curTime = time.Now()
-	// add time.Duration to curTime to make sure we use the monotonic clock if available
-	n.epoch = curTime.Add(time.Unix(Epoch/1000, (Epoch%1000)*1000000).Sub(curTime))
+	if n.clock == nil {
+		var curTime = time.Now()
+		// add time.Duration to curTime to make sure we use the monotonic clock if available
+		n.epoch = curTime.Add(cfg.Epoch.Sub(curTime))
+	} else {
+		n.epoch = cfg.Epoch
+	}
 
 	return &n, nil
 }
@@ -131,33 +168,164 @@ func NewNode(node int64) (*Node, error) {
 // To help guarantee uniqueness
 // - Make sure your system is keeping accurate system time
 // - Make sure you never have multiple nodes running with the same node ID
+// It panics if the rollback policy fails, which never happens with the
+// default policy of a node without a Config.Clock, or if the lease of a node
+// made by NewLeasedNode is lost; use Next to get the error.
 func (n *Node) Generate() ID {
+	id, err := n.Next()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
 
+// Next creates and returns a unique snowflake ID, or an error if the clock
+// went backwards and the rollback policy could not handle it, or the lease
+// of the node ID is lost
+func (n *Node) Next() (ID, error) {
 	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.leaseErr(); err != nil {
+		return 0, err
+	}
+	if err := n.tick(); err != nil {
+		return 0, err
+	}
+	return n.id(), nil
+}
+
+// GenerateN creates count unique snowflake IDs at once, in increasing order
+// unless the clock goes backwards meanwhile
+func (n *Node) GenerateN(count int) ([]ID, error) {
+	if count < 0 {
+		return nil, errors.New("snowflake: count must not be negative")
+	}
+	ids := make([]ID, 0, count)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.leaseErr(); err != nil {
+		return nil, err
+	}
+	for len(ids) < count {
+		if err := n.tick(); err != nil {
+			return nil, err
+		}
+		ids = append(ids, n.id())
+	}
+	return ids, nil
+}
+
+// leaseErr returns ErrLeaseLost when the node ID lease is lost
+func (n *Node) leaseErr() error {
+	if n.lease == nil {
+		return nil
+	}
+	return n.lease.err()
+}
 
-	now := time.Since(n.epoch).Nanoseconds() / 1000000
+func (n *Node) now() int64 {
+	if n.clock != nil {
+		return n.clock().Sub(n.epoch).Nanoseconds() / 1000000
+	}
+	return time.Since(n.epoch).Nanoseconds() / 1000000
+}
+
+// tick moves the node to the time and step of the next ID
+func (n *Node) tick() error {
+	now := n.now()
+
+	if now < n.time {
+		var err error
+		if now, err = n.rolledBack(now); err != nil {
+			return err
+		}
+	}
 
+	step := int64(0)
 	if now == n.time {
-		n.step = (n.step + 1) & n.stepMask
+		step = (n.step + 1) & n.stepMask
 
-		if n.step == 0 { // 表示当前时间12位的步骤用完了，当前时间不能使用，需要更大的时间，要不会产生重复id
+		if step == 0 { // 表示当前时间12位的步骤用完了，当前时间不能使用，需要更大的时间，要不会产生重复id
 			for now <= n.time {
-				now = time.Since(n.epoch).Nanoseconds() / 1000000 // 获取到比初始时间大的时间
+				now = n.now() // 获取到比初始时间大的时间
+				if now < n.time && n.policy != RollbackWait {
+					var err error
+					if now, err = n.rolledBack(now); err != nil {
+						return err
+					}
+					break
+				}
 			}
 		}
-	} else {
-		n.step = 0
 	}
 
+	if now > n.timeMax {
+		return ErrTimeOverflow
+	}
+	if now > n.highest {
+		// no ID was ever made at this time, the borrowed counter can start over
+		n.highest = now
+		n.rollback = 0
+	}
 	n.time = now
+	n.step = step
+	return nil
+}
 
-	r := ID((now)<<n.timeShift |
+// rolledBack applies the rollback policy when the clock shows now, before the
+// time of the last ID, and returns the time to use
+func (n *Node) rolledBack(now int64) (int64, error) {
+	switch n.policy {
+	case RollbackWait:
+		behind := time.Duration(n.time-now) * time.Millisecond
+		if n.maxWait > 0 && behind > n.maxWait {
+			return 0, ErrClockRollback
+		}
+		for now < n.time {
+			time.Sleep(time.Duration(n.time-now) * time.Millisecond)
+			now = n.now()
+		}
+		return now, nil
+	case RollbackBorrow:
+		if n.rollback == n.rollbackMax {
+			return 0, ErrClockRollback
+		}
+		n.rollback++
+		return now, nil
+	default:
+		return 0, ErrClockRollback
+	}
+}
+
+func (n *Node) id() ID {
+	return ID(n.time<<n.timeShift |
+		n.rollback<<n.rollbackShift |
 		(n.node << n.nodeShift) |
 		(n.step),
 	) // 时间 节点 步骤 或运算
+}
 
-	n.mu.Unlock()
-	return r
+// Time returns the time of an ID made by this node
+func (n *Node) Time(id ID) time.Time {
+	return n.epoch.Add(time.Duration(int64(id)>>n.timeShift) * time.Millisecond).Round(0)
+}
+
+// NodeID returns the node number of an ID made with the layout of this node
+func (n *Node) NodeID(id ID) int64 {
+	return int64(id) & n.nodeMask >> n.nodeShift
+}
+
+// Step returns the step (or sequence) number of an ID made with the layout of this node
+func (n *Node) Step(id ID) int64 {
+	return int64(id) & n.stepMask
+}
+
+// Rollback returns the counter borrowed after clock rollbacks in an ID made with the layout of this node
+func (n *Node) Rollback(id ID) int64 {
+	return int64(id) & n.rollbackMask >> n.rollbackShift
 }
 
 // Int64 returns an int64 of the snowflake ID
@@ -360,4 +528,4 @@ func (f *ID) UnmarshalJSON(b []byte) error {
 
 	*f = ID(i)
 	return nil
-}
\ No newline at end of file
+}
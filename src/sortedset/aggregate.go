@@ -0,0 +1,123 @@
+package sortedset
+
+import (
+	"math"
+)
+
+// Aggregate specifies how scores of the same key in several sets are combined
+type Aggregate int
+
+const (
+	AggregateSum Aggregate = iota
+	AggregateMin
+	AggregateMax
+)
+
+func (a Aggregate) combine(acc, score SCORE) SCORE {
+	switch a {
+	case AggregateMin:
+		if score < acc {
+			return score
+		}
+		return acc
+	case AggregateMax:
+		if score > acc {
+			return score
+		}
+		return acc
+	default:
+		return zeroNaN(acc + score)
+	}
+}
+
+// zeroNaN turns NaN, e.g. the result of inf + -inf or inf * 0, into 0 like Redis does
+func zeroNaN(score SCORE) SCORE {
+	if math.IsNaN(float64(score)) {
+		return 0
+	}
+	return score
+}
+
+func weight(weights []float64, i int) SCORE {
+	if i < len(weights) {
+		return SCORE(weights[i])
+	}
+	return 1
+}
+
+// Union returns a new set with the keys of all sets, like ZUNIONSTORE.
+// The score of every set is multiplied by its weight, missing weights are 1,
+// and the weighted scores of a key are combined with aggregate.
+// The value of a key is taken from the first set containing it.
+//
+// Time complexity of this method is : O(N*log(N)) with N the total number of nodes
+func Union(sets []*SortedSet, weights []float64, aggregate Aggregate) *SortedSet {
+	type member struct {
+		score SCORE
+		value interface{}
+	}
+	members := make(map[string]*member)
+	var keys []string
+
+	for i, set := range sets {
+		w := weight(weights, i)
+		for x := set.header.level[0].forward; x != nil; x = x.level[0].forward {
+			score := zeroNaN(x.score * w)
+			if m, ok := members[x.key]; ok {
+				m.score = aggregate.combine(m.score, score)
+				continue
+			}
+			members[x.key] = &member{score: score, value: x.Value}
+			keys = append(keys, x.key)
+		}
+	}
+
+	result := New()
+	for _, key := range keys {
+		result.AddOrUpdate(key, members[key].score, members[key].value)
+	}
+	return result
+}
+
+// Intersect returns a new set with the keys present in every set, like ZINTERSTORE.
+// Weights, aggregation and values are handled like in Union.
+//
+// Time complexity of this method is : O(N*K*log(N)) with N the size of the smallest set and K the number of sets
+func Intersect(sets []*SortedSet, weights []float64, aggregate Aggregate) *SortedSet {
+	result := New()
+	if len(sets) == 0 {
+		return result
+	}
+
+	// iterate over the smallest set and look the keys up in the others
+	smallest := 0
+	for i, set := range sets {
+		if set.length < sets[smallest].length {
+			smallest = i
+		}
+	}
+
+	for x := sets[smallest].header.level[0].forward; x != nil; x = x.level[0].forward {
+		var score SCORE
+		var value interface{}
+		found := true
+		for i, set := range sets {
+			node := set.dict[x.key]
+			if node == nil {
+				found = false
+				break
+			}
+
+			weighted := zeroNaN(node.score * weight(weights, i))
+			if i == 0 {
+				score, value = weighted, node.Value
+			} else {
+				score = aggregate.combine(score, weighted)
+			}
+		}
+		if found {
+			result.AddOrUpdate(x.key, score, value)
+		}
+	}
+	return result
+}
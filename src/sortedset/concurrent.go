@@ -0,0 +1,168 @@
+package sortedset
+
+import (
+	"io"
+	"sync"
+)
+
+// ConcurrentSortedSet wraps a SortedSet with a RWMutex so it can be shared by goroutines.
+// Read-only methods take the read lock; methods changing the set take the write lock.
+//
+// The returned nodes are copies taken under the lock, they are detached from the set
+// and do not see later changes.
+type ConcurrentSortedSet struct {
+	mu  sync.RWMutex
+	set *SortedSet
+}
+
+// Create a new ConcurrentSortedSet
+func NewConcurrent() *ConcurrentSortedSet {
+	return &ConcurrentSortedSet{set: New()}
+}
+
+// Get the number of elements
+func (this *ConcurrentSortedSet) GetCount() int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.GetCount()
+}
+
+// get the element with minimum score, nil if the set is empty
+func (this *ConcurrentSortedSet) PeekMin() *SortedSetNode {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.PeekMin().copy()
+}
+
+// get and remove the element with minimal score, nil if the set is empty
+func (this *ConcurrentSortedSet) PopMin() *SortedSetNode {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.PopMin().copy()
+}
+
+// get the element with maximum score, nil if the set is empty
+func (this *ConcurrentSortedSet) PeekMax() *SortedSetNode {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.PeekMax().copy()
+}
+
+// get and remove the element with maximum score, nil if the set is empty
+func (this *ConcurrentSortedSet) PopMax() *SortedSetNode {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.PopMax().copy()
+}
+
+// Add an element into the sorted set with specific key / value / score, see SortedSet.AddOrUpdate
+func (this *ConcurrentSortedSet) AddOrUpdate(key string, score SCORE, value interface{}) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.AddOrUpdate(key, score, value)
+}
+
+// Add an element into the sorted set, see SortedSet.TryAddOrUpdate
+func (this *ConcurrentSortedSet) TryAddOrUpdate(key string, score SCORE, value interface{}) (bool, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.TryAddOrUpdate(key, score, value)
+}
+
+// Delete element specified by key
+func (this *ConcurrentSortedSet) Remove(key string) *SortedSetNode {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.Remove(key).copy()
+}
+
+// Get the nodes whose score within the specific range, see SortedSet.GetByScoreRange
+func (this *ConcurrentSortedSet) GetByScoreRange(start SCORE, end SCORE, options *GetByScoreRangeOptions) []*SortedSetNode {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return copyNodes(this.set.GetByScoreRange(start, end, options))
+}
+
+// Count the nodes whose score within the specific range, see SortedSet.CountByScoreRange
+func (this *ConcurrentSortedSet) CountByScoreRange(start SCORE, end SCORE, options *GetByScoreRangeOptions) int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.CountByScoreRange(start, end, options)
+}
+
+// Get the nodes whose key within the specific range, see SortedSet.GetByLexRange
+func (this *ConcurrentSortedSet) GetByLexRange(start string, end string, options *GetByLexRangeOptions) ([]*SortedSetNode, error) {
+	if options != nil && options.Remove {
+		this.mu.Lock()
+		defer this.mu.Unlock()
+	} else {
+		this.mu.RLock()
+		defer this.mu.RUnlock()
+	}
+	nodes, err := this.set.GetByLexRange(start, end, options)
+	return copyNodes(nodes), err
+}
+
+// Count the nodes whose key within the specific range, see SortedSet.CountByLexRange
+func (this *ConcurrentSortedSet) CountByLexRange(start string, end string) (int, error) {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.CountByLexRange(start, end)
+}
+
+// Get nodes within specific rank range [start, end], see SortedSet.GetByRankRange
+func (this *ConcurrentSortedSet) GetByRankRange(start int, end int, remove bool) []*SortedSetNode {
+	if remove {
+		this.mu.Lock()
+		defer this.mu.Unlock()
+	} else {
+		this.mu.RLock()
+		defer this.mu.RUnlock()
+	}
+	return copyNodes(this.set.GetByRankRange(start, end, remove))
+}
+
+// Get node by rank, see SortedSet.GetByRank
+func (this *ConcurrentSortedSet) GetByRank(rank int, remove bool) *SortedSetNode {
+	nodes := this.GetByRankRange(rank, rank, remove)
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return nil
+}
+
+// Get node by key
+func (this *ConcurrentSortedSet) GetByKey(key string) *SortedSetNode {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.GetByKey(key).copy()
+}
+
+// Find the rank of the node specified by key, see SortedSet.FindRank
+func (this *ConcurrentSortedSet) FindRank(key string) int {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.FindRank(key)
+}
+
+// Write a snapshot of the set to w, see SortedSet.WriteTo
+func (this *ConcurrentSortedSet) WriteTo(w io.Writer) (int64, error) {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return this.set.WriteTo(w)
+}
+
+// Replace the content of the set with a snapshot read from r, see SortedSet.ReadFrom
+func (this *ConcurrentSortedSet) ReadFrom(r io.Reader) (int64, error) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.set.ReadFrom(r)
+}
+
+// copyNodes replaces nodes with their detached copies
+func copyNodes(nodes []*SortedSetNode) []*SortedSetNode {
+	for i, node := range nodes {
+		nodes[i] = node.copy()
+	}
+	return nodes
+}
@@ -29,7 +29,7 @@ Every node in the set is associated with these properties.
     type SortedSetNode struct {
         key      string      // unique key of this node
         Value    interface{} // associated data
-        score    SCORE       // int64 score to determine the order of this node in the set
+        score    SCORE       // float64 score to determine the order of this node in the set
     }
 Each node in the set is associated with a key. While keys are unique, scores may be repeated. Nodes are taken in order (from low score to high score) instead of ordered afterwards. If scores are the same, the node is ordered by its key in lexicographic order. Each node in the set also can be accessed by rank, which represents the position in the sorted set.
 Sorted Set is implemented basing on skip list and hash map internally. With sorted sets you can add, remove, or update nodes in a very fast way (in a time proportional to the logarithm of the number of nodes). You can also get ranges by score or by rank (position) in a very fast way. Accessing the middle of a sorted set is also very fast, so you can use Sorted Sets as a smart list of non repeating nodes where you can quickly access everything you need: nodes in order, fast existence test, fast access to nodes in the middle!
@@ -97,5 +97,18 @@ Examples
         ExcludeStart: true,
         ExcludeEnd: true,
     })
+    // count the nodes whose score are within the interval [60,100]
+    set.CountByScoreRange(60, 100, nil)
+    // get the nodes whose key are within the interval ["b","e"), when all nodes have the same score
+    set.GetByLexRange("[b", "(e", nil)
+    // get the nodes whose key are greater than "b" in reverse order
+    set.GetByLexRange("+", "(b", nil)
+    // combine sets like ZUNIONSTORE / ZINTERSTORE
+    sortedset.Union([]*sortedset.SortedSet{set, other}, []float64{1, 2}, sortedset.AggregateSum)
+    sortedset.Intersect([]*sortedset.SortedSet{set, other}, nil, sortedset.AggregateMax)
+    // save and restore the set
+    set.WriteTo(file)
+    set.ReadFrom(file)
+A SortedSet is not safe for concurrent use, use NewConcurrent() to create a set guarded by a RWMutex.
 */
 package sortedset
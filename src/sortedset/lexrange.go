@@ -0,0 +1,173 @@
+package sortedset
+
+import (
+	"errors"
+)
+
+// ErrInvalidLexRange is returned when a lex range item is not "-", "+", "[value" or "(value"
+var ErrInvalidLexRange = errors.New("sortedset: min or max not valid string range item")
+
+// lexBound is a parsed ZRANGEBYLEX range item
+type lexBound struct {
+	value     string
+	exclusive bool
+	inf       int // -1 for "-", 1 for "+", 0 otherwise
+}
+
+func parseLexBound(item string) (lexBound, error) {
+	switch {
+	case item == "-":
+		return lexBound{inf: -1}, nil
+	case item == "+":
+		return lexBound{inf: 1}, nil
+	case len(item) > 0 && item[0] == '[':
+		return lexBound{value: item[1:]}, nil
+	case len(item) > 0 && item[0] == '(':
+		return lexBound{value: item[1:], exclusive: true}, nil
+	default:
+		return lexBound{}, ErrInvalidLexRange
+	}
+}
+
+// before reports whether key sorts before the range starting at b
+func (b lexBound) before(key string) bool {
+	switch {
+	case b.inf != 0:
+		return b.inf > 0
+	case b.exclusive:
+		return key <= b.value
+	default:
+		return key < b.value
+	}
+}
+
+// notAfter reports whether key does not sort after the range ending at b
+func (b lexBound) notAfter(key string) bool {
+	switch {
+	case b.inf != 0:
+		return b.inf > 0
+	case b.exclusive:
+		return key < b.value
+	default:
+		return key <= b.value
+	}
+}
+
+// rankOfLast returns the rank of the last node for which match returns true,
+// 0 if there is none. match must hold for a prefix of the nodes in order.
+// It walks the levels like FindRank does, summing up the spans.
+func (this *SortedSet) rankOfLast(match func(*SortedSetNode) bool) int {
+	rank := 0
+	x := this.header
+	for i := this.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && match(x.level[i].forward) {
+			rank += int(x.level[i].span)
+			x = x.level[i].forward
+		}
+	}
+	return rank
+}
+
+// scoreRankRange returns the ranks (lo, hi] of the nodes within the score range
+func (this *SortedSet) scoreRankRange(start SCORE, end SCORE, options *GetByScoreRangeOptions) (int, int) {
+	excludeStart := options != nil && options.ExcludeStart
+	excludeEnd := options != nil && options.ExcludeEnd
+	if start > end {
+		start, end = end, start
+		excludeStart, excludeEnd = excludeEnd, excludeStart
+	}
+
+	lo := this.rankOfLast(func(x *SortedSetNode) bool {
+		return x.score < start || excludeStart && x.score == start
+	})
+	hi := this.rankOfLast(func(x *SortedSetNode) bool {
+		return x.score < end || !excludeEnd && x.score == end
+	})
+	return lo, hi
+}
+
+// Count the nodes whose score within the specific range, like ZCOUNT
+// Limit of options is ignored
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet) CountByScoreRange(start SCORE, end SCORE, options *GetByScoreRangeOptions) int {
+	lo, hi := this.scoreRankRange(start, end, options)
+	if hi < lo {
+		return 0
+	}
+	return hi - lo
+}
+
+type GetByLexRangeOptions struct {
+	Offset int  // skip the first nodes of the range
+	Limit  int  // limit the max nodes to return
+	Remove bool // remove the returned nodes
+}
+
+// lexRankRange returns the ranks (lo, hi] of the nodes within the lex range
+// and whether the range is given in reverse order
+func (this *SortedSet) lexRankRange(start string, end string) (int, int, bool, error) {
+	startBound, err := parseLexBound(start)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	endBound, err := parseLexBound(end)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	reverse := startBound.inf > 0 || endBound.inf < 0 ||
+		startBound.inf == 0 && endBound.inf == 0 && startBound.value > endBound.value
+	if reverse {
+		startBound, endBound = endBound, startBound
+	}
+
+	lo := this.rankOfLast(func(x *SortedSetNode) bool { return startBound.before(x.key) })
+	hi := this.rankOfLast(func(x *SortedSetNode) bool { return endBound.notAfter(x.key) })
+	return lo, hi, reverse, nil
+}
+
+// Get the nodes whose key within the specific range, like ZRANGEBYLEX / ZREVRANGEBYLEX
+// Range items are "[value" (inclusive), "(value" (exclusive), "-" and "+" (unbounded).
+// The nodes are expected to have the same score, otherwise the result is unspecified.
+//
+// If start is greater than end, e.g. GetByLexRange("+", "[a", nil), the returned array is in reverse order
+//
+// Time complexity of this method is : O(log(N)+M) with M the number of nodes returned
+func (this *SortedSet) GetByLexRange(start string, end string, options *GetByLexRangeOptions) ([]*SortedSetNode, error) {
+	lo, hi, reverse, err := this.lexRankRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, limit := 0, hi-lo
+	if options != nil {
+		offset = options.Offset
+		if options.Limit > 0 && options.Limit < limit {
+			limit = options.Limit
+		}
+	}
+	if offset < 0 || offset >= hi-lo || limit <= 0 {
+		return nil, nil
+	}
+	if offset+limit > hi-lo {
+		limit = hi - lo - offset
+	}
+
+	remove := options != nil && options.Remove
+	if reverse {
+		return this.GetByRankRange(hi-offset, hi-offset-limit+1, remove), nil
+	}
+	return this.GetByRankRange(lo+1+offset, lo+offset+limit, remove), nil
+}
+
+// Count the nodes whose key within the specific range, like ZLEXCOUNT
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet) CountByLexRange(start string, end string) (int, error) {
+	lo, hi, _, err := this.lexRankRange(start, end)
+	if err != nil || hi < lo {
+		return 0, err
+	}
+	return hi - lo, nil
+}
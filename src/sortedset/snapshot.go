@@ -0,0 +1,199 @@
+package sortedset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrInvalidSnapshot is returned by ReadFrom when the data is not a snapshot written by WriteTo
+var ErrInvalidSnapshot = errors.New("sortedset: invalid snapshot")
+
+var snapshotMagic = []byte("ZSET\x01")
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r interface {
+		io.Reader
+		io.ByteReader
+	}
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// Write a binary snapshot of the set to w, implementing io.WriterTo.
+// Nodes are written in rank order as key, score and gob encoded value,
+// so the concrete types stored in Value must be registered with gob.Register.
+//
+// Time complexity of this method is : O(N)
+func (this *SortedSet) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	var buf [binary.MaxVarintLen64]byte
+	writeUvarint := func(v uint64) error {
+		_, err := bw.Write(buf[:binary.PutUvarint(buf[:], v)])
+		return err
+	}
+
+	if _, err := bw.Write(snapshotMagic); err != nil {
+		return cw.n, err
+	}
+	if err := writeUvarint(uint64(this.length)); err != nil {
+		return cw.n, err
+	}
+
+	var value bytes.Buffer
+	for x := this.header.level[0].forward; x != nil; x = x.level[0].forward {
+		// a nil value is written as an empty one, gob never produces empty output
+		value.Reset()
+		if x.Value != nil {
+			if err := gob.NewEncoder(&value).Encode(&x.Value); err != nil {
+				return cw.n, err
+			}
+		}
+
+		if err := writeUvarint(uint64(len(x.key))); err != nil {
+			return cw.n, err
+		}
+		if _, err := bw.WriteString(x.key); err != nil {
+			return cw.n, err
+		}
+		binary.BigEndian.PutUint64(buf[:8], math.Float64bits(float64(x.score)))
+		if _, err := bw.Write(buf[:8]); err != nil {
+			return cw.n, err
+		}
+		if err := writeUvarint(uint64(value.Len())); err != nil {
+			return cw.n, err
+		}
+		if _, err := bw.Write(value.Bytes()); err != nil {
+			return cw.n, err
+		}
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Replace the content of the set with a snapshot written by WriteTo, implementing io.ReaderFrom.
+// The set is left untouched if the snapshot can not be read.
+//
+// Time complexity of this method is : O(N*log(N))
+func (this *SortedSet) ReadFrom(r io.Reader) (int64, error) {
+	// avoid reading ahead past the snapshot when possible
+	cr := &countingReader{}
+	if br, ok := r.(interface {
+		io.Reader
+		io.ByteReader
+	}); ok {
+		cr.r = br
+	} else {
+		cr.r = bufio.NewReader(r)
+	}
+
+	restored, err := readSnapshot(cr)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return cr.n, err
+	}
+
+	*this = *restored
+	return cr.n, nil
+}
+
+func readSnapshot(r *countingReader) (*SortedSet, error) {
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, snapshotMagic) {
+		return nil, ErrInvalidSnapshot
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	set := New()
+	var scoreBits [8]byte
+	for i := uint64(0); i < count; i++ {
+		key, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, scoreBits[:]); err != nil {
+			return nil, err
+		}
+		score := SCORE(math.Float64frombits(binary.BigEndian.Uint64(scoreBits[:])))
+
+		encoded, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if len(encoded) > 0 {
+			if err := gob.NewDecoder(bytes.NewReader(encoded)).Decode(&value); err != nil {
+				return nil, err
+			}
+		}
+
+		added, err := set.TryAddOrUpdate(string(key), score, value)
+		if err != nil || !added { // NaN score or duplicated key
+			return nil, ErrInvalidSnapshot
+		}
+	}
+	return set, nil
+}
+
+// readBytes reads a uvarint length prefixed byte slice
+func readBytes(r *countingReader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt32 {
+		return nil, ErrInvalidSnapshot
+	}
+	// the length comes from the input, so the buffer grows with the data
+	// actually read instead of being allocated up front
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
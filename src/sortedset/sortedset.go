@@ -1,10 +1,15 @@
 package sortedset
 
 import (
+	"errors"
+	"math"
 	"math/rand"
 )
 
-type SCORE int64 // the type of score
+// ErrNaNScore is returned by TryAddOrUpdate when the score is NaN
+var ErrNaNScore = errors.New("sortedset: NaN score")
+
+type SCORE float64 // the type of score, NaN is not allowed
 
 const SKIPLIST_MAXLEVEL = 32 /* Should be enough for 2^32 elements */
 const SKIPLIST_P = 0.25      /* Skiplist P = 1/4 */
@@ -209,9 +214,22 @@ func (this *SortedSet) PopMax() *SortedSetNode {
 
 // Add an element into the sorted set with specific key / value / score.
 // if the element is added, this method returns true; otherwise false means updated
+// A NaN score can not be ordered, so it is ignored and false is returned; use TryAddOrUpdate to detect it.
 //
 // Time complexity of this method is : O(log(N))
 func (this *SortedSet) AddOrUpdate(key string, score SCORE, value interface{}) bool {
+	added, _ := this.TryAddOrUpdate(key, score, value)
+	return added
+}
+
+// Same as AddOrUpdate, but ErrNaNScore is returned and the set is left untouched if score is NaN
+//
+// Time complexity of this method is : O(log(N))
+func (this *SortedSet) TryAddOrUpdate(key string, score SCORE, value interface{}) (bool, error) {
+	if math.IsNaN(float64(score)) {
+		return false, ErrNaNScore
+	}
+
 	var newNode *SortedSetNode = nil
 
 	found := this.dict[key]
@@ -230,7 +248,7 @@ func (this *SortedSet) AddOrUpdate(key string, score SCORE, value interface{}) b
 	if newNode != nil {
 		this.dict[key] = newNode
 	}
-	return found == nil
+	return found == nil, nil
 }
 
 // Delete element specified by key
@@ -0,0 +1,228 @@
+package sortedset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func keys(nodes []*SortedSetNode) string {
+	var b bytes.Buffer
+	for _, node := range nodes {
+		b.WriteString(node.Key())
+	}
+	return b.String()
+}
+
+func TestFloatScoresTieBreakByKey(t *testing.T) {
+	set := New()
+	set.AddOrUpdate("c", 1.5, nil)
+	set.AddOrUpdate("a", 1.5, nil)
+	set.AddOrUpdate("b", 0.25, nil)
+	set.AddOrUpdate("d", SCORE(math.Inf(-1)), nil)
+
+	if got := keys(set.GetByRankRange(1, -1, false)); got != "dbac" {
+		t.Fatalf("order = %q", got)
+	}
+	if rank := set.FindRank("c"); rank != 4 {
+		t.Fatalf("FindRank(c) = %d", rank)
+	}
+	if n := set.CountByScoreRange(0.25, 1.5, &GetByScoreRangeOptions{ExcludeStart: true}); n != 2 {
+		t.Fatalf("CountByScoreRange = %d", n)
+	}
+	if n := set.CountByScoreRange(1.5, SCORE(math.Inf(-1)), nil); n != 4 {
+		t.Fatalf("reversed CountByScoreRange = %d", n)
+	}
+}
+
+func TestNaNScore(t *testing.T) {
+	set := New()
+	set.AddOrUpdate("a", 1, nil)
+
+	if set.AddOrUpdate("a", SCORE(math.NaN()), nil) {
+		t.Fatal("NaN score added")
+	}
+	if _, err := set.TryAddOrUpdate("b", SCORE(math.NaN()), nil); err != ErrNaNScore {
+		t.Fatalf("err = %v", err)
+	}
+	if set.GetCount() != 1 || set.GetByKey("a").Score() != 1 {
+		t.Fatal("NaN score changed the set")
+	}
+}
+
+func TestLexRange(t *testing.T) {
+	set := New()
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		set.AddOrUpdate(key, 0, nil)
+	}
+
+	tests := []struct {
+		start, end string
+		options    *GetByLexRangeOptions
+		want       string
+	}{
+		{"-", "[c", nil, "abc"},
+		{"-", "(c", nil, "ab"},
+		{"[aaa", "(g", nil, "bcdef"},
+		{"+", "(d", nil, "gfe"},
+		{"-", "+", &GetByLexRangeOptions{Offset: 2, Limit: 3}, "cde"},
+		{"+", "-", &GetByLexRangeOptions{Offset: 1, Limit: 2}, "fe"},
+		{"(c", "[c", nil, ""},
+	}
+	for _, tt := range tests {
+		nodes, err := set.GetByLexRange(tt.start, tt.end, tt.options)
+		if err != nil {
+			t.Fatalf("GetByLexRange(%q, %q): %v", tt.start, tt.end, err)
+		}
+		if got := keys(nodes); got != tt.want {
+			t.Errorf("GetByLexRange(%q, %q) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+
+	if n, _ := set.CountByLexRange("[b", "[f"); n != 5 {
+		t.Fatalf("CountByLexRange = %d", n)
+	}
+	if _, err := set.GetByLexRange("a", "+", nil); err != ErrInvalidLexRange {
+		t.Fatalf("err = %v", err)
+	}
+
+	nodes, _ := set.GetByLexRange("[b", "[c", &GetByLexRangeOptions{Remove: true})
+	if keys(nodes) != "bc" || set.GetCount() != 5 || set.FindRank("d") != 2 {
+		t.Fatalf("remove by lex range: %q, count %d", keys(nodes), set.GetCount())
+	}
+}
+
+func TestUnionAndIntersect(t *testing.T) {
+	a := New()
+	a.AddOrUpdate("x", 1, "ax")
+	a.AddOrUpdate("y", 2, "ay")
+	b := New()
+	b.AddOrUpdate("y", 3, "by")
+	b.AddOrUpdate("z", 4, "bz")
+
+	union := Union([]*SortedSet{a, b}, []float64{2}, AggregateSum)
+	if got := keys(union.GetByRankRange(1, -1, false)); got != "xzy" {
+		t.Fatalf("union order = %q", got)
+	}
+	if node := union.GetByKey("y"); node.Score() != 7 || node.Value != "ay" {
+		t.Fatalf("union y = %v %v", node.Score(), node.Value)
+	}
+
+	inter := Intersect([]*SortedSet{a, b}, nil, AggregateMax)
+	if inter.GetCount() != 1 || inter.GetByKey("y").Score() != 3 {
+		t.Fatalf("intersect = %q", keys(inter.GetByRankRange(1, -1, false)))
+	}
+
+	inf := New()
+	inf.AddOrUpdate("x", SCORE(math.Inf(1)), nil)
+	if score := Union([]*SortedSet{inf}, []float64{0}, AggregateSum).GetByKey("x").Score(); score != 0 {
+		t.Fatalf("inf * 0 = %v", score)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	set := New()
+	set.AddOrUpdate("a", -1.25, "alice")
+	set.AddOrUpdate("b", 10, 42)
+	set.AddOrUpdate("c", SCORE(math.Inf(1)), nil)
+
+	var buf bytes.Buffer
+	written, err := set.WriteTo(&buf)
+	if err != nil || written != int64(buf.Len()) {
+		t.Fatalf("WriteTo = %d, %v (buffer %d)", written, err, buf.Len())
+	}
+	data := buf.Bytes()
+
+	restored := New()
+	restored.AddOrUpdate("stale", 0, nil)
+	read, err := restored.ReadFrom(bytes.NewReader(data))
+	if err != nil || read != written {
+		t.Fatalf("ReadFrom = %d, %v", read, err)
+	}
+	if got := keys(restored.GetByRankRange(1, -1, false)); got != "abc" {
+		t.Fatalf("restored order = %q", got)
+	}
+	if a, b := restored.GetByKey("a"), restored.GetByKey("b"); a.Score() != -1.25 || a.Value != "alice" || b.Value != 42 {
+		t.Fatalf("restored a = %v %v, b = %v", a.Score(), a.Value, b.Value)
+	}
+	if restored.GetByKey("c").Value != nil {
+		t.Fatal("nil value not restored")
+	}
+
+	if _, err := restored.ReadFrom(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Fatal("expected error for truncated snapshot")
+	}
+	if restored.GetCount() != 3 {
+		t.Fatal("failed ReadFrom modified the set")
+	}
+	if _, err := New().ReadFrom(bytes.NewReader([]byte("nope!"))); err != ErrInvalidSnapshot {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestSnapshotCorruptLength(t *testing.T) {
+	// one element whose key claims to be 2GB long but only has two bytes
+	data := append([]byte{}, snapshotMagic...)
+	var n [binary.MaxVarintLen64]byte
+	data = append(data, n[:binary.PutUvarint(n[:], 1)]...)
+	data = append(data, n[:binary.PutUvarint(n[:], math.MaxInt32)]...)
+	data = append(data, "ab"...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := New().ReadFrom(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("err = %v", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Fatalf("allocated %d bytes for a corrupt length", allocated)
+	}
+}
+
+func TestConcurrentSortedSet(t *testing.T) {
+	set := NewConcurrent()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := string(rune('a'+i)) + string(rune('a'+j%26))
+				set.AddOrUpdate(key, SCORE(j), nil)
+				set.FindRank(key)
+				set.GetByScoreRange(0, 50, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if set.GetCount() != 8*26 {
+		t.Fatalf("count = %d", set.GetCount())
+	}
+	if min := set.PopMin(); min == nil || set.GetCount() != 8*26-1 {
+		t.Fatalf("PopMin = %v", min)
+	}
+}
+
+func TestConcurrentSortedSetCopies(t *testing.T) {
+	set := NewConcurrent()
+	set.AddOrUpdate("a", 1, "old")
+
+	node := set.GetByKey("a")
+	set.AddOrUpdate("a", 2, "new")
+	if node.Score() != 1 || node.Value != "old" {
+		t.Fatalf("returned node changed to %v %v", node.Score(), node.Value)
+	}
+
+	node.Value = "changed"
+	if got := set.GetByKey("a"); got.Value != "new" {
+		t.Fatalf("set value = %v", got.Value)
+	}
+}
@@ -23,3 +23,11 @@ func (this *SortedSetNode) Key() string {
 func (this *SortedSetNode) Score() SCORE {
 	return this.score
 }
+
+// copy returns the node without its links, nil for nil node
+func (this *SortedSetNode) copy() *SortedSetNode {
+	if this == nil {
+		return nil
+	}
+	return &SortedSetNode{key: this.key, Value: this.Value, score: this.score}
+}
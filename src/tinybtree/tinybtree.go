@@ -1,66 +1,142 @@
 package tinybtree
 
+import "sync/atomic"
+
 const maxItems = 31 // use an odd number
 const minItems = maxItems / 2
 
-type item struct {
-	key string
-	value interface{}
+type item[K any, V any] struct {
+	key   K
+	value V
 }
 
-type node struct {
-	numItems int
-	items [maxItems] item
-	children [maxItems + 1]*node
+// cow identifies the tree owning a node, nodes owned by another tree
+// are shared with a copy and must be cloned before they are modified.
+// Copy marks the token shared instead of replacing it, so the source tree
+// takes a new token on its next write.
+type cow struct {
+	shared int32
 }
 
-type leaf sturct {
+type node[K any, V any] struct {
+	cow      *cow
 	numItems int
-	items [maxItems]item
+	items    [maxItems]item[K, V]
+	children [maxItems + 1]*node[K, V]
 }
-// BTree is an ordered set of key/value pairs where the key is a string
-// and the value is an interface{}
-type BTree struct {
+
+// BTreeG is an ordered set of key/value pairs where keys are ordered by a less function
+type BTreeG[K any, V any] struct {
+	less   func(a, b K) bool
+	cow    *cow
 	height int
-	root *node
+	root   *node[K, V]
 	length int
 }
+
+// BTree is an ordered set of key/value pairs where the key is a string
+// and the value is an interface{}, its zero value is an empty tree
+type BTree = BTreeG[string, interface{}]
+
+// Ordered is a constraint for key types ordered by <, like golang.org/x/exp/constraints.Ordered
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+// NewBTreeG returns an empty tree ordering keys with less,
+// use NewOrdered for keys ordered by <
+func NewBTreeG[K any, V any](less func(a, b K) bool) *BTreeG[K, V] {
+	if less == nil {
+		panic("tinybtree: nil less function")
+	}
+	return &BTreeG[K, V]{less: less}
+}
+
+// NewOrdered returns an empty tree ordering keys by <
+func NewOrdered[K Ordered, V any]() *BTreeG[K, V] {
+	return &BTreeG[K, V]{less: func(a, b K) bool { return a < b }}
+}
+
+// own prepares tr for a write, it takes a new cow token if the current one
+// was shared by Copy, and sets the less function of a zero value BTree
+func (tr *BTreeG[K, V]) own() {
+	if tr.cow == nil || atomic.LoadInt32(&tr.cow.shared) != 0 {
+		tr.cow = new(cow)
+	}
+	if tr.less == nil {
+		less, ok := any(func(a, b string) bool { return a < b }).(func(a, b K) bool)
+		if !ok {
+			panic("tinybtree: less function required, create the tree with NewBTreeG or NewOrdered")
+		}
+		tr.less = less
+	}
+}
+
+func (tr *BTreeG[K, V]) newNode() *node[K, V] {
+	return &node[K, V]{cow: tr.cow}
+}
+
+// cowLoad returns the node at cn, cloning it first if it is shared with a copy of the tree
+func (tr *BTreeG[K, V]) cowLoad(cn **node[K, V]) *node[K, V] {
+	if (*cn).cow != tr.cow {
+		n := **cn
+		n.cow = tr.cow
+		*cn = &n
+	}
+	return *cn
+}
+
+// Copy returns a copy of the tree in O(1).
+// Nodes are shared until one of the trees modifies them. Copy only reads tr,
+// so it can run concurrently with other reads and copies of tr.
+func (tr *BTreeG[K, V]) Copy() *BTreeG[K, V] {
+	tr2 := *tr
+	if tr.cow != nil {
+		atomic.StoreInt32(&tr.cow.shared, 1)
+	}
+	tr2.cow = new(cow)
+	return &tr2
+}
+
 // 找到一个 >= key的item
-func (n *node) find(key string) (index int, found bool) {
+func (tr *BTreeG[K, V]) find(n *node[K, V], key K) (index int, found bool) {
 	i, j := 0, n.numItems
-	for i<j { // 二分法找key
+	for i < j { // 二分法找key
 		h := i + (j-i)/2
-		if key >= n.items[h].key {
-			i = h+1
+		if !tr.less(key, n.items[h].key) {
+			i = h + 1
 		} else {
 			j = h
 		}
 	}
-	if i >0 &&n.items[i-1].key >= key {
+	if i > 0 && !tr.less(n.items[i-1].key, key) {
 		return i - 1, true
 	}
 	return i, false
 }
 
 // Set or replace a value for a key
-func (tr *BTree) Set(key string, value interface{}) (
-	prev interface{}, replaced bool,
+func (tr *BTreeG[K, V]) Set(key K, value V) (
+	prev V, replaced bool,
 ) {
+	tr.own()
 	if tr.root == nil {
-		tr.root = new(node)
-		tr.root.items[0] = item{key, value}
+		tr.root = tr.newNode()
+		tr.root.items[0] = item[K, V]{key, value}
 		tr.root.numItems = 1
 		tr.length = 1
 		return
 	}
-	prev, replaced = tr.root.set(key, value, tr.height)
+	prev, replaced = tr.nodeSet(&tr.root, key, value, tr.height)
 	if replaced {
 		return
 	}
 	if tr.root.numItems == maxItems {
 		n := tr.root
-		right, median := n.split(tr.height)
-		tr.root = new(node)
+		right, median := tr.split(n, tr.height)
+		tr.root = tr.newNode()
 		tr.root.children[0] = n
 		tr.root.items[0] = median
 		tr.root.children[1] = right
@@ -71,8 +147,33 @@ func (tr *BTree) Set(key string, value interface{}) (
 	return
 }
 
-func (n *node) split(height int) (right *node, median item) {
-	right = new(node)
+// Load is for bulk loading keys in ascending order.
+// A key greater than all others is appended to the rightmost leaf without
+// searching, any other key falls back to Set.
+func (tr *BTreeG[K, V]) Load(key K, value V) (prev V, replaced bool) {
+	if tr.root == nil {
+		return tr.Set(key, value)
+	}
+	tr.own()
+
+	cn := &tr.root
+	for h := tr.height; h > 0; h-- {
+		n := tr.cowLoad(cn)
+		cn = &n.children[n.numItems]
+	}
+	n := tr.cowLoad(cn)
+	if n.numItems < maxItems-1 && tr.less(n.items[n.numItems-1].key, key) {
+		n.items[n.numItems] = item[K, V]{key, value}
+		n.numItems++
+		tr.length++
+		return
+	}
+	return tr.Set(key, value)
+}
+
+// split moves the upper half of n, which must not be shared, to a new node
+func (tr *BTreeG[K, V]) split(n *node[K, V], height int) (right *node[K, V], median item[K, V]) {
+	right = tr.newNode()
 	median = n.items[maxItems/2]
 	copy(right.items[:maxItems/2], n.items[maxItems/2+1:])
 	if height > 0 {
@@ -85,16 +186,17 @@ func (n *node) split(height int) (right *node, median item) {
 		}
 	}
 	for i := maxItems / 2; i < maxItems; i++ {
-		n.items[i] = item{}
+		n.items[i] = item[K, V]{}
 	}
 	n.numItems = maxItems / 2
 	return
 }
 
-func (n *node) set(key string, value interface{}, height int) (
-	prev interface{}, replaced bool,
+func (tr *BTreeG[K, V]) nodeSet(cn **node[K, V], key K, value V, height int) (
+	prev V, replaced bool,
 ) {
-	i, found := n.find(key)
+	n := tr.cowLoad(cn)
+	i, found := tr.find(n, key)
 	if found {
 		prev = n.items[i].value
 		n.items[i].value = value
@@ -104,16 +206,16 @@ func (n *node) set(key string, value interface{}, height int) (
 		for j := n.numItems; j > i; j-- {
 			n.items[j] = n.items[j-1]
 		}
-		n.items[i] = item{key, value}
+		n.items[i] = item[K, V]{key, value}
 		n.numItems++
-		return nil, false
+		return prev, false
 	}
-	prev, replaced = n.children[i].set(key, value, height-1)
+	prev, replaced = tr.nodeSet(&n.children[i], key, value, height-1)
 	if replaced {
 		return
 	}
 	if n.children[i].numItems == maxItems {
-		right, median := n.children[i].split(height - 1)
+		right, median := tr.split(n.children[i], height-1)
 		copy(n.children[i+1:], n.children[i:])
 		copy(n.items[i+1:], n.items[i:])
 		n.items[i] = median
@@ -124,14 +226,14 @@ func (n *node) set(key string, value interface{}, height int) (
 }
 
 // Scan all items in tree
-func (tr *BTree) Scan(iter func(key string, value interface{}) bool) {
+func (tr *BTreeG[K, V]) Scan(iter func(key K, value V) bool) {
 	if tr.root != nil {
 		tr.root.scan(iter, tr.height)
 	}
 }
 
-func (n *node) scan(
-	iter func(key string, value interface{}) bool, height int,
+func (n *node[K, V]) scan(
+	iter func(key K, value V) bool, height int,
 ) bool {
 	if height == 0 {
 		for i := 0; i < n.numItems; i++ {
@@ -153,41 +255,91 @@ func (n *node) scan(
 }
 
 // Get a value for key
-func (tr *BTree) Get(key string) (value interface{}, gotten bool) {
+func (tr *BTreeG[K, V]) Get(key K) (value V, gotten bool) {
+	if tr.root == nil {
+		return
+	}
+	n := tr.root
+	for height := tr.height; ; height-- {
+		i, found := tr.find(n, key)
+		if found {
+			return n.items[i].value, true
+		}
+		if height == 0 {
+			return value, false
+		}
+		n = n.children[i]
+	}
+}
+
+// Len returns the number of items in the tree
+func (tr *BTreeG[K, V]) Len() int {
+	return tr.length
+}
+
+// Min returns the item with the smallest key
+func (tr *BTreeG[K, V]) Min() (key K, value V, ok bool) {
 	if tr.root == nil {
 		return
 	}
-	return tr.root.get(key, tr.height)
+	n := tr.root
+	for height := tr.height; height > 0; height-- {
+		n = n.children[0]
+	}
+	return n.items[0].key, n.items[0].value, true
 }
 
-func (n *node) get(key string, height int) (value interface{}, gotten bool) {
-	i, found := n.find(key)
-	if found {
-		return n.items[i].value, true
+// Max returns the item with the largest key
+func (tr *BTreeG[K, V]) Max() (key K, value V, ok bool) {
+	if tr.root == nil {
+		return
 	}
-	if height == 0 {
-		return nil, false
+	n := tr.root
+	for height := tr.height; height > 0; height-- {
+		n = n.children[n.numItems]
 	}
-	return n.children[i].get(key, height-1)
+	return n.items[n.numItems-1].key, n.items[n.numItems-1].value, true
 }
 
-// Len returns the number of items in the tree
-func (tr *BTree) Len() int {
-	return tr.length
+// PopMin removes and returns the item with the smallest key
+func (tr *BTreeG[K, V]) PopMin() (key K, value V, ok bool) {
+	key, value, ok = tr.Min()
+	if ok {
+		tr.Delete(key)
+	}
+	return
+}
+
+// PopMax removes and returns the item with the largest key
+func (tr *BTreeG[K, V]) PopMax() (key K, value V, ok bool) {
+	if tr.root == nil {
+		return
+	}
+	tr.own()
+	var zero K
+	prevItem, _ := tr.nodeDelete(&tr.root, true, zero, tr.height)
+	tr.deleted()
+	return prevItem.key, prevItem.value, true
 }
 
 // Delete a value for a key
-func (tr *BTree) Delete(key string) (prev interface{}, deleted bool) {
+func (tr *BTreeG[K, V]) Delete(key K) (prev V, deleted bool) {
 	if tr.root == nil {
 		return
 	}
-	var prevItem item
-	prevItem, deleted = tr.root.delete(false, key, tr.height)
+	tr.own()
+	var prevItem item[K, V]
+	prevItem, deleted = tr.nodeDelete(&tr.root, false, key, tr.height)
 	if !deleted {
 		return
 	}
-	prev = prevItem.value
-	if tr.root.numItems == 0 {
+	tr.deleted()
+	return prevItem.value, true
+}
+
+// deleted shrinks the tree after an item was removed
+func (tr *BTreeG[K, V]) deleted() {
+	if tr.root.numItems == 0 && tr.height > 0 {
 		tr.root = tr.root.children[0]
 		tr.height--
 	}
@@ -196,43 +348,42 @@ func (tr *BTree) Delete(key string) (prev interface{}, deleted bool) {
 		tr.root = nil
 		tr.height = 0
 	}
-	return
 }
 
-func (n *node) delete(max bool, key string, height int) (
-	prev item, deleted bool,
+func (tr *BTreeG[K, V]) nodeDelete(cn **node[K, V], max bool, key K, height int) (
+	prev item[K, V], deleted bool,
 ) {
+	n := tr.cowLoad(cn)
 	i, found := 0, false
 	if max {
 		i, found = n.numItems-1, true
 	} else {
-		i, found = n.find(key)
+		i, found = tr.find(n, key)
 	}
 	if height == 0 {
 		if found {
 			prev = n.items[i]
 			// found the items at the leaf, remove it and return.
 			copy(n.items[i:], n.items[i+1:n.numItems])
-			n.items[n.numItems-1] = item{}
-			n.children[n.numItems] = nil
+			n.items[n.numItems-1] = item[K, V]{}
 			n.numItems--
 			return prev, true
 		}
-		return item{}, false
+		return prev, false
 	}
 
 	if found {
 		if max {
 			i++
-			prev, deleted = n.children[i].delete(true, "", height-1)
+			prev, deleted = tr.nodeDelete(&n.children[i], true, key, height-1)
 		} else {
 			prev = n.items[i]
-			maxItem, _ := n.children[i].delete(true, "", height-1)
+			maxItem, _ := tr.nodeDelete(&n.children[i], true, key, height-1)
 			n.items[i] = maxItem
 			deleted = true
 		}
 	} else {
-		prev, deleted = n.children[i].delete(max, key, height-1)
+		prev, deleted = tr.nodeDelete(&n.children[i], max, key, height-1)
 	}
 	if !deleted {
 		return
@@ -241,81 +392,80 @@ func (n *node) delete(max bool, key string, height int) (
 		if i == n.numItems {
 			i--
 		}
-		if n.children[i].numItems+n.children[i+1].numItems+1 < maxItems {
+		left := tr.cowLoad(&n.children[i])
+		right := tr.cowLoad(&n.children[i+1])
+		if left.numItems+right.numItems+1 < maxItems {
 			// merge left + item + right
-			n.children[i].items[n.children[i].numItems] = n.items[i]
-			copy(n.children[i].items[n.children[i].numItems+1:],
-				n.children[i+1].items[:n.children[i+1].numItems])
+			left.items[left.numItems] = n.items[i]
+			copy(left.items[left.numItems+1:], right.items[:right.numItems])
 			if height > 1 {
-				copy(n.children[i].children[n.children[i].numItems+1:],
-					n.children[i+1].children[:n.children[i+1].numItems+1])
+				copy(left.children[left.numItems+1:], right.children[:right.numItems+1])
 			}
-			n.children[i].numItems += n.children[i+1].numItems + 1
+			left.numItems += right.numItems + 1
 			copy(n.items[i:], n.items[i+1:n.numItems])
 			copy(n.children[i+1:], n.children[i+2:n.numItems+1])
-			n.items[n.numItems] = item{}
-			n.children[n.numItems+1] = nil
+			n.items[n.numItems-1] = item[K, V]{}
+			n.children[n.numItems] = nil
 			n.numItems--
-		} else if n.children[i].numItems > n.children[i+1].numItems {
+		} else if left.numItems > right.numItems {
 			// move left -> right
-			copy(n.children[i+1].items[1:],
-				n.children[i+1].items[:n.children[i+1].numItems])
+			copy(right.items[1:], right.items[:right.numItems])
 			if height > 1 {
-				copy(n.children[i+1].children[1:],
-					n.children[i+1].children[:n.children[i+1].numItems+1])
+				copy(right.children[1:], right.children[:right.numItems+1])
 			}
-			n.children[i+1].items[0] = n.items[i]
+			right.items[0] = n.items[i]
 			if height > 1 {
-				n.children[i+1].children[0] =
-					n.children[i].children[n.children[i].numItems]
+				right.children[0] = left.children[left.numItems]
 			}
-			n.children[i+1].numItems++
-			n.items[i] = n.children[i].items[n.children[i].numItems-1]
-			n.children[i].items[n.children[i].numItems-1] = item{}
+			right.numItems++
+			n.items[i] = left.items[left.numItems-1]
+			left.items[left.numItems-1] = item[K, V]{}
 			if height > 1 {
-				n.children[i].children[n.children[i].numItems] = nil
+				left.children[left.numItems] = nil
 			}
-			n.children[i].numItems--
+			left.numItems--
 		} else {
 			// move right -> left
-			n.children[i].items[n.children[i].numItems] = n.items[i]
+			left.items[left.numItems] = n.items[i]
+			if height > 1 {
+				left.children[left.numItems+1] = right.children[0]
+			}
+			left.numItems++
+			n.items[i] = right.items[0]
+			copy(right.items[:], right.items[1:right.numItems])
 			if height > 1 {
-				n.children[i].children[n.children[i].numItems+1] =
-					n.children[i+1].children[0]
+				copy(right.children[:], right.children[1:right.numItems+1])
 			}
-			n.children[i].numItems++
-			n.items[i] = n.children[i+1].items[0]
-			copy(n.children[i+1].items[:],
-				n.children[i+1].items[1:n.children[i+1].numItems])
+			right.numItems--
+			right.items[right.numItems] = item[K, V]{}
 			if height > 1 {
-				copy(n.children[i+1].children[:],
-					n.children[i+1].children[1:n.children[i+1].numItems+1])
+				right.children[right.numItems+1] = nil
 			}
-			n.children[i+1].numItems--
 		}
 	}
 	return
 }
 
 // Ascend the tree within the range [pivot, last]
-func (tr *BTree) Ascend(
-	pivot string,
-	iter func(key string, value interface{}) bool,
+func (tr *BTreeG[K, V]) Ascend(
+	pivot K,
+	iter func(key K, value V) bool,
 ) {
 	if tr.root != nil {
-		tr.root.ascend(pivot, iter, tr.height)
+		tr.ascend(tr.root, pivot, iter, tr.height)
 	}
 }
 
-func (n *node) ascend(
-	pivot string,
-	iter func(key string, value interface{}) bool,
+func (tr *BTreeG[K, V]) ascend(
+	n *node[K, V],
+	pivot K,
+	iter func(key K, value V) bool,
 	height int,
 ) bool {
-	i, found := n.find(pivot)
+	i, found := tr.find(n, pivot)
 	if !found {
 		if height > 0 {
-			if !n.children[i].ascend(pivot, iter, height-1) {
+			if !tr.ascend(n.children[i], pivot, iter, height-1) {
 				return false
 			}
 		}
@@ -333,15 +483,28 @@ func (n *node) ascend(
 	return true
 }
 
+// AscendRange iterates the tree within the range [lo, hi)
+func (tr *BTreeG[K, V]) AscendRange(
+	lo, hi K,
+	iter func(key K, value V) bool,
+) {
+	tr.Ascend(lo, func(key K, value V) bool {
+		if !tr.less(key, hi) {
+			return false
+		}
+		return iter(key, value)
+	})
+}
+
 // Reverse all items in tree
-func (tr *BTree) Reverse(iter func(key string, value interface{}) bool) {
+func (tr *BTreeG[K, V]) Reverse(iter func(key K, value V) bool) {
 	if tr.root != nil {
 		tr.root.reverse(iter, tr.height)
 	}
 }
 
-func (n *node) reverse(
-	iter func(key string, value interface{}) bool, height int,
+func (n *node[K, V]) reverse(
+	iter func(key K, value V) bool, height int,
 ) bool {
 	if height == 0 {
 		for i := n.numItems - 1; i >= 0; i-- {
@@ -366,24 +529,25 @@ func (n *node) reverse(
 }
 
 // Descend the tree within the range [pivot, first]
-func (tr *BTree) Descend(
-	pivot string,
-	iter func(key string, value interface{}) bool,
+func (tr *BTreeG[K, V]) Descend(
+	pivot K,
+	iter func(key K, value V) bool,
 ) {
 	if tr.root != nil {
-		tr.root.descend(pivot, iter, tr.height)
+		tr.descend(tr.root, pivot, iter, tr.height)
 	}
 }
 
-func (n *node) descend(
-	pivot string,
-	iter func(key string, value interface{}) bool,
+func (tr *BTreeG[K, V]) descend(
+	n *node[K, V],
+	pivot K,
+	iter func(key K, value V) bool,
 	height int,
 ) bool {
-	i, found := n.find(pivot)
+	i, found := tr.find(n, pivot)
 	if !found {
 		if height > 0 {
-			if !n.children[i].descend(pivot, iter, height-1) {
+			if !tr.descend(n.children[i], pivot, iter, height-1) {
 				return false
 			}
 		}
@@ -400,4 +564,4 @@ func (n *node) descend(
 		}
 	}
 	return true
-}
\ No newline at end of file
+}
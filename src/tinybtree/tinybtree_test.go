@@ -0,0 +1,194 @@
+package tinybtree
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func keysOf(tr *BTreeG[int, int]) []int {
+	var keys []int
+	tr.Scan(func(key, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+func TestStringAlias(t *testing.T) {
+	var tr BTree
+	for _, key := range []string{"b", "c", "a"} {
+		tr.Set(key, key+"!")
+	}
+	if v, ok := tr.Get("a"); !ok || v != "a!" {
+		t.Fatalf("Get(a) = %v, %v", v, ok)
+	}
+
+	var keys []string
+	tr.Descend("b", func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Fatalf("Descend(b) = %v", keys)
+	}
+}
+
+func TestRandomAgainstMap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tr := NewBTreeG[int, int](func(a, b int) bool { return a < b })
+	m := make(map[int]int)
+
+	for i := 0; i < 20000; i++ {
+		key := rnd.Intn(5000)
+		if rnd.Intn(3) == 0 {
+			_, deleted := tr.Delete(key)
+			_, ok := m[key]
+			if deleted != ok {
+				t.Fatalf("Delete(%d) = %v, want %v", key, deleted, ok)
+			}
+			delete(m, key)
+		} else {
+			prev, replaced := tr.Set(key, i)
+			if old, ok := m[key]; replaced != ok || replaced && prev != old {
+				t.Fatalf("Set(%d) = %d, %v", key, prev, replaced)
+			}
+			m[key] = i
+		}
+	}
+
+	if tr.Len() != len(m) {
+		t.Fatalf("Len() = %d, want %d", tr.Len(), len(m))
+	}
+	var want []int
+	for key := range m {
+		want = append(want, key)
+	}
+	sort.Ints(want)
+	got := keysOf(tr)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("key %d = %d, want %d", i, got[i], want[i])
+		}
+		if v, _ := tr.Get(want[i]); v != m[want[i]] {
+			t.Fatalf("Get(%d) = %d", want[i], v)
+		}
+	}
+}
+
+func TestCopyOnWrite(t *testing.T) {
+	tr := NewOrdered[int, int]()
+	for i := 0; i < 1000; i++ {
+		tr.Load(i, i)
+	}
+
+	snapshot := tr.Copy()
+	for i := 0; i < 1000; i += 2 {
+		tr.Delete(i)
+	}
+	tr.Set(5000, 1)
+	tr.Set(1, -1)
+
+	if snapshot.Len() != 1000 || tr.Len() != 501 {
+		t.Fatalf("Len() = %d, %d", snapshot.Len(), tr.Len())
+	}
+	for i, key := range keysOf(snapshot) {
+		if key != i {
+			t.Fatalf("snapshot key %d = %d", i, key)
+		}
+		if v, _ := snapshot.Get(key); v != i {
+			t.Fatalf("snapshot Get(%d) = %d", key, v)
+		}
+	}
+	if v, _ := tr.Get(1); v != -1 {
+		t.Fatalf("Get(1) = %d", v)
+	}
+
+	// modifying the copy does not change the original
+	snapshot.Set(1, 100)
+	if v, _ := tr.Get(1); v != -1 {
+		t.Fatalf("Get(1) = %d after modifying the copy", v)
+	}
+}
+
+func TestConcurrentCopies(t *testing.T) {
+	tr := NewOrdered[int, int]()
+	for i := 0; i < 1000; i++ {
+		tr.Load(i, i)
+	}
+
+	// Copy only reads the source, copies can be taken and modified in parallel
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			c := tr.Copy()
+			for i := 0; i < 1000; i += 2 {
+				c.Set(i, g)
+			}
+			tr.Get(g)
+		}(g)
+	}
+	wg.Wait()
+
+	// the source takes a new token on its next write, leaving the copies alone
+	snapshot := tr.Copy()
+	tr.Delete(0)
+	if v, ok := snapshot.Get(0); !ok || v != 0 || tr.Len() != 999 {
+		t.Fatalf("snapshot Get(0) = %d, %v", v, ok)
+	}
+	for i, key := range keysOf(tr) {
+		if v, _ := tr.Get(key); key != i+1 || v != key {
+			t.Fatalf("key %d = %d, %d", i, key, v)
+		}
+	}
+}
+
+func TestLessRequired(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("zero value tree with int keys did not panic")
+		}
+	}()
+	var tr BTreeG[int, int]
+	tr.Set(1, 1)
+}
+
+func TestRangeAndMinMax(t *testing.T) {
+	tr := NewOrdered[int, int]()
+	for i := 0; i < 200; i++ {
+		tr.Load(i*2, i)
+	}
+	tr.Load(1, 0) // out of order, falls back to Set
+
+	var keys []int
+	tr.AscendRange(10, 20, func(key, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	if len(keys) != 5 || keys[0] != 10 || keys[4] != 18 {
+		t.Fatalf("AscendRange(10, 20) = %v", keys)
+	}
+
+	if key, _, ok := tr.Min(); !ok || key != 0 {
+		t.Fatalf("Min() = %d", key)
+	}
+	if key, _, ok := tr.Max(); !ok || key != 398 {
+		t.Fatalf("Max() = %d", key)
+	}
+	for _, want := range []int{0, 1, 2} {
+		if key, _, _ := tr.PopMin(); key != want {
+			t.Fatalf("PopMin() = %d, want %d", key, want)
+		}
+	}
+	if key, _, _ := tr.PopMax(); key != 398 || tr.Len() != 197 {
+		t.Fatalf("PopMax() = %d, Len() = %d", key, tr.Len())
+	}
+
+	empty := NewOrdered[int, int]()
+	if _, _, ok := empty.PopMin(); ok {
+		t.Fatal("PopMin() on empty tree")
+	}
+}
@@ -0,0 +1,74 @@
+package uuid
+
+import "fmt"
+
+// Crockford's base32 alphabet, in ASCII order so that the encoded
+// strings sort like the UUIDs.
+const sortableAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var sortableDecode [256]byte
+
+func init() {
+	for i := range sortableDecode {
+		sortableDecode[i] = 0xFF
+	}
+	for i := 0; i < len(sortableAlphabet); i++ {
+		sortableDecode[sortableAlphabet[i]] = byte(i)
+		sortableDecode[sortableAlphabet[i]|0x20] = byte(i) // lower case
+	}
+}
+
+// SortableString returns the UUID as 26 characters of Crockford's base32,
+// like a ULID. The strings sort in the same order as the UUIDs, so time
+// ordered UUIDs (v6, v7) give time ordered strings.
+func (u UUID) SortableString() string {
+	buf := make([]byte, 26)
+
+	// the first character holds the 3 highest bits, then 5 bits each
+	buf[0] = sortableAlphabet[u[0]>>5]
+	acc := uint16(u[0] & 0x1f)
+	bits := uint(5)
+	j := 1
+	for i := 1; i < 16; i++ {
+		acc = acc<<8 | uint16(u[i])
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			buf[j] = sortableAlphabet[acc>>bits&0x1f]
+			j++
+		}
+	}
+	return string(buf)
+}
+
+// FromSortableString returns UUID parsed from the output of SortableString.
+// Lower case letters are accepted.
+func FromSortableString(s string) (u UUID, err error) {
+	if len(s) != 26 {
+		err = fmt.Errorf("uuid: sortable string must be 26 characters long, got %d", len(s))
+		return
+	}
+	if sortableDecode[s[0]] > 7 {
+		err = fmt.Errorf("uuid: invalid sortable string: %s", s)
+		return
+	}
+
+	acc := uint16(sortableDecode[s[0]])
+	bits := uint(3)
+	i := 0
+	for j := 1; j < len(s); j++ {
+		v := sortableDecode[s[j]]
+		if v == 0xFF {
+			err = fmt.Errorf("uuid: invalid sortable string: %s", s)
+			return
+		}
+		acc = acc<<5 | uint16(v)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			u[i] = byte(acc >> bits)
+			i++
+		}
+	}
+	return
+}
@@ -48,6 +48,14 @@ var (
 	// posixPID = uint32(os.Getpid()) //   当前父进程id
 )
 
+// UUID v7 storage.
+var (
+	v7Mutex       sync.Mutex
+	unixMilliFunc = unixMilli
+	v7LastMilli   int64
+	v7Counter     uint16
+)
+
 // String parse helpers.
 var (
 	urnPrefix  = []byte("urn:uuid:")
@@ -97,6 +105,11 @@ func unixTimeFunc() uint64 {
 	return epochStart + uint64(time.Now().UnixNano()/100)
 }
 
+// Returns the number of milliseconds since the Unix epoch.
+func unixMilli() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
 // UUID representation compliant with specification
 // described in RFC 4122.
 type UUID [16]byte // 16个字节
@@ -448,6 +461,126 @@ func NewV5(ns UUID, name string) UUID {
 	return u
 }
 
+// NewV6 returns UUID based on current timestamp and MAC address, like NewV1
+// but with the timestamp bits reordered so that the UUIDs sort by time.
+func NewV6() UUID {
+	timeNow, clockSeq, hardwareAddr := getStorage()
+	return newV6(timeNow, clockSeq, hardwareAddr)
+}
+
+func newV6(timeNow uint64, clockSeq uint16, node []byte) UUID {
+	u := UUID{}
+
+	binary.BigEndian.PutUint32(u[0:], uint32(timeNow>>28))
+	binary.BigEndian.PutUint16(u[4:], uint16(timeNow>>12))
+	binary.BigEndian.PutUint16(u[6:], uint16(timeNow&0xfff))
+	binary.BigEndian.PutUint16(u[8:], clockSeq)
+
+	copy(u[10:], node)
+
+	u.SetVersion(6)
+	u.SetVariant()
+
+	return u
+}
+
+// Returns UUID v7 storage state: the Unix timestamp in milliseconds and
+// a 12 bits counter. The counter starts at a random value below 2048 for
+// every millisecond and is incremented for every UUID of the same
+// millisecond, so the UUIDs of one process are strictly increasing.
+// If the counter overflows or the clock goes backwards, the timestamp of
+// the last UUID is incremented instead.
+func getV7Storage() (int64, uint16) {
+	v7Mutex.Lock()
+	defer v7Mutex.Unlock()
+
+	milli := unixMilliFunc()
+	if milli <= v7LastMilli {
+		v7Counter++
+		if v7Counter <= 0xfff {
+			return v7LastMilli, v7Counter
+		}
+		milli = v7LastMilli + 1
+	}
+
+	buf := make([]byte, 2)
+	safeRandom(buf)
+	v7LastMilli = milli
+	v7Counter = binary.BigEndian.Uint16(buf) & 0x7ff
+
+	return v7LastMilli, v7Counter
+}
+
+// NewV7 returns UUID based on the current Unix timestamp in milliseconds
+// followed by random bits. The UUIDs generated by a process are strictly
+// increasing, even from several goroutines.
+func NewV7() UUID {
+	milli, counter := getV7Storage()
+
+	rand := make([]byte, 8)
+	safeRandom(rand)
+
+	return newV7(milli, counter, rand)
+}
+
+func newV7(milli int64, counter uint16, rand []byte) UUID {
+	u := UUID{}
+
+	binary.BigEndian.PutUint16(u[0:], uint16(milli>>32))
+	binary.BigEndian.PutUint32(u[2:], uint32(milli))
+	binary.BigEndian.PutUint16(u[6:], counter&0xfff)
+	copy(u[8:], rand)
+
+	u.SetVersion(7)
+	u.SetVariant()
+
+	return u
+}
+
+// NewV8 returns UUID with a custom layout: the 122 bits of data left
+// by the version and variant bits are used as they are.
+func NewV8(data [16]byte) UUID {
+	u := UUID(data)
+	u.SetVersion(8)
+	u.SetVariant()
+
+	return u
+}
+
+// NewV8FromHash returns UUID based on hashing of namespace UUID and name
+// with another hash than MD5 (v3) or SHA-1 (v5), such as SHA-256.
+func NewV8FromHash(h hash.Hash, ns UUID, name string) UUID {
+	u := newFromHash(h, ns, name)
+	u.SetVersion(8)
+	u.SetVariant()
+
+	return u
+}
+
+// Time returns the time a time-based UUID (v1, v6 and v7) was generated at.
+// v1 and v6 UUIDs have a 100-nanosecond precision, v7 UUIDs a millisecond one.
+func (u UUID) Time() (time.Time, error) {
+	var ts uint64
+	switch u.Version() {
+	case 1:
+		ts = uint64(binary.BigEndian.Uint16(u[6:])&0xfff)<<48 |
+			uint64(binary.BigEndian.Uint16(u[4:]))<<32 |
+			uint64(binary.BigEndian.Uint32(u[0:]))
+	case 6:
+		ts = uint64(binary.BigEndian.Uint32(u[0:]))<<28 |
+			uint64(binary.BigEndian.Uint16(u[4:]))<<12 |
+			uint64(binary.BigEndian.Uint16(u[6:])&0xfff)
+	case 7:
+		milli := int64(binary.BigEndian.Uint16(u[0:]))<<32 | int64(binary.BigEndian.Uint32(u[2:]))
+		return time.Unix(milli/1000, milli%1000*int64(time.Millisecond)), nil
+	default:
+		return time.Time{}, fmt.Errorf("uuid: version %d UUID has no time", u.Version())
+	}
+
+	ts -= epochStart
+	return time.Unix(int64(ts/1e7), int64(ts%1e7)*100), nil
+}
+
 // Returns UUID based on hashing of namespace UUID and name.
 func newFromHash(h hash.Hash, ns UUID, name string) UUID {
 	u := UUID{}
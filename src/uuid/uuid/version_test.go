@@ -0,0 +1,158 @@
+package uuid
+
+import (
+	"crypto/sha256"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Test vectors of RFC 9562, appendix A and B.
+func TestNewV6Vector(t *testing.T) {
+	// 2022-02-22 19:22:22 UTC, in 100-nanosecond intervals since 1582-10-15
+	var ts uint64 = 0x1EC9414C232AB00
+	node := []byte{0x9f, 0x6b, 0xde, 0xce, 0xd8, 0x46}
+	u := newV6(ts, 0x33c8, node)
+
+	if u.String() != "1ec9414c-232a-6b00-b3c8-9f6bdeced846" {
+		t.Errorf("Incorrect UUIDv6: %s", u)
+	}
+	if u.Version() != 6 || u.Variant() != VariantRFC4122 {
+		t.Errorf("UUIDv6 generated with incorrect version %d or variant %d", u.Version(), u.Variant())
+	}
+
+	want := time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+	if tm, err := u.Time(); err != nil || !tm.Equal(want) {
+		t.Errorf("Incorrect UUIDv6 time %s, %v", tm, err)
+	}
+
+	v1 := FromStringOrNil("c232ab00-9414-11ec-b3c8-9f6bdeced846")
+	if tm, err := v1.Time(); err != nil || !tm.Equal(want) {
+		t.Errorf("Incorrect UUIDv1 time %s, %v", tm, err)
+	}
+}
+
+func TestNewV7Vector(t *testing.T) {
+	u := newV7(0x017F22E279B0, 0xcc3, []byte{0x18, 0xc4, 0xdc, 0x0c, 0x0c, 0x07, 0x39, 0x8f})
+
+	if u.String() != "017f22e2-79b0-7cc3-98c4-dc0c0c07398f" {
+		t.Errorf("Incorrect UUIDv7: %s", u)
+	}
+	want := time.Date(2022, 2, 22, 19, 22, 22, 0, time.UTC)
+	if tm, err := u.Time(); err != nil || !tm.Equal(want) {
+		t.Errorf("Incorrect UUIDv7 time %s, %v", tm, err)
+	}
+}
+
+func TestNewV8Vector(t *testing.T) {
+	u := NewV8([16]byte{0x24, 0x89, 0xe9, 0xad, 0x2e, 0xe2, 0x0e, 0x00, 0x0e, 0xc9, 0x32, 0xd5, 0xf6, 0x91, 0x81, 0xc0})
+	if u.String() != "2489e9ad-2ee2-8e00-8ec9-32d5f69181c0" {
+		t.Errorf("Incorrect UUIDv8: %s", u)
+	}
+
+	u = NewV8FromHash(sha256.New(), NamespaceDNS, "www.example.com")
+	if u.String() != "5c146b14-3c52-8afd-938a-375d0df1fbf6" {
+		t.Errorf("Incorrect UUIDv8 from SHA-256: %s", u)
+	}
+	if _, err := u.Time(); err == nil {
+		t.Errorf("UUIDv8 should have no time")
+	}
+}
+
+func TestNewV6(t *testing.T) {
+	u1 := NewV6()
+	u2 := NewV6()
+	if u1.Version() != 6 || Equal(u1, u2) {
+		t.Errorf("Incorrect UUIDv6 %s, %s", u1, u2)
+	}
+	if tm, _ := u1.Time(); time.Since(tm) > time.Minute {
+		t.Errorf("Incorrect UUIDv6 time %s", tm)
+	}
+}
+
+func TestNewV7Monotonic(t *testing.T) {
+	oldFunc := unixMilliFunc
+	defer func() { unixMilliFunc = oldFunc }()
+
+	// a stopped clock overflows the counter, then goes backwards
+	milli := int64(1645557742000)
+	unixMilliFunc = func() int64 { return milli }
+
+	var mu sync.Mutex
+	var all []UUID
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				u := NewV7()
+				mu.Lock()
+				all = append(all, u)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	milli -= 10
+	last := NewV7()
+
+	seen := map[UUID]bool{}
+	for _, u := range all {
+		if seen[u] {
+			t.Fatalf("Duplicate UUIDv7 %s", u)
+		}
+		seen[u] = true
+		if u.String() >= last.String() {
+			t.Fatalf("UUIDv7 %s generated after %s", last, u)
+		}
+	}
+
+	// the UUIDs of one goroutine are increasing
+	var prev UUID
+	for i := 0; i < 100; i++ {
+		u := NewV7()
+		if u.String() <= prev.String() {
+			t.Fatalf("UUIDv7 %s not after %s", u, prev)
+		}
+		prev = u
+	}
+}
+
+func TestSortableString(t *testing.T) {
+	u := FromStringOrNil("017f22e2-79b0-7cc3-98c4-dc0c0c07398f")
+	s := u.SortableString()
+	if s != "01FWHE4YDGFK1SHH6W1G60EECF" {
+		t.Errorf("Incorrect sortable string %s", s)
+	}
+	for _, in := range []string{s, "01fwhe4ydgfk1shh6w1g60eecf"} {
+		if u2, err := FromSortableString(in); err != nil || !Equal(u, u2) {
+			t.Errorf("FromSortableString(%s) = %s, %v", in, u2, err)
+		}
+	}
+	if s := (UUID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}).SortableString(); s != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("Incorrect sortable string %s", s)
+	}
+
+	for _, in := range []string{"", "01FWHE4YDGFK1SHH6W1G60EEC", "81FWHE4YDGFK1SHH6W1G60EECF", "01FWHE4YDGFK1SHH6W1G60EECU"} {
+		if _, err := FromSortableString(in); err == nil {
+			t.Errorf("FromSortableString(%q) should fail", in)
+		}
+	}
+
+	uuids := make([]UUID, 100)
+	strs := make([]string, 100)
+	for i := range uuids {
+		uuids[i] = NewV4()
+		strs[i] = uuids[i].SortableString()
+	}
+	sort.Slice(uuids, func(i, j int) bool { return uuids[i].String() < uuids[j].String() })
+	sort.Strings(strs)
+	for i := range uuids {
+		if uuids[i].SortableString() != strs[i] {
+			t.Fatalf("Sortable strings not in UUID order at %d", i)
+		}
+	}
+}
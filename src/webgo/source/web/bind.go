@@ -0,0 +1,341 @@
+package web
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultMaxMemory = 32 << 20 // 32 MB
+
+// MaxBodySize is the largest request body read by Bind, bigger bodies
+// fail with status 413.
+var MaxBodySize int64 = 32 << 20
+
+// Validator checks the `validate` struct tags of the values decoded by Bind.
+// Register custom validations on it before serving.
+var Validator = newValidator()
+
+var errBindTarget = errors.New("Bind needs a pointer to a struct")
+
+// FieldError is the failed validation of a field.
+type FieldError struct {
+	Field   string `json:"field" xml:"name,attr"`
+	Tag     string `json:"tag" xml:"tag,attr"`
+	Param   string `json:"param,omitempty" xml:"param,attr,omitempty"`
+	Message string `json:"message" xml:",chardata"`
+}
+
+// BindError is returned by Bind when the request can't be decoded or the
+// decoded value is not valid. It is rendered as the body of the response.
+type BindError struct {
+	XMLName xml.Name     `json:"-" xml:"error"`
+	Status  int          `json:"-" xml:"-"`
+	Message string       `json:"message" xml:"message"`
+	Fields  []FieldError `json:"fields,omitempty" xml:"field"`
+}
+
+func (e *BindError) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return e.Message + ": " + strings.Join(msgs, "; ")
+}
+
+// Bind decodes the request into dst according to its Content-Type: JSON,
+// XML, urlencoded or multipart form. Requests without a body are decoded
+// from the query string. Form fields are matched with the `form` tag or the
+// field name, path params with the `param` tag. dst is then validated with
+// its `validate` tags.
+// The body is limited to MaxBodySize.
+// The error is a *BindError with status 400, 413 for a body over MaxBodySize,
+// or 415 for an unknown Content-Type.
+func (ctx *Context) Bind(dst interface{}) error {
+	if err := ctx.decode(dst); err != nil {
+		if _, ok := err.(*BindError); ok {
+			return err
+		}
+		if strings.Contains(err.Error(), "request body too large") {
+			return &BindError{Status: 413, Message: "Request body too large"}
+		}
+		return &BindError{Status: 400, Message: err.Error()}
+	}
+	if v := reflect.ValueOf(dst); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		if len(ctx.pathParams) > 0 {
+			params := map[string][]string{}
+			for _, p := range ctx.pathParams {
+				params[p.Key] = append(params[p.Key], p.Value)
+			}
+			if err := bindValues(v.Elem(), params, nil, "param"); err != nil {
+				return &BindError{Status: 400, Message: err.Error()}
+			}
+		}
+		if err := Validator.Struct(dst); err != nil {
+			return validationError(err)
+		}
+	}
+	return nil
+}
+
+// MustBind calls Bind and renders the error, it returns false when the
+// handler must stop.
+func (ctx *Context) MustBind(dst interface{}) bool {
+	err := ctx.Bind(dst)
+	if err == nil {
+		return true
+	}
+	be := err.(*BindError)
+	if rerr := ctx.Render(be.Status, be); rerr != nil {
+		ctx.Abort(be.Status, be.Error())
+	}
+	return false
+}
+
+func (ctx *Context) decode(dst interface{}) error {
+	req := ctx.Request
+	if req.Body == nil || req.ContentLength == 0 && req.Header.Get("Content-Type") == "" {
+		return bindStruct(dst, req.URL.Query(), nil)
+	}
+	req.Body = http.MaxBytesReader(ctx.ResponseWriter, req.Body, MaxBodySize)
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return &BindError{Status: 415, Message: "Invalid Content-Type: " + err.Error()}
+	}
+	switch mediaType {
+	case "application/json":
+		err := json.NewDecoder(req.Body).Decode(dst)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	case "application/xml", "text/xml":
+		err := xml.NewDecoder(req.Body).Decode(dst)
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	case "application/x-www-form-urlencoded":
+		if err := req.ParseForm(); err != nil {
+			return err
+		}
+		return bindStruct(dst, req.Form, nil)
+	case "multipart/form-data":
+		if err := req.ParseMultipartForm(defaultMaxMemory); err != nil {
+			return err
+		}
+		return bindStruct(dst, req.MultipartForm.Value, req.MultipartForm.File)
+	}
+	return &BindError{Status: 415, Message: "Unsupported Content-Type " + mediaType}
+}
+
+func bindStruct(dst interface{}, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errBindTarget
+	}
+	return bindValues(v.Elem(), values, files, "form")
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// bindValues sets the fields of the struct v named by tag from values and files
+func bindValues(v reflect.Value, values map[string][]string, files map[string][]*multipart.FileHeader, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+		name := sf.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			if err := bindValues(fv, values, files, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			if tag == "param" {
+				continue
+			}
+			name = sf.Name
+		}
+		name = strings.Split(name, ",")[0]
+
+		if fhs, ok := files[name]; ok {
+			switch {
+			case sf.Type == fileHeaderType:
+				fv.Set(reflect.ValueOf(fhs[0]))
+			case sf.Type == reflect.SliceOf(fileHeaderType):
+				fv.Set(reflect.ValueOf(fhs))
+			}
+			continue
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if fv.Kind() == reflect.Slice && !reflect.PtrTo(fv.Type()).Implements(unmarshalerType) {
+			s := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+			for j, val := range vals {
+				if err := setValue(s.Index(j), val); err != nil {
+					return fmt.Errorf("Invalid value %q for field %s: %v", val, name, err)
+				}
+			}
+			fv.Set(s)
+			continue
+		}
+		if err := setValue(fv, vals[0]); err != nil {
+			return fmt.Errorf("Invalid value %q for field %s: %v", vals[0], name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == timeType {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(tm))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "on" { // checkbox
+			s = "true"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// report the fields with the name the client sent
+	v.RegisterTagNameFunc(func(sf reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "xml"} {
+			name := strings.Split(sf.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return sf.Name
+	})
+	return v
+}
+
+func validationError(err error) error {
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return &BindError{Status: 400, Message: err.Error()}
+	}
+	be := &BindError{Status: 400, Message: "Validation failed"}
+	for _, fe := range errs {
+		be.Fields = append(be.Fields, FieldError{
+			Field:   fe.Namespace()[strings.Index(fe.Namespace(), ".")+1:],
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return be
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "min", "gte":
+		return field + " must be at least " + fe.Param()
+	case "max", "lte":
+		return field + " must be at most " + fe.Param()
+	case "gt":
+		return field + " must be greater than " + fe.Param()
+	case "lt":
+		return field + " must be less than " + fe.Param()
+	case "len":
+		return field + " must have a length of " + fe.Param()
+	case "oneof":
+		return field + " must be one of " + fe.Param()
+	}
+	if fe.Param() != "" {
+		return field + " failed on the " + fe.Tag() + "=" + fe.Param() + " rule"
+	}
+	return field + " failed on the " + fe.Tag() + " rule"
+}
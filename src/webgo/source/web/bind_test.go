@@ -0,0 +1,174 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type signup struct {
+	ID    int      `param:"id"`
+	Name  string   `json:"name" form:"name" validate:"required"`
+	Email string   `json:"email" form:"email" validate:"required,email"`
+	Age   uint8    `json:"age" form:"age" validate:"gte=18,lte=130"`
+	Tags  []string `json:"tags" form:"tag"`
+	Admin bool     `json:"-" form:"admin"`
+}
+
+func bindRequest(route, method, path, ctype string, body io.Reader) (signup, *httptest.ResponseRecorder) {
+	var got signup
+	s := newTestServer()
+	s.Match(method, route, func(ctx *Context) {
+		if ctx.MustBind(&got) {
+			ctx.Render(201, &got)
+		}
+	})
+	req := httptest.NewRequest(method, path, body)
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return got, w
+}
+
+func TestBindJSON(t *testing.T) {
+	got, w := bindRequest("/users/:id", "POST", "/users/7", "application/json; charset=utf-8",
+		strings.NewReader(`{"name":"bob","email":"bob@example.com","age":30,"tags":["a","b"]}`))
+	if w.Code != 201 {
+		t.Fatalf("code = %d, body %s", w.Code, w.Body.String())
+	}
+	if got.ID != 7 || got.Name != "bob" || got.Age != 30 || len(got.Tags) != 2 {
+		t.Errorf("bound %+v", got)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	got, w := bindRequest("/signup", "POST", "/signup", "application/x-www-form-urlencoded",
+		strings.NewReader("name=ann&email=ann@example.com&age=40&tag=x&tag=y&admin=on"))
+	if w.Code != 201 || got.Name != "ann" || got.Age != 40 || !got.Admin || strings.Join(got.Tags, ",") != "x,y" {
+		t.Errorf("code %d bound %+v", w.Code, got)
+	}
+
+	got, w = bindRequest("/signup", "GET", "/signup?name=joe&email=joe@example.com&age=20", "", nil)
+	if w.Code != 201 || got.Name != "joe" || got.Age != 20 {
+		t.Errorf("query: code %d bound %+v", w.Code, got)
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	type upload struct {
+		Title string                `form:"title" validate:"required"`
+		File  *multipart.FileHeader `form:"file" validate:"required"`
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "report")
+	fw, _ := mw.CreateFormFile("file", "report.txt")
+	fw.Write([]byte("content"))
+	mw.Close()
+
+	var got upload
+	s := newTestServer()
+	s.Post("/upload", func(ctx *Context) string {
+		if err := ctx.Bind(&got); err != nil {
+			return err.Error()
+		}
+		return "ok"
+	})
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Body.String() != "ok" || got.Title != "report" || got.File == nil || got.File.Filename != "report.txt" {
+		t.Errorf("body %q bound %+v", w.Body.String(), got)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	_, w := bindRequest("/signup", "POST", "/signup", "application/json",
+		strings.NewReader(`{"name":"","email":"nope","age":12}`))
+	if w.Code != 400 {
+		t.Fatalf("code = %d", w.Code)
+	}
+	var be BindError
+	if err := json.Unmarshal(w.Body.Bytes(), &be); err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]string{}
+	for _, f := range be.Fields {
+		fields[f.Field] = f.Tag
+	}
+	if len(fields) != 3 || fields["name"] != "required" || fields["email"] != "email" || fields["age"] != "gte" {
+		t.Errorf("fields = %+v", be.Fields)
+	}
+
+	_, w = bindRequest("/signup", "POST", "/signup", "application/json", strings.NewReader(`{"name":`))
+	if w.Code != 400 {
+		t.Errorf("syntax error code = %d", w.Code)
+	}
+	_, w = bindRequest("/signup", "POST", "/signup", "text/csv", strings.NewReader("a,b"))
+	if w.Code != 415 {
+		t.Errorf("unsupported type code = %d", w.Code)
+	}
+	_, w = bindRequest("/signup", "POST", "/signup", "application/x-www-form-urlencoded", strings.NewReader("age=old"))
+	if w.Code != 400 || !strings.Contains(w.Body.String(), "Invalid value") {
+		t.Errorf("invalid value = %d %s", w.Code, w.Body.String())
+	}
+
+	defer func(n int64) { MaxBodySize = n }(MaxBodySize)
+	MaxBodySize = 64
+	_, w = bindRequest("/signup", "POST", "/signup", "application/json",
+		strings.NewReader(`{"name":"`+strings.Repeat("a", 100)+`","email":"a@example.com","age":20}`))
+	if w.Code != 413 {
+		t.Errorf("large body code = %d", w.Code)
+	}
+}
+
+func TestRenderNegotiation(t *testing.T) {
+	s := newTestServer()
+	s.Templates = template.Must(template.New("user").Parse(`<p>{{.Name}}</p>`))
+	type user struct{ Name string }
+	s.Get("/user", func(ctx *Context) {
+		ctx.Render(200, View{Name: "user", Data: user{"<bob>"}})
+	})
+	s.Get("/data", func(ctx *Context) {
+		ctx.Render(200, map[string]int{"n": 1})
+	})
+
+	tests := []struct {
+		path, accept string
+		code         int
+		ctype, body  string
+	}{
+		{"/user", "", 200, "application/json", `{"Name":"\u003cbob\u003e"}` + "\n"},
+		{"/user", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", 200, "text/html", "<p>&lt;bob&gt;</p>"},
+		{"/user", "application/xml", 200, "application/xml", `<?xml version="1.0" encoding="UTF-8"?>` + "\n<user><Name>&lt;bob&gt;</Name></user>"},
+		{"/user", "text/*;q=0.5, application/json;q=0.1", 200, "text/xml", ""},
+		{"/data", "text/html", 406, "text/html", "Not Acceptable"},
+		{"/data", "*/*", 200, "application/json", `{"n":1}` + "\n"},
+		{"/data", "application/xml", 500, "text/html", "Server Error"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != tt.code || !strings.HasPrefix(w.Header().Get("Content-Type"), tt.ctype) {
+			t.Errorf("%s %q: %d %q", tt.path, tt.accept, w.Code, w.Header().Get("Content-Type"))
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %q: body %q, want %q", tt.path, tt.accept, w.Body.String(), tt.body)
+		}
+	}
+}
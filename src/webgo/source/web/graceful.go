@@ -0,0 +1,161 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// restartEnv tells the child started by Restart that its listener is fd 3
+const restartEnv = "WEBGO_INHERIT_LISTENER"
+
+var ErrRestartUnsupported = errors.New("The listener of the server can't be passed to a new process")
+
+// restartCommand returns the command starting the new process of Restart
+var restartCommand = func() *exec.Cmd {
+	path, err := os.Executable()
+	if err != nil {
+		path = os.Args[0]
+	}
+	return exec.Command(path, os.Args[1:]...)
+}
+
+// listen opens the listener of addr, or takes the one passed by the parent
+// process when it was started by Restart.
+func (s *Server) listen(addr string) (net.Listener, error) {
+	if os.Getenv(restartEnv) == "" {
+		return net.Listen("tcp", addr)
+	}
+	os.Unsetenv(restartEnv)
+	f := os.NewFile(3, "listener")
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// serve serves srv on served, a listener over the socket l, until Shutdown
+// returns or the listener is closed.
+func (s *Server) serve(srv *http.Server, l, served net.Listener) error {
+	done := make(chan struct{})
+	s.mu.Lock()
+	s.l, s.srv, s.done = l, srv, done
+	s.mu.Unlock()
+
+	if s.Config.HandleSignals {
+		stop := s.handleSignals()
+		defer stop()
+	}
+
+	err := srv.Serve(served)
+	if err == http.ErrServerClosed {
+		// wait for the in-flight requests
+		<-done
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for the in-flight requests
+// to complete or for ctx to be done. The Run methods return afterwards.
+// FCGI and SCGI servers only close their listener.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv, l, done := s.srv, s.l, s.done
+	s.mu.Unlock()
+
+	if srv == nil {
+		if l != nil {
+			return l.Close()
+		}
+		return nil
+	}
+	err := srv.Shutdown(ctx)
+	s.mu.Lock()
+	if done != nil && s.done == done {
+		close(done)
+		s.done = nil
+	}
+	s.mu.Unlock()
+	return err
+}
+
+// Restart starts a new process of the program which inherits the listening
+// socket, then shuts s down gracefully: the requests in flight complete
+// while the new process accepts the next connections.
+func (s *Server) Restart(ctx context.Context) error {
+	s.mu.Lock()
+	l := s.l
+	s.mu.Unlock()
+
+	fl, ok := l.(interface{ File() (*os.File, error) })
+	if !ok {
+		return ErrRestartUnsupported
+	}
+	f, err := fl.File()
+	if err != nil {
+		return err
+	}
+
+	cmd := restartCommand()
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, restartEnv+"=1")
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	cmd.ExtraFiles = []*os.File{f}
+	err = cmd.Start()
+	f.Close()
+	if err != nil {
+		return err
+	}
+	s.Logger.Printf("web.go restarted as process %d\n", cmd.Process.Pid)
+	go cmd.Process.Release()
+
+	return s.Shutdown(ctx)
+}
+
+// handleSignals restarts s on SIGHUP and shuts it down on SIGINT and
+// SIGTERM, within Config.ShutdownTimeout. It returns a function stopping it.
+func (s *Server) handleSignals() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	quit := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-sigs:
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if s.Config.ShutdownTimeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, s.Config.ShutdownTimeout)
+			}
+			defer cancel()
+
+			var err error
+			if sig == syscall.SIGHUP {
+				s.Logger.Println("Received SIGHUP, restarting")
+				err = s.Restart(ctx)
+			} else {
+				s.Logger.Printf("Received %v, shutting down\n", sig)
+				err = s.Shutdown(ctx)
+			}
+			if err != nil {
+				s.Logger.Println("Graceful stop error:", err)
+			}
+		case <-quit:
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigs)
+		close(quit)
+	}
+}
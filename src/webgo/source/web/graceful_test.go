@@ -0,0 +1,170 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+func fetch(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+var noKeepAlive = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+// serveSlow serves s on a local listener with a /slow route taking d,
+// started is closed once the request reached the handler
+func serveSlow(t *testing.T, s *Server, d time.Duration) (url string, started chan struct{}, served chan error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started = make(chan struct{})
+	s.Get("/slow", func() string {
+		close(started)
+		time.Sleep(d)
+		return "parent slow"
+	})
+	served = make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+	return "http://" + l.Addr().String(), started, served
+}
+
+func TestShutdownDrains(t *testing.T) {
+	s := newTestServer()
+	url, started, served := serveSlow(t, s, 200*time.Millisecond)
+
+	slow := make(chan string, 1)
+	go func() { slow <- fetch(t, noKeepAlive, url+"/slow") }()
+	<-started
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if body := <-slow; body != "parent slow" {
+		t.Errorf("in-flight request got %q", body)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+	if _, err := noKeepAlive.Get(url + "/"); err == nil {
+		t.Error("server still accepts connections after Shutdown")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s := newTestServer()
+	url, started, served := serveSlow(t, s, time.Second)
+	go fetch(t, noKeepAlive, url+"/slow")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown = %v, want deadline exceeded", err)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+}
+
+func TestH2C(t *testing.T) {
+	s := newTestServer()
+	s.Config.H2C = true
+	s.Get("/proto", func(ctx *Context) string { return ctx.Request.Proto })
+	url, _, _ := serveSlow(t, s, 0)
+	defer s.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	if proto := fetch(t, client, url+"/proto"); proto != "HTTP/2.0" {
+		t.Errorf("h2c proto = %q", proto)
+	}
+	if proto := fetch(t, noKeepAlive, url+"/proto"); proto != "HTTP/1.1" {
+		t.Errorf("http/1.1 proto = %q", proto)
+	}
+}
+
+// TestRestartChild is the process started by TestRestart
+func TestRestartChild(t *testing.T) {
+	if os.Getenv("WEBGO_TEST_CHILD") == "" {
+		t.Skip("helper process of TestRestart")
+	}
+	s := newTestServer()
+	s.Get("/", func() string { return "child" })
+	s.Get("/quit", func() { go s.Shutdown(context.Background()) })
+	time.AfterFunc(10*time.Second, s.Close)
+	s.Run("")
+}
+
+func TestRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("listeners can't be inherited on windows")
+	}
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	defer func(cmd func() *exec.Cmd) { restartCommand = cmd }(restartCommand)
+	restartCommand = func() *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRestartChild$")
+		cmd.Env = append(os.Environ(), "WEBGO_TEST_CHILD=1")
+		cmd.Stdout = devNull
+		return cmd
+	}
+
+	s := newTestServer()
+	s.Get("/", func() string { return "parent" })
+	url, started, served := serveSlow(t, s, 300*time.Millisecond)
+	if body := fetch(t, noKeepAlive, url+"/"); body != "parent" {
+		t.Fatalf("before restart got %q", body)
+	}
+
+	slow := make(chan string, 1)
+	go func() { slow <- fetch(t, noKeepAlive, url+"/slow") }()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Restart(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case body := <-slow:
+		if body != "parent slow" {
+			t.Errorf("in-flight request got %q", body)
+		}
+	case <-time.After(time.Second):
+		t.Error("in-flight request did not finish across the restart")
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Serve = %v", err)
+	}
+
+	// the connections queued meanwhile are accepted by the child
+	if body := fetch(t, noKeepAlive, url+"/"); body != "child" {
+		t.Errorf("after restart got %q", body)
+	}
+	fetch(t, noKeepAlive, url+"/quit")
+}
@@ -0,0 +1,138 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrNotAcceptable = errors.New("No acceptable representation for the request")
+
+// View is a value rendered with the template Name when the client prefers
+// HTML, its Data is encoded as JSON or XML otherwise.
+type View struct {
+	Name string
+	Data interface{}
+}
+
+// Render writes v with the status code in the format preferred by the
+// Accept header of the request: JSON, XML or, when v is a View and the
+// server has Templates, HTML. JSON is used when the request accepts anything.
+// If no format is acceptable, a 406 response is written and
+// ErrNotAcceptable returned, a 500 one if v can't be encoded.
+func (ctx *Context) Render(status int, v interface{}) error {
+	offers := []string{"application/json", "application/xml", "text/xml"}
+	view, isView := v.(View)
+	if pv, ok := v.(*View); ok {
+		view, isView = *pv, true
+	}
+	if isView {
+		v = view.Data
+		if ctx.Server.Templates != nil && ctx.Server.Templates.Lookup(view.Name) != nil {
+			offers = append(offers, "text/html")
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	ctype := ctx.Negotiate(offers...)
+	switch ctype {
+	case "application/json":
+		err = json.NewEncoder(&buf).Encode(v)
+	case "application/xml", "text/xml":
+		buf.WriteString(xml.Header)
+		err = xml.NewEncoder(&buf).Encode(v)
+	case "text/html":
+		err = ctx.Server.Templates.ExecuteTemplate(&buf, view.Name, v)
+	default:
+		ctx.Abort(406, "Not Acceptable")
+		return ErrNotAcceptable
+	}
+	if err != nil {
+		ctx.Abort(500, "Server Error")
+		return err
+	}
+
+	ctx.SetHeader("Content-Type", ctype+"; charset=utf-8", true)
+	ctx.SetHeader("Content-Length", strconv.Itoa(buf.Len()), true)
+	ctx.ResponseWriter.WriteHeader(status)
+	_, err = ctx.ResponseWriter.Write(buf.Bytes())
+	return err
+}
+
+// Negotiate returns the offer the Accept header of the request prefers, the
+// first one on a tie or without Accept header, an empty string if none is
+// acceptable.
+func (ctx *Context) Negotiate(offers ...string) string {
+	header := ctx.Request.Header.Get("Accept")
+	if header == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	accepts := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accepts, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type acceptRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var accepts []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		slash := strings.IndexByte(mediaType, '/')
+		if slash < 0 {
+			continue
+		}
+		a := acceptRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					a.q = q
+				}
+			}
+		}
+		accepts = append(accepts, a)
+	}
+	return accepts
+}
+
+// acceptQuality returns the q of the most specific range matching offer
+func acceptQuality(accepts []acceptRange, offer string) float64 {
+	typ, subtype := offer, ""
+	if i := strings.IndexByte(offer, '/'); i >= 0 {
+		typ, subtype = offer[:i], offer[i+1:]
+	}
+	q, specificity := 0.0, -1
+	for _, a := range accepts {
+		s := -1
+		switch {
+		case a.typ == typ && a.subtype == subtype:
+			s = 2
+		case a.typ == typ && a.subtype == "*":
+			s = 1
+		case a.typ == "*" && a.subtype == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = a.q, s
+		}
+	}
+	return q
+}
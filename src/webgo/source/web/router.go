@@ -0,0 +1,149 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var ErrNoParam = errors.New("No path parameter with this name")
+
+// Middleware wraps the handler of the matched route. The middlewares of the
+// server run first, then the ones of the groups from the outermost.
+type Middleware func(http.Handler) http.Handler
+
+// PathParam is a segment of the path captured by a :param or *wildcard
+// route, or by a named group (?P<name>...) of a regex route.
+type PathParam struct {
+	Key   string
+	Value string
+}
+
+// RouteGroup registers routes under a common prefix and middleware chain.
+// The prefix may contain :params too, for example /users/:id.
+type RouteGroup struct {
+	server     *Server
+	parent     *RouteGroup
+	prefix     string
+	middleware []Middleware
+}
+
+// Use appends middlewares to the chain of every route of server s.
+func (s *Server) Use(middleware ...Middleware) {
+	s.middleware = append(s.middleware, middleware...)
+}
+
+// Group returns a group of routes starting with prefix.
+func (s *Server) Group(prefix string) *RouteGroup {
+	return &RouteGroup{server: s, prefix: prefix}
+}
+
+// Use appends middlewares to the chain of the routes of group g.
+func (g *RouteGroup) Use(middleware ...Middleware) {
+	g.middleware = append(g.middleware, middleware...)
+}
+
+// Group returns a nested group, its prefix and middlewares follow the ones of g.
+func (g *RouteGroup) Group(prefix string) *RouteGroup {
+	return &RouteGroup{server: g.server, parent: g, prefix: g.prefix + prefix}
+}
+
+// Get adds a handler for the 'GET' http method for group g.
+func (g *RouteGroup) Get(route string, handler interface{}) {
+	g.server.addGroupRoute(g, route, "GET", handler)
+}
+
+// Post adds a handler for the 'POST' http method for group g.
+func (g *RouteGroup) Post(route string, handler interface{}) {
+	g.server.addGroupRoute(g, route, "POST", handler)
+}
+
+// Put adds a handler for the 'PUT' http method for group g.
+func (g *RouteGroup) Put(route string, handler interface{}) {
+	g.server.addGroupRoute(g, route, "PUT", handler)
+}
+
+// Delete adds a handler for the 'DELETE' http method for group g.
+func (g *RouteGroup) Delete(route string, handler interface{}) {
+	g.server.addGroupRoute(g, route, "DELETE", handler)
+}
+
+// Match adds a handler for an arbitrary http method for group g.
+func (g *RouteGroup) Match(method string, route string, handler interface{}) {
+	g.server.addGroupRoute(g, route, method, handler)
+}
+
+// Handle adds a custom http.Handler for group g.
+func (g *RouteGroup) Handle(route string, method string, httpHandler http.Handler) {
+	g.server.addGroupRoute(g, route, method, httpHandler)
+}
+
+// chain wraps h with the middlewares of the server and of the groups of the route
+func (s *Server) chain(g *RouteGroup, h http.Handler) http.Handler {
+	for ; g != nil; g = g.parent {
+		for i := len(g.middleware) - 1; i >= 0; i-- {
+			h = g.middleware[i](h)
+		}
+	}
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	return h
+}
+
+// Param returns the value of the path parameter name, an empty string if
+// the route has no such parameter.
+func (ctx *Context) Param(name string) string {
+	v, _ := ctx.lookupParam(name)
+	return v
+}
+
+// ParamInt returns the path parameter name converted to an int.
+func (ctx *Context) ParamInt(name string) (int, error) {
+	v, err := ctx.lookupParam(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(v)
+}
+
+// ParamInt64 returns the path parameter name converted to an int64.
+func (ctx *Context) ParamInt64(name string) (int64, error) {
+	v, err := ctx.lookupParam(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+// ParamFloat64 returns the path parameter name converted to a float64.
+func (ctx *Context) ParamFloat64(name string) (float64, error) {
+	v, err := ctx.lookupParam(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// ParamBool returns the path parameter name converted to a bool.
+func (ctx *Context) ParamBool(name string) (bool, error) {
+	v, err := ctx.lookupParam(name)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(v)
+}
+
+// PathParams returns the path parameters of the matched route in order.
+func (ctx *Context) PathParams() []PathParam {
+	return ctx.pathParams
+}
+
+func (ctx *Context) lookupParam(name string) (string, error) {
+	for _, p := range ctx.pathParams {
+		if p.Key == name {
+			return p.Value, nil
+		}
+	}
+	return "", ErrNoParam
+}
@@ -0,0 +1,182 @@
+package web
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func newTestServer() *Server {
+	s := NewServer()
+	s.Config = &ServerConfig{RecoverPanic: true}
+	s.SetLogger(log.New(io.Discard, "", 0))
+	return s
+}
+
+func get(s *Server, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestTreeRoutes(t *testing.T) {
+	s := newTestServer()
+	s.Get("/", func() string { return "index" })
+	s.Get("/users/:id", func(ctx *Context) string {
+		id, err := ctx.ParamInt("id")
+		if err != nil {
+			ctx.Abort(400, err.Error())
+			return ""
+		}
+		return "user " + strconv.Itoa(id*2)
+	})
+	s.Get("/users/:id/posts/:post", func(ctx *Context, id, post string) string {
+		return id + "-" + post + "-" + ctx.Param("post")
+	})
+	s.Get("/files/*path", func(ctx *Context) string { return ctx.Param("path") })
+	s.Get("/list/", func() string { return "list" })
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/", 200, "index"},
+		{"GET", "/users/21", 200, "user 42"},
+		{"HEAD", "/users/21", 200, "user 42"},
+		{"GET", "/users/abc", 400, `strconv.Atoi: parsing "abc": invalid syntax`},
+		{"GET", "/users/1/posts/2", 200, "1-2-2"},
+		{"GET", "/files/a/b.txt", 200, "/a/b.txt"},
+		{"GET", "/list", 301, "Redirecting to: /list/"},
+		{"GET", "/users/1/", 301, "Redirecting to: /users/1"},
+		{"POST", "/users/1", 404, "Page not found"},
+		{"GET", "/nothing", 404, "Page not found"},
+	}
+	for _, tt := range tests {
+		w := get(s, tt.method, tt.path)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
+
+func TestRegexFallback(t *testing.T) {
+	s := newTestServer()
+	s.Get("/static", func() string { return "static" })
+	s.Get(`/(\d+)`, func(n string) string { return "number " + n })
+	s.Get(`/(?P<name>[a-z]+)\.json`, func(ctx *Context, name string) string {
+		return name + "=" + ctx.Param("name")
+	})
+
+	for path, body := range map[string]string{
+		"/static":   "static",
+		"/123":      "number 123",
+		"/abc.json": "abc=abc",
+	} {
+		if w := get(s, "GET", path); w.Body.String() != body {
+			t.Errorf("GET %s = %q, want %q", path, w.Body.String(), body)
+		}
+	}
+}
+
+func TestGroupsAndMiddleware(t *testing.T) {
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Trace", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(403)
+		})
+	}
+
+	s := newTestServer()
+	s.Use(trace("server"))
+	api := s.Group("/api")
+	api.Use(trace("api"))
+	v1 := api.Group("/v1")
+	v1.Use(trace("v1"))
+	v1.Get("/items/:id", func(ctx *Context) string { return "item " + ctx.Param("id") })
+	v1.Handle("/health", "GET", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	admin := s.Group("/admin")
+	admin.Use(deny)
+	admin.Get("/", func() string { return "secret" })
+
+	w := get(s, "GET", "/api/v1/items/7")
+	if w.Body.String() != "item 7" {
+		t.Fatalf("body = %q", w.Body.String())
+	}
+	trace1 := w.Header()["X-Trace"]
+	if len(trace1) != 3 || trace1[0] != "server" || trace1[1] != "api" || trace1[2] != "v1" {
+		t.Errorf("middleware order = %v", trace1)
+	}
+	if w := get(s, "GET", "/api/v1/health"); w.Body.String() != "ok" || len(w.Header()["X-Trace"]) != 3 {
+		t.Errorf("http.Handler route = %q %v", w.Body.String(), w.Header()["X-Trace"])
+	}
+	if w := get(s, "GET", "/admin/"); w.Code != 403 || w.Body.Len() != 0 {
+		t.Errorf("admin = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	s := newTestServer()
+	s.Get("/users/:id", func() string { return "first" })
+	// matched as a regex after the tree
+	s.Get("/users/:name", func() string { return "second" })
+	if w := get(s, "GET", "/users/1"); w.Body.String() != "first" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestConflictFallsBackToRegex(t *testing.T) {
+	s := newTestServer()
+	s.Get("/user/:id", func(ctx *Context) string { return "user " + ctx.Param("id") })
+	s.Get("/user/new", func() string { return "new user" })
+	s.Get("/item/new", func() string { return "new item" })
+	s.Get("/item/:id/*rest", func(ctx *Context, id, rest string) string { return id + rest + ctx.Param("rest") })
+	s.Get("/a.c", func() string { return "regex" })
+
+	for path, body := range map[string]string{
+		"/user/new":        "new user",
+		"/user/7":          "user 7",
+		"/item/new":        "new item",
+		"/item/3/x/y":      "3/x/y/x/y",
+		"/abc":             "regex",
+		"/a.c":             "regex",
+		"/user/new/extra":  "Page not found",
+		"/item/new/extra/": "new/extra//extra/",
+	} {
+		if w := get(s, "GET", path); w.Body.String() != body {
+			t.Errorf("GET %s = %q, want %q", path, w.Body.String(), body)
+		}
+	}
+}
+
+func TestIsTreePath(t *testing.T) {
+	for r, want := range map[string]bool{
+		"/":              true,
+		"/a/:b/c":        true,
+		"/a/:b/c.html":   false,
+		"/a.c":           false,
+		"/static/*file":  true,
+		"/(.*)":          false,
+		"/a*":            false,
+		"/a/*":           false,
+		`/(\d+)`:         false,
+		"^/x$":           false,
+		"/search/[a-z]+": false,
+	} {
+		if got := isTreePath(r); got != want {
+			t.Errorf("isTreePath(%q) = %v, want %v", r, got, want)
+		}
+	}
+}
@@ -103,8 +103,8 @@ func (s *Server) readScgiRequest(fd io.ReadWriteCloser) (*http.Request, error) {
     return nil, err
   }
 
-  if length < 16384 {
-    reutrn nil, errors.New("Max header size is 16k")
+  if length > 16384 {
+    return nil, errors.New("Max header size is 16k")
   }
 
   headerData := make([]byte, length)
@@ -145,7 +145,7 @@ func (s *Server) handleScgiRequest(fd io.ReadWriteCloser) {
   defer fd.Close()
   req, err := s.readScgiRequest(fd)
   if err != nil {
-    s.Logger.Println("Error reading SCGI request: %q", err.Error())
+    s.Logger.Printf("Error reading SCGI request: %q\n", err.Error())
     return
   }
   sc := scgiConn{fd, req, make(map[string][]string), false}
@@ -181,5 +181,4 @@ func (s *Server) listenAndServeScgi(addr string) error {
     }
     go s.handleScgiRequest(fd)
   }
-  return nil
 }
\ No newline at end of file
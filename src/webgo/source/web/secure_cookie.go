@@ -32,14 +32,14 @@ func (ctx *Context) SetSecureCookie(name string, val string, age int64) error {
 	if len(server.encKey) == 0 || len(server.signKey) == 0 {
 		return ErrInvalidKey
 	}
-	ciphertext, err := encrypt([](val), server.encKey)
+	ciphertext, err := encrypt([]byte(val), server.encKey)
 	if err != nil {
 		return err
 	}
 
 	sig := sign(ciphertext, server.signKey)
 	data := base64.StdEncoding.EncodeToString(ciphertext) + "|" + base64.StdEncoding.EncodeToString(sig)
-	ctx.SetCookie(NewCookie(name, value, age))
+	ctx.SetCookie(NewCookie(name, data, age))
 	return nil
 }
 
@@ -60,7 +60,7 @@ func (ctx *Context) GetSecureCookie(name string) (string, bool) {
 		if err != nil {
 			return "", false
 		}
-		expectedSig := sign([]byte(ciphertext), ctx.Sever.signKey)
+		expectedSig := sign([]byte(ciphertext), ctx.Server.signKey)
 		if !bytes.Equal(expectedSig, sig) {
 			return "", false
 		}
@@ -73,7 +73,7 @@ func (ctx *Context) GetSecureCookie(name string) (string, bool) {
 	return "", false
 }
 
-func getKey(password, salt string) []byte {
+func genKey(password, salt string) []byte {
 	return pbkdf2.Key([]byte(password), []byte(salt), pbkdf2Iterations, keySize, sha512.New)
 }
 
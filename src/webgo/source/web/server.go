@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"crypto/tls"
 	"fmt"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"golang.org/x/net/websocket"
+	"html/template"
 	"log"
 	"net"
 	"net/http"
@@ -16,6 +19,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,16 +32,32 @@ type ServerConfig struct {
 	RecoverPanic bool
 	Profiler     bool
 	ColorOutput  bool
+	// H2C serves HTTP/2 without TLS, RunTLS always offers HTTP/2
+	H2C bool
+	// HandleSignals restarts the server on SIGHUP and shuts it down
+	// on SIGINT and SIGTERM
+	HandleSignals bool
+	// ShutdownTimeout bounds the graceful stop of HandleSignals, 0 waits
+	// for all the requests
+	ShutdownTimeout time.Duration
 }
 
 type Server struct {
-	Config  *ServerConfig
-	routes  []route
-	Logger  *log.Logger
-	Env     map[string]interface{}
-	l       net.Listener
-	encKey  []byte
-	signKey []byte
+	Config     *ServerConfig
+	routes     []route
+	trees      map[string]*node
+	middleware []Middleware
+	Logger     *log.Logger
+	Env        map[string]interface{}
+	l          net.Listener
+	srv        *http.Server
+	done       chan struct{}
+	mu         sync.Mutex
+	encKey     []byte
+	signKey    []byte
+
+	// Templates render the View values when the client prefers HTML
+	Templates *template.Template
 }
 
 func NewServer() *Server {
@@ -69,25 +89,102 @@ type route struct {
 	method      string
 	handler     reflect.Value
 	httphandler http.Handler
+	group       *RouteGroup
+	literal     bool // a plain path which conflicted with the tree, matched before it
 }
 
+// addRoute adds a route of server s. The routes written with plain segments,
+// :param and *wildcard are stored in a radix tree per method and are matched
+// first, the other ones are compiled as regex and tried in order.
+// A tree route conflicting with the routes already in the tree, like
+// /user/new next to /user/:id, is compiled as regex too; a plain one is
+// matched before the tree so that it wins over the params.
 func (s *Server) addRoute(r string, method string, handler interface{}) {
-	cr, err := regexp.Compile(r)
-	if err != nil {
-		s.Logger.Printf("Error in route regex %q\n", r)
-		return
-	}
+	s.addGroupRoute(nil, r, method, handler)
+}
 
+func (s *Server) addGroupRoute(g *RouteGroup, r string, method string, handler interface{}) {
+	rt := route{r: r, method: method, group: g}
 	switch handler.(type) {
 	case http.Handler:
-		s.routes = append(s.routes, route{r: r, cr: cr, method: method, httpHandler: handler.(http.Handler)})
+		rt.httphandler = handler.(http.Handler)
 	case reflect.Value:
-		fv := handler.(reflect.Value)
-		s.routes = append(s.routes, route{r: r, cr: cr, method: method, handler: fv})
+		rt.handler = handler.(reflect.Value)
 	default:
-		fv := reflect.ValueOf(handler)
-		s.routes = append(s.routes, route{r: r, cr: cr, method: method, handler: fv})
+		rt.handler = reflect.ValueOf(handler)
+	}
+
+	prefix := ""
+	if g != nil {
+		prefix = g.prefix
+	}
+	expr := regexp.QuoteMeta(prefix) + r
+	if isTreePath(prefix + r) {
+		rt.r = prefix + r
+		if s.addTreeRoute(&rt) == nil {
+			return
+		}
+		expr = treeRegex(rt.r)
+		rt.literal = !strings.ContainsAny(rt.r, ":*")
+	}
+
+	cr, err := regexp.Compile(expr)
+	if err != nil {
+		s.Logger.Printf("Error in route regex %q\n", r)
+		return
 	}
+	rt.cr = cr
+	s.routes = append(s.routes, rt)
+}
+
+// addTreeRoute adds rt to the tree of its method, it returns an error when
+// rt conflicts with a route of the tree
+func (s *Server) addTreeRoute(rt *route) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	if s.trees == nil {
+		s.trees = map[string]*node{}
+	}
+	root := s.trees[rt.method]
+	if root == nil {
+		root = new(node)
+		s.trees[rt.method] = root
+	}
+	root.addRoute(rt.r, rt)
+	return nil
+}
+
+// treeRegex converts a tree route to the equivalent regex, :param and
+// *wildcard become named groups so that ctx.Param keeps working
+func treeRegex(path string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for {
+		i := strings.IndexAny(path, ":*")
+		if i < 0 {
+			b.WriteString(regexp.QuoteMeta(path))
+			break
+		}
+		end := strings.IndexByte(path[i:], '/')
+		if end < 0 {
+			end = len(path) - i
+		}
+		name := path[i+1 : i+end]
+		if path[i] == ':' {
+			b.WriteString(regexp.QuoteMeta(path[:i]))
+			fmt.Fprintf(&b, "(?P<%s>[^/]+)", name)
+		} else {
+			// like in the tree, the wildcard keeps its leading slash
+			b.WriteString(regexp.QuoteMeta(path[:i-1]))
+			fmt.Fprintf(&b, "(?P<%s>/.*)", name)
+		}
+		path = path[i+end:]
+	}
+	b.WriteByte('$')
+	return b.String()
 }
 
 // ServeHTTP is the interface method for Go's http server package
@@ -96,10 +193,7 @@ func (s *Server) ServeHTTP(c http.ResponseWriter, req *http.Request) {
 }
 
 func (s *Server) Process(c http.ResponseWriter, req *http.Request) {
-	route := s.routeHandler(req, c)
-	if route != nil {
-		route.httpHandler.ServeHTTP(c, req)
-	}
+	s.routeHandler(req, c)
 }
 
 // Get adds a handler for the 'GET' http method for server s.
@@ -141,6 +235,29 @@ func (s *Server) Websocket(route string, httpHandler websocket.Handler) {
 func (s *Server) Run(addr string) {
 	s.initServer()
 
+	l, err := s.listen(addr)
+	if err != nil {
+		log.Fatal("ListenAndServe:", err)
+	}
+
+	s.Logger.Printf("web.go serving %s\n", l.Addr())
+	if err := s.Serve(l); err != nil {
+		s.Logger.Println("Serve:", err)
+	}
+}
+
+// Serve serves HTTP requests for s on l until Shutdown returns.
+func (s *Server) Serve(l net.Listener) error {
+	s.initServer()
+	srv := &http.Server{Handler: s.handler()}
+	if s.Config.H2C {
+		srv.Handler = h2c.NewHandler(srv.Handler, &http2.Server{})
+	}
+	return s.serve(srv, l, l)
+}
+
+// handler returns the handler of s with the profiler when it is enabled
+func (s *Server) handler() http.Handler {
 	mux := http.NewServeMux()
 	if s.Config.Profiler {
 		mux.Handle("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
@@ -149,18 +266,7 @@ func (s *Server) Run(addr string) {
 		mux.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 	}
 	mux.Handle("/", s)
-
-	l, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatal("ListenAndServe:", err)
-	}
-
-	s.Logger.Printf("web.go serving %s\n", l.Addr())
-	s.l = l
-	err = http.Serve(s.l, mux)
-	s.l.Close()
-
-	// http.ListenAndServe => l, _ := net.Listen("tcp", add); http.Serve(l, mux)
+	return mux
 }
 
 // RunFcgi starts the web application and serves FastCGI requests for s.
@@ -178,23 +284,35 @@ func (s *Server) RunScgi(addr string) {
 }
 
 // RunTLS starts the web application and serves HTTPS requests for s.
+// HTTP/2 is negotiated unless config sets its own NextProtos.
 func (s *Server) RunTLS(addr string, config *tls.Config) error {
 	s.initServer()
-	mux := http.NewServeMux()
-	mux.Handle("/", s)
 
-	l, err := tls.Listen("tcp", addr, config)
+	l, err := s.listen(addr)
 	if err != nil {
 		log.Fatal("Listen: ", err)
 		return err
 	}
+
+	if config == nil {
+		config = &tls.Config{}
+	}
+	srv := &http.Server{Handler: s.handler(), TLSConfig: config.Clone()}
+	if len(srv.TLSConfig.NextProtos) == 0 {
+		if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+			l.Close()
+			return err
+		}
+	}
 	s.Logger.Printf("web.go serving %s\n", l.Addr())
 
-	s.l = l
-	return http.Serve(s.l, mux)
+	return s.serve(srv, l, tls.NewListener(l, srv.TLSConfig))
 }
 
+// Close closes the listener at once, Shutdown lets the requests complete.
 func (s *Server) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.l != nil {
 		s.l.Close()
 	}
@@ -225,7 +343,7 @@ func (s *Server) safelyCall(function reflect.Value, args []reflect.Value) (resp
 
 // requiresContext determines whether 'handlerType' contains
 // an argument to 'web.Ctx' as its first argument
-func reqiresContext(handlerType reflect.Type) bool {
+func requiresContext(handlerType reflect.Type) bool {
 	if handlerType.NumIn() == 0 {
 		return false
 	}
@@ -310,13 +428,12 @@ func (s *Server) ttyColor(msg string, colorCode string) string {
 
 // the main route handler in web.go
 // Tries to handle the given request.
-// Finds the route matching the request, and execute the callback associated
-// with it.  In case of custom http handlers, this function returns an "unused"
-// route. The caller is then responsible for calling the httpHandler associated
-// with the returned route.
-func (s *Server) routeHandler(req *http.Request, w http.ResponseWriter) (unused *route) {
+// Finds the route matching the request in the trees, then in the regex
+// routes, and execute the callback associated with it through the
+// middleware chain.
+func (s *Server) routeHandler(req *http.Request, w http.ResponseWriter) {
 	requestPath := req.URL.Path
-	ctx := Context{req, map[string]string{}, s, w}
+	ctx := Context{Request: req, Params: map[string]string{}, Server: s, ResponseWriter: w}
 
 	ctx.SetHeader("Server", "web.go", true)
 	tm := time.Now().UTC()
@@ -337,10 +454,94 @@ func (s *Server) routeHandler(req *http.Request, w http.ResponseWriter) (unused
 		}
 	}
 
+	route, args, tsr := s.findRoute(&ctx)
+	if route != nil {
+		s.chain(route.group, s.routeCall(&ctx, route, args)).ServeHTTP(ctx.ResponseWriter, ctx.Request)
+		return
+	}
+
+	if tsr && requestPath != "/" {
+		code := 301 // Permanent redirect, request with GET method
+		if req.Method != "GET" {
+			// Temporary redirect, request with same method
+			code = 307
+		}
+		if len(requestPath) > 1 && requestPath[len(requestPath)-1] == '/' {
+			requestPath = requestPath[:len(requestPath)-1]
+		} else {
+			requestPath += "/"
+		}
+		if req.URL.RawQuery != "" {
+			requestPath += "?" + req.URL.RawQuery
+		}
+		ctx.Redirect(code, requestPath)
+		return
+	}
+
+	// try serving index.html or index.htm
+	if req.Method == "GET" || req.Method == "HEAD" {
+		if s.tryServingFile(path.Join(requestPath, "index.html"), req, w) {
+			return
+		} else if s.tryServingFile(path.Join(requestPath, "index.htm"), req, w) {
+			return
+		}
+	}
+	ctx.Abort(404, "Page not found")
+}
+
+// findRoute returns the route matching the request of ctx and the arguments
+// captured from the path, which are also saved as the path params of ctx.
+// tsr reports that a tree route exists with (without) a trailing slash.
+func (s *Server) findRoute(ctx *Context) (rt *route, args []string, tsr bool) {
+	req := ctx.Request
+	requestPath := req.URL.Path
+
+	// plain routes which conflicted with the tree win over its params
+	if rt, args = s.matchRoutes(ctx, true); rt != nil {
+		return rt, args, false
+	}
+
+	methods := []string{req.Method}
+	if req.Method == "HEAD" {
+		methods = append(methods, "GET")
+	}
+	for _, method := range methods {
+		root := s.trees[method]
+		if root == nil {
+			continue
+		}
+		ctx.pathParams = ctx.pathParams[:0]
+		var redirect bool
+		if rt, redirect = root.getValue(requestPath, &ctx.pathParams); rt != nil {
+			for _, p := range ctx.pathParams {
+				args = append(args, p.Value)
+			}
+			return rt, args, false
+		}
+		tsr = tsr || redirect
+	}
+	ctx.pathParams = ctx.pathParams[:0]
+
+	rt, args = s.matchRoutes(ctx, false)
+	if rt != nil {
+		return rt, args, false
+	}
+	return nil, nil, tsr
+}
+
+// matchRoutes returns the first regex route matching the request of ctx, only
+// among the literal ones if literal is set, with the arguments captured by the regex
+func (s *Server) matchRoutes(ctx *Context, literal bool) (*route, []string) {
+	req := ctx.Request
+	requestPath := req.URL.Path
+
 	for i := 0; i < len(s.routes); i++ {
-		route := s.routes[i]
+		route := &s.routes[i]
 		cr := route.cr
 
+		if literal && !route.literal {
+			continue
+		}
 		if req.Method != route.method && !(req.Method == "HEAD" && route.method == "GET") {
 			continue
 		}
@@ -352,23 +553,44 @@ func (s *Server) routeHandler(req *http.Request, w http.ResponseWriter) (unused
 			continue
 		}
 
-		if route.httpHandler != nil {
-			unused = &route
+		for j, name := range cr.SubexpNames() {
+			if name != "" {
+				ctx.pathParams = append(ctx.pathParams, PathParam{Key: name, Value: match[j]})
+			}
+		}
+		return route, match[1:]
+	}
+	return nil, nil
+}
+
+// routeCall returns the last handler of the chain of route, it calls the
+// callback with the path arguments its signature accepts.
+func (s *Server) routeCall(ctx *Context, route *route, args []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// a middleware may have replaced the writer or the request
+		ctx.ResponseWriter, ctx.Request = w, req
+
+		if route.httphandler != nil {
+			route.httphandler.ServeHTTP(w, req)
 			return
 		}
 
 		ctx.SetHeader("Content-Type", "text/html; charset=utf-8", true)
 
-		var args []reflect.Value
+		var in []reflect.Value
 		handlerType := route.handler.Type()
 		if requiresContext(handlerType) {
-			args = append(args, reflect.ValueOf(&ctx))
+			in = append(in, reflect.ValueOf(ctx))
 		}
-		for _, arg := range match[1:] {
-			args = append(args, reflect.ValueOf(arg))
+		for _, arg := range args {
+			if !handlerType.IsVariadic() && len(in) == handlerType.NumIn() {
+				// the handler reads the path params with ctx.Param
+				break
+			}
+			in = append(in, reflect.ValueOf(arg))
 		}
 
-		ret, err := s.safelyCall(route.handler, args)
+		ret, err := s.safelyCall(route.handler, in)
 		if err != nil {
 			//there was an error or panic while calling the handler
 			ctx.Abort(500, "Server Error")
@@ -391,19 +613,7 @@ func (s *Server) routeHandler(req *http.Request, w http.ResponseWriter) (unused
 		if err != nil {
 			ctx.Server.Logger.Println("Error during write: ", err)
 		}
-		return
-	}
-
-	// try serving index.html or index.htm
-	if req.Method == "GET" || req.Method == "HEAD" {
-		if s.tryServingFile(path.Join(requestPath, "index.html"), req, w) {
-			return
-		} else if s.tryServingFile(path.Join(requestPath, "index.htm"), req, w) {
-			return
-		}
-	}
-	ctx.Abort(404, "Page not found")
-	return
+	})
 }
 
 // SetLogger sets the logger for server s
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"crypto/tls"
 	"golang.org/x/net/websocket"
 	"log"
@@ -21,6 +22,8 @@ type Context struct {
 	Params  map[string]string
 	Server  *Server
 	http.ResponseWriter
+
+	pathParams []PathParam
 }
 
 // WriteString writes string data into the response object.
@@ -69,10 +72,10 @@ func (ctx *Context) NotFound(message string) {
 
 func (ctx *Context) ContentType(val string) string {
 	var ctype string
-	if strings.ContainsRune(val, "/") {
+	if strings.ContainsRune(val, '/') {
 		ctype = val
 	} else {
-		if !string.HasPrefix(val, ".") {
+		if !strings.HasPrefix(val, ".") {
 			val = "." + val
 		}
 		ctype = mime.TypeByExtension(val)
@@ -137,6 +140,17 @@ func Close() {
 	mainServer.Close()
 }
 
+// Shutdown gracefully stops the main server.
+func Shutdown(ctx context.Context) error {
+	return mainServer.Shutdown(ctx)
+}
+
+// Restart passes the listener of the main server to a new process of the
+// program and gracefully stops it.
+func Restart(ctx context.Context) error {
+	return mainServer.Restart(ctx)
+}
+
 // Get adds a handler for the 'GET' http method in the main server.
 func Get(route string, handler interface{}) {
 	mainServer.Get(route, handler)
@@ -172,6 +186,16 @@ func Websocket(route string, httpHandler websocket.Handler) {
 	mainServer.Websocket(route, httpHandler)
 }
 
+// Use appends middlewares to the chain of every route of the main server.
+func Use(middleware ...Middleware) {
+	mainServer.Use(middleware...)
+}
+
+// Group returns a group of routes of the main server starting with prefix.
+func Group(prefix string) *RouteGroup {
+	return mainServer.Group(prefix)
+}
+
 // SetLogger sets the logger for the main server.
 func SetLogger(logger *log.Logger) {
 	mainServer.Logger = logger
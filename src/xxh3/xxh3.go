@@ -4,7 +4,6 @@ package xxh3
 import (
 	"encoding/binary"
 	"math/bits"
-	"reflect"
 	"unsafe"
 )
 
@@ -29,10 +28,11 @@ var kKey = []uint32{
 }
 
 func asUint64s(k []uint32) []uint64 {
-	hdr := *(*reflect.SliceHeader)(unsafe.Pointer(&k))
+	if len(k) < 2 {
+		return nil
+	}
 	// was uint32, now uint64
-	hdr.Len, hdr.Cap = hdr.Len/2, hdr.Cap/2
-	return *(*[]uint64)(unsafe.Pointer(&hdr))
+	return unsafe.Slice((*uint64)(unsafe.Pointer(&k[0])), len(k)/2)
 }
 
 func XXH3_mul128(ll1, ll2 uint64) uint64 {
@@ -0,0 +1,258 @@
+package hiset
+
+import (
+	"sort"
+
+	"github.com/pathbox/learning-go/src/bitset/bits-and-blooms/bitset"
+)
+
+// arrayMaxSize 超过这个数量的container改用bitmap, 4096个uint16和65536位的bitmap一样大
+const arrayMaxSize = 4096
+
+// container 保存高48位相同的数据的低16位, 稀疏时是有序数组, 稠密时是bitmap
+type container struct {
+	array  []uint16       // bitmap为nil时使用
+	bitmap *bitset.BitSet // 65536位
+	card   int
+}
+
+func newBitmap() *bitset.BitSet {
+	return bitset.New(1 << 16)
+}
+
+func (c *container) contains(v uint16) bool {
+	if c.bitmap != nil {
+		return c.bitmap.Test(uint(v))
+	}
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	return i < len(c.array) && c.array[i] == v
+}
+
+func (c *container) add(v uint16) bool {
+	if c.bitmap != nil {
+		if c.bitmap.Test(uint(v)) {
+			return false
+		}
+		c.bitmap.Set(uint(v))
+		c.card++
+		return true
+	}
+
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	if i < len(c.array) && c.array[i] == v {
+		return false
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = v
+	c.card++
+	if c.card > arrayMaxSize {
+		c.toBitmap()
+	}
+	return true
+}
+
+func (c *container) remove(v uint16) bool {
+	if c.bitmap != nil {
+		if !c.bitmap.Test(uint(v)) {
+			return false
+		}
+		c.bitmap.Clear(uint(v))
+		c.card--
+		if c.card <= arrayMaxSize/2 {
+			c.toArray()
+		}
+		return true
+	}
+
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= v })
+	if i == len(c.array) || c.array[i] != v {
+		return false
+	}
+	c.array = append(c.array[:i], c.array[i+1:]...)
+	c.card--
+	return true
+}
+
+func (c *container) toBitmap() {
+	c.bitmap = newBitmap()
+	for _, v := range c.array {
+		c.bitmap.Set(uint(v))
+	}
+	c.array = nil
+}
+
+func (c *container) toArray() {
+	c.array = make([]uint16, 0, c.card)
+	c.each(func(v uint16) bool {
+		c.array = append(c.array, v)
+		return true
+	})
+	c.bitmap = nil
+}
+
+// normalize 根据数量选择数组或bitmap
+func (c *container) normalize() {
+	switch {
+	case c.bitmap != nil && c.card <= arrayMaxSize:
+		c.toArray()
+	case c.bitmap == nil && c.card > arrayMaxSize:
+		c.toBitmap()
+	}
+}
+
+// each 从小到大遍历, fn返回false时停止
+func (c *container) each(fn func(v uint16) bool) bool {
+	if c.bitmap == nil {
+		for _, v := range c.array {
+			if !fn(v) {
+				return false
+			}
+		}
+		return true
+	}
+	for i, ok := c.bitmap.NextSet(0); ok && i < 1<<16; i, ok = c.bitmap.NextSet(i + 1) {
+		if !fn(uint16(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *container) clone() *container {
+	n := &container{card: c.card}
+	if c.bitmap != nil {
+		n.bitmap = c.bitmap.Clone()
+	} else {
+		n.array = append([]uint16(nil), c.array...)
+	}
+	return n
+}
+
+func (c *container) equals(o *container) bool {
+	if c.card != o.card {
+		return false
+	}
+	if c.bitmap != nil && o.bitmap != nil {
+		return c.bitmap.Equal(o.bitmap)
+	}
+	if c.bitmap == nil && o.bitmap == nil {
+		for i := range c.array {
+			if c.array[i] != o.array[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return c.each(o.contains)
+}
+
+func (c *container) union(o *container) *container {
+	switch {
+	case c.bitmap != nil && o.bitmap != nil:
+		b := c.bitmap.Union(o.bitmap)
+		return &container{bitmap: b, card: int(b.Count())}
+	case c.bitmap != nil || o.bitmap != nil:
+		if c.bitmap == nil {
+			c, o = o, c
+		}
+		n := c.clone()
+		for _, v := range o.array {
+			n.add(v)
+		}
+		return n
+	}
+
+	// 两个有序数组归并
+	a, b := c.array, o.array
+	merged := make([]uint16, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			merged = append(merged, a[i])
+			i++
+		case a[i] > b[j]:
+			merged = append(merged, b[j])
+			j++
+		default:
+			merged = append(merged, a[i])
+			i++
+			j++
+		}
+	}
+	merged = append(merged, a[i:]...)
+	merged = append(merged, b[j:]...)
+	n := &container{array: merged, card: len(merged)}
+	n.normalize()
+	return n
+}
+
+func (c *container) intersect(o *container) *container {
+	switch {
+	case c.bitmap != nil && o.bitmap != nil:
+		b := c.bitmap.Intersection(o.bitmap)
+		n := &container{bitmap: b, card: int(b.Count())}
+		n.normalize()
+		return n
+	case c.bitmap != nil || o.bitmap != nil:
+		if c.bitmap == nil {
+			c, o = o, c
+		}
+		n := &container{}
+		for _, v := range o.array {
+			if c.bitmap.Test(uint(v)) {
+				n.array = append(n.array, v)
+			}
+		}
+		n.card = len(n.array)
+		return n
+	}
+
+	a, b := c.array, o.array
+	n := &container{}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			n.array = append(n.array, a[i])
+			i++
+			j++
+		}
+	}
+	n.card = len(n.array)
+	return n
+}
+
+func (c *container) difference(o *container) *container {
+	switch {
+	case c.bitmap != nil && o.bitmap != nil:
+		b := c.bitmap.Difference(o.bitmap)
+		n := &container{bitmap: b, card: int(b.Count())}
+		n.normalize()
+		return n
+	case c.bitmap != nil:
+		n := c.clone()
+		for _, v := range o.array {
+			if n.bitmap.Test(uint(v)) {
+				n.bitmap.Clear(uint(v))
+				n.card--
+			}
+		}
+		n.normalize()
+		return n
+	}
+
+	n := &container{}
+	for _, v := range c.array {
+		if !o.contains(v) {
+			n.array = append(n.array, v)
+		}
+	}
+	n.card = len(n.array)
+	return n
+}
@@ -0,0 +1,420 @@
+package hiset
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/pathbox/learning-go/src/bitset/bits-and-blooms/bitset"
+)
+
+// Integer 整数类型
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// ErrInvalidIntSet UnmarshalBinary的数据不合法
+var ErrInvalidIntSet = errors.New("hiset: invalid IntSet data")
+
+// IntSet 压缩的整数set, 参考roaring bitmap: 数据按高48位分组,
+// 每组的低16位存在一个container里, 稀疏时是有序数组, 稠密时是bitmap.
+// 百万级的整数ID只占几百KB, 并集交集差集按container计算.
+type IntSet[T Integer] struct {
+	keys       []uint64 // 高48位, 从小到大
+	containers []*container
+	size       int
+}
+
+var _ Set[int] = (*IntSet[int])(nil)
+
+// NewIntSet 初始化压缩的整数Set
+func NewIntSet[T Integer](items ...T) *IntSet[T] {
+	s := &IntSet[T]{}
+	_ = s.Add(items...)
+	return s
+}
+
+// encode 转成uint64, 有符号数翻转符号位, 保持大小顺序
+func encode[T Integer](item T) uint64 {
+	var zero T
+	if zero-1 < zero {
+		return uint64(item) ^ 1<<63
+	}
+	return uint64(item)
+}
+
+func decode[T Integer](v uint64) T {
+	var zero T
+	if zero-1 < zero {
+		return T(v ^ 1<<63)
+	}
+	return T(v)
+}
+
+// find 返回高位key所在的下标
+func (s *IntSet[T]) find(key uint64) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key })
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+// Add 添加数据
+func (s *IntSet[T]) Add(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		v := encode(item)
+		i, ok := s.find(v >> 16)
+		if !ok {
+			s.keys = append(s.keys, 0)
+			copy(s.keys[i+1:], s.keys[i:])
+			s.keys[i] = v >> 16
+			s.containers = append(s.containers, nil)
+			copy(s.containers[i+1:], s.containers[i:])
+			s.containers[i] = &container{}
+		}
+		if s.containers[i].add(uint16(v)) {
+			s.size++
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Remove 删除匹配的数据
+func (s *IntSet[T]) Remove(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		v := encode(item)
+		i, ok := s.find(v >> 16)
+		if !ok || !s.containers[i].remove(uint16(v)) {
+			continue
+		}
+		s.size--
+		changed = true
+		if s.containers[i].card == 0 {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			s.containers = append(s.containers[:i], s.containers[i+1:]...)
+		}
+	}
+	return changed
+}
+
+func (s *IntSet[T]) Contains(items ...T) bool {
+	for _, item := range items {
+		v := encode(item)
+		i, ok := s.find(v >> 16)
+		if !ok || !s.containers[i].contains(uint16(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsEmpty 校验set是否为空
+func (s *IntSet[T]) IsEmpty() bool {
+	return s.size == 0
+}
+
+// Size 返回set长度
+func (s *IntSet[T]) Size() int {
+	return s.size
+}
+
+// Clear 清空数据
+func (s *IntSet[T]) Clear() {
+	*s = IntSet[T]{}
+}
+
+// Range 按顺序遍历[from, to)范围内的数据, fn返回false时停止
+func (s *IntSet[T]) Range(from, to T, fn func(item T) bool) {
+	lo, hi := encode(from), encode(to)
+	i, _ := s.find(lo >> 16)
+	for ; i < len(s.keys); i++ {
+		high := s.keys[i] << 16
+		if high >= hi {
+			return
+		}
+		more := s.containers[i].each(func(low uint16) bool {
+			v := high | uint64(low)
+			if v < lo {
+				return true
+			}
+			if v >= hi {
+				return false
+			}
+			return fn(decode[T](v))
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// each 从小到大遍历所有数据
+func (s *IntSet[T]) each(fn func(item T) bool) {
+	for i, c := range s.containers {
+		high := s.keys[i] << 16
+		if !c.each(func(low uint16) bool { return fn(decode[T](high | uint64(low))) }) {
+			return
+		}
+	}
+}
+
+// ToArray 按从小到大返回set列表
+func (s *IntSet[T]) ToArray() []T {
+	ret := make([]T, 0, s.size)
+	s.each(func(item T) bool {
+		ret = append(ret, item)
+		return true
+	})
+	return ret
+}
+
+// Equals 校验两个set是否相等
+func (s *IntSet[T]) Equals(cmpSet Set[T]) bool {
+	if cmpSet == nil || cmpSet.Size() != s.size {
+		return false
+	}
+
+	o, ok := cmpSet.(*IntSet[T])
+	if !ok {
+		return s.Contains(cmpSet.ToArray()...)
+	}
+	if len(o.keys) != len(s.keys) {
+		return false
+	}
+	for i := range s.keys {
+		if s.keys[i] != o.keys[i] || !s.containers[i].equals(o.containers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *IntSet[T]) clone() *IntSet[T] {
+	n := &IntSet[T]{
+		keys:       append([]uint64(nil), s.keys...),
+		containers: make([]*container, len(s.containers)),
+		size:       s.size,
+	}
+	for i, c := range s.containers {
+		n.containers[i] = c.clone()
+	}
+	return n
+}
+
+// toIntSet 其他类型的set先转成IntSet
+func toIntSet[T Integer](other Set[T]) *IntSet[T] {
+	if o, ok := other.(*IntSet[T]); ok {
+		return o
+	}
+	if other == nil {
+		return &IntSet[T]{}
+	}
+	return NewIntSet(other.ToArray()...)
+}
+
+// appendContainer 添加非空的container
+func (s *IntSet[T]) appendContainer(key uint64, c *container) {
+	if c.card == 0 {
+		return
+	}
+	s.keys = append(s.keys, key)
+	s.containers = append(s.containers, c)
+	s.size += c.card
+}
+
+// Union 取并集, 返回*IntSet
+func (s *IntSet[T]) Union(other Set[T]) Set[T] {
+	o := toIntSet(other)
+	n := &IntSet[T]{}
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(o.keys) {
+		switch {
+		case s.keys[i] < o.keys[j]:
+			n.appendContainer(s.keys[i], s.containers[i].clone())
+			i++
+		case s.keys[i] > o.keys[j]:
+			n.appendContainer(o.keys[j], o.containers[j].clone())
+			j++
+		default:
+			n.appendContainer(s.keys[i], s.containers[i].union(o.containers[j]))
+			i++
+			j++
+		}
+	}
+	for ; i < len(s.keys); i++ {
+		n.appendContainer(s.keys[i], s.containers[i].clone())
+	}
+	for ; j < len(o.keys); j++ {
+		n.appendContainer(o.keys[j], o.containers[j].clone())
+	}
+	return n
+}
+
+// Intersection 取交集, 返回*IntSet
+func (s *IntSet[T]) Intersection(other Set[T]) Set[T] {
+	o := toIntSet(other)
+	n := &IntSet[T]{}
+	i, j := 0, 0
+	for i < len(s.keys) && j < len(o.keys) {
+		switch {
+		case s.keys[i] < o.keys[j]:
+			i++
+		case s.keys[i] > o.keys[j]:
+			j++
+		default:
+			n.appendContainer(s.keys[i], s.containers[i].intersect(o.containers[j]))
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// Difference 取差集, 返回*IntSet
+func (s *IntSet[T]) Difference(other Set[T]) Set[T] {
+	o := toIntSet(other)
+	n := &IntSet[T]{}
+	j := 0
+	for i := range s.keys {
+		for j < len(o.keys) && o.keys[j] < s.keys[i] {
+			j++
+		}
+		if j < len(o.keys) && o.keys[j] == s.keys[i] {
+			n.appendContainer(s.keys[i], s.containers[i].difference(o.containers[j]))
+		} else {
+			n.appendContainer(s.keys[i], s.containers[i].clone())
+		}
+	}
+	return n
+}
+
+// MarshalJSON marshal json implement
+func (s *IntSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToArray())
+}
+
+// UnmarshalJSON unmarshal from json
+func (s *IntSet[T]) UnmarshalJSON(b []byte) error {
+	var arr []T
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+
+	s.Clear()
+	s.Add(arr...)
+	return nil
+}
+
+const (
+	arrayContainer  = 0
+	bitmapContainer = 1
+)
+
+// MarshalBinary 序列化: container数量, 然后每个container的高位key、类型和数据,
+// 数组存每个uint16, bitmap存1024个uint64, 都是小端
+func (s *IntSet[T]) MarshalBinary() ([]byte, error) {
+	buf := appendUvarint(nil, uint64(len(s.keys)))
+	for i, c := range s.containers {
+		buf = appendUvarint(buf, s.keys[i])
+		if c.bitmap != nil {
+			buf = append(buf, bitmapContainer)
+			words := c.bitmap.Bytes()
+			for w := 0; w < 1<<16/64; w++ {
+				var word uint64
+				if w < len(words) {
+					word = words[w]
+				}
+				buf = appendUint64(buf, word)
+			}
+			continue
+		}
+		buf = append(buf, arrayContainer)
+		buf = appendUvarint(buf, uint64(len(c.array)))
+		for _, v := range c.array {
+			buf = appendUint16(buf, v)
+		}
+	}
+	return buf, nil
+}
+
+// appendUvarint, appendUint64和appendUint16代替Go 1.19才有的binary.AppendUvarint等函数
+func appendUvarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	return append(buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// UnmarshalBinary 反序列化MarshalBinary的数据
+func (s *IntSet[T]) UnmarshalBinary(data []byte) error {
+	n := IntSet[T]{}
+	count, l := binary.Uvarint(data)
+	if l <= 0 {
+		return ErrInvalidIntSet
+	}
+	data = data[l:]
+
+	for ; count > 0; count-- {
+		key, l := binary.Uvarint(data)
+		if l <= 0 || len(data) == l || (len(n.keys) > 0 && key <= n.keys[len(n.keys)-1]) {
+			return ErrInvalidIntSet
+		}
+		kind := data[l]
+		data = data[l+1:]
+
+		c := &container{}
+		switch kind {
+		case bitmapContainer:
+			if len(data) < 1<<16/8 {
+				return ErrInvalidIntSet
+			}
+			words := make([]uint64, 1<<16/64)
+			for w := range words {
+				words[w] = binary.LittleEndian.Uint64(data[w*8:])
+			}
+			data = data[1<<16/8:]
+			c.bitmap = bitset.From(words)
+			c.card = int(c.bitmap.Count())
+		case arrayContainer:
+			size, l := binary.Uvarint(data)
+			if l <= 0 || size > arrayMaxSize || uint64(len(data)-l) < size*2 {
+				return ErrInvalidIntSet
+			}
+			data = data[l:]
+			c.array = make([]uint16, size)
+			for i := range c.array {
+				c.array[i] = binary.LittleEndian.Uint16(data[i*2:])
+				if i > 0 && c.array[i] <= c.array[i-1] {
+					return ErrInvalidIntSet
+				}
+			}
+			data = data[size*2:]
+			c.card = len(c.array)
+		default:
+			return ErrInvalidIntSet
+		}
+		c.normalize()
+		n.appendContainer(key, c)
+	}
+	if len(data) != 0 {
+		return ErrInvalidIntSet
+	}
+
+	*s = n
+	return nil
+}
@@ -0,0 +1,153 @@
+package hiset
+
+import "encoding/json"
+
+type linkedNode[T comparable] struct {
+	item       T
+	prev, next *linkedNode[T]
+}
+
+// linkedSet 保持插入顺序的set, map索引双向链表, 增删都是O(1)
+type linkedSet[T comparable] struct {
+	nodes       map[T]*linkedNode[T]
+	first, last *linkedNode[T]
+}
+
+func newLinkedSet[T comparable]() *linkedSet[T] {
+	return &linkedSet[T]{
+		nodes: make(map[T]*linkedNode[T]),
+	}
+}
+
+// Add 添加数据, 新数据放在末尾
+func (s *linkedSet[T]) Add(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		if _, ok := s.nodes[item]; ok {
+			continue
+		}
+		changed = true
+		n := &linkedNode[T]{item: item, prev: s.last}
+		if s.last == nil {
+			s.first = n
+		} else {
+			s.last.next = n
+		}
+		s.last = n
+		s.nodes[item] = n
+	}
+	return changed
+}
+
+// Clear 清空数据
+func (s *linkedSet[T]) Clear() {
+	s.nodes = make(map[T]*linkedNode[T])
+	s.first, s.last = nil, nil
+}
+
+// Size 返回set长度
+func (s *linkedSet[T]) Size() int {
+	return len(s.nodes)
+}
+
+func (s *linkedSet[T]) Contains(items ...T) bool {
+	for _, item := range items {
+		if _, ok := s.nodes[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals 校验两个set是否相等, 不比较顺序
+func (s *linkedSet[T]) Equals(cmpSet Set[T]) bool {
+	if cmpSet == nil || cmpSet.Size() != len(s.nodes) {
+		return false
+	}
+
+	for _, item := range cmpSet.ToArray() {
+		if _, ok := s.nodes[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Union 取并集, 先当前set的顺序, 再另一个set的顺序
+func (s *linkedSet[T]) Union(other Set[T]) Set[T] {
+	n := newLinkedSet[T]()
+	union[T](s, other, n)
+	return n
+}
+
+// Intersection 取交集, 保持当前set的顺序
+func (s *linkedSet[T]) Intersection(other Set[T]) Set[T] {
+	n := newLinkedSet[T]()
+	intersection[T](s, other, n)
+	return n
+}
+
+// Difference 取差集, 保持当前set的顺序
+func (s *linkedSet[T]) Difference(other Set[T]) Set[T] {
+	n := newLinkedSet[T]()
+	difference[T](s, other, n)
+	return n
+}
+
+// IsEmpty 校验set是否为空
+func (s *linkedSet[T]) IsEmpty() bool {
+	return len(s.nodes) == 0
+}
+
+// Remove 删除匹配的数据
+func (s *linkedSet[T]) Remove(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		n, ok := s.nodes[item]
+		if !ok {
+			continue
+		}
+		if n.prev == nil {
+			s.first = n.next
+		} else {
+			n.prev.next = n.next
+		}
+		if n.next == nil {
+			s.last = n.prev
+		} else {
+			n.next.prev = n.prev
+		}
+		delete(s.nodes, item)
+		changed = true
+	}
+
+	return changed
+}
+
+// ToArray 按插入顺序返回set列表
+func (s *linkedSet[T]) ToArray() []T {
+	ret := make([]T, 0, len(s.nodes))
+	for n := s.first; n != nil; n = n.next {
+		ret = append(ret, n.item)
+	}
+	return ret
+}
+
+// MarshalJSON marshal json implement
+func (s *linkedSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToArray())
+}
+
+// UnmarshalJSON unmarshal from json
+func (s *linkedSet[T]) UnmarshalJSON(b []byte) error {
+	var arr []T
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+
+	s.Clear()
+	s.Add(arr...)
+	return nil
+}
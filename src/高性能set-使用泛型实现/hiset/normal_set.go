@@ -77,6 +77,13 @@ func (s *set[T]) Intersection(other Set[T]) Set[T] {
 	return n
 }
 
+// Difference 取差集
+func (s *set[T]) Difference(other Set[T]) Set[T] {
+	n := newSet[T]()
+	difference[T](s, other, n)
+	return n
+}
+
 // IsEmpty 校验set是否为空
 func (s *set[T]) IsEmpty() bool {
 	return len(s.setMap) == 0
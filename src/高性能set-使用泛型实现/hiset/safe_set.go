@@ -109,3 +109,10 @@ func (s *threadSafeSet[T]) Intersection(other Set[T]) Set[T] {
 	intersection[T](s, other, n)
 	return n
 }
+
+// Difference 取差集
+func (s *threadSafeSet[T]) Difference(other Set[T]) Set[T] {
+	n := newThreadSafeSet[T]()
+	difference[T](s, other, n)
+	return n
+}
@@ -29,6 +29,9 @@ type Set[T comparable] interface {
 	// Intersection 两个set取交集
 	Intersection(Set[T]) Set[T]
 
+	// Difference 取差集, 在当前set而不在另一个set中的数据
+	Difference(Set[T]) Set[T]
+
 	// Clear 清空set
 	Clear()
 
@@ -81,3 +84,16 @@ func intersection[T comparable](old1 Set[T], old2 Set[T], new Set[T]) {
 		}
 	}
 }
+
+func difference[T comparable](old1 Set[T], old2 Set[T], new Set[T]) {
+	if old1 == nil {
+		return
+	}
+
+	old1Array := old1.ToArray()
+	for index, item := range old1Array {
+		if old2 == nil || !old2.Contains(item) {
+			new.Add(old1Array[index])
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package hiset
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLinkedSetOrder(t *testing.T) {
+	s := NewLinkedSet(3, 1, 2)
+	s.Add(1, 5)
+	s.Remove(3)
+	if got := s.ToArray(); !reflect.DeepEqual(got, []int{1, 2, 5}) {
+		t.Fatalf("ToArray() = %v", got)
+	}
+
+	b, _ := s.MarshalJSON()
+	if string(b) != "[1,2,5]" {
+		t.Fatalf("MarshalJSON() = %s", b)
+	}
+	u := s.Union(NewLinkedSet(9, 2))
+	if got := u.ToArray(); !reflect.DeepEqual(got, []int{1, 2, 5, 9}) {
+		t.Fatalf("Union() = %v", got)
+	}
+	if got := s.Difference(NewSet(2)).ToArray(); !reflect.DeepEqual(got, []int{1, 5}) {
+		t.Fatalf("Difference() = %v", got)
+	}
+}
+
+func TestSortedSet(t *testing.T) {
+	s := NewSortedSet(50, 10, 40, 20, 30)
+	if !s.Add(25) || s.Add(25) {
+		t.Fatal("Add should only report new items")
+	}
+	if got := s.ToArray(); !reflect.DeepEqual(got, []int{10, 20, 25, 30, 40, 50}) {
+		t.Fatalf("ToArray() = %v", got)
+	}
+
+	var r []int
+	s.Range(20, 40, func(item int) bool {
+		r = append(r, item)
+		return true
+	})
+	if !reflect.DeepEqual(r, []int{20, 25, 30}) {
+		t.Fatalf("Range(20, 40) = %v", r)
+	}
+
+	for _, c := range []struct {
+		item, floor, ceiling int
+		hasFloor, hasCeiling bool
+	}{
+		{5, 0, 10, false, true},
+		{10, 10, 10, true, true},
+		{27, 25, 30, true, true},
+		{60, 50, 0, true, false},
+	} {
+		f, ok := s.Floor(c.item)
+		if ok != c.hasFloor || (ok && f != c.floor) {
+			t.Errorf("Floor(%d) = %d, %v", c.item, f, ok)
+		}
+		ce, ok := s.Ceiling(c.item)
+		if ok != c.hasCeiling || (ok && ce != c.ceiling) {
+			t.Errorf("Ceiling(%d) = %d, %v", c.item, ce, ok)
+		}
+	}
+	if min, _ := s.Min(); min != 10 {
+		t.Errorf("Min() = %d", min)
+	}
+	if max, _ := s.Max(); max != 50 {
+		t.Errorf("Max() = %d", max)
+	}
+
+	u := s.Union(NewSet(5, 10))
+	if got := u.ToArray(); !reflect.DeepEqual(got, []int{5, 10, 20, 25, 30, 40, 50}) {
+		t.Fatalf("Union() = %v", got)
+	}
+	if s.Size() != 6 {
+		t.Fatal("Union modified the set")
+	}
+
+	words := NewSortedSet("pear", "apple")
+	var back SortedSet[string] = NewSortedSet[string]()
+	b, _ := json.Marshal(words)
+	if err := json.Unmarshal(b, back); err != nil || !back.Equals(words) || string(b) != `["apple","pear"]` {
+		t.Fatalf("JSON round trip %s, %v", b, err)
+	}
+}
+
+func TestIntSet(t *testing.T) {
+	s := NewIntSet[int64](-5, 0, 1, 70000, -70000, 1<<40)
+	if s.Size() != 6 || !s.Contains(-5, 1<<40) || s.Contains(2) {
+		t.Fatalf("Size() = %d", s.Size())
+	}
+	if got := s.ToArray(); !reflect.DeepEqual(got, []int64{-70000, -5, 0, 1, 70000, 1 << 40}) {
+		t.Fatalf("ToArray() = %v", got)
+	}
+
+	var r []int64
+	s.Range(-5, 70000, func(item int64) bool {
+		r = append(r, item)
+		return true
+	})
+	if !reflect.DeepEqual(r, []int64{-5, 0, 1}) {
+		t.Fatalf("Range() = %v", r)
+	}
+
+	s.Remove(-70000, 1<<40, 3)
+	if s.Size() != 4 || len(s.keys) != 3 { // -5; 0 和 1; 70000
+		t.Fatalf("Remove left %d items in %d containers", s.Size(), len(s.keys))
+	}
+}
+
+// randomSets 返回同样数据的IntSet和map实现的set, 有稀疏和稠密的container
+func randomSets(r *rand.Rand) (*IntSet[uint32], Set[uint32]) {
+	s := NewIntSet[uint32]()
+	m := NewSet[uint32]()
+	for i := 0; i < 20000; i++ {
+		v := uint32(r.Intn(1 << 16)) // 稠密
+		if i%2 == 0 {
+			v = uint32(r.Intn(1 << 24)) // 稀疏
+		}
+		s.Add(v)
+		m.Add(v)
+	}
+	for i := 0; i < 3000; i++ {
+		v := uint32(r.Intn(1 << 16))
+		s.Remove(v)
+		m.Remove(v)
+	}
+	return s, m
+}
+
+func sorted(items []uint32) []uint32 {
+	sort.Slice(items, func(i, j int) bool { return items[i] < items[j] })
+	return items
+}
+
+func TestIntSetAlgebra(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	a, ma := randomSets(r)
+	b, mb := randomSets(r)
+
+	if a.Size() != ma.Size() || !a.Equals(ma) || !reflect.DeepEqual(a.ToArray(), sorted(ma.ToArray())) {
+		t.Fatal("IntSet differs from map set")
+	}
+
+	for name, c := range map[string][2]Set[uint32]{
+		"union":        {a.Union(b), ma.Union(mb)},
+		"intersection": {a.Intersection(b), ma.Intersection(mb)},
+		"difference":   {a.Difference(b), ma.Difference(mb)},
+		"mixed":        {a.Difference(mb), ma.Difference(b)},
+	} {
+		if _, ok := c[0].(*IntSet[uint32]); !ok {
+			t.Fatalf("%s is not an IntSet", name)
+		}
+		if !reflect.DeepEqual(c[0].ToArray(), sorted(c[1].ToArray())) || !c[0].Equals(c[1]) {
+			t.Fatalf("%s differs: %d vs %d items", name, c[0].Size(), c[1].Size())
+		}
+	}
+}
+
+func TestIntSetBinary(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	a, _ := randomSets(r)
+
+	b, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back IntSet[uint32]
+	if err := back.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Equals(a) {
+		t.Fatal("binary round trip changed the set")
+	}
+
+	for _, bad := range [][]byte{nil, b[:len(b)-1], append(b, 0)} {
+		if err := back.UnmarshalBinary(bad); err != ErrInvalidIntSet {
+			t.Errorf("UnmarshalBinary(%d bytes) = %v", len(bad), err)
+		}
+	}
+}
+
+func BenchmarkIntersectionIntSet(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	s1, _ := randomSets(r)
+	s2, _ := randomSets(r)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s1.Intersection(s2)
+	}
+}
+
+func BenchmarkIntersectionMapSet(b *testing.B) {
+	r := rand.New(rand.NewSource(3))
+	_, s1 := randomSets(r)
+	_, s2 := randomSets(r)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s1.Intersection(s2)
+	}
+}
@@ -0,0 +1,189 @@
+package hiset
+
+import (
+	"encoding/json"
+
+	"github.com/pathbox/learning-go/src/tinybtree"
+)
+
+// SortedSet 有序set, 数据按从小到大排列
+type SortedSet[T tinybtree.Ordered] interface {
+	Set[T]
+
+	// Range 按顺序遍历[from, to)范围内的数据, fn返回false时停止
+	Range(from, to T, fn func(item T) bool)
+
+	// Floor 返回小于等于item的最大数据
+	Floor(item T) (T, bool)
+
+	// Ceiling 返回大于等于item的最小数据
+	Ceiling(item T) (T, bool)
+
+	// Min 返回最小的数据
+	Min() (T, bool)
+
+	// Max 返回最大的数据
+	Max() (T, bool)
+}
+
+// sortedSet 基于B树的有序set, 增删查都是O(log n)
+type sortedSet[T tinybtree.Ordered] struct {
+	tree *tinybtree.BTreeG[T, struct{}]
+}
+
+func newSortedSet[T tinybtree.Ordered]() *sortedSet[T] {
+	return &sortedSet[T]{
+		tree: tinybtree.NewOrdered[T, struct{}](),
+	}
+}
+
+// NewSortedSet 初始化有序Set
+func NewSortedSet[T tinybtree.Ordered](items ...T) SortedSet[T] {
+	s := newSortedSet[T]()
+	_ = s.Add(items...)
+	return s
+}
+
+// Add 添加数据
+func (s *sortedSet[T]) Add(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		if _, replaced := s.tree.Set(item, struct{}{}); !replaced {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Clear 清空数据
+func (s *sortedSet[T]) Clear() {
+	s.tree = tinybtree.NewOrdered[T, struct{}]()
+}
+
+// Size 返回set长度
+func (s *sortedSet[T]) Size() int {
+	return s.tree.Len()
+}
+
+func (s *sortedSet[T]) Contains(items ...T) bool {
+	for _, item := range items {
+		if _, ok := s.tree.Get(item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals 校验两个set是否相等
+func (s *sortedSet[T]) Equals(cmpSet Set[T]) bool {
+	if cmpSet == nil || cmpSet.Size() != s.tree.Len() {
+		return false
+	}
+
+	for _, item := range cmpSet.ToArray() {
+		if _, ok := s.tree.Get(item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Union 取并集
+func (s *sortedSet[T]) Union(other Set[T]) Set[T] {
+	n := &sortedSet[T]{tree: s.tree.Copy()}
+	if other != nil {
+		n.Add(other.ToArray()...)
+	}
+	return n
+}
+
+// Intersection 取交集
+func (s *sortedSet[T]) Intersection(other Set[T]) Set[T] {
+	n := newSortedSet[T]()
+	intersection[T](s, other, n)
+	return n
+}
+
+// Difference 取差集
+func (s *sortedSet[T]) Difference(other Set[T]) Set[T] {
+	n := newSortedSet[T]()
+	difference[T](s, other, n)
+	return n
+}
+
+// IsEmpty 校验set是否为空
+func (s *sortedSet[T]) IsEmpty() bool {
+	return s.tree.Len() == 0
+}
+
+// Remove 删除匹配的数据
+func (s *sortedSet[T]) Remove(items ...T) bool {
+	changed := false
+
+	for _, item := range items {
+		if _, deleted := s.tree.Delete(item); deleted {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ToArray 按从小到大返回set列表
+func (s *sortedSet[T]) ToArray() []T {
+	ret := make([]T, 0, s.tree.Len())
+	s.tree.Scan(func(item T, _ struct{}) bool {
+		ret = append(ret, item)
+		return true
+	})
+	return ret
+}
+
+func (s *sortedSet[T]) Range(from, to T, fn func(item T) bool) {
+	s.tree.AscendRange(from, to, func(item T, _ struct{}) bool {
+		return fn(item)
+	})
+}
+
+func (s *sortedSet[T]) Floor(item T) (floor T, ok bool) {
+	s.tree.Descend(item, func(key T, _ struct{}) bool {
+		floor, ok = key, true
+		return false
+	})
+	return
+}
+
+func (s *sortedSet[T]) Ceiling(item T) (ceiling T, ok bool) {
+	s.tree.Ascend(item, func(key T, _ struct{}) bool {
+		ceiling, ok = key, true
+		return false
+	})
+	return
+}
+
+func (s *sortedSet[T]) Min() (T, bool) {
+	item, _, ok := s.tree.Min()
+	return item, ok
+}
+
+func (s *sortedSet[T]) Max() (T, bool) {
+	item, _, ok := s.tree.Max()
+	return item, ok
+}
+
+// MarshalJSON marshal json implement
+func (s *sortedSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToArray())
+}
+
+// UnmarshalJSON unmarshal from json
+func (s *sortedSet[T]) UnmarshalJSON(b []byte) error {
+	var arr []T
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return err
+	}
+
+	s.Clear()
+	s.Add(arr...)
+	return nil
+}